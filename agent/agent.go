@@ -62,6 +62,7 @@ type Agent struct {
 	httpServer          *shttp.Server
 	tidMapper           *topology.TIDMapper
 	topologyForwarder   *TopologyForwarder
+	hostUpdater         *hostUpdater
 }
 
 // NewAnalyzerWSStructClientPool creates a new http WebSocket client Pool
@@ -81,7 +82,7 @@ func NewAnalyzerWSStructClientPool(authOptions *shttp.AuthenticationOpts) (*shtt
 
 	for _, sa := range addresses {
 		c := shttp.NewWSClientFromConfig(common.AgentService, config.GetURL("ws", sa.Addr, sa.Port, "/ws/agent"), authOptions, nil)
-		pool.AddClient(c)
+		pool.AddClient(shttp.NewReconnectingSpeaker(c, shttp.DefaultWSBackoff))
 	}
 
 	return pool, nil
@@ -134,6 +135,7 @@ func (a *Agent) Start() {
 	a.topologyProbeBundle.Start()
 	a.flowProbeBundle.Start()
 	a.onDemandProbeServer.Start()
+	a.hostUpdater.Start()
 
 	// everything is ready, then initiate the websocket connection
 	go a.analyzerClientPool.ConnectAll()
@@ -141,6 +143,7 @@ func (a *Agent) Start() {
 
 // Stop agent services
 func (a *Agent) Stop() {
+	a.hostUpdater.Stop()
 	a.flowProbeBundle.Stop()
 	a.analyzerClientPool.Stop()
 	a.topologyProbeBundle.Stop()
@@ -159,6 +162,12 @@ func (a *Agent) Stop() {
 	a.tidMapper.Stop()
 }
 
+// ReloadMetadata applies the changes of the agent.metadata configuration
+// section to the host node
+func (a *Agent) ReloadMetadata() {
+	a.hostUpdater.updateConfigMetadata()
+}
+
 // NewAgent instanciates a new Agent aiming to launch probes (topology and flow)
 func NewAgent() (*Agent, error) {
 	backend, err := graph.NewMemoryBackend()
@@ -267,6 +276,7 @@ func NewAgent() (*Agent, error) {
 		httpServer:          hserver,
 		tidMapper:           tm,
 		topologyForwarder:   tforwarder,
+		hostUpdater:         newHostUpdater(g, rootNode),
 	}
 
 	api.RegisterStatusAPI(hserver, agent, apiAuthBackend)
@@ -0,0 +1,95 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// parseIPMILanPrint parses the output of 'ipmitool lan print <channel>'
+func parseIPMILanPrint(out []byte) (*BMCInfo, error) {
+	bmc := &BMCInfo{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+
+		switch strings.TrimSpace(kv[0]) {
+		case "IP Address":
+			bmc.IPAddress = value
+		case "MAC Address":
+			bmc.MAC = value
+		case "802.1q VLAN ID":
+			if vlan, err := strconv.ParseInt(value, 10, 64); err == nil {
+				bmc.VLAN = vlan
+			}
+		}
+	}
+
+	if bmc.IPAddress == "" || bmc.IPAddress == "0.0.0.0" {
+		return nil, errors.New("No IP address configured")
+	}
+
+	return bmc, nil
+}
+
+func getBMCInfo(channels []string) (*BMCInfo, error) {
+	if _, err := os.Stat("/dev/ipmi0"); err != nil {
+		return nil, err
+	}
+
+	path, err := exec.LookPath("ipmitool")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		ctx, cancel := context.WithTimeout(context.Background(), ipmitoolTimeout)
+		out, err := exec.CommandContext(ctx, path, "lan", "print", channel).Output()
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		if bmc, err := parseIPMILanPrint(out); err == nil {
+			bmc.Channel = channel
+			return bmc, nil
+		}
+	}
+
+	return nil, fmt.Errorf("No BMC address found on channels %v", channels)
+}
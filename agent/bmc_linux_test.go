@@ -0,0 +1,52 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"testing"
+)
+
+const ipmitoolLanOutput = `Set in Progress         : Set Complete
+IP Address Source       : Static Address
+IP Address              : 10.0.0.12
+Subnet Mask             : 255.255.255.0
+MAC Address             : 3c:a8:2a:11:22:33
+802.1q VLAN ID          : 100
+`
+
+func TestIPMILanPrint(t *testing.T) {
+	bmc, err := parseIPMILanPrint([]byte(ipmitoolLanOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bmc.IPAddress != "10.0.0.12" || bmc.MAC != "3c:a8:2a:11:22:33" || bmc.VLAN != 100 {
+		t.Fatalf("Parsing of ipmitool lan print failed, got: %+v", bmc)
+	}
+
+	if _, err = parseIPMILanPrint([]byte("IP Address              : 0.0.0.0\n")); err == nil {
+		t.Fatal("Parsing of an unconfigured BMC should return an error")
+	}
+}
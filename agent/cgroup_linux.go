@@ -0,0 +1,97 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// parseProcCgroups returns the enabled controllers listed in /proc/cgroups
+func parseProcCgroups(content []byte) []string {
+	var controllers []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// subsys_name hierarchy num_cgroups enabled
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[3] != "1" {
+			continue
+		}
+		controllers = append(controllers, fields[0])
+	}
+
+	sort.Strings(controllers)
+	return controllers
+}
+
+// parseCgroupControllers returns the controllers of a cgroup.controllers file
+func parseCgroupControllers(content []byte) []string {
+	controllers := strings.Fields(string(content))
+	sort.Strings(controllers)
+	return controllers
+}
+
+// getCgroupsInfo detects the cgroup version from the layout of /sys/fs/cgroup,
+// systemd mounts cgroup2 at the root in unified mode and under 'unified' in
+// hybrid mode
+func getCgroupsInfo() (*CgroupsInfo, error) {
+	if content, err := ioutil.ReadFile(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return &CgroupsInfo{
+			Version:     2,
+			Controllers: parseCgroupControllers(content),
+			SystemdMode: "unified",
+		}, nil
+	}
+
+	content, err := ioutil.ReadFile("/proc/cgroups")
+	if err != nil {
+		return nil, err
+	}
+
+	cgroups := &CgroupsInfo{
+		Version:     1,
+		Controllers: parseProcCgroups(content),
+		SystemdMode: "legacy",
+	}
+
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "unified", "cgroup.procs")); err == nil {
+		cgroups.SystemdMode = "hybrid"
+	}
+
+	return cgroups, nil
+}
@@ -0,0 +1,60 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+const procCgroups = `#subsys_name	hierarchy	num_cgroups	enabled
+cpuset	7	4	1
+cpu	3	60	1
+cpuacct	3	60	1
+blkio	6	60	1
+memory	8	98	1
+devices	4	60	1
+freezer	10	4	1
+net_cls	2	4	1
+perf_event	9	4	0
+`
+
+func TestProcCgroups(t *testing.T) {
+	expected := []string{"blkio", "cpu", "cpuacct", "cpuset", "devices", "freezer", "memory", "net_cls"}
+
+	controllers := parseProcCgroups([]byte(procCgroups))
+	if !reflect.DeepEqual(controllers, expected) {
+		t.Fatalf("Parsing of /proc/cgroups failed, expected: %+v, got: %+v", expected, controllers)
+	}
+}
+
+func TestCgroupControllers(t *testing.T) {
+	expected := []string{"cpu", "cpuset", "io", "memory", "pids"}
+
+	controllers := parseCgroupControllers([]byte("cpuset cpu io memory pids\n"))
+	if !reflect.DeepEqual(controllers, expected) {
+		t.Fatalf("Parsing of cgroup.controllers failed, expected: %+v, got: %+v", expected, controllers)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+const cloudInitInstanceData = "/run/cloud-init/instance-data.json"
+
+// isCloudInitSensitiveKey returns true for the keys that may hold user
+// provided data like the user-data, they are never reported
+func isCloudInitSensitiveKey(key string, sensitiveKeys map[string]bool) bool {
+	if sensitiveKeys[key] {
+		return true
+	}
+
+	for _, component := range strings.Split(strings.ToLower(key), ".") {
+		component = strings.Replace(component, "-", "_", -1)
+		if component == "user_data" || component == "userdata" {
+			return true
+		}
+	}
+	return false
+}
+
+// removeCloudInitSensitiveKeys removes the sensitive keys from the instance data
+func removeCloudInitSensitiveKeys(data map[string]interface{}, prefix string, sensitiveKeys map[string]bool) {
+	for k, v := range data {
+		key := prefix + k
+		if isCloudInitSensitiveKey(key, sensitiveKeys) {
+			delete(data, k)
+			continue
+		}
+
+		if m, ok := v.(map[string]interface{}); ok {
+			removeCloudInitSensitiveKeys(m, key+".", sensitiveKeys)
+		}
+	}
+}
+
+// parseCloudInitInstanceData extracts the given keys from the cloud-init instance
+// data, it returns the extracted values and the instance ID
+func parseCloudInitInstanceData(content []byte, keys []string) (map[string]interface{}, string, error) {
+	var data interface{}
+	if err := common.JSONDecode(bytes.NewReader(content), &data); err != nil {
+		return nil, "", err
+	}
+
+	instanceData, ok := common.NormalizeValue(data).(map[string]interface{})
+	if !ok {
+		return nil, "", errors.New("Wrong cloud-init instance data format")
+	}
+
+	sensitiveKeys := make(map[string]bool)
+	if list, ok := instanceData["sensitive_keys"].([]interface{}); ok {
+		for _, key := range list {
+			if key, ok := key.(string); ok {
+				sensitiveKeys[strings.Replace(key, "/", ".", -1)] = true
+			}
+		}
+	}
+	removeCloudInitSensitiveKeys(instanceData, "", sensitiveKeys)
+
+	cloudInit := make(map[string]interface{})
+	for _, key := range keys {
+		if value, err := common.GetField(instanceData, key); err == nil {
+			common.SetField(cloudInit, key, value)
+		}
+	}
+
+	var instanceID string
+	if value, err := common.GetField(instanceData, "v1.instance_id"); err == nil {
+		instanceID, _ = value.(string)
+	}
+
+	return cloudInit, instanceID, nil
+}
+
+func getCloudInitInfo(keys []string) (map[string]interface{}, string, error) {
+	content, err := ioutil.ReadFile(cloudInitInstanceData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return parseCloudInitInstanceData(content, keys)
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+const cloudInitInstanceDataContent = `{
+  "base64_encoded_keys": [],
+  "sensitive_keys": ["ds/meta_data/secret"],
+  "ds": {
+    "_doc": "EXPERIMENTAL",
+    "meta_data": {
+      "secret": "password",
+      "tags": ["web", "prod"]
+    },
+    "user_data": "#cloud-config"
+  },
+  "v1": {
+    "cloud_name": "aws",
+    "instance_id": "i-0123456789",
+    "local_hostname": "ip-10-0-0-1",
+    "region": "eu-west-1"
+  }
+}`
+
+func TestCloudInitInstanceData(t *testing.T) {
+	keys := []string{"ds", "v1.cloud_name", "v1.region", "v1.unknown"}
+
+	cloudInit, instanceID, err := parseCloudInitInstanceData([]byte(cloudInitInstanceDataContent), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if instanceID != "i-0123456789" {
+		t.Fatalf("Expected instance ID i-0123456789, got: %s", instanceID)
+	}
+
+	if value, _ := common.GetField(cloudInit, "v1.cloud_name"); value != "aws" {
+		t.Fatalf("Expected cloud name aws, got: %v", value)
+	}
+
+	if _, err := common.GetField(cloudInit, "ds.meta_data.tags"); err != nil {
+		t.Fatalf("Tags should be reported: %+v", cloudInit)
+	}
+
+	if _, err := common.GetField(cloudInit, "ds.user_data"); err == nil {
+		t.Fatalf("User data should never be reported: %+v", cloudInit)
+	}
+
+	if _, err := common.GetField(cloudInit, "ds.meta_data.secret"); err == nil {
+		t.Fatalf("Sensitive keys should never be reported: %+v", cloudInit)
+	}
+
+	if _, err := common.GetField(cloudInit, "v1.unknown"); err == nil {
+		t.Fatalf("Unknown keys should not be reported: %+v", cloudInit)
+	}
+}
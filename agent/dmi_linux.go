@@ -0,0 +1,294 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dmiMemoryDevice = 17
+
+// dmidecodeTimeout bounds dmidecode as it runs when the host node is created
+const dmidecodeTimeout = 5 * time.Second
+
+// SMBIOS memory types, see DMTF DSP0134 section 7.18.2
+var dmiMemoryTypes = map[byte]string{
+	0x01: "Other",
+	0x02: "Unknown",
+	0x03: "DRAM",
+	0x04: "EDRAM",
+	0x05: "VRAM",
+	0x06: "SRAM",
+	0x07: "RAM",
+	0x08: "ROM",
+	0x09: "Flash",
+	0x0A: "EEPROM",
+	0x0B: "FEPROM",
+	0x0C: "EPROM",
+	0x0D: "CDRAM",
+	0x0E: "3DRAM",
+	0x0F: "SDRAM",
+	0x10: "SGRAM",
+	0x11: "RDRAM",
+	0x12: "DDR",
+	0x13: "DDR2",
+	0x14: "DDR2 FB-DIMM",
+	0x18: "DDR3",
+	0x19: "FBD2",
+	0x1A: "DDR4",
+	0x1B: "LPDDR",
+	0x1C: "LPDDR2",
+	0x1D: "LPDDR3",
+	0x1E: "LPDDR4",
+	0x1F: "Logical non-volatile device",
+	0x20: "HBM",
+	0x21: "HBM2",
+	0x22: "DDR5",
+	0x23: "LPDDR5",
+}
+
+// dmiString returns the string referenced by index in the string set following
+// the formatted area of a SMBIOS structure
+func dmiString(strs [][]byte, index byte) string {
+	if index == 0 || int(index) > len(strs) {
+		return ""
+	}
+	return strings.TrimSpace(string(strs[index-1]))
+}
+
+// parseDMIMemoryDevice parses a raw SMBIOS type 17 structure
+func parseDMIMemoryDevice(raw []byte) (*DIMMInfo, error) {
+	if len(raw) < 2 || raw[0] != dmiMemoryDevice {
+		return nil, errors.New("Not a valid memory device structure")
+	}
+
+	length := int(raw[1])
+	if length < 0x15 || length > len(raw) {
+		return nil, fmt.Errorf("Memory device structure truncated: %d < %d", len(raw), length)
+	}
+
+	formatted := raw[:length]
+	strs := bytes.Split(raw[length:], []byte{0})
+
+	dimm := &DIMMInfo{
+		Locator:     dmiString(strs, formatted[0x10]),
+		BankLocator: dmiString(strs, formatted[0x11]),
+		Type:        dmiMemoryTypes[formatted[0x12]],
+	}
+
+	switch size := binary.LittleEndian.Uint16(formatted[0x0C:]); {
+	case size == 0xFFFF:
+		// unknown size
+	case size == 0x7FFF && length >= 0x20:
+		dimm.Size = int64(binary.LittleEndian.Uint32(formatted[0x1C:]) & 0x7FFFFFFF)
+	case size&0x8000 != 0:
+		dimm.Size = int64(size&0x7FFF) / 1024
+	default:
+		dimm.Size = int64(size)
+	}
+
+	if length >= 0x17 {
+		dimm.Speed = int64(binary.LittleEndian.Uint16(formatted[0x15:]))
+	}
+	if length >= 0x18 {
+		dimm.Manufacturer = dmiString(strs, formatted[0x17])
+	}
+
+	return dimm, nil
+}
+
+func getSysfsDIMMs() ([]*DIMMInfo, error) {
+	entries, err := filepath.Glob(fmt.Sprintf("/sys/firmware/dmi/entries/%d-*/raw", dmiMemoryDevice))
+	if err != nil {
+		return nil, err
+	}
+
+	var dimms []*DIMMInfo
+	for _, entry := range entries {
+		raw, err := ioutil.ReadFile(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		dimm, err := parseDMIMemoryDevice(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse %s: %s", entry, err)
+		}
+		dimms = append(dimms, dimm)
+	}
+
+	return dimms, nil
+}
+
+// parseDMIDecodeSize returns a size in MB from a dmidecode value
+func parseDMIDecodeSize(value string) int64 {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return 0
+	}
+
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	switch fields[1] {
+	case "kB", "KB":
+		return size / 1024
+	case "GB":
+		return size * 1024
+	case "TB":
+		return size * 1024 * 1024
+	}
+	return size
+}
+
+// parseDMIDecodeMemory parses the output of 'dmidecode -t memory'
+func parseDMIDecodeMemory(out []byte) []*DIMMInfo {
+	var dimms []*DIMMInfo
+	var dimm *DIMMInfo
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "Memory Device" {
+			dimm = &DIMMInfo{}
+			dimms = append(dimms, dimm)
+			continue
+		}
+
+		if dimm == nil || !strings.HasPrefix(line, "\t") {
+			dimm = nil
+			continue
+		}
+
+		kv := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+
+		switch kv[0] {
+		case "Size":
+			dimm.Size = parseDMIDecodeSize(value)
+		case "Locator":
+			dimm.Locator = value
+		case "Bank Locator":
+			if value != "Not Specified" {
+				dimm.BankLocator = value
+			}
+		case "Type":
+			if value != "Unknown" {
+				dimm.Type = value
+			}
+		case "Speed":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				dimm.Speed, _ = strconv.ParseInt(fields[0], 10, 64)
+			}
+		case "Manufacturer":
+			if value != "Not Specified" {
+				dimm.Manufacturer = value
+			}
+		}
+	}
+
+	return dimms
+}
+
+func getDMIDecodeDIMMs() ([]*DIMMInfo, error) {
+	path, err := exec.LookPath("dmidecode")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dmidecodeTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "-t", "memory").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDMIDecodeMemory(out), nil
+}
+
+func getMemoryInfo() (*MemoryInfo, error) {
+	dimms, err := getDMIDecodeDIMMs()
+	if err != nil || len(dimms) == 0 {
+		if dimms, err = getSysfsDIMMs(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(dimms) == 0 {
+		return nil, errors.New("No memory device found")
+	}
+
+	memory := &MemoryInfo{DIMMs: dimms, SlotsTotal: int64(len(dimms))}
+	for _, dimm := range dimms {
+		if dimm.Size != 0 {
+			memory.SlotsUsed++
+		}
+	}
+
+	return memory, nil
+}
+
+// parseDMIProductUUID returns the normalized SMBIOS system UUID, unset UUIDs
+// are reported as errors
+func parseDMIProductUUID(value string) (string, error) {
+	uuid := strings.ToLower(strings.TrimSpace(value))
+	if len(uuid) != 36 {
+		return "", fmt.Errorf("Invalid product UUID: %s", value)
+	}
+
+	switch strings.Replace(uuid, "-", "", -1) {
+	case strings.Repeat("0", 32), strings.Repeat("f", 32):
+		return "", errors.New("Product UUID not set")
+	}
+
+	return uuid, nil
+}
+
+func getDMIProductUUID() (string, error) {
+	content, err := ioutil.ReadFile("/sys/class/dmi/id/product_uuid")
+	if err != nil {
+		return "", err
+	}
+
+	return parseDMIProductUUID(string(content))
+}
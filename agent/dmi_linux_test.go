@@ -0,0 +1,112 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+const dmidecodeOutput = `# dmidecode 3.1
+Getting SMBIOS data from sysfs.
+SMBIOS 2.8 present.
+
+Handle 0x1100, DMI type 17, 40 bytes
+Memory Device
+	Array Handle: 0x1000
+	Size: 16384 MB
+	Form Factor: DIMM
+	Locator: DIMM_A1
+	Bank Locator: Not Specified
+	Type: DDR4
+	Speed: 2666 MT/s
+	Manufacturer: Samsung
+
+Handle 0x1101, DMI type 17, 40 bytes
+Memory Device
+	Array Handle: 0x1000
+	Size: No Module Installed
+	Form Factor: Unknown
+	Locator: DIMM_A2
+	Bank Locator: Not Specified
+	Type: Unknown
+	Speed: Unknown
+	Manufacturer: Not Specified
+`
+
+func TestDMIDecodeMemory(t *testing.T) {
+	expected := []*DIMMInfo{
+		{Locator: "DIMM_A1", Size: 16384, Speed: 2666, Type: "DDR4", Manufacturer: "Samsung"},
+		{Locator: "DIMM_A2"},
+	}
+
+	dimms := parseDMIDecodeMemory([]byte(dmidecodeOutput))
+	if !reflect.DeepEqual(dimms, expected) {
+		t.Fatalf("Parsing of dmidecode output failed, expected: %+v, got: %+v", expected, dimms)
+	}
+}
+
+func TestDMIMemoryDevice(t *testing.T) {
+	raw := make([]byte, 0x1C)
+	raw[0], raw[1] = dmiMemoryDevice, 0x1C
+	raw[0x0C], raw[0x0D] = 0x00, 0x20 // 8192 MB
+	raw[0x10] = 1
+	raw[0x12] = 0x1A
+	raw[0x15], raw[0x16] = 0x60, 0x09 // 2400 MT/s
+	raw[0x17] = 2
+	raw = append(raw, []byte("DIMM 0\x00Hynix\x00\x00")...)
+
+	expected := &DIMMInfo{Locator: "DIMM 0", Size: 8192, Speed: 2400, Type: "DDR4", Manufacturer: "Hynix"}
+
+	dimm, err := parseDMIMemoryDevice(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(dimm, expected) {
+		t.Fatalf("Parsing of SMBIOS memory device failed, expected: %+v, got: %+v", expected, dimm)
+	}
+
+	if _, err = parseDMIMemoryDevice(raw[:0x10]); err == nil {
+		t.Fatal("Parsing of a truncated SMBIOS memory device should return an error")
+	}
+}
+
+func TestDMIProductUUID(t *testing.T) {
+	uuid, err := parseDMIProductUUID("4C4C4544-0034-5810-8032-B4C04F4B3132\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "4c4c4544-0034-5810-8032-b4c04f4b3132"; uuid != expected {
+		t.Fatalf("Parsing of product UUID failed, expected: %s, got: %s", expected, uuid)
+	}
+
+	for _, value := range []string{"", "Not Settable", "00000000-0000-0000-0000-000000000000", "FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF"} {
+		if _, err := parseDMIProductUUID(value); err == nil {
+			t.Fatalf("Parsing of product UUID '%s' should return an error", value)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dnsTimeout     = 2 * time.Second
+	dnsCacheExpire = 30 * time.Minute
+)
+
+// DNSInfo defines the names of the host as seen by the DNS
+type DNSInfo struct {
+	FQDN            string
+	PTRNames        []string
+	DNSInconsistent bool
+}
+
+type dnsCacheEntry struct {
+	names  []string
+	expire time.Time
+}
+
+// dnsResolver resolves the host addresses, the reverse lookups are cached
+type dnsResolver struct {
+	sync.Mutex
+	cache map[string]dnsCacheEntry
+}
+
+func (r *dnsResolver) lookupAddr(addr string) []string {
+	r.Lock()
+	entry, ok := r.cache[addr]
+	r.Unlock()
+
+	if ok && time.Now().Before(entry.expire) {
+		return entry.names
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	names, _ := net.DefaultResolver.LookupAddr(ctx, addr)
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, ".")
+	}
+
+	r.Lock()
+	r.cache[addr] = dnsCacheEntry{names: names, expire: time.Now().Add(dnsCacheExpire)}
+	r.Unlock()
+
+	return names
+}
+
+// getFQDN returns the fully qualified domain name of the host, like 'hostname -f'
+// it uses the configured hostname if it contains a domain or the canonical name
+func getFQDN() string {
+	hostname, _ := os.Hostname()
+	if buffer, err := ioutil.ReadFile("/etc/hostname"); err == nil {
+		if name := strings.TrimSpace(string(buffer)); name != "" {
+			hostname = name
+		}
+	}
+
+	if strings.Contains(hostname, ".") {
+		return hostname
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	if cname, err := net.DefaultResolver.LookupCNAME(ctx, hostname); err == nil && cname != "" {
+		return strings.TrimSuffix(cname, ".")
+	}
+	return hostname
+}
+
+// getHostAddresses returns the global unicast addresses of the host
+func getHostAddresses() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+
+	var ips []string
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.IsGlobalUnicast() {
+			ips = append(ips, ipnet.IP.String())
+		}
+	}
+	return ips
+}
+
+// isDNSInconsistent returns true when the forward resolution of the FQDN doesn't
+// point to the host or when the reverse resolution doesn't return the FQDN
+func isDNSInconsistent(fqdn string, forward []string, local []string, ptrNames []string) bool {
+	if len(forward) > 0 {
+		var found bool
+		for _, addr := range forward {
+			for _, l := range local {
+				if net.ParseIP(addr).Equal(net.ParseIP(l)) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return true
+		}
+	}
+
+	if len(ptrNames) > 0 {
+		for _, name := range ptrNames {
+			if strings.EqualFold(name, fqdn) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (r *dnsResolver) getDNSInfo() *DNSInfo {
+	info := &DNSInfo{FQDN: getFQDN()}
+
+	addrs := getHostAddresses()
+
+	names := make(map[string]bool)
+	for _, addr := range addrs {
+		for _, name := range r.lookupAddr(addr) {
+			names[name] = true
+		}
+	}
+	for name := range names {
+		info.PTRNames = append(info.PTRNames, name)
+	}
+	sort.Strings(info.PTRNames)
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	forward, _ := net.DefaultResolver.LookupHost(ctx, info.FQDN)
+	info.DNSInconsistent = isDNSInconsistent(info.FQDN, forward, addrs, info.PTRNames)
+
+	return info
+}
+
+func newDNSResolver() *dnsResolver {
+	return &dnsResolver{cache: make(map[string]dnsCacheEntry)}
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"testing"
+)
+
+func TestDNSInconsistent(t *testing.T) {
+	local := []string{"10.0.0.1", "2001:db8::1"}
+
+	if isDNSInconsistent("host.example.com", []string{"10.0.0.1"}, local, []string{"host.example.com"}) {
+		t.Fatal("Matching forward and reverse resolutions should be consistent")
+	}
+
+	if !isDNSInconsistent("host.example.com", []string{"10.0.0.2"}, local, []string{"host.example.com"}) {
+		t.Fatal("Forward resolution to a foreign address should be inconsistent")
+	}
+
+	if !isDNSInconsistent("host.example.com", []string{"2001:db8::1"}, local, []string{"other.example.com"}) {
+		t.Fatal("Reverse resolution to another name should be inconsistent")
+	}
+
+	if isDNSInconsistent("host", nil, local, nil) {
+		t.Fatal("Missing resolutions should not be reported as inconsistent")
+	}
+}
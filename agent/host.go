@@ -23,69 +23,956 @@
 package agent
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/host"
 
 	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/topology"
 	"github.com/skydive-project/skydive/topology/graph"
 )
 
 // CPUInfo defines host information
 type CPUInfo struct {
 	CPU        int64
-	VendorID   string `json:"VendorID,omitempty"`
-	Family     string `json:"Family,omitempty"`
-	Model      string `json:"Model,omitempty"`
-	Stepping   int64  `json:"Stepping,omitempty"`
-	PhysicalID string `json:"PhysicalID,omitempty"`
-	CoreID     string `json:"CoreID,omitempty"`
-	Cores      int64  `json:"Cores,omitempty"`
-	ModelName  string `json:"ModelName,omitempty"`
-	Mhz        int64  `json:"Mhz,omitempty"`
-	CacheSize  int64  `json:"CacheSize,omitempty"`
-	Microcode  string `json:"Microcode,omitempty"`
+	VendorID   string   `json:"VendorID,omitempty"`
+	Family     string   `json:"Family,omitempty"`
+	Model      string   `json:"Model,omitempty"`
+	Stepping   int64    `json:"Stepping,omitempty"`
+	PhysicalID string   `json:"PhysicalID,omitempty"`
+	CoreID     string   `json:"CoreID,omitempty"`
+	Cores      int64    `json:"Cores,omitempty"`
+	Threads    int64    `json:"Threads,omitempty"`
+	ModelName  string   `json:"ModelName,omitempty"`
+	Mhz        float64  `json:"Mhz,omitempty"`
+	CacheSize  int64    `json:"CacheSize,omitempty"`
+	Microcode  string   `json:"Microcode,omitempty"`
+	Flags      []string `json:"Flags,omitempty"`
+	FlagsHash  string   `json:"FlagsHash,omitempty"`
+}
+
+// KernelModuleInfo defines a loaded kernel module
+type KernelModuleInfo struct {
+	Size     int64
+	RefCount int64
+	Version  string `json:"Version,omitempty"`
+}
+
+// SMARTInfo defines the SMART health summary of a disk, MediaWearout is the
+// percentage of the media endurance used
+type SMARTInfo struct {
+	Healthy            bool
+	Temperature        int64 `json:"Temperature,omitempty"`
+	PowerOnHours       int64 `json:"PowerOnHours,omitempty"`
+	ReallocatedSectors int64
+	MediaWearout       int64 `json:"MediaWearout,omitempty"`
+}
+
+// DiskInfo defines a physical disk of the host
+type DiskInfo struct {
+	SMART *SMARTInfo
+	State string `json:"State,omitempty"`
+}
+
+// IRQViolation defines a NIC interrupt allowed to run on isolated CPUs
+type IRQViolation struct {
+	IRQ    int64
+	Device string
+}
+
+// SwapInfo defines a swap device or file of the host, sizes are in KB
+type SwapInfo struct {
+	Device   string
+	Type     string
+	Size     int64
+	Used     int64
+	Priority int64
+}
+
+// PressureAvg defines the share of time in percent some or all tasks were
+// stalled on a resource over the last 10, 60 and 300 seconds
+type PressureAvg struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+}
+
+// PressureInfo defines the pressure stall information of a resource
+type PressureInfo struct {
+	Some *PressureAvg
+	Full *PressureAvg `json:"Full,omitempty"`
+}
+
+// SMTInfo defines the simultaneous multithreading state of the host
+type SMTInfo struct {
+	Active  bool
+	Control string
+}
+
+// KernelInfo defines the preemption model of the running kernel
+type KernelInfo struct {
+	Preemption string
+	Realtime   bool
+}
+
+// KernelTaintInfo defines the taint state of the running kernel
+type KernelTaintInfo struct {
+	Value int64
+	Flags []string
+}
+
+// CgroupsInfo defines the cgroup hierarchy used by the host
+type CgroupsInfo struct {
+	Version     int64
+	Controllers []string
+	SystemdMode string
+}
+
+// OSReleaseInfo defines the operating system identification of /etc/os-release
+type OSReleaseInfo struct {
+	ID         string
+	IDLike     []string `json:"IDLike,omitempty"`
+	VersionID  string   `json:"VersionID,omitempty"`
+	Variant    string   `json:"Variant,omitempty"`
+	PrettyName string   `json:"PrettyName,omitempty"`
+	CPEName    string   `json:"CPEName,omitempty"`
+}
+
+// MACInfo defines the mandatory access control status of the host
+type MACInfo struct {
+	Type     string
+	Mode     string
+	Policy   string `json:"Policy,omitempty"`
+	Profiles int64  `json:"Profiles,omitempty"`
+}
+
+// DIMMInfo defines a memory slot of the host, Size is 0 when the slot is empty
+type DIMMInfo struct {
+	Locator      string
+	BankLocator  string `json:"BankLocator,omitempty"`
+	Size         int64
+	Speed        int64  `json:"Speed,omitempty"`
+	Type         string `json:"Type,omitempty"`
+	Manufacturer string `json:"Manufacturer,omitempty"`
+}
+
+// MemoryInfo defines the memory layout of the host
+type MemoryInfo struct {
+	DIMMs      []*DIMMInfo
+	SlotsTotal int64
+	SlotsUsed  int64
+}
+
+// PowerSupplyInfo defines a power supply or a battery of the host, Online is
+// false for a supply reported offline like an unplugged mains adapter
+type PowerSupplyInfo struct {
+	Name     string
+	Type     string
+	Present  bool
+	Online   bool
+	Failed   bool
+	Status   string `json:"Status,omitempty"`
+	Capacity int64  `json:"Capacity,omitempty"`
+}
+
+// PowerInfo defines the power supplies of the host
+type PowerInfo struct {
+	Supplies []*PowerSupplyInfo
+}
+
+// BMCInfo defines the baseboard management controller of the host
+type BMCInfo struct {
+	Channel   string
+	IPAddress string
+	MAC       string `json:"MAC,omitempty"`
+	VLAN      int64  `json:"VLAN,omitempty"`
+}
+
+const (
+	hardwareUpdate = 10 * time.Minute
+
+	// cpuFreqDelta is the minimal change of the average CPU frequency in MHz
+	// that triggers an update of the host node
+	cpuFreqDelta = 100
+
+	timeSyncUpdate = 3 * time.Minute
+
+	dnsUpdate = 5 * time.Minute
+
+	lldpUpdate = 2 * time.Minute
+
+	// pressureDelta is the minimal change of a pressure average in percent
+	// that triggers an update of the host node
+	pressureDelta = 1.0
+
+	// tunedVerifyPeriod is the minimal delay between two verifications of
+	// the same tuned profile
+	tunedVerifyPeriod = 30 * time.Minute
+)
+
+// CPUFreqInfo defines the CPU frequency scaling settings of the host
+type CPUFreqInfo struct {
+	Governor      string
+	MinMHz        int64
+	MaxMHz        int64
+	CurrentMHzAvg float64
+}
+
+// TimeSyncInfo defines the time synchronization status of the host,
+// Synchronized is either "true", "false" or "unknown" when no time
+// synchronization tool is available
+type TimeSyncInfo struct {
+	Synchronized string
+	Source       string  `json:"Source,omitempty"`
+	OffsetMs     float64 `json:"OffsetMs,omitempty"`
+	Stratum      int64   `json:"Stratum,omitempty"`
+}
+
+// NICInfo defines the driver, firmware and offload settings of a physical interface
+type NICInfo struct {
+	Driver        string
+	DriverVersion string
+	Firmware      string
+	BusInfo       string
+	Offloads      map[string]bool
+}
+
+// configMetadataOptions are the keys of the agent.metadata section used to
+// configure the host metadata collection, they are not reported as metadata
+var configMetadataOptions = map[string]bool{
+	"lshw_timeout":   true,
+	"kernel_modules": true,
+	"sysctls":        true,
+	"exclude":        true,
+	"hash_excluded":  true,
+	"cpu_detail":     true,
+}
+
+// reservedMetadataKeys are the host metadata that can't be set by the
+// agent.metadata configuration section once the agent is started
+var reservedMetadataKeys = map[string]bool{
+	"Name":     true,
+	"Type":     true,
+	"Hostname": true,
+	"CPU":      true,
+	"Hardware": true,
+}
+
+// hostUpdater periodically refreshes the host metadata that may change at runtime
+type hostUpdater struct {
+	graph          *graph.Graph
+	node           *graph.Node
+	quit           chan bool
+	keys           map[string]bool
+	hardwareHash   string
+	cpuFreq        *CPUFreqInfo
+	configMetadata map[string]interface{}
+	dnsResolver    *dnsResolver
+	unknownSysctls map[string]bool
+	lldpSwitches   map[graph.Identifier]*graph.Node
+	tunedVerify    *tunedVerification
+	pressure       map[string]*PressureInfo
+}
+
+// getDynamicMetadata returns the host metadata that may change at runtime
+func (u *hostUpdater) getDynamicMetadata() graph.Metadata {
+	m := graph.Metadata{}
+
+	if mac, err := getMACInfo(); err == nil {
+		m.SetField("MAC", mac)
+	}
+
+	if power, redundancyLost, err := getPowerInfo(); err == nil {
+		m.SetField("Power", power)
+		m.SetField("PowerRedundancyLost", redundancyLost)
+	}
+
+	if modules, err := getKernelModules(config.GetStringSlice("agent.metadata.kernel_modules")); err == nil {
+		m.SetField("KernelModules", modules)
+	}
+
+	if sysctls := u.getSysctls(config.GetStringSlice("agent.metadata.sysctls")); len(sysctls) > 0 {
+		m.SetField("Sysctl", sysctls)
+	}
+
+	if fips, err := getFIPSEnabled(); err == nil {
+		m.SetField("FIPS", fips)
+	}
+
+	if policy, err := getCryptoPolicy(); err == nil {
+		m.SetField("CryptoPolicy", policy)
+	}
+
+	if !isLibvirtProbeEnabled() {
+		if virtualization, err := getVirtualizationInfo(); err == nil {
+			m.SetField("Virtualization", virtualization)
+		}
+	}
+
+	if isolated, err := getIsolatedCPUs(); err == nil && len(isolated) > 0 {
+		if violations, err := getIRQViolations(isolated); err == nil {
+			if len(violations) > 0 {
+				m.SetField("IRQViolations", violations)
+			}
+			m.SetField("IRQIsolationClean", len(violations) == 0)
+		}
+	}
+
+	if swaps, err := getSwapInfo(); err == nil {
+		var size, used int64
+		for _, swap := range swaps {
+			size += swap.Size
+			used += swap.Used
+		}
+
+		m.SetField("Swap", swaps)
+		if size > 0 {
+			usedPct := float64(used) * 100 / float64(size)
+			m.SetField("SwapUsedPct", usedPct)
+			m.SetField("SwapPressure", usedPct > float64(config.GetInt("agent.swap.max_used")))
+		}
+	}
+
+	if smt, err := getSMTInfo(); err == nil {
+		m.SetField("SMT", smt)
+	}
+
+	if siblings, err := getCoreSiblings(); err == nil && len(siblings) > 0 {
+		m.SetField("CoreSiblings", siblings)
+	}
+
+	if taint, err := getKernelTaint(); err == nil {
+		m.SetField("KernelTaint", taint)
+	}
+
+	if profile, err := getTunedProfile(); err == nil {
+		m.SetField("TunedProfile", profile)
+
+		if config.GetBool("agent.tuned.verify") {
+			if verified, err := u.verifyTunedProfile(profile); err == nil {
+				m.SetField("TunedVerified", verified)
+			}
+		}
+	}
+
+	if cgroups, err := getCgroupsInfo(); err == nil {
+		m.SetField("Cgroups", cgroups)
+	}
+
+	if cpuFreq, err := getCPUFreqInfo(); err == nil {
+		// keep the previous value if only the current frequency slightly changed
+		// to avoid generating an event at each update
+		if last := u.cpuFreq; last != nil && last.Governor == cpuFreq.Governor &&
+			last.MinMHz == cpuFreq.MinMHz && last.MaxMHz == cpuFreq.MaxMHz &&
+			math.Abs(last.CurrentMHzAvg-cpuFreq.CurrentMHzAvg) < cpuFreqDelta {
+			cpuFreq = last
+		}
+		u.cpuFreq = cpuFreq
+
+		m.SetField("CPUFreq", cpuFreq)
+	}
+
+	return m
+}
+
+// verifyTunedProfile verifies the given tuned profile, the result is cached
+// as the verification is expensive and only refreshed when the profile changed
+func (u *hostUpdater) verifyTunedProfile(profile string) (bool, error) {
+	if last := u.tunedVerify; last != nil && last.profile == profile && time.Since(last.time) < tunedVerifyPeriod {
+		return last.verified, nil
+	}
+
+	verified, err := verifyTunedProfile()
+	if err != nil {
+		return false, err
+	}
+	u.tunedVerify = &tunedVerification{profile: profile, verified: verified, time: time.Now()}
+
+	return verified, nil
+}
+
+// getSysctls returns the values of the given sysctl keys, the keys that can't
+// be read are logged only once
+func (u *hostUpdater) getSysctls(keys []string) map[string]interface{} {
+	sysctls := make(map[string]interface{})
+	for _, key := range keys {
+		value, err := readSysctl(key)
+		if err != nil {
+			if !u.unknownSysctls[key] {
+				logging.GetLogger().Warningf("Unable to read sysctl %s: %s", key, err)
+				u.unknownSysctls[key] = true
+			}
+			continue
+		}
+		delete(u.unknownSysctls, key)
+
+		common.SetField(sysctls, key, value)
+	}
+
+	return sysctls
+}
+
+func (u *hostUpdater) updateMetadata() {
+	dynamic := u.getDynamicMetadata()
+	m := getMetadataFilter().filterMetadata(dynamic)
+
+	u.graph.Lock()
+	for k, v := range m {
+		u.graph.AddMetadata(u.node, k, v)
+	}
+
+	if swaps, ok := dynamic["Swap"].([]*SwapInfo); ok {
+		u.linkSwapDevices(swaps)
+	}
+
+	// remove the metadata that are not reported anymore
+	for k := range u.keys {
+		if _, ok := m[k]; !ok {
+			u.graph.DelMetadata(u.node, k)
+		}
+	}
+	u.graph.Unlock()
+
+	u.keys = make(map[string]bool)
+	for k := range m {
+		u.keys[k] = true
+	}
+}
+
+// linkSwapDevices links the host node to the block device nodes used as swap
+func (u *hostUpdater) linkSwapDevices(swaps []*SwapInfo) {
+	for _, swap := range swaps {
+		if swap.Type != "partition" {
+			continue
+		}
+
+		filter := graph.Metadata{"Type": "blockdev", "Name": filepath.Base(swap.Device)}
+		if blockdev := u.graph.LookupFirstChild(u.node, filter); blockdev != nil {
+			u.graph.LinkOnce(u.node, blockdev, graph.Metadata{"RelationType": "swap"})
+		}
+	}
+}
+
+// setMetadata sets a metadata of the host node once filtered by the
+// agent.metadata.exclude configuration, the graph has to be locked
+func (u *hostUpdater) setMetadata(key string, value interface{}) {
+	if value, keep := getMetadataFilter().filterField(key, value); keep {
+		u.graph.AddMetadata(u.node, key, value)
+	} else {
+		u.graph.DelMetadata(u.node, key)
+	}
+}
+
+// updateNICMetadata merges the driver, firmware and offload settings of the
+// physical interfaces into the interface nodes owned by the host node
+func (u *hostUpdater) updateNICMetadata() {
+	nics, err := getNICInfos()
+	if err != nil {
+		logging.GetLogger().Debugf("Unable to retrieve NIC information: %s", err)
+		return
+	}
+
+	u.graph.Lock()
+	defer u.graph.Unlock()
+
+	for name, nic := range nics {
+		// the interface node may not have been created yet by the netlink probe,
+		// it will be updated during the next refresh
+		for _, intf := range u.graph.LookupChildren(u.node, graph.Metadata{"Name": name}, topology.OwnershipMetadata) {
+			tr := u.graph.StartMetadataTransaction(intf)
+			tr.AddMetadata("DriverVersion", nic.DriverVersion)
+			tr.AddMetadata("Firmware", nic.Firmware)
+			tr.AddMetadata("BusInfo", nic.BusInfo)
+			tr.AddMetadata("Offloads", nic.Offloads)
+			tr.Commit()
+		}
+	}
+}
+
+// updateBMCMetadata retrieves the BMC address, as it may take a while it is
+// meant to be called asynchronously
+func (u *hostUpdater) updateBMCMetadata() {
+	bmc, err := getBMCInfo(config.GetStringSlice("agent.bmc.channels"))
+	if err != nil {
+		logging.GetLogger().Debugf("Unable to retrieve BMC address: %s", err)
+		return
+	}
+
+	u.graph.Lock()
+	u.setMetadata("BMC", bmc)
+	u.graph.Unlock()
+}
+
+// updateHardwareMetadata retrieves the hardware description using lshw and
+// updates the node only when it changed since the last call
+func (u *hostUpdater) updateHardwareMetadata() {
+	timeout := config.GetDuration("agent.metadata.lshw_timeout")
+
+	hardware, hash, err := getHardwareInfo(timeout)
+	if err != nil {
+		logging.GetLogger().Debugf("Unable to retrieve hardware description: %s", err)
+		return
+	}
+
+	if hash == u.hardwareHash {
+		return
+	}
+	u.hardwareHash = hash
+
+	u.graph.Lock()
+	u.setMetadata("Hardware", hardware)
+	u.graph.Unlock()
+}
+
+// updateConfigMetadata applies the changes of the agent.metadata configuration
+// section to the host node, keys colliding with the host metadata are rejected
+// and the excluded fields are filtered out as when the node is created
+func (u *hostUpdater) updateConfigMetadata() {
+	configMetadata, err := getConfigMetadata()
+	if err != nil {
+		logging.GetLogger().Errorf("Unable to reload host metadata: %s", err)
+		return
+	}
+	configMetadata = getMetadataFilter().filterMetadata(configMetadata)
+
+	u.graph.Lock()
+	defer u.graph.Unlock()
+
+	metadata := u.node.Metadata()
+	applied := make(map[string]interface{})
+
+	tr := u.graph.StartMetadataTransaction(u.node)
+	for k, v := range configMetadata {
+		if _, ok := u.configMetadata[k]; !ok {
+			if _, exists := metadata[k]; exists || reservedMetadataKeys[k] {
+				logging.GetLogger().Errorf("Metadata %s of agent.metadata collides with a host metadata, ignored", k)
+				continue
+			}
+		}
+
+		tr.AddMetadata(k, v)
+		applied[k] = v
+	}
+
+	for k := range u.configMetadata {
+		if _, ok := applied[k]; !ok {
+			tr.DelMetadata(k)
+		}
+	}
+	tr.Commit()
+
+	u.configMetadata = applied
+}
+
+// runEvery calls fn right away and then every period until the updater is stopped
+func (u *hostUpdater) runEvery(period time.Duration, fn func()) {
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		fn()
+		for {
+			select {
+			case <-u.quit:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// updateTimeSyncMetadata reports the time synchronization status, the
+// ClockUnsynced flag is raised when the clock offset exceeds the configured limit
+func (u *hostUpdater) updateTimeSyncMetadata() {
+	timeSync := getTimeSyncInfo()
+
+	maxOffset := float64(config.GetInt("agent.time_sync.max_offset"))
+	unsynced := timeSync.Synchronized == "false" || math.Abs(timeSync.OffsetMs) > maxOffset
+
+	u.graph.Lock()
+	u.setMetadata("TimeSync", timeSync)
+	u.setMetadata("ClockUnsynced", unsynced)
+	u.graph.Unlock()
+}
+
+// updateDNSMetadata reports the FQDN and the reverse DNS names of the host,
+// lookups may take a while so it is meant to be called asynchronously
+func (u *hostUpdater) updateDNSMetadata() {
+	dns := u.dnsResolver.getDNSInfo()
+
+	m := graph.Metadata{"FQDN": dns.FQDN, "DNSInconsistent": dns.DNSInconsistent}
+	if len(dns.PTRNames) > 0 {
+		m["PTRNames"] = dns.PTRNames
+	}
+	m = getMetadataFilter().filterMetadata(m)
+
+	u.graph.Lock()
+	tr := u.graph.StartMetadataTransaction(u.node)
+	for _, k := range []string{"FQDN", "PTRNames", "DNSInconsistent"} {
+		if v, ok := m[k]; ok {
+			tr.AddMetadata(k, v)
+		} else {
+			tr.DelMetadata(k)
+		}
+	}
+	tr.Commit()
+	u.graph.Unlock()
+}
+
+// updateDisksMetadata reports the SMART health of the physical disks
+func (u *hostUpdater) updateDisksMetadata() {
+	disks, err := getDisksInfo()
+	if err != nil {
+		logging.GetLogger().Debugf("Unable to retrieve disks health: %s", err)
+		return
+	}
+
+	u.graph.Lock()
+	u.setMetadata("Disks", disks)
+	u.graph.Unlock()
+}
+
+// updateLLDPMetadata reports the LLDP neighbors of the host interfaces and,
+// if enabled, links the interfaces to nodes synthesized for the switches
+func (u *hostUpdater) updateLLDPMetadata() {
+	neighbors, err := getLLDPNeighbors()
+	if err != nil {
+		logging.GetLogger().Debugf("Unable to retrieve LLDP neighbors: %s", err)
+		return
+	}
+
+	u.graph.Lock()
+	defer u.graph.Unlock()
+
+	u.setMetadata("LLDP", neighbors)
+
+	if !config.GetBool("agent.lldp.switch_nodes") {
+		return
+	}
+
+	switches := make(map[graph.Identifier]*graph.Node)
+	for ifname, neighbor := range neighbors {
+		if neighbor.ChassisID == "" {
+			continue
+		}
+
+		intf := u.graph.LookupFirstChild(u.node, graph.Metadata{"Name": ifname})
+		if intf == nil {
+			continue
+		}
+
+		name := neighbor.ChassisName
+		if name == "" {
+			name = neighbor.ChassisID
+		}
+
+		id := graph.GenIDNameBased(string(u.node.ID), "lldp-"+neighbor.ChassisID)
+		node := u.graph.GetNode(id)
+		if node == nil {
+			m := graph.Metadata{
+				"Name":      name,
+				"Type":      "switch",
+				"ChassisID": neighbor.ChassisID,
+			}
+			if neighbor.MgmtAddress != "" {
+				m["MgmtAddress"] = neighbor.MgmtAddress
+			}
+			node = u.graph.NewNode(id, m)
+		}
+		switches[id] = node
+
+		if !topology.HaveLayer2Link(u.graph, intf, node) {
+			topology.AddLayer2Link(u.graph, intf, node, graph.Metadata{"PortID": neighbor.PortID})
+		}
+	}
+
+	// remove the switches that are not seen anymore
+	for id, node := range u.lldpSwitches {
+		if _, found := switches[id]; !found {
+			u.graph.DelNode(node)
+		}
+	}
+	u.lldpSwitches = switches
+}
+
+// pressureChanged returns whether one of the pressure averages changed by
+// more than pressureDelta
+func pressureChanged(last, pressure map[string]*PressureInfo) bool {
+	if len(last) != len(pressure) {
+		return true
+	}
+
+	avgChanged := func(a, b *PressureAvg) bool {
+		if a == nil || b == nil {
+			return a != b
+		}
+		return math.Abs(a.Avg10-b.Avg10) >= pressureDelta ||
+			math.Abs(a.Avg60-b.Avg60) >= pressureDelta ||
+			math.Abs(a.Avg300-b.Avg300) >= pressureDelta
+	}
+
+	for resource, info := range pressure {
+		lastInfo, found := last[resource]
+		if !found || avgChanged(lastInfo.Some, info.Some) || avgChanged(lastInfo.Full, info.Full) {
+			return true
+		}
+	}
+	return false
+}
+
+// updatePressureMetadata reports the pressure stall information of the host,
+// the node is only updated on significant changes
+func (u *hostUpdater) updatePressureMetadata() {
+	pressure, err := getPressureInfo()
+	if err != nil {
+		logging.GetLogger().Debugf("Unable to retrieve pressure stall information: %s", err)
+		return
+	}
+
+	if !pressureChanged(u.pressure, pressure) {
+		return
+	}
+	u.pressure = pressure
+
+	u.graph.Lock()
+	u.setMetadata("Pressure", pressure)
+	u.graph.Unlock()
+}
+
+// Start the host metadata updater
+func (u *hostUpdater) Start() {
+	if config.GetBool("agent.bmc.enabled") {
+		go u.updateBMCMetadata()
+	}
+
+	u.runEvery(hardwareUpdate, u.updateHardwareMetadata)
+	u.runEvery(timeSyncUpdate, u.updateTimeSyncMetadata)
+	u.runEvery(dnsUpdate, u.updateDNSMetadata)
+	u.runEvery(lldpUpdate, u.updateLLDPMetadata)
+	u.runEvery(config.GetDuration("agent.pressure.update"), u.updatePressureMetadata)
+	u.runEvery(config.GetDuration("agent.smart.update"), u.updateDisksMetadata)
+
+	// the first update runs in the background as some of the collectors may
+	// be slow and must not delay the connection to the analyzers
+	hostUpdate := config.GetDuration("agent.host_update")
+	go func() {
+		ticker := time.NewTicker(hostUpdate)
+		defer ticker.Stop()
+
+		u.updateMetadata()
+		u.updateNICMetadata()
+		for {
+			select {
+			case <-u.quit:
+				return
+			case <-ticker.C:
+				u.updateMetadata()
+				u.updateNICMetadata()
+			}
+		}
+	}()
+}
+
+// Stop the host metadata updater
+func (u *hostUpdater) Stop() {
+	close(u.quit)
+}
+
+func newHostUpdater(g *graph.Graph, n *graph.Node) *hostUpdater {
+	// metadata applied by createRootNode
+	configMetadata, _ := getConfigMetadata()
+	configMetadata = getMetadataFilter().filterMetadata(configMetadata)
+	for k := range configMetadata {
+		if reservedMetadataKeys[k] {
+			delete(configMetadata, k)
+		}
+	}
+
+	return &hostUpdater{
+		graph:          g,
+		node:           n,
+		quit:           make(chan bool),
+		keys:           make(map[string]bool),
+		configMetadata: configMetadata,
+		dnsResolver:    newDNSResolver(),
+		unknownSysctls: make(map[string]bool),
+	}
+}
+
+// filterCPUFlags returns the flags that are part of the allowlist
+func filterCPUFlags(flags []string, allowlist []string) []string {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, flag := range allowlist {
+		allowed[flag] = true
+	}
+
+	var filtered []string
+	for _, flag := range flags {
+		if allowed[flag] {
+			filtered = append(filtered, flag)
+		}
+	}
+
+	return filtered
+}
+
+// hashCPUFlags returns a hash of the full flag list that doesn't depend on the order of the flags
+func hashCPUFlags(flags []string) string {
+	sorted := make([]string, len(flags))
+	copy(sorted, flags)
+	sort.Strings(sorted)
+
+	sum := sha1.Sum([]byte(strings.Join(sorted, " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// getConfigMetadata returns the metadata of the agent.metadata configuration section
+func getConfigMetadata() (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+
+	if configMetadata := config.Get("agent.metadata"); configMetadata != nil {
+		configMetadata, ok := common.NormalizeValue(configMetadata).(map[string]interface{})
+		if !ok {
+			return nil, errors.New("agent.metadata has wrong format")
+		}
+		for k, v := range configMetadata {
+			if configMetadataOptions[k] {
+				continue
+			}
+			m[k] = v
+		}
+	}
+
+	return m, nil
+}
+
+// hostInfoProvider holds the collectors used to build the host node so that
+// they can be stubbed
+type hostInfoProvider struct {
+	hostname       func() (string, error)
+	configMetadata func() (map[string]interface{}, error)
+	cloudInit      func(keys []string) (map[string]interface{}, string, error)
+	cpuInfo        func() ([]cpu.InfoStat, error)
+	hostInfo       func() (*host.InfoStat, error)
+}
+
+var defaultHostInfoProvider = &hostInfoProvider{
+	hostname:       os.Hostname,
+	configMetadata: getConfigMetadata,
+	cloudInit:      getCloudInitInfo,
+	cpuInfo:        cpu.Info,
+	hostInfo:       host.Info,
 }
 
 // createRootNode creates a graph.Node based on the host properties and aims to have an unique ID
 func createRootNode(g *graph.Graph) (*graph.Node, error) {
+	return newRootNode(g, defaultHostInfoProvider)
+}
+
+// newRootNode creates the host node using the given collectors, only the
+// hostname is mandatory, the failures of the other collectors are logged
+func newRootNode(g *graph.Graph, provider *hostInfoProvider) (*graph.Node, error) {
 	hostID := config.GetString("host_id")
-	hostname, err := os.Hostname()
+	hostname, err := provider.hostname()
 	if err != nil {
 		return nil, err
 	}
 	m := graph.Metadata{"Name": hostID, "Type": "host", "Hostname": hostname}
 
 	// Fill the metadata from the configuration file
-	if configMetadata := config.Get("agent.metadata"); configMetadata != nil {
-		configMetadata, ok := common.NormalizeValue(configMetadata).(map[string]interface{})
-		if !ok {
-			return nil, errors.New("agent.metadata has wrong format")
-		}
+	if configMetadata, err := provider.configMetadata(); err == nil {
 		for k, v := range configMetadata {
 			m[k] = v
 		}
+	} else {
+		logging.GetLogger().Errorf("Unable to apply the metadata of the configuration: %s", err)
+	}
+
+	// Retrieves the instance data from cloud-init, fallback to the instance ID file
+	// for cloud-init versions not providing the merged instance data
+	var instanceID, instanceIDSource string
+	if cloudInit, id, err := provider.cloudInit(config.GetStringSlice("agent.cloud_init.keys")); err == nil {
+		if len(cloudInit) > 0 {
+			m.SetField("CloudInit", cloudInit)
+		}
+		instanceID, instanceIDSource = id, "cloud-init"
+	} else if buffer, err := ioutil.ReadFile("/var/lib/cloud/data/instance-id"); err == nil {
+		instanceID, instanceIDSource = strings.TrimSpace(string(buffer)), "cloud-init"
+	}
+
+	// Without cloud-init, use the SMBIOS UUID which is the instance UUID
+	// set by libvirt and nova for virtual machines
+	if instanceID == "" {
+		if uuid, err := getDMIProductUUID(); err == nil {
+			instanceID, instanceIDSource = uuid, "dmi"
+		}
 	}
 
-	// Retrieves the instance ID from cloud-init
-	if buffer, err := ioutil.ReadFile("/var/lib/cloud/data/instance-id"); err == nil {
-		m.SetField("InstanceID", strings.TrimSpace(string(buffer)))
+	if instanceID != "" {
+		m.SetField("InstanceID", instanceID)
+		m.SetField("InstanceIDSource", instanceIDSource)
 	}
 
 	if isolated, err := getIsolatedCPUs(); err == nil {
 		m.SetField("IsolatedCPU", isolated)
 	}
 
-	cpuInfo, err := cpu.Info()
+	if cpus, err := provider.getCPUs(); err == nil {
+		sockets, cores := countCPUSockets(cpus)
+		m.SetField("Sockets", sockets)
+		m.SetField("CoresTotal", cores)
+		m.SetField("ThreadsTotal", int64(len(cpus)))
+
+		if config.GetString("agent.metadata.cpu_detail") != "logical" {
+			cpus = aggregateCPUsBySocket(cpus)
+		}
+		m.SetField("CPU", cpus)
+	} else {
+		logging.GetLogger().Warningf("Unable to retrieve CPU information: %s", err)
+	}
+
+	if memory, err := getMemoryInfo(); err == nil {
+		m.SetField("Memory", memory)
+	} else {
+		logging.GetLogger().Debugf("Unable to retrieve memory layout: %s", err)
+	}
+
+	if hostInfo, err := provider.hostInfo(); err == nil {
+		setHostInfoMetadata(m, hostInfo)
+	} else {
+		logging.GetLogger().Warningf("Unable to retrieve host information: %s", err)
+	}
+
+	if osRelease, err := getOSRelease(); err == nil {
+		m.SetField("OSRelease", osRelease)
+	}
+
+	m = getMetadataFilter().filterMetadata(m)
+
+	return g.NewNode(graph.GenID(), m), nil
+}
+
+func (p *hostInfoProvider) getCPUs() ([]*CPUInfo, error) {
+	cpuInfo, err := p.cpuInfo()
 	if err != nil {
 		return nil, err
 	}
 
+	flagsAllowlist := config.GetStringSlice("agent.cpu_flags")
+
 	var cpus []*CPUInfo
 	for _, cpu := range cpuInfo {
 		c := &CPUInfo{
@@ -98,20 +985,59 @@ func createRootNode(g *graph.Graph) (*graph.Node, error) {
 			CoreID:     cpu.CoreID,
 			Cores:      int64(cpu.Cores),
 			ModelName:  cpu.ModelName,
-			Mhz:        int64(cpu.Mhz),
+			Mhz:        cpu.Mhz,
 			CacheSize:  int64(cpu.CacheSize),
 			Microcode:  cpu.Microcode,
+			Flags:      filterCPUFlags(cpu.Flags, flagsAllowlist),
+			FlagsHash:  hashCPUFlags(cpu.Flags),
 		}
 		cpus = append(cpus, c)
 	}
 
-	m.SetField("CPU", cpus)
+	return cpus, nil
+}
 
-	hostInfo, err := host.Info()
-	if err != nil {
-		return nil, err
+// countCPUSockets returns the number of sockets and physical cores
+func countCPUSockets(cpus []*CPUInfo) (int64, int64) {
+	sockets := make(map[string]bool)
+	cores := make(map[string]bool)
+	for _, cpu := range cpus {
+		sockets[cpu.PhysicalID] = true
+		cores[cpu.PhysicalID+"/"+cpu.CoreID] = true
 	}
+	return int64(len(sockets)), int64(len(cores))
+}
+
+// aggregateCPUsBySocket returns one entry per socket holding the number of
+// cores and threads, the shared fields are the ones of the first logical CPU
+// of the socket which is also used as CPU number
+func aggregateCPUsBySocket(cpus []*CPUInfo) []*CPUInfo {
+	var sockets []*CPUInfo
+	bySocket := make(map[string]*CPUInfo)
+	cores := make(map[string]bool)
+
+	for _, cpu := range cpus {
+		socket, found := bySocket[cpu.PhysicalID]
+		if !found {
+			socket = &CPUInfo{}
+			*socket = *cpu
+			socket.CoreID = ""
+			socket.Cores = 0
+			bySocket[cpu.PhysicalID] = socket
+			sockets = append(sockets, socket)
+		}
 
+		socket.Threads++
+		if core := cpu.PhysicalID + "/" + cpu.CoreID; !cores[core] {
+			cores[core] = true
+			socket.Cores++
+		}
+	}
+
+	return sockets
+}
+
+func setHostInfoMetadata(m graph.Metadata, hostInfo *host.InfoStat) {
 	if hostInfo.OS != "" {
 		m.SetField("OS", hostInfo.OS)
 	}
@@ -126,6 +1052,10 @@ func createRootNode(g *graph.Graph) (*graph.Node, error) {
 	}
 	if hostInfo.KernelVersion != "" {
 		m.SetField("KernelVersion", hostInfo.KernelVersion)
+
+		if kernel, err := getKernelInfo(hostInfo.KernelVersion); err == nil {
+			m.SetField("Kernel", kernel)
+		}
 	}
 	if hostInfo.VirtualizationSystem != "" {
 		m.SetField("VirtualizationSystem", hostInfo.VirtualizationSystem)
@@ -133,6 +1063,4 @@ func createRootNode(g *graph.Graph) (*graph.Node, error) {
 	if hostInfo.VirtualizationRole != "" {
 		m.SetField("VirtualizationRole", hostInfo.VirtualizationRole)
 	}
-
-	return g.NewNode(graph.GenID(), m), nil
 }
@@ -25,12 +25,21 @@
 package agent
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
+const cryptoPolicyTimeout = 5 * time.Second
+
 func parseIsolatedCPUs(str string) ([]int64, error) {
 	list := strings.Split(str, ",")
 
@@ -79,3 +88,351 @@ func getIsolatedCPUs() ([]int64, error) {
 
 	return parseIsolatedCPUs(strings.TrimSpace(string(buffer)))
 }
+
+func parseSELinuxConfig(content []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "SELINUXTYPE=") {
+			return strings.Trim(strings.TrimPrefix(line, "SELINUXTYPE="), `"'`)
+		}
+	}
+	return ""
+}
+
+func getSELinuxInfo() (*MACInfo, error) {
+	buffer, err := ioutil.ReadFile("/sys/fs/selinux/enforce")
+	if err != nil {
+		return nil, err
+	}
+
+	mac := &MACInfo{Type: "SELinux", Mode: "permissive"}
+	if strings.TrimSpace(string(buffer)) == "1" {
+		mac.Mode = "enforcing"
+	}
+
+	if buffer, err := ioutil.ReadFile("/etc/selinux/config"); err == nil {
+		mac.Policy = parseSELinuxConfig(buffer)
+	}
+
+	return mac, nil
+}
+
+func getAppArmorInfo() (*MACInfo, error) {
+	buffer, err := ioutil.ReadFile("/sys/module/apparmor/parameters/enabled")
+	if err != nil {
+		return nil, err
+	}
+
+	mac := &MACInfo{Type: "AppArmor", Mode: "disabled"}
+	if strings.TrimSpace(string(buffer)) != "Y" {
+		return mac, nil
+	}
+	mac.Mode = "enabled"
+
+	if buffer, err := ioutil.ReadFile("/sys/kernel/security/apparmor/profiles"); err == nil {
+		mac.Profiles = int64(bytes.Count(buffer, []byte("\n")))
+	}
+
+	return mac, nil
+}
+
+func getMACInfo() (*MACInfo, error) {
+	if mac, err := getSELinuxInfo(); err == nil {
+		return mac, nil
+	}
+
+	if mac, err := getAppArmorInfo(); err == nil {
+		return mac, nil
+	}
+
+	return nil, errors.New("No mandatory access control found")
+}
+
+// cpuFreqPolicy defines the frequencies in kHz of a cpufreq policy
+type cpuFreqPolicy struct {
+	governor string
+	min      int64
+	max      int64
+	cur      int64
+}
+
+func aggregateCPUFreq(policies []cpuFreqPolicy) *CPUFreqInfo {
+	info := &CPUFreqInfo{}
+
+	var sum int64
+	for i, policy := range policies {
+		if i == 0 {
+			info.Governor = policy.governor
+			info.MinMHz, info.MaxMHz = policy.min, policy.max
+		} else if info.Governor != policy.governor {
+			info.Governor = "mixed"
+		}
+
+		if policy.min < info.MinMHz {
+			info.MinMHz = policy.min
+		}
+		if policy.max > info.MaxMHz {
+			info.MaxMHz = policy.max
+		}
+		sum += policy.cur
+	}
+
+	info.MinMHz /= 1000
+	info.MaxMHz /= 1000
+	info.CurrentMHzAvg = float64(sum) / float64(len(policies)) / 1000
+
+	return info
+}
+
+func readSysfsInt(path string) (int64, error) {
+	buffer, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(buffer)), 10, 64)
+}
+
+func getCPUFreqInfo() (*CPUFreqInfo, error) {
+	paths, err := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq")
+	if err != nil {
+		return nil, err
+	}
+
+	// CPUs sharing a policy point to the same directory
+	seen := make(map[string]bool)
+
+	var policies []cpuFreqPolicy
+	for _, path := range paths {
+		if path, err = filepath.EvalSymlinks(path); err != nil || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		governor, err := ioutil.ReadFile(filepath.Join(path, "scaling_governor"))
+		if err != nil {
+			continue
+		}
+
+		policy := cpuFreqPolicy{governor: strings.TrimSpace(string(governor))}
+		policy.min, _ = readSysfsInt(filepath.Join(path, "scaling_min_freq"))
+		policy.max, _ = readSysfsInt(filepath.Join(path, "scaling_max_freq"))
+		policy.cur, _ = readSysfsInt(filepath.Join(path, "scaling_cur_freq"))
+
+		policies = append(policies, policy)
+	}
+
+	if len(policies) == 0 {
+		return nil, errors.New("No cpufreq policy found")
+	}
+
+	return aggregateCPUFreq(policies), nil
+}
+
+func getFIPSEnabled() (bool, error) {
+	buffer, err := ioutil.ReadFile("/proc/sys/crypto/fips_enabled")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(buffer)) == "1", nil
+}
+
+// getCryptoPolicy returns the system wide crypto policy of RHEL-family hosts
+func getCryptoPolicy() (string, error) {
+	path, err := exec.LookPath("update-crypto-policies")
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cryptoPolicyTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--show").Output()
+	if err != nil {
+		return "", err
+	}
+
+	policy := strings.TrimSpace(string(out))
+	if policy == "" {
+		return "", errors.New("No crypto policy reported")
+	}
+	return policy, nil
+}
+
+// parseProcModules parses the content of /proc/modules and returns the modules
+// part of the allowlist, all the modules are returned if the allowlist is "all"
+func parseProcModules(content []byte, allowlist []string) map[string]*KernelModuleInfo {
+	all := len(allowlist) == 1 && allowlist[0] == "all"
+
+	allowed := make(map[string]bool, len(allowlist))
+	for _, name := range allowlist {
+		allowed[strings.Replace(name, "-", "_", -1)] = true
+	}
+
+	modules := make(map[string]*KernelModuleInfo)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// nf_conntrack 139264 5 xt_conntrack,nf_nat, Live 0x0000000000000000
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || (!all && !allowed[fields[0]]) {
+			continue
+		}
+
+		module := &KernelModuleInfo{}
+		module.Size, _ = strconv.ParseInt(fields[1], 10, 64)
+		module.RefCount, _ = strconv.ParseInt(fields[2], 10, 64)
+
+		modules[fields[0]] = module
+	}
+
+	return modules
+}
+
+func getKernelModules(allowlist []string) (map[string]*KernelModuleInfo, error) {
+	content, err := ioutil.ReadFile("/proc/modules")
+	if err != nil {
+		return nil, err
+	}
+
+	modules := parseProcModules(content, allowlist)
+	for name, module := range modules {
+		if version, err := ioutil.ReadFile(filepath.Join("/sys/module", name, "version")); err == nil {
+			module.Version = strings.TrimSpace(string(version))
+		}
+	}
+
+	return modules, nil
+}
+
+// parseProcSwaps parses the content of /proc/swaps
+func parseProcSwaps(content []byte) []*SwapInfo {
+	var swaps []*SwapInfo
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// /dev/sda2 partition 8388604 0 -2
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 5 || fields[0] == "Filename" {
+			continue
+		}
+
+		swap := &SwapInfo{Device: unescapeProcPath(fields[0]), Type: fields[1]}
+		swap.Size, _ = strconv.ParseInt(fields[2], 10, 64)
+		swap.Used, _ = strconv.ParseInt(fields[3], 10, 64)
+		swap.Priority, _ = strconv.ParseInt(fields[4], 10, 64)
+
+		swaps = append(swaps, swap)
+	}
+
+	return swaps
+}
+
+// unescapeProcPath decodes the octal escaped characters of paths in /proc files
+func unescapeProcPath(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func getSwapInfo() ([]*SwapInfo, error) {
+	content, err := ioutil.ReadFile("/proc/swaps")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProcSwaps(content), nil
+}
+
+// parseSysctlValue returns the value of a sysctl as an integer when possible
+func parseSysctlValue(value string) interface{} {
+	value = strings.TrimSpace(value)
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// readSysctl reads the value of a sysctl key like net.ipv4.ip_forward from /proc/sys
+func readSysctl(key string) (interface{}, error) {
+	if strings.Contains(key, "..") || strings.Contains(key, "/") {
+		return nil, fmt.Errorf("Invalid sysctl key: %s", key)
+	}
+
+	buffer, err := ioutil.ReadFile(filepath.Join("/proc/sys", strings.Replace(key, ".", "/", -1)))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSysctlValue(string(buffer)), nil
+}
+
+// parseOSRelease parses the content of an os-release file
+func parseOSRelease(content []byte) (*OSReleaseInfo, error) {
+	osRelease := &OSReleaseInfo{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		value := kv[1]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+
+		switch kv[0] {
+		case "ID":
+			osRelease.ID = value
+		case "ID_LIKE":
+			osRelease.IDLike = strings.Fields(value)
+		case "VERSION_ID":
+			osRelease.VersionID = value
+		case "VARIANT":
+			osRelease.Variant = value
+		case "PRETTY_NAME":
+			osRelease.PrettyName = value
+		case "CPE_NAME":
+			osRelease.CPEName = value
+		}
+	}
+
+	if osRelease.ID == "" {
+		return nil, errors.New("No ID found in os-release")
+	}
+
+	return osRelease, nil
+}
+
+func getOSRelease() (*OSReleaseInfo, error) {
+	content, err := ioutil.ReadFile("/etc/os-release")
+	if err != nil {
+		if content, err = ioutil.ReadFile("/usr/lib/os-release"); err != nil {
+			return nil, err
+		}
+	}
+
+	return parseOSRelease(content)
+}
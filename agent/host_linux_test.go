@@ -53,3 +53,162 @@ func TestIsolatedCPU(t *testing.T) {
 		t.Fatal("Parsing of isolated cpu should return an error")
 	}
 }
+
+func TestSELinuxConfig(t *testing.T) {
+	content := []byte("# comment\nSELINUX=enforcing\nSELINUXTYPE=targeted\n")
+
+	if policy := parseSELinuxConfig(content); policy != "targeted" {
+		t.Fatalf("Parsing of SELinux config failed, expected: targeted, got: %s", policy)
+	}
+}
+
+func TestCPUFreqAggregate(t *testing.T) {
+	policies := []cpuFreqPolicy{
+		{governor: "performance", min: 1200000, max: 3000000, cur: 2000000},
+		{governor: "performance", min: 800000, max: 3500000, cur: 3000000},
+	}
+
+	expected := &CPUFreqInfo{Governor: "performance", MinMHz: 800, MaxMHz: 3500, CurrentMHzAvg: 2500}
+	if info := aggregateCPUFreq(policies); !reflect.DeepEqual(info, expected) {
+		t.Fatalf("Aggregation of cpufreq policies failed, expected: %+v, got: %+v", expected, info)
+	}
+
+	policies[1].governor = "powersave"
+	if info := aggregateCPUFreq(policies); info.Governor != "mixed" {
+		t.Fatalf("Governor should be mixed, got: %s", info.Governor)
+	}
+}
+
+func TestProcModules(t *testing.T) {
+	content := []byte(`openvswitch 139264 2 - Live 0x0000000000000000
+nf_conntrack 139264 5 xt_conntrack,nf_nat,openvswitch, Live 0x0000000000000000
+snd_hda_intel 53248 3 - Live 0x0000000000000000
+`)
+
+	expected := map[string]*KernelModuleInfo{
+		"openvswitch":  {Size: 139264, RefCount: 2},
+		"nf_conntrack": {Size: 139264, RefCount: 5},
+	}
+
+	if modules := parseProcModules(content, []string{"openvswitch", "nf-conntrack", "sctp"}); !reflect.DeepEqual(modules, expected) {
+		t.Fatalf("Parsing of /proc/modules failed, expected: %+v, got: %+v", expected, modules)
+	}
+
+	if modules := parseProcModules(content, []string{"all"}); len(modules) != 3 {
+		t.Fatalf("All the modules should be reported, got: %+v", modules)
+	}
+}
+
+func TestProcSwaps(t *testing.T) {
+	content := []byte(`Filename				Type		Size		Used		Priority
+/dev/sda2                               partition	8388604		1024		-2
+/var/lib/swap\040file                    file		2097148		0		-3
+`)
+
+	expected := []*SwapInfo{
+		{Device: "/dev/sda2", Type: "partition", Size: 8388604, Used: 1024, Priority: -2},
+		{Device: "/var/lib/swap file", Type: "file", Size: 2097148, Priority: -3},
+	}
+
+	if swaps := parseProcSwaps(content); !reflect.DeepEqual(swaps, expected) {
+		t.Fatalf("Parsing of /proc/swaps failed, expected: %+v, got: %+v", expected, swaps)
+	}
+}
+
+func TestSysctlValue(t *testing.T) {
+	if value := parseSysctlValue("1\n"); value != int64(1) {
+		t.Fatalf("Integer sysctl should be parsed as an integer, got: %v (%T)", value, value)
+	}
+
+	if value := parseSysctlValue("32768\t60999\n"); value != "32768 60999" {
+		t.Fatalf("Multiple values sysctl should be parsed as a string, got: %v", value)
+	}
+
+	if _, err := readSysctl("net/../../etc/passwd"); err == nil {
+		t.Fatal("Reading an invalid sysctl key should return an error")
+	}
+}
+
+const rhelOSRelease = `NAME="Red Hat Enterprise Linux Server"
+VERSION="7.5 (Maipo)"
+ID="rhel"
+ID_LIKE="fedora"
+VARIANT="Server"
+VARIANT_ID="server"
+VERSION_ID="7.5"
+PRETTY_NAME="Red Hat Enterprise Linux Server 7.5 (Maipo)"
+CPE_NAME="cpe:/o:redhat:enterprise_linux:7.5:GA:server"
+HOME_URL="https://www.redhat.com/"
+`
+
+const ubuntuOSRelease = `NAME="Ubuntu"
+VERSION="18.04.1 LTS (Bionic Beaver)"
+ID=ubuntu
+ID_LIKE=debian
+PRETTY_NAME="Ubuntu 18.04.1 LTS"
+VERSION_ID="18.04"
+SUPPORT_URL="https://help.ubuntu.com/"
+`
+
+const flatcarOSRelease = `NAME="Flatcar Container Linux by Kinvolk"
+ID=flatcar
+ID_LIKE=coreos
+VERSION=1911.3.0
+VERSION_ID=1911.3.0
+BUILD_ID=2018-10-15-0104
+PRETTY_NAME="Flatcar Container Linux by Kinvolk 1911.3.0 (Rhyolite)"
+ANSI_COLOR="38;5;75"
+HOME_URL="https://flatcar-linux.org/"
+`
+
+func TestOSRelease(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected *OSReleaseInfo
+	}{
+		{
+			content: rhelOSRelease,
+			expected: &OSReleaseInfo{
+				ID:         "rhel",
+				IDLike:     []string{"fedora"},
+				VersionID:  "7.5",
+				Variant:    "Server",
+				PrettyName: "Red Hat Enterprise Linux Server 7.5 (Maipo)",
+				CPEName:    "cpe:/o:redhat:enterprise_linux:7.5:GA:server",
+			},
+		},
+		{
+			content: ubuntuOSRelease,
+			expected: &OSReleaseInfo{
+				ID:         "ubuntu",
+				IDLike:     []string{"debian"},
+				VersionID:  "18.04",
+				PrettyName: "Ubuntu 18.04.1 LTS",
+			},
+		},
+		{
+			content: flatcarOSRelease,
+			expected: &OSReleaseInfo{
+				ID:         "flatcar",
+				IDLike:     []string{"coreos"},
+				VersionID:  "1911.3.0",
+				PrettyName: "Flatcar Container Linux by Kinvolk 1911.3.0 (Rhyolite)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		osRelease, err := parseOSRelease([]byte(test.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(osRelease, test.expected) {
+			t.Fatalf("Parsing of os-release failed, expected: %+v, got: %+v", test.expected, osRelease)
+		}
+	}
+
+	if _, err := parseOSRelease([]byte("NAME=Unknown\n")); err == nil {
+		t.Fatal("Parsing of an os-release without ID should return an error")
+	}
+}
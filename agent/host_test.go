@@ -0,0 +1,173 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/host"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+func TestCPUFlagsFilter(t *testing.T) {
+	flags := []string{"fpu", "vme", "sse4_2", "aes", "avx2", "hypervisor", "rdrand"}
+	allowlist := []string{"vmx", "svm", "avx2", "avx512f", "aes", "sse4_2", "hypervisor"}
+
+	expected := []string{"sse4_2", "aes", "avx2", "hypervisor"}
+
+	if filtered := filterCPUFlags(flags, allowlist); !reflect.DeepEqual(filtered, expected) {
+		t.Fatalf("Filtering of CPU flags failed, expected: %v, got: %v", expected, filtered)
+	}
+
+	if filtered := filterCPUFlags(flags, nil); len(filtered) != 0 {
+		t.Fatalf("Filtering of CPU flags with an empty allowlist should return no flag, got: %v", filtered)
+	}
+
+	if hashCPUFlags(flags) != hashCPUFlags([]string{"rdrand", "hypervisor", "avx2", "aes", "sse4_2", "vme", "fpu"}) {
+		t.Fatal("Hash of CPU flags should not depend on the flags order")
+	}
+}
+
+func TestCPUSocketAggregation(t *testing.T) {
+	var cpus []*CPUInfo
+	for i := 0; i < 8; i++ {
+		cpus = append(cpus, &CPUInfo{
+			CPU:        int64(i),
+			VendorID:   "GenuineIntel",
+			PhysicalID: strconv.Itoa(i % 2),
+			CoreID:     strconv.Itoa((i / 2) % 2),
+			Cores:      1,
+			ModelName:  "Intel(R) Xeon(R) Gold 6130 CPU @ 2.10GHz",
+			Microcode:  "0x200004d",
+		})
+	}
+
+	expected := []*CPUInfo{
+		{CPU: 0, VendorID: "GenuineIntel", PhysicalID: "0", Cores: 2, Threads: 4, ModelName: "Intel(R) Xeon(R) Gold 6130 CPU @ 2.10GHz", Microcode: "0x200004d"},
+		{CPU: 1, VendorID: "GenuineIntel", PhysicalID: "1", Cores: 2, Threads: 4, ModelName: "Intel(R) Xeon(R) Gold 6130 CPU @ 2.10GHz", Microcode: "0x200004d"},
+	}
+
+	if sockets := aggregateCPUsBySocket(cpus); !reflect.DeepEqual(sockets, expected) {
+		t.Fatalf("Aggregation of CPUs failed, expected: %+v, got: %+v", expected, sockets)
+	}
+
+	if sockets, cores := countCPUSockets(cpus); sockets != 2 || cores != 4 {
+		t.Fatalf("Counting of CPUs failed, expected: 2 sockets and 4 cores, got: %d sockets and %d cores", sockets, cores)
+	}
+}
+
+func newTestHostInfoProvider() *hostInfoProvider {
+	return &hostInfoProvider{
+		hostname: func() (string, error) {
+			return "host-a", nil
+		},
+		configMetadata: func() (map[string]interface{}, error) {
+			return map[string]interface{}{"Rack": "r12"}, nil
+		},
+		cloudInit: func(keys []string) (map[string]interface{}, string, error) {
+			return map[string]interface{}{"ds": "nocloud"}, "i-0123", nil
+		},
+		cpuInfo: func() ([]cpu.InfoStat, error) {
+			return []cpu.InfoStat{{CPU: 0, VendorID: "GenuineIntel"}}, nil
+		},
+		hostInfo: func() (*host.InfoStat, error) {
+			return &host.InfoStat{OS: "linux"}, nil
+		},
+	}
+}
+
+func TestRootNodeCollectorErrors(t *testing.T) {
+	collectorErr := errors.New("collector failure")
+
+	tests := []struct {
+		name    string
+		stub    func(p *hostInfoProvider)
+		missing string
+	}{
+		{
+			name: "config",
+			stub: func(p *hostInfoProvider) {
+				p.configMetadata = func() (map[string]interface{}, error) { return nil, collectorErr }
+			},
+			missing: "Rack",
+		},
+		{
+			name: "cloud-init",
+			stub: func(p *hostInfoProvider) {
+				p.cloudInit = func(keys []string) (map[string]interface{}, string, error) { return nil, "", collectorErr }
+			},
+			missing: "CloudInit",
+		},
+		{
+			name: "cpu",
+			stub: func(p *hostInfoProvider) {
+				p.cpuInfo = func() ([]cpu.InfoStat, error) { return nil, collectorErr }
+			},
+			missing: "CPU",
+		},
+		{
+			name: "host",
+			stub: func(p *hostInfoProvider) {
+				p.hostInfo = func() (*host.InfoStat, error) { return nil, collectorErr }
+			},
+			missing: "OS",
+		},
+	}
+
+	for _, test := range tests {
+		backend, _ := graph.NewMemoryBackend()
+		g := graph.NewGraph("host-a", backend, common.AgentService)
+
+		provider := newTestHostInfoProvider()
+		test.stub(provider)
+
+		node, err := newRootNode(g, provider)
+		if err != nil {
+			t.Fatalf("Failure of the %s collector should not prevent the host node creation: %s", test.name, err)
+		}
+
+		if hostname, _ := node.GetFieldString("Hostname"); hostname != "host-a" {
+			t.Fatalf("Host node should have a hostname, got: %s", hostname)
+		}
+
+		if _, err := node.GetField(test.missing); err == nil {
+			t.Fatalf("Host node should not have a %s field on failure of the %s collector", test.missing, test.name)
+		}
+	}
+
+	backend, _ := graph.NewMemoryBackend()
+	g := graph.NewGraph("host-a", backend, common.AgentService)
+
+	provider := newTestHostInfoProvider()
+	provider.hostname = func() (string, error) { return "", collectorErr }
+
+	if _, err := newRootNode(g, provider); err == nil {
+		t.Fatal("Host node creation should fail if the hostname can't be retrieved")
+	}
+}
@@ -0,0 +1,143 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseInterrupts parses the content of /proc/interrupts and returns the
+// action name of each IRQ. Only the IRQ number and the last column are
+// extracted as lines can be very long on hosts with many CPUs.
+func parseInterrupts(content []byte) map[int64]string {
+	irqs := make(map[int64]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+
+		colon := bytes.IndexByte(line, ':')
+		if colon == -1 {
+			continue
+		}
+
+		irq, err := strconv.ParseInt(string(line[:colon]), 10, 64)
+		if err != nil {
+			// header or non numbered interrupts like NMI
+			continue
+		}
+
+		action := line[bytes.LastIndexAny(line, " \t")+1:]
+		irqs[irq] = string(action)
+	}
+
+	return irqs
+}
+
+// getNICIRQs returns the IRQs used by the physical interfaces, either using
+// the MSI IRQs of the device or the action name found in /proc/interrupts
+func getNICIRQs(irqs map[int64]string) map[int64]string {
+	nicIRQs := make(map[int64]string)
+
+	links, err := ioutil.ReadDir("/sys/class/net")
+	if err != nil {
+		return nicIRQs
+	}
+
+	for _, link := range links {
+		name := link.Name()
+		if _, err := os.Stat(filepath.Join("/sys/class/net", name, "device")); err != nil {
+			continue
+		}
+
+		msiIRQs, _ := ioutil.ReadDir(filepath.Join("/sys/class/net", name, "device", "msi_irqs"))
+		for _, msiIRQ := range msiIRQs {
+			if irq, err := strconv.ParseInt(msiIRQ.Name(), 10, 64); err == nil {
+				nicIRQs[irq] = name
+			}
+		}
+
+		for irq, action := range irqs {
+			if action == name || strings.HasPrefix(action, name+"-") {
+				nicIRQs[irq] = name
+			}
+		}
+	}
+
+	return nicIRQs
+}
+
+// intersectCPUs returns whether the two CPU lists have a CPU in common
+func intersectCPUs(a []int64, b []int64) bool {
+	set := make(map[int64]bool, len(a))
+	for _, cpu := range a {
+		set[cpu] = true
+	}
+
+	for _, cpu := range b {
+		if set[cpu] {
+			return true
+		}
+	}
+	return false
+}
+
+// getIRQViolations returns the NIC IRQs whose affinity intersects the isolated CPUs
+func getIRQViolations(isolated []int64) ([]*IRQViolation, error) {
+	content, err := ioutil.ReadFile("/proc/interrupts")
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []*IRQViolation
+	for irq, device := range getNICIRQs(parseInterrupts(content)) {
+		buffer, err := ioutil.ReadFile(filepath.Join("/proc/irq", strconv.FormatInt(irq, 10), "smp_affinity_list"))
+		if err != nil {
+			continue
+		}
+
+		affinity, err := parseIsolatedCPUs(strings.TrimSpace(string(buffer)))
+		if err != nil {
+			continue
+		}
+
+		if intersectCPUs(affinity, isolated) {
+			violations = append(violations, &IRQViolation{IRQ: irq, Device: device})
+		}
+	}
+
+	// keep a stable order to not generate an update at each refresh
+	sort.Slice(violations, func(i, j int) bool { return violations[i].IRQ < violations[j].IRQ })
+
+	return violations, nil
+}
@@ -0,0 +1,57 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+const procInterrupts = `            CPU0       CPU1       CPU2       CPU3
+   0:         22          0          0          0   IO-APIC   2-edge      timer
+  24:       1234          0          5          0   PCI-MSI 524288-edge      eth0-TxRx-0
+  25:          0       4321          0          0   PCI-MSI 524289-edge      eth0-TxRx-1
+ NMI:          0          0          0          0   Non-maskable interrupts
+`
+
+func TestParseInterrupts(t *testing.T) {
+	expected := map[int64]string{
+		0:  "timer",
+		24: "eth0-TxRx-0",
+		25: "eth0-TxRx-1",
+	}
+
+	if irqs := parseInterrupts([]byte(procInterrupts)); !reflect.DeepEqual(irqs, expected) {
+		t.Fatalf("Parsing of /proc/interrupts failed, expected: %v, got: %v", expected, irqs)
+	}
+
+	if !intersectCPUs([]int64{0, 1, 2}, []int64{2, 3}) {
+		t.Fatal("CPU lists should intersect")
+	}
+
+	if intersectCPUs([]int64{0, 1}, []int64{2, 3}) {
+		t.Fatal("CPU lists should not intersect")
+	}
+}
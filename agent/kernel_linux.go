@@ -0,0 +1,180 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// kernelTaintFlags are the names of the bits of /proc/sys/kernel/tainted,
+// see Documentation/admin-guide/tainted-kernels.rst
+var kernelTaintFlags = []string{
+	"proprietary_module",
+	"forced_module_load",
+	"out_of_spec_system",
+	"forced_module_unload",
+	"machine_check",
+	"bad_page",
+	"user_request",
+	"kernel_died",
+	"acpi_overridden",
+	"warning",
+	"staging_driver",
+	"firmware_workaround",
+	"out_of_tree_module",
+	"unsigned_module",
+	"soft_lockup",
+	"live_patched",
+	"auxiliary",
+	"struct_randomization",
+}
+
+// parseKernelConfigPreemption returns the preemption model of a kernel
+// build configuration
+func parseKernelConfigPreemption(content []byte) string {
+	var preemption string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		switch strings.TrimSpace(scanner.Text()) {
+		case "CONFIG_PREEMPT_RT=y", "CONFIG_PREEMPT_RT_FULL=y":
+			// RT kernels also set CONFIG_PREEMPT
+			return "rt"
+		case "CONFIG_PREEMPT=y":
+			preemption = "full"
+		case "CONFIG_PREEMPT_VOLUNTARY=y":
+			if preemption == "" {
+				preemption = "voluntary"
+			}
+		case "CONFIG_PREEMPT_NONE=y":
+			if preemption == "" {
+				preemption = "none"
+			}
+		}
+	}
+
+	return preemption
+}
+
+// parseKernelVersionPreemption returns the preemption model from the kernel
+// build string as reported by 'uname -v'
+func parseKernelVersionPreemption(version string) string {
+	fields := strings.Fields(version)
+	for i, field := range fields {
+		switch field {
+		case "PREEMPT_RT":
+			return "rt"
+		case "PREEMPT":
+			if i+1 < len(fields) && fields[i+1] == "RT" {
+				return "rt"
+			}
+			return "full"
+		}
+	}
+
+	// the build string doesn't mention voluntary preemption
+	return ""
+}
+
+// readKernelConfig reads the build configuration of the running kernel
+// from /boot or from /proc/config.gz when /boot is not readable
+func readKernelConfig(release string) ([]byte, error) {
+	if content, err := ioutil.ReadFile("/boot/config-" + release); err == nil {
+		return content, nil
+	}
+
+	f, err := os.Open("/proc/config.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+func getKernelInfo(release string) (*KernelInfo, error) {
+	var preemption string
+	if content, err := readKernelConfig(release); err == nil {
+		preemption = parseKernelConfigPreemption(content)
+	}
+
+	if preemption == "" {
+		preemption = parseKernelVersionPreemption(readSysfsString("/proc/sys/kernel/version"))
+	}
+
+	if preemption == "" {
+		return nil, errors.New("Unable to determine the kernel preemption model")
+	}
+
+	return &KernelInfo{
+		Preemption: preemption,
+		Realtime:   preemption == "rt" || readSysfsString("/sys/kernel/realtime") == "1",
+	}, nil
+}
+
+// decodeKernelTaint returns the names of the taint flags set in value, the
+// list is empty but not nil for an untainted kernel
+func decodeKernelTaint(value int64) []string {
+	flags := []string{}
+	for bit := uint(0); bit < 64; bit++ {
+		if value&(1<<bit) == 0 {
+			continue
+		}
+
+		if int(bit) < len(kernelTaintFlags) {
+			flags = append(flags, kernelTaintFlags[bit])
+		} else {
+			flags = append(flags, "bit_"+strconv.Itoa(int(bit)))
+		}
+	}
+	return flags
+}
+
+func getKernelTaint() (*KernelTaintInfo, error) {
+	content, err := ioutil.ReadFile("/proc/sys/kernel/tainted")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KernelTaintInfo{Value: value, Flags: decodeKernelTaint(value)}, nil
+}
@@ -0,0 +1,82 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKernelConfigPreemption(t *testing.T) {
+	tests := map[string]string{
+		"CONFIG_PREEMPT_NONE=y\n# CONFIG_PREEMPT_VOLUNTARY is not set\n":                 "none",
+		"# CONFIG_PREEMPT_NONE is not set\nCONFIG_PREEMPT_VOLUNTARY=y\n":                 "voluntary",
+		"CONFIG_PREEMPT_BUILD=y\nCONFIG_PREEMPT_VOLUNTARY=y\nCONFIG_PREEMPT_DYNAMIC=y\n": "voluntary",
+		"CONFIG_PREEMPT=y\nCONFIG_PREEMPT_COUNT=y\n":                                     "full",
+		"CONFIG_PREEMPT=y\nCONFIG_PREEMPT_RT=y\n":                                        "rt",
+		"CONFIG_PREEMPT_RTB=y\nCONFIG_PREEMPT_RT_FULL=y\n":                               "rt",
+		"CONFIG_HZ_1000=y\n": "",
+	}
+
+	for content, expected := range tests {
+		if preemption := parseKernelConfigPreemption([]byte(content)); preemption != expected {
+			t.Fatalf("Parsing of kernel config failed, expected: %s, got: %s", expected, preemption)
+		}
+	}
+}
+
+func TestKernelVersionPreemption(t *testing.T) {
+	tests := map[string]string{
+		"#1 SMP Wed Oct 10 09:43:59 UTC 2018":                 "",
+		"#1 SMP PREEMPT Thu Oct 11 17:26:06 UTC 2018":         "full",
+		"#1 SMP PREEMPT RT Mon Sep 24 13:31:46 UTC 2018":      "rt",
+		"#1 SMP PREEMPT_RT Tue Oct 2 08:12:19 CEST 2018":      "rt",
+		"#1 SMP PREEMPT_DYNAMIC Tue Oct 2 08:12:19 CEST 2018": "",
+	}
+
+	for version, expected := range tests {
+		if preemption := parseKernelVersionPreemption(version); preemption != expected {
+			t.Fatalf("Parsing of kernel version failed, expected: %s, got: %s", expected, preemption)
+		}
+	}
+}
+
+func TestKernelTaint(t *testing.T) {
+	tests := []struct {
+		value    int64
+		expected []string
+	}{
+		{value: 0, expected: []string{}},
+		{value: 1, expected: []string{"proprietary_module"}},
+		{value: 4096 + 8192, expected: []string{"out_of_tree_module", "unsigned_module"}},
+		{value: 128 + 512 + 1<<20, expected: []string{"kernel_died", "warning", "bit_20"}},
+	}
+
+	for _, test := range tests {
+		if flags := decodeKernelTaint(test.value); !reflect.DeepEqual(flags, test.expected) {
+			t.Fatalf("Decoding of kernel taint %d failed, expected: %+v, got: %+v", test.value, test.expected, flags)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skydive-project/skydive/config"
+)
+
+const (
+	libvirtSocket  = "/var/run/libvirt/libvirt-sock"
+	libvirtTimeout = 10 * time.Second
+
+	// running state of a libvirt domain
+	libvirtDomainRunning = 1
+)
+
+// VirtualizationInfo defines a summary of the libvirt guests of the host
+type VirtualizationInfo struct {
+	GuestsRunning     int64
+	GuestsDefined     int64
+	VCPUsAllocated    int64 `json:"vCPUsAllocated"`
+	MemoryAllocatedKb int64
+}
+
+// parseVirshDomstats parses the output of 'virsh domstats --state --vcpu --balloon',
+// only the running guests are accounted for the allocated resources
+func parseVirshDomstats(out []byte) *VirtualizationInfo {
+	info := &VirtualizationInfo{}
+
+	var running bool
+	var vcpus, memory int64
+
+	flush := func() {
+		if running {
+			info.GuestsRunning++
+			info.VCPUsAllocated += vcpus
+			info.MemoryAllocatedKb += memory
+		}
+		running, vcpus, memory = false, 0, 0
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "Domain:") {
+			flush()
+			info.GuestsDefined++
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		value, err := strconv.ParseInt(kv[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch kv[0] {
+		case "state.state":
+			running = value == libvirtDomainRunning
+		case "vcpu.current":
+			vcpus = value
+		case "balloon.current":
+			memory = value
+		}
+	}
+	flush()
+
+	return info
+}
+
+// isLibvirtProbeEnabled returns whether the libvirt topology probe is
+// enabled, in that case it already reports the guests
+func isLibvirtProbeEnabled() bool {
+	for _, probe := range config.GetStringSlice("agent.topology.probes") {
+		if probe == "libvirt" {
+			return true
+		}
+	}
+	return false
+}
+
+// getVirtualizationInfo returns a summary of the libvirt guests when
+// libvirtd is reachable through its local socket
+func getVirtualizationInfo() (*VirtualizationInfo, error) {
+	if _, err := os.Stat(libvirtSocket); err != nil {
+		return nil, err
+	}
+
+	path, err := exec.LookPath("virsh")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), libvirtTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "-r", "-c", "qemu:///system", "domstats", "--state", "--vcpu", "--balloon").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseVirshDomstats(out), nil
+}
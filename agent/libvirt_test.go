@@ -0,0 +1,73 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skydive-project/skydive/config"
+)
+
+const virshDomstatsOutput = `Domain: 'vm1'
+  state.state=1
+  state.reason=1
+  balloon.current=2097152
+  balloon.maximum=2097152
+  vcpu.current=2
+  vcpu.maximum=4
+
+Domain: 'vm2'
+  state.state=5
+  state.reason=0
+  vcpu.current=8
+
+Domain: 'vm3'
+  state.state=1
+  state.reason=1
+  balloon.current=1048576
+  vcpu.current=1
+`
+
+func TestVirshDomstats(t *testing.T) {
+	expected := &VirtualizationInfo{GuestsRunning: 2, GuestsDefined: 3, VCPUsAllocated: 3, MemoryAllocatedKb: 3145728}
+
+	if info := parseVirshDomstats([]byte(virshDomstatsOutput)); !reflect.DeepEqual(info, expected) {
+		t.Fatalf("Parsing of virsh domstats failed, expected: %+v, got: %+v", expected, info)
+	}
+}
+
+func TestLibvirtProbeEnabled(t *testing.T) {
+	probes := config.GetStringSlice("agent.topology.probes")
+	defer config.Set("agent.topology.probes", probes)
+
+	config.Set("agent.topology.probes", []string{"ovsdb", "netlink"})
+	if isLibvirtProbeEnabled() {
+		t.Error("libvirt probe should not be reported enabled")
+	}
+
+	config.Set("agent.topology.probes", []string{"ovsdb", "libvirt"})
+	if !isLibvirtProbeEnabled() {
+		t.Error("libvirt probe should be reported enabled, the guest summary would be duplicated")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"time"
+)
+
+const lldpcliTimeout = 10 * time.Second
+
+// LLDPNeighborInfo defines the switch and port learned through LLDP on an interface
+type LLDPNeighborInfo struct {
+	ChassisName     string `json:"ChassisName,omitempty"`
+	ChassisID       string `json:"ChassisID,omitempty"`
+	PortID          string `json:"PortID,omitempty"`
+	PortDescription string `json:"PortDescription,omitempty"`
+	MgmtAddress     string `json:"MgmtAddress,omitempty"`
+}
+
+// lldpValue returns the value of a lldpcli JSON attribute which is either
+// a plain value, an object holding a 'value' key or a list of those
+func lldpValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		return lldpValue(v["value"])
+	case []interface{}:
+		if len(v) > 0 {
+			return lldpValue(v[0])
+		}
+	}
+	return ""
+}
+
+// lldpEntries flattens the lldpcli representation of a list of named objects
+// which is either a single object or a list of single keyed objects
+func lldpEntries(v interface{}) map[string]map[string]interface{} {
+	entries := make(map[string]map[string]interface{})
+
+	var objs []interface{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		objs = []interface{}{v}
+	case []interface{}:
+		objs = v
+	}
+
+	for _, obj := range objs {
+		if obj, ok := obj.(map[string]interface{}); ok {
+			for name, entry := range obj {
+				if entry, ok := entry.(map[string]interface{}); ok {
+					entries[name] = entry
+				}
+			}
+		}
+	}
+
+	return entries
+}
+
+// parseLLDPCliNeighbors parses the output of 'lldpcli show neighbors -f json'
+func parseLLDPCliNeighbors(out []byte) (map[string]*LLDPNeighborInfo, error) {
+	var content struct {
+		LLDP map[string]interface{} `json:"lldp"`
+	}
+	if err := json.Unmarshal(out, &content); err != nil {
+		return nil, err
+	}
+
+	neighbors := make(map[string]*LLDPNeighborInfo)
+	for ifname, intf := range lldpEntries(content.LLDP["interface"]) {
+		neighbor := &LLDPNeighborInfo{}
+
+		for name, chassis := range lldpEntries(intf["chassis"]) {
+			// chassis without system name are keyed by 'id'
+			if _, found := chassis["id"]; found {
+				if name != "id" {
+					neighbor.ChassisName = name
+				}
+				neighbor.ChassisID = lldpValue(chassis["id"])
+			} else {
+				neighbor.ChassisID = lldpValue(chassis)
+			}
+			neighbor.MgmtAddress = lldpValue(chassis["mgmt-ip"])
+		}
+
+		if port, ok := intf["port"].(map[string]interface{}); ok {
+			neighbor.PortID = lldpValue(port["id"])
+			neighbor.PortDescription = lldpValue(port["descr"])
+		}
+
+		neighbors[ifname] = neighbor
+	}
+
+	return neighbors, nil
+}
+
+// getLLDPNeighbors returns the LLDP neighbors per interface as reported by
+// lldpd, an error is returned if lldpd is not available
+func getLLDPNeighbors() (map[string]*LLDPNeighborInfo, error) {
+	path, err := exec.LookPath("lldpcli")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lldpcliTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "show", "neighbors", "-f", "json").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors, err := parseLLDPCliNeighbors(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(neighbors) == 0 {
+		return nil, errors.New("No LLDP neighbor found")
+	}
+
+	return neighbors, nil
+}
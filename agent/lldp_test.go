@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+const lldpcliSingleOutput = `{
+  "lldp": {
+    "interface": {
+      "eth0": {
+        "via": "LLDP",
+        "rid": "1",
+        "age": "0 day, 00:10:12",
+        "chassis": {
+          "tor-sw1": {
+            "id": {"type": "mac", "value": "00:1c:73:aa:bb:cc"},
+            "descr": "Arista Networks EOS",
+            "mgmt-ip": "10.0.0.1"
+          }
+        },
+        "port": {
+          "id": {"type": "ifname", "value": "Ethernet12"},
+          "descr": "host-a eth0",
+          "ttl": "120"
+        }
+      }
+    }
+  }
+}`
+
+const lldpcliMultipleOutput = `{
+  "lldp": {
+    "interface": [
+      {
+        "eth0": {
+          "via": "LLDP",
+          "chassis": {
+            "tor-sw1": {
+              "id": {"type": "mac", "value": "00:1c:73:aa:bb:cc"},
+              "mgmt-ip": ["10.0.0.1", "fe80::1"]
+            }
+          },
+          "port": {
+            "id": {"type": "ifname", "value": "Ethernet12"}
+          }
+        }
+      },
+      {
+        "eth1": {
+          "via": "LLDP",
+          "chassis": {
+            "id": {"type": "mac", "value": "00:1c:73:dd:ee:ff"}
+          },
+          "port": {
+            "id": {"type": "mac", "value": "00:1c:73:dd:ee:01"},
+            "descr": "Gi1/0/1"
+          }
+        }
+      }
+    ]
+  }
+}`
+
+func TestLLDPCliNeighbors(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected map[string]*LLDPNeighborInfo
+	}{
+		{
+			output: lldpcliSingleOutput,
+			expected: map[string]*LLDPNeighborInfo{
+				"eth0": {ChassisName: "tor-sw1", ChassisID: "00:1c:73:aa:bb:cc", PortID: "Ethernet12", PortDescription: "host-a eth0", MgmtAddress: "10.0.0.1"},
+			},
+		},
+		{
+			output: lldpcliMultipleOutput,
+			expected: map[string]*LLDPNeighborInfo{
+				"eth0": {ChassisName: "tor-sw1", ChassisID: "00:1c:73:aa:bb:cc", PortID: "Ethernet12", MgmtAddress: "10.0.0.1"},
+				"eth1": {ChassisID: "00:1c:73:dd:ee:ff", PortID: "00:1c:73:dd:ee:01", PortDescription: "Gi1/0/1"},
+			},
+		},
+		{
+			output:   `{"lldp": {}}`,
+			expected: map[string]*LLDPNeighborInfo{},
+		},
+	}
+
+	for _, test := range tests {
+		neighbors, err := parseLLDPCliNeighbors([]byte(test.output))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(neighbors, test.expected) {
+			t.Fatalf("Parsing of lldpcli output failed, expected: %+v, got: %+v", test.expected, neighbors)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+// parseHardwareInfo normalizes the JSON output of lshw and returns it
+// along with a hash of the normalized value
+func parseHardwareInfo(out []byte) (interface{}, string, error) {
+	var hardware interface{}
+	if err := common.JSONDecode(bytes.NewReader(out), &hardware); err != nil {
+		return nil, "", err
+	}
+	hardware = common.NormalizeValue(hardware)
+
+	// map keys are sorted by the JSON encoder so that the hash is stable
+	normalized, err := json.Marshal(hardware)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha1.Sum(normalized)
+	return hardware, hex.EncodeToString(sum[:]), nil
+}
+
+// getHardwareInfo runs lshw and returns the hardware description, the
+// process is killed if it runs longer than timeout
+func getHardwareInfo(timeout time.Duration) (interface{}, string, error) {
+	path, err := exec.LookPath("lshw")
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// the command is killed if it doesn't complete before the timeout
+	out, err := exec.CommandContext(ctx, path, "-quiet", "-json").Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, "", fmt.Errorf("lshw didn't complete within %s", timeout)
+		}
+		return nil, "", err
+	}
+
+	return parseHardwareInfo(out)
+}
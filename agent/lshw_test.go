@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"testing"
+)
+
+func TestHardwareInfoHash(t *testing.T) {
+	_, hash1, err := parseHardwareInfo([]byte(`{"id": "host", "vendor": "Dell", "children": [{"id": "core"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, hash2, err := parseHardwareInfo([]byte(`{"children": [{"id": "core"}], "vendor": "Dell", "id": "host"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash1 != hash2 {
+		t.Fatalf("Hash of the same hardware description should be equal: %s != %s", hash1, hash2)
+	}
+
+	_, hash3, err := parseHardwareInfo([]byte(`{"id": "host", "vendor": "HP", "children": [{"id": "core"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash1 == hash3 {
+		t.Fatal("Hash of different hardware descriptions should differ")
+	}
+
+	if _, _, err = parseHardwareInfo([]byte(`{"id": `)); err == nil {
+		t.Fatal("Parsing of an invalid lshw output should return an error")
+	}
+}
@@ -0,0 +1,135 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const ethtoolTimeout = 5 * time.Second
+
+// offloads reported for each physical interface
+var nicOffloads = map[string]bool{
+	"rx-checksumming":              true,
+	"tx-checksumming":              true,
+	"tcp-segmentation-offload":     true,
+	"generic-segmentation-offload": true,
+	"generic-receive-offload":      true,
+}
+
+// parseEthtoolDriverInfo parses the output of 'ethtool -i <interface>'
+func parseEthtoolDriverInfo(out []byte, nic *NICInfo) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+
+		switch kv[0] {
+		case "driver":
+			nic.Driver = value
+		case "version":
+			nic.DriverVersion = value
+		case "firmware-version":
+			nic.Firmware = value
+		case "bus-info":
+			nic.BusInfo = value
+		}
+	}
+}
+
+// parseEthtoolFeatures parses the output of 'ethtool -k <interface>' and
+// returns the state of the selected offloads
+func parseEthtoolFeatures(out []byte) map[string]bool {
+	offloads := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 || !nicOffloads[kv[0]] {
+			continue
+		}
+
+		// "on", "off" or "off [fixed]"
+		offloads[kv[0]] = strings.HasPrefix(strings.TrimSpace(kv[1]), "on")
+	}
+
+	return offloads
+}
+
+func runEthtool(path string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ethtoolTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, path, args...).Output()
+}
+
+// getNICInfos returns the driver information of the physical interfaces,
+// virtual interfaces like veth or bridges don't have a device and are skipped
+func getNICInfos() (map[string]*NICInfo, error) {
+	path, err := exec.LookPath("ethtool")
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := ioutil.ReadDir("/sys/class/net")
+	if err != nil {
+		return nil, err
+	}
+
+	nics := make(map[string]*NICInfo)
+	for _, link := range links {
+		name := link.Name()
+		if _, err := os.Stat(filepath.Join("/sys/class/net", name, "device")); err != nil {
+			continue
+		}
+
+		out, err := runEthtool(path, "-i", name)
+		if err != nil {
+			continue
+		}
+
+		nic := &NICInfo{}
+		parseEthtoolDriverInfo(out, nic)
+
+		if out, err = runEthtool(path, "-k", name); err == nil {
+			nic.Offloads = parseEthtoolFeatures(out)
+		}
+
+		nics[name] = nic
+	}
+
+	return nics, nil
+}
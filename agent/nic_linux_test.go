@@ -0,0 +1,70 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+const ethtoolDriverOutput = `driver: ixgbe
+version: 5.1.0-k
+firmware-version: 0x800003e7
+expansion-rom-version:
+bus-info: 0000:03:00.0
+supports-statistics: yes
+`
+
+const ethtoolFeaturesOutput = `Features for eth0:
+rx-checksumming: on
+tx-checksumming: on
+	tx-checksum-ipv4: off [fixed]
+scatter-gather: on
+tcp-segmentation-offload: off
+generic-segmentation-offload: on
+generic-receive-offload: off [fixed]
+`
+
+func TestEthtoolParsing(t *testing.T) {
+	nic := &NICInfo{}
+	parseEthtoolDriverInfo([]byte(ethtoolDriverOutput), nic)
+
+	expected := &NICInfo{Driver: "ixgbe", DriverVersion: "5.1.0-k", Firmware: "0x800003e7", BusInfo: "0000:03:00.0"}
+	if !reflect.DeepEqual(nic, expected) {
+		t.Fatalf("Parsing of ethtool -i failed, expected: %+v, got: %+v", expected, nic)
+	}
+
+	offloads := parseEthtoolFeatures([]byte(ethtoolFeaturesOutput))
+	expectedOffloads := map[string]bool{
+		"rx-checksumming":              true,
+		"tx-checksumming":              true,
+		"tcp-segmentation-offload":     false,
+		"generic-segmentation-offload": true,
+		"generic-receive-offload":      false,
+	}
+	if !reflect.DeepEqual(offloads, expectedOffloads) {
+		t.Fatalf("Parsing of ethtool -k failed, expected: %+v, got: %+v", expectedOffloads, offloads)
+	}
+}
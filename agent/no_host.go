@@ -29,3 +29,87 @@ import "errors"
 func getIsolatedCPUs() ([]int64, error) {
 	return nil, errors.New("Not available on this platform")
 }
+
+func getMACInfo() (*MACInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getMemoryInfo() (*MemoryInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getPowerInfo() (*PowerInfo, bool, error) {
+	return nil, false, errors.New("Not available on this platform")
+}
+
+func getBMCInfo(channels []string) (*BMCInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getCPUFreqInfo() (*CPUFreqInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getNICInfos() (map[string]*NICInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getFIPSEnabled() (bool, error) {
+	return false, errors.New("Not available on this platform")
+}
+
+func getCryptoPolicy() (string, error) {
+	return "", errors.New("Not available on this platform")
+}
+
+func getKernelModules(allowlist []string) (map[string]*KernelModuleInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getDisksInfo() (map[string]*DiskInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getSwapInfo() ([]*SwapInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func readSysctl(key string) (interface{}, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getIRQViolations(isolated []int64) ([]*IRQViolation, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getOSRelease() (*OSReleaseInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getCgroupsInfo() (*CgroupsInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getKernelInfo(release string) (*KernelInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getKernelTaint() (*KernelTaintInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getSMTInfo() (*SMTInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getCoreSiblings() (map[string]string, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getPressureInfo() (map[string]*PressureInfo, error) {
+	return nil, errors.New("Not available on this platform")
+}
+
+func getDMIProductUUID() (string, error) {
+	return "", errors.New("Not available on this platform")
+}
@@ -0,0 +1,201 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ipmitoolTimeout      = 10 * time.Second
+	sysfsPowerSupplyRoot = "/sys/class/power_supply"
+)
+
+func readSysfsString(path string) string {
+	buffer, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(buffer))
+}
+
+// readSysfsPowerSupplies returns the power supplies and batteries exposed in
+// the power_supply class directory root
+func readSysfsPowerSupplies(root string) []*PowerSupplyInfo {
+	paths, _ := filepath.Glob(filepath.Join(root, "*"))
+
+	var supplies []*PowerSupplyInfo
+	for _, path := range paths {
+		supply := &PowerSupplyInfo{
+			Name:    filepath.Base(path),
+			Type:    readSysfsString(filepath.Join(path, "type")),
+			Present: true,
+			Online:  true,
+			Status:  readSysfsString(filepath.Join(path, "status")),
+		}
+
+		if present := readSysfsString(filepath.Join(path, "present")); present == "0" {
+			supply.Present = false
+		}
+
+		switch supply.Type {
+		case "Battery", "UPS":
+			if capacity, err := strconv.ParseInt(readSysfsString(filepath.Join(path, "capacity")), 10, 64); err == nil {
+				supply.Capacity = capacity
+			}
+			if health := readSysfsString(filepath.Join(path, "health")); health == "Dead" || health == "Unspecified failure" {
+				supply.Failed = true
+			}
+		case "USB":
+			// USB ports are not power supplies of the host
+			continue
+		default:
+			// an unplugged mains adapter is offline, not failed
+			if online := readSysfsString(filepath.Join(path, "online")); online == "0" {
+				supply.Online = false
+			}
+		}
+
+		supplies = append(supplies, supply)
+	}
+
+	return supplies
+}
+
+func getSysfsPowerSupplies() []*PowerSupplyInfo {
+	return readSysfsPowerSupplies(sysfsPowerSupplyRoot)
+}
+
+// parseIPMIPowerSupplies parses the output of 'ipmitool sdr type "Power Supply"',
+// it returns the power supplies and whether the BMC reported a redundancy loss
+func parseIPMIPowerSupplies(out []byte) ([]*PowerSupplyInfo, bool) {
+	var supplies []*PowerSupplyInfo
+	var redundancyLost bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) != 5 {
+			continue
+		}
+
+		name := strings.TrimSpace(fields[0])
+		status := strings.TrimSpace(fields[2])
+		events := strings.TrimSpace(fields[4])
+
+		if strings.Contains(name, "Redundancy") {
+			if strings.Contains(events, "Redundancy Lost") {
+				redundancyLost = true
+			}
+			continue
+		}
+
+		// sensor not available
+		if status == "ns" {
+			continue
+		}
+
+		supply := &PowerSupplyInfo{
+			Name:    name,
+			Type:    "Power Supply",
+			Present: strings.Contains(events, "Presence detected"),
+			Online:  true,
+			Status:  events,
+		}
+
+		for _, failure := range []string{"Failure detected", "Predictive failure", "Power Supply AC lost", "input lost"} {
+			if strings.Contains(events, failure) {
+				supply.Failed = true
+			}
+		}
+
+		supplies = append(supplies, supply)
+	}
+
+	return supplies, redundancyLost
+}
+
+func getIPMIPowerSupplies() ([]*PowerSupplyInfo, bool, error) {
+	path, err := exec.LookPath("ipmitool")
+	if err != nil {
+		return nil, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ipmitoolTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "sdr", "type", "Power Supply").Output()
+	if err != nil {
+		return nil, false, err
+	}
+
+	supplies, redundancyLost := parseIPMIPowerSupplies(out)
+	return supplies, redundancyLost, nil
+}
+
+// isPowerRedundancyLost returns true when one of multiple power supplies failed,
+// batteries and UPSes are not power supplies of the host
+func isPowerRedundancyLost(supplies []*PowerSupplyInfo) bool {
+	var count, failed int
+	for _, supply := range supplies {
+		if supply.Type == "Battery" || supply.Type == "UPS" {
+			continue
+		}
+
+		count++
+		if supply.Failed || !supply.Present {
+			failed++
+		}
+	}
+
+	return count > 1 && failed > 0
+}
+
+func getPowerInfo() (*PowerInfo, bool, error) {
+	supplies := getSysfsPowerSupplies()
+
+	var redundancyLost bool
+	if ipmiSupplies, lost, err := getIPMIPowerSupplies(); err == nil {
+		supplies = append(supplies, ipmiSupplies...)
+		redundancyLost = lost
+	}
+
+	if len(supplies) == 0 {
+		return nil, false, errors.New("No power supply found")
+	}
+
+	redundancyLost = redundancyLost || isPowerRedundancyLost(supplies)
+
+	return &PowerInfo{Supplies: supplies}, redundancyLost, nil
+}
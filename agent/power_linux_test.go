@@ -0,0 +1,141 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const ipmitoolPowerOutput = `PS1 Status       | 71h | ok  | 10.1 | Presence detected
+PS2 Status       | 72h | ok  | 10.2 | Presence detected, Failure detected
+PS3 Status       | 73h | ns  | 10.3 | No Reading
+PS Redundancy    | 77h | ok  |  7.1 | Redundancy Lost
+`
+
+func TestIPMIPowerSupplies(t *testing.T) {
+	supplies, redundancyLost := parseIPMIPowerSupplies([]byte(ipmitoolPowerOutput))
+
+	if len(supplies) != 2 {
+		t.Fatalf("Expected 2 power supplies, got: %d", len(supplies))
+	}
+
+	if !supplies[0].Present || supplies[0].Failed {
+		t.Fatalf("PS1 should be present and not failed: %+v", supplies[0])
+	}
+
+	if !supplies[1].Failed {
+		t.Fatalf("PS2 should be failed: %+v", supplies[1])
+	}
+
+	if !redundancyLost {
+		t.Fatal("Redundancy loss reported by the BMC not detected")
+	}
+
+	if !isPowerRedundancyLost(supplies) {
+		t.Fatal("Redundancy loss should be detected when one of the power supplies failed")
+	}
+
+	if isPowerRedundancyLost(supplies[:1]) {
+		t.Fatal("Redundancy can't be lost with a single power supply")
+	}
+}
+
+func TestSysfsPowerSupplies(t *testing.T) {
+	tests := []struct {
+		name           string
+		sysfs          map[string]map[string]string
+		expected       []*PowerSupplyInfo
+		redundancyLost bool
+	}{
+		{
+			name: "single PSU with a dead UPS",
+			sysfs: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "1"},
+				"ups0": {"type": "UPS", "health": "Dead", "capacity": "0"},
+			},
+			expected: []*PowerSupplyInfo{
+				{Name: "AC", Type: "Mains", Present: true, Online: true},
+				{Name: "ups0", Type: "UPS", Present: true, Online: true, Failed: true},
+			},
+		},
+		{
+			name: "unplugged laptop",
+			sysfs: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "0"},
+				"BAT0": {"type": "Battery", "status": "Discharging", "capacity": "80", "health": "Good"},
+				"usb":  {"type": "USB", "online": "1"},
+			},
+			expected: []*PowerSupplyInfo{
+				{Name: "AC", Type: "Mains", Present: true},
+				{Name: "BAT0", Type: "Battery", Present: true, Online: true, Status: "Discharging", Capacity: 80},
+			},
+		},
+		{
+			name: "redundant PSUs with one removed",
+			sysfs: map[string]map[string]string{
+				"PSU1": {"type": "Mains", "online": "1"},
+				"PSU2": {"type": "Mains", "present": "0"},
+			},
+			expected: []*PowerSupplyInfo{
+				{Name: "PSU1", Type: "Mains", Present: true, Online: true},
+				{Name: "PSU2", Type: "Mains", Online: true},
+			},
+			redundancyLost: true,
+		},
+	}
+
+	for _, test := range tests {
+		root, err := ioutil.TempDir("", "skydive-power")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(root)
+
+		for name, files := range test.sysfs {
+			dir := filepath.Join(root, name)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for file, content := range files {
+				if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(content+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		supplies := readSysfsPowerSupplies(root)
+		if !reflect.DeepEqual(supplies, test.expected) {
+			t.Errorf("%s: unexpected power supplies, expected: %+v, got: %+v", test.name, test.expected, supplies)
+		}
+
+		if lost := isPowerRedundancyLost(supplies); lost != test.redundancyLost {
+			t.Errorf("%s: expected redundancy lost %v, got %v", test.name, test.redundancyLost, lost)
+		}
+	}
+}
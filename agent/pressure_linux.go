@@ -0,0 +1,112 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pressureResources maps the files of /proc/pressure to the metadata keys
+var pressureResources = map[string]string{
+	"cpu":    "CPU",
+	"memory": "Memory",
+	"io":     "IO",
+}
+
+// parsePressure parses a /proc/pressure file
+func parsePressure(content []byte) (*PressureInfo, error) {
+	pressure := &PressureInfo{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		avg := &PressureAvg{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+
+			var dest *float64
+			switch kv[0] {
+			case "avg10":
+				dest = &avg.Avg10
+			case "avg60":
+				dest = &avg.Avg60
+			case "avg300":
+				dest = &avg.Avg300
+			default:
+				continue
+			}
+
+			value, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return nil, err
+			}
+			*dest = value
+		}
+
+		switch fields[0] {
+		case "some":
+			pressure.Some = avg
+		case "full":
+			pressure.Full = avg
+		}
+	}
+
+	if pressure.Some == nil {
+		return nil, errors.New("No pressure stall information found")
+	}
+
+	return pressure, nil
+}
+
+func getPressureInfo() (map[string]*PressureInfo, error) {
+	pressures := make(map[string]*PressureInfo)
+	for file, resource := range pressureResources {
+		content, err := ioutil.ReadFile(filepath.Join("/proc/pressure", file))
+		if err != nil {
+			return nil, err
+		}
+
+		pressure, err := parsePressure(content)
+		if err != nil {
+			return nil, err
+		}
+		pressures[resource] = pressure
+	}
+
+	return pressures, nil
+}
@@ -0,0 +1,66 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPressure(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected *PressureInfo
+	}{
+		{
+			content: "some avg10=1.53 avg60=0.87 avg300=0.32 total=125478\n",
+			expected: &PressureInfo{
+				Some: &PressureAvg{Avg10: 1.53, Avg60: 0.87, Avg300: 0.32},
+			},
+		},
+		{
+			content: "some avg10=0.00 avg60=0.10 avg300=0.05 total=15723\nfull avg10=0.00 avg60=0.02 avg300=0.01 total=8042\n",
+			expected: &PressureInfo{
+				Some: &PressureAvg{Avg10: 0, Avg60: 0.1, Avg300: 0.05},
+				Full: &PressureAvg{Avg10: 0, Avg60: 0.02, Avg300: 0.01},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		pressure, err := parsePressure([]byte(test.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(pressure, test.expected) {
+			t.Fatalf("Parsing of pressure failed, expected: %+v, got: %+v", test.expected, pressure)
+		}
+	}
+
+	if _, err := parsePressure([]byte("")); err == nil {
+		t.Fatal("Parsing of an empty pressure file should return an error")
+	}
+}
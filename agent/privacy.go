@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// metadataFilter removes or hashes the host metadata matching a list of
+// dotted paths. Each path element is a glob pattern matched against a key
+// or a list index, '**' matches any number of elements.
+type metadataFilter struct {
+	patterns [][]string
+	hash     bool
+}
+
+func newMetadataFilter(paths []string, hash bool) *metadataFilter {
+	f := &metadataFilter{hash: hash}
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			f.patterns = append(f.patterns, strings.Split(p, "."))
+		}
+	}
+	return f
+}
+
+// hashMetadataValue returns a stable hash of a value so that excluded values
+// can still be compared
+func hashMetadataValue(value interface{}) string {
+	data, _ := json.Marshal(value)
+	h := sha1.Sum(data)
+	return hex.EncodeToString(h[:])
+}
+
+// matchPathElement returns whether a path element matches the given key
+func matchPathElement(pattern, key string) bool {
+	matched, _ := path.Match(pattern, key)
+	return matched
+}
+
+// exclude applies the elements of a pattern to a value, it returns the new
+// value and false if the value itself has to be removed. Lists are traversed
+// transparently, the pattern being applied to each of their elements.
+func (f *metadataFilter) exclude(value interface{}, pattern []string) (interface{}, bool) {
+	if len(pattern) == 0 {
+		if f.hash {
+			return hashMetadataValue(value), true
+		}
+		return nil, false
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		var elements []interface{}
+		for _, element := range v {
+			if element, keep := f.exclude(element, pattern); keep {
+				elements = append(elements, element)
+			}
+		}
+		return elements, true
+	case map[string]interface{}:
+		if pattern[0] == "**" {
+			// '**' matching no element
+			excluded, keep := f.exclude(v, pattern[1:])
+			if !keep {
+				return nil, false
+			}
+			if _, ok := excluded.(map[string]interface{}); !ok {
+				return excluded, true
+			}
+		}
+
+		for key, child := range v {
+			var keep bool
+			switch {
+			case pattern[0] == "**":
+				child, keep = f.exclude(child, pattern)
+			case matchPathElement(pattern[0], key):
+				child, keep = f.exclude(child, pattern[1:])
+			default:
+				continue
+			}
+
+			if keep {
+				v[key] = child
+			} else {
+				delete(v, key)
+			}
+		}
+	}
+
+	return value, true
+}
+
+// filterField returns the filtered value of a metadata, false is returned if
+// the metadata has to be removed
+func (f *metadataFilter) filterField(key string, value interface{}) (interface{}, bool) {
+	var patterns [][]string
+	for _, pattern := range f.patterns {
+		if pattern[0] == "**" || matchPathElement(pattern[0], key) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	if len(patterns) == 0 {
+		return value, true
+	}
+
+	// work on a generic copy of the value as it may be a structure
+	// shared with the collectors
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	var generic interface{}
+	if err := common.JSONDecode(bytes.NewReader(data), &generic); err != nil {
+		return nil, false
+	}
+	generic = common.NormalizeValue(generic)
+
+	root := map[string]interface{}{key: generic}
+	for _, pattern := range patterns {
+		f.exclude(root, pattern)
+	}
+
+	generic, found := root[key]
+	return generic, found
+}
+
+// filterMetadata returns the metadata without the excluded fields
+func (f *metadataFilter) filterMetadata(m graph.Metadata) graph.Metadata {
+	if len(f.patterns) == 0 {
+		return m
+	}
+
+	filtered := graph.Metadata{}
+	for k, v := range m {
+		if v, keep := f.filterField(k, v); keep {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+// getMetadataFilter returns the filter defined by the agent.metadata.exclude
+// configuration, it is evaluated at each call to follow configuration reloads
+func getMetadataFilter() *metadataFilter {
+	return newMetadataFilter(config.GetStringSlice("agent.metadata.exclude"), config.GetBool("agent.metadata.hash_excluded"))
+}
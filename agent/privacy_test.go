@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+func newPrivacyTestMetadata() graph.Metadata {
+	return graph.Metadata{
+		"Name": "host-a",
+		"DMI": map[string]interface{}{
+			"ProductName":   "PowerEdge R640",
+			"ProductSerial": "4XK2M52",
+		},
+		"Hardware": map[string]interface{}{
+			"id":     "host-a",
+			"serial": "4XK2M52",
+			"children": []interface{}{
+				map[string]interface{}{"id": "core", "serial": ".4XK2M52.CNIVC00"},
+				map[string]interface{}{"id": "disk", "children": []interface{}{
+					map[string]interface{}{"id": "disk:0", "serial": "S3Z9NB0K"},
+				}},
+			},
+		},
+		"InstanceID": "i-0123456789",
+	}
+}
+
+func TestMetadataFilterExclude(t *testing.T) {
+	f := newMetadataFilter([]string{"DMI.ProductSerial", "Hardware.**.serial", "InstanceID"}, false)
+
+	expected := graph.Metadata{
+		"Name": "host-a",
+		"DMI": map[string]interface{}{
+			"ProductName": "PowerEdge R640",
+		},
+		"Hardware": map[string]interface{}{
+			"id": "host-a",
+			"children": []interface{}{
+				map[string]interface{}{"id": "core"},
+				map[string]interface{}{"id": "disk", "children": []interface{}{
+					map[string]interface{}{"id": "disk:0"},
+				}},
+			},
+		},
+	}
+
+	if m := f.filterMetadata(newPrivacyTestMetadata()); !reflect.DeepEqual(m, expected) {
+		t.Fatalf("Filtering of metadata failed, expected: %+v, got: %+v", expected, m)
+	}
+}
+
+func TestMetadataFilterGlob(t *testing.T) {
+	f := newMetadataFilter([]string{"Hardware.*.serial"}, false)
+
+	m := f.filterMetadata(newPrivacyTestMetadata())
+
+	hardware := m["Hardware"].(map[string]interface{})
+	if _, found := hardware["serial"]; !found {
+		t.Fatal("Serial of the hardware root should not be filtered by 'Hardware.*.serial'")
+	}
+
+	core := hardware["children"].([]interface{})[0].(map[string]interface{})
+	if _, found := core["serial"]; found {
+		t.Fatal("Serial of the hardware children should be filtered by 'Hardware.*.serial'")
+	}
+}
+
+func TestMetadataFilterHash(t *testing.T) {
+	f := newMetadataFilter([]string{"DMI.ProductSerial", "InstanceID"}, true)
+
+	m1 := f.filterMetadata(newPrivacyTestMetadata())
+	m2 := f.filterMetadata(newPrivacyTestMetadata())
+
+	serial := m1["DMI"].(map[string]interface{})["ProductSerial"]
+	if serial == "4XK2M52" || serial != m2["DMI"].(map[string]interface{})["ProductSerial"] {
+		t.Fatalf("Excluded value should be replaced by a stable hash, got: %v", serial)
+	}
+
+	if m1["InstanceID"] == "i-0123456789" || m1["InstanceID"] != hashMetadataValue("i-0123456789") {
+		t.Fatalf("Excluded value should be replaced by its hash, got: %v", m1["InstanceID"])
+	}
+}
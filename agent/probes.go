@@ -32,6 +32,7 @@ import (
 	"github.com/skydive-project/skydive/topology/graph"
 	"github.com/skydive-project/skydive/topology/probes/docker"
 	"github.com/skydive-project/skydive/topology/probes/lxd"
+	"github.com/skydive-project/skydive/topology/probes/mounts"
 	"github.com/skydive-project/skydive/topology/probes/netlink"
 	"github.com/skydive-project/skydive/topology/probes/netns"
 	"github.com/skydive-project/skydive/topology/probes/neutron"
@@ -99,6 +100,8 @@ func NewTopologyProbeBundleFromConfig(g *graph.Graph, n *graph.Node) (*probe.Pro
 			probes[t] = opencontrail
 		case "socketinfo":
 			probes[t] = socketinfo.NewSocketInfoProbe(g, n)
+		case "mounts":
+			probes[t] = mounts.NewMountsProbe(g, n)
 		default:
 			logging.GetLogger().Errorf("unknown probe type %s", t)
 		}
@@ -0,0 +1,174 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const smartctlTimeout = 30 * time.Second
+
+// smartctlOutput defines the subset of the 'smartctl --json' output used to
+// build the SMART summary of ATA, SCSI and NVMe devices
+type smartctlOutput struct {
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATASmartAttributes struct {
+		Table []struct {
+			ID    int64 `json:"id"`
+			Value int64 `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeSmartHealth *struct {
+		CriticalWarning int64 `json:"critical_warning"`
+		Temperature     int64 `json:"temperature"`
+		PercentageUsed  int64 `json:"percentage_used"`
+		PowerOnHours    int64 `json:"power_on_hours"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// ATA attributes used by the SMART summary
+const (
+	ataReallocatedSectors    = 5
+	ataWearLevelingCount     = 177
+	ataSSDLifeLeft           = 231
+	ataMediaWearoutIndicator = 233
+)
+
+// parseSmartctlOutput parses the JSON output of 'smartctl -H -A --json'
+func parseSmartctlOutput(out []byte) (*SMARTInfo, error) {
+	var output smartctlOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, err
+	}
+
+	if output.SmartStatus == nil {
+		return nil, errors.New("No SMART status reported")
+	}
+
+	smart := &SMARTInfo{
+		Healthy:      output.SmartStatus.Passed,
+		Temperature:  output.Temperature.Current,
+		PowerOnHours: output.PowerOnTime.Hours,
+	}
+
+	for _, attr := range output.ATASmartAttributes.Table {
+		switch attr.ID {
+		case ataReallocatedSectors:
+			smart.ReallocatedSectors = attr.Raw.Value
+		case ataWearLevelingCount, ataSSDLifeLeft, ataMediaWearoutIndicator:
+			// normalized values start at 100 for a new device
+			smart.MediaWearout = 100 - attr.Value
+		}
+	}
+
+	if nvme := output.NVMeSmartHealth; nvme != nil {
+		smart.Healthy = smart.Healthy && nvme.CriticalWarning == 0
+		smart.MediaWearout = nvme.PercentageUsed
+		if smart.Temperature == 0 {
+			smart.Temperature = nvme.Temperature
+		}
+		if smart.PowerOnHours == 0 {
+			smart.PowerOnHours = nvme.PowerOnHours
+		}
+	}
+
+	return smart, nil
+}
+
+func getSMARTInfo(path string, device string) (*SMARTInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), smartctlTimeout)
+	defer cancel()
+
+	// smartctl exit status is a bit mask also reporting the disk status, only
+	// the 2 lowest bits report a command failure
+	out, err := exec.CommandContext(ctx, path, "-H", "-A", "--json", device).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(interface{ ExitStatus() int }); ok && status.ExitStatus()&0x03 == 0 {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSmartctlOutput(out)
+}
+
+// getDisksInfo returns the SMART health of the physical disks, virtual block
+// devices like loop or device mapper don't have a device and are skipped
+func getDisksInfo() (map[string]*DiskInfo, error) {
+	path, err := exec.LookPath("smartctl")
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := ioutil.ReadDir("/sys/block")
+	if err != nil {
+		return nil, err
+	}
+
+	disks := make(map[string]*DiskInfo)
+	for _, device := range devices {
+		name := device.Name()
+		if _, err := os.Stat(filepath.Join("/sys/block", name, "device")); err != nil {
+			continue
+		}
+
+		smart, err := getSMARTInfo(path, filepath.Join("/dev", name))
+		if err != nil {
+			continue
+		}
+
+		disk := &DiskInfo{SMART: smart}
+		if !smart.Healthy {
+			disk.State = "failing"
+		}
+		disks[name] = disk
+	}
+
+	if len(disks) == 0 {
+		return nil, errors.New("No SMART capable disk found")
+	}
+
+	return disks, nil
+}
@@ -0,0 +1,77 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+const smartctlATAOutput = `{
+  "smart_status": {"passed": true},
+  "temperature": {"current": 34},
+  "power_on_time": {"hours": 12000},
+  "ata_smart_attributes": {
+    "table": [
+      {"id": 5, "name": "Reallocated_Sector_Ct", "value": 100, "raw": {"value": 8}},
+      {"id": 233, "name": "Media_Wearout_Indicator", "value": 97, "raw": {"value": 0}}
+    ]
+  }
+}`
+
+const smartctlNVMeOutput = `{
+  "smart_status": {"passed": true},
+  "nvme_smart_health_information_log": {
+    "critical_warning": 4,
+    "temperature": 41,
+    "percentage_used": 12,
+    "power_on_hours": 3500
+  }
+}`
+
+func TestSmartctlOutput(t *testing.T) {
+	expected := &SMARTInfo{Healthy: true, Temperature: 34, PowerOnHours: 12000, ReallocatedSectors: 8, MediaWearout: 3}
+
+	smart, err := parseSmartctlOutput([]byte(smartctlATAOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(smart, expected) {
+		t.Fatalf("Parsing of ATA smartctl output failed, expected: %+v, got: %+v", expected, smart)
+	}
+
+	expected = &SMARTInfo{Healthy: false, Temperature: 41, PowerOnHours: 3500, MediaWearout: 12}
+
+	if smart, err = parseSmartctlOutput([]byte(smartctlNVMeOutput)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(smart, expected) {
+		t.Fatalf("Parsing of NVMe smartctl output failed, expected: %+v, got: %+v", expected, smart)
+	}
+
+	if _, err = parseSmartctlOutput([]byte(`{"device": {}}`)); err == nil {
+		t.Fatal("Parsing of an output without SMART status should return an error")
+	}
+}
@@ -0,0 +1,77 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const sysfsCPURoot = "/sys/devices/system/cpu"
+
+func getSMTInfo() (*SMTInfo, error) {
+	active, err := readSysfsInt(filepath.Join(sysfsCPURoot, "smt", "active"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &SMTInfo{
+		Active:  active == 1,
+		Control: readSysfsString(filepath.Join(sysfsCPURoot, "smt", "control")),
+	}, nil
+}
+
+// readCoreSiblings returns the CPU threads of each physical core, keyed by
+// '<package id>-<core id>' as core ids are only unique within a package.
+// Offline CPUs don't expose their topology and are ignored.
+func readCoreSiblings(root string) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(root, "cpu[0-9]*", "topology"))
+	if err != nil {
+		return nil, err
+	}
+
+	siblings := make(map[string]string)
+	for _, path := range paths {
+		pkg, err := readSysfsInt(filepath.Join(path, "physical_package_id"))
+		if err != nil {
+			continue
+		}
+
+		core, err := readSysfsInt(filepath.Join(path, "core_id"))
+		if err != nil {
+			continue
+		}
+
+		if list := readSysfsString(filepath.Join(path, "thread_siblings_list")); list != "" {
+			siblings[fmt.Sprintf("%d-%d", pkg, core)] = list
+		}
+	}
+
+	return siblings, nil
+}
+
+func getCoreSiblings() (map[string]string, error) {
+	return readCoreSiblings(sysfsCPURoot)
+}
@@ -0,0 +1,80 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCoreSiblings(t *testing.T) {
+	root, err := ioutil.TempDir("", "skydive-cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// 2 packages of 2 cores with 2 threads, cpu7 is offline
+	topology := []struct {
+		pkg, core int
+		siblings  string
+	}{
+		{0, 0, "0,4"}, {0, 1, "1,5"}, {1, 0, "2,6"}, {1, 1, "3,7"},
+		{0, 0, "0,4"}, {0, 1, "1,5"}, {1, 0, "2,6"},
+	}
+
+	for cpu, cpuTopology := range topology {
+		dir := filepath.Join(root, fmt.Sprintf("cpu%d", cpu), "topology")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		files := map[string]string{
+			"physical_package_id":  fmt.Sprintf("%d\n", cpuTopology.pkg),
+			"core_id":              fmt.Sprintf("%d\n", cpuTopology.core),
+			"thread_siblings_list": cpuTopology.siblings + "\n",
+		}
+		for name, content := range files {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	expected := map[string]string{"0-0": "0,4", "0-1": "1,5", "1-0": "2,6", "1-1": "3,7"}
+
+	siblings, err := readCoreSiblings(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(siblings, expected) {
+		t.Fatalf("Reading of core siblings failed, expected: %+v, got: %+v", expected, siblings)
+	}
+}
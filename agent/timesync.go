@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const timeSyncTimeout = 5 * time.Second
+
+// parseChronyTracking parses the output of 'chronyc tracking'
+func parseChronyTracking(out []byte) *TimeSyncInfo {
+	timeSync := &TimeSyncInfo{Synchronized: "unknown"}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+
+		switch strings.TrimSpace(kv[0]) {
+		case "Reference ID":
+			// A9FEA97B (169.254.169.123)
+			if start, end := strings.Index(value, "("), strings.LastIndex(value, ")"); start != -1 && end > start {
+				timeSync.Source = value[start+1 : end]
+			}
+		case "Stratum":
+			timeSync.Stratum, _ = strconv.ParseInt(value, 10, 64)
+		case "System time":
+			// 0.000012345 seconds fast of NTP time
+			fields := strings.Fields(value)
+			if len(fields) < 3 {
+				continue
+			}
+			if offset, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				timeSync.OffsetMs = offset * 1000
+				if fields[2] == "slow" {
+					timeSync.OffsetMs = -timeSync.OffsetMs
+				}
+			}
+		case "Leap status":
+			if value == "Not synchronised" {
+				timeSync.Synchronized = "false"
+			} else {
+				timeSync.Synchronized = "true"
+			}
+		}
+	}
+
+	return timeSync
+}
+
+// parseTimedatectlShow parses the output of 'timedatectl show'
+func parseTimedatectlShow(out []byte) *TimeSyncInfo {
+	timeSync := &TimeSyncInfo{Synchronized: "unknown"}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 || kv[0] != "NTPSynchronized" {
+			continue
+		}
+
+		if kv[1] == "yes" {
+			timeSync.Synchronized = "true"
+		} else {
+			timeSync.Synchronized = "false"
+		}
+	}
+
+	return timeSync
+}
+
+func runTimeSyncCommand(name string, args ...string) ([]byte, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeSyncTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, path, args...).Output()
+}
+
+// getTimeSyncInfo returns the time synchronization status using chrony or
+// systemd-timesyncd, the status is unknown if none of them is available
+func getTimeSyncInfo() *TimeSyncInfo {
+	if out, err := runTimeSyncCommand("chronyc", "tracking"); err == nil {
+		return parseChronyTracking(out)
+	}
+
+	if out, err := runTimeSyncCommand("timedatectl", "show"); err == nil {
+		return parseTimedatectlShow(out)
+	}
+
+	return &TimeSyncInfo{Synchronized: "unknown"}
+}
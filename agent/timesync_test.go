@@ -0,0 +1,49 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+const chronyTrackingOutput = `Reference ID    : A9FEA97B (169.254.169.123)
+Stratum         : 4
+Ref time (UTC)  : Mon Oct 15 10:20:30 2018
+System time     : 0.001500000 seconds slow of NTP time
+Last offset     : +0.000001234 seconds
+RMS offset      : 0.000012345 seconds
+Leap status     : Normal
+`
+
+func TestChronyTracking(t *testing.T) {
+	expected := &TimeSyncInfo{Synchronized: "true", Source: "169.254.169.123", OffsetMs: -1.5, Stratum: 4}
+
+	if timeSync := parseChronyTracking([]byte(chronyTrackingOutput)); !reflect.DeepEqual(timeSync, expected) {
+		t.Fatalf("Parsing of chronyc tracking failed, expected: %+v, got: %+v", expected, timeSync)
+	}
+
+	if timeSync := parseTimedatectlShow([]byte("NTP=yes\nNTPSynchronized=no\n")); timeSync.Synchronized != "false" {
+		t.Fatalf("Parsing of timedatectl show failed, got: %+v", timeSync)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	tunedActiveProfile = "/etc/tuned/active_profile"
+	tunedAdmTimeout    = 30 * time.Second
+)
+
+// tunedVerification holds the result of the last verification of a profile
+type tunedVerification struct {
+	profile  string
+	verified bool
+	time     time.Time
+}
+
+// parseTunedAdmActive parses the output of 'tuned-adm active'
+func parseTunedAdmActive(out []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Current active profile:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Current active profile:"))
+		}
+	}
+	return ""
+}
+
+// getTunedProfile returns the active tuned profile, several profiles may be
+// merged in which case they are separated by spaces
+func getTunedProfile() (string, error) {
+	if content, err := ioutil.ReadFile(tunedActiveProfile); err == nil {
+		if profile := strings.TrimSpace(string(content)); profile != "" {
+			return profile, nil
+		}
+	}
+
+	path, err := exec.LookPath("tuned-adm")
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tunedAdmTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "active").Output()
+	if err != nil {
+		return "", err
+	}
+
+	if profile := parseTunedAdmActive(out); profile != "" {
+		return profile, nil
+	}
+	return "", errors.New("No active tuned profile")
+}
+
+// verifyTunedProfile returns whether the settings of the active profile are
+// applied, 'tuned-adm verify' exits with a non zero code otherwise
+func verifyTunedProfile() (bool, error) {
+	path, err := exec.LookPath("tuned-adm")
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tunedAdmTimeout)
+	defer cancel()
+
+	if err := exec.CommandContext(ctx, path, "verify").Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
@@ -55,8 +55,19 @@ var AgentCmd = &cobra.Command{
 
 		logging.GetLogger().Notice("Skydive Agent started")
 		ch := make(chan os.Signal)
-		signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
-		<-ch
+		signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		for sig := range ch {
+			if sig != syscall.SIGHUP {
+				break
+			}
+
+			logging.GetLogger().Info("Reloading configuration")
+			if err := config.ReloadConfig(); err != nil {
+				logging.GetLogger().Errorf("Unable to reload configuration: %s", err)
+				continue
+			}
+			agent.ReloadMetadata()
+		}
 
 		agent.Stop()
 
@@ -48,6 +48,8 @@ var (
 	ErrFieldNotFound = errors.New("Field not found")
 	// ErrFieldWrongType error field has wrong type
 	ErrFieldWrongType = errors.New("Field has wrong type")
+	// ErrFieldNotMap error an intermediate field of a path is not a map
+	ErrFieldNotMap = errors.New("Field is not a map")
 	// ErrNotFound error no result was found
 	ErrNotFound = errors.New("No result found")
 	// ErrTimeout network timeout
@@ -82,6 +84,14 @@ func ToInt64(i interface{}) (int64, error) {
 		return int64(v), nil
 	case uint:
 		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
 	case int32:
 		return int64(v), nil
 	case uint32:
@@ -130,7 +140,7 @@ func ToFloat64(f interface{}) (float64, error) {
 		}
 	case string:
 		return strconv.ParseFloat(v, 64)
-	case int, uint, int32, uint32, int64, uint64:
+	case int, uint, int8, uint8, int16, uint16, int32, uint32, int64, uint64:
 		i, err := ToInt64(f)
 		if err != nil {
 			return 0, err
@@ -144,6 +154,45 @@ func ToFloat64(f interface{}) (float64, error) {
 	return 0, fmt.Errorf("not a float: %v", f)
 }
 
+// ToBool converts booleans, their string representations and numbers to bool
+func ToBool(b interface{}) (bool, error) {
+	switch v := b.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	case json.Number, int, uint, int8, uint8, int16, uint16, int32, uint32, int64, uint64, float32, float64:
+		i, err := ToFloat64(b)
+		if err != nil {
+			return false, err
+		}
+		return i != 0, nil
+	}
+	return false, fmt.Errorf("not a boolean: %v", b)
+}
+
+// ToStringList converts a list of strings, typed or not, to []string
+func ToStringList(l interface{}) ([]string, error) {
+	if l, ok := l.([]string); ok {
+		return l, nil
+	}
+
+	list, ok := toList(l)
+	if !ok {
+		return nil, ErrFieldWrongType
+	}
+
+	sl := make([]string, len(list))
+	for i, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, ErrFieldWrongType
+		}
+		sl[i] = s
+	}
+	return sl, nil
+}
+
 func floatCompare(a interface{}, b interface{}) (int, error) {
 	f1, err := ToFloat64(a)
 	if err != nil {
@@ -312,27 +361,40 @@ type Metric interface {
 	GetFields() []string
 }
 
-// SetField set a value in a tree based on dot key ("a.b.c.d" = "ok")
-func SetField(obj map[string]interface{}, k string, v interface{}) bool {
+// SetField set a value in a tree based on dot key ("a.b.c.d" = "ok"). The missing
+// intermediate maps are created, ErrFieldNotMap is returned if an intermediate
+// value is not a map, in which case the tree is left untouched.
+func SetField(obj map[string]interface{}, k string, v interface{}) error {
 	components := strings.Split(k, ".")
-	for n, component := range components {
-		if n == len(components)-1 {
-			obj[component] = v
-		} else {
-			m, ok := obj[component]
-			if !ok {
-				m := make(map[string]interface{})
-				obj[component] = m
-				obj = m
-			} else if obj, ok = m.(map[string]interface{}); !ok {
-				return false
-			}
+	last := len(components) - 1
+
+	// check the existing intermediate values before creating anything
+	parent := obj
+	for _, component := range components[:last] {
+		value, ok := parent[component]
+		if !ok {
+			break
+		}
+		if parent, ok = value.(map[string]interface{}); !ok {
+			return ErrFieldNotMap
 		}
 	}
-	return true
+
+	for _, component := range components[:last] {
+		m, ok := obj[component].(map[string]interface{})
+		if !ok {
+			m = make(map[string]interface{})
+			obj[component] = m
+		}
+		obj = m
+	}
+	obj[components[last]] = v
+
+	return nil
 }
 
-// DelField detete a value in a tree based on dot key
+// DelField detete a value in a tree based on dot key, the parents left empty
+// are removed as well
 func DelField(obj map[string]interface{}, k string) bool {
 	components := strings.Split(k, ".")
 	o, ok := obj[components[0]]
@@ -357,38 +419,70 @@ func DelField(obj map[string]interface{}, k string) bool {
 	return removed
 }
 
-// GetField retrieves a value from a tree from the dot key like "a.b.c.d"
+// GetField retrieves a value from a tree from the dot key like "a.b.c.d".
+// A numeric component indexes a list, "a.0.b", while a "*" component
+// returns the list of the values of all its elements, "a.*.b". A non
+// numeric component applied on a list of maps has the same effect.
 func GetField(obj map[string]interface{}, k string) (interface{}, error) {
-	components := strings.Split(k, ".")
-	for n, component := range components {
-		i, ok := obj[component]
+	return getField(obj, strings.Split(k, "."), "")
+}
+
+func toList(i interface{}) ([]interface{}, bool) {
+	if list, ok := i.([]interface{}); ok {
+		return list, true
+	}
+
+	value := reflect.ValueOf(i)
+	if value.Kind() != reflect.Slice {
+		return nil, false
+	}
+
+	list := make([]interface{}, value.Len())
+	for n := range list {
+		list[n] = value.Index(n).Interface()
+	}
+	return list, true
+}
+
+func getField(obj interface{}, components []string, path string) (interface{}, error) {
+	if len(components) == 0 {
+		return obj, nil
+	}
+	component := components[0]
+
+	if m, ok := obj.(map[string]interface{}); ok {
+		i, ok := m[component]
 		if !ok {
 			return nil, ErrFieldNotFound
 		}
+		return getField(i, components[1:], path+"."+component)
+	}
 
-		if n == len(components)-1 {
-			return i, nil
-		}
+	list, ok := toList(obj)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a map, but a %+v", strings.TrimPrefix(path, "."), reflect.TypeOf(obj))
+	}
 
-		if list, ok := i.([]interface{}); ok {
-			var results []interface{}
-			for _, v := range list {
-				switch v := v.(type) {
-				case map[string]interface{}:
-					if obj, err := GetField(v, strings.Join(components[n+1:], ".")); err == nil {
-						results = append(results, obj)
-					}
-				}
-			}
-			return results, nil
+	if index, err := strconv.Atoi(component); err == nil {
+		if index < 0 || index >= len(list) {
+			return nil, ErrFieldNotFound
 		}
+		return getField(list[index], components[1:], path+"."+component)
+	}
 
-		if obj, ok = i.(map[string]interface{}); !ok {
-			return nil, fmt.Errorf("%s is not a map, but a %+v", component, reflect.TypeOf(obj))
+	var results []interface{}
+	for _, v := range list {
+		if component == "*" {
+			if obj, err := getField(v, components[1:], path+"."+component); err == nil {
+				results = append(results, obj)
+			}
+		} else if _, ok := v.(map[string]interface{}); ok {
+			if obj, err := getField(v, components, path); err == nil {
+				results = append(results, obj)
+			}
 		}
 	}
-
-	return obj, nil
+	return results, nil
 }
 
 func getFields(obj map[string]interface{}, path string) ([]string, error) {
@@ -153,16 +153,20 @@ func TestSetField(t *testing.T) {
 		},
 	}
 
-	if SetField(actual, "a.b.c", true) {
-		t.Errorf("Expected SetField to not overwrite any key")
+	if err := SetField(actual, "a.b.c", true); err != ErrFieldNotMap {
+		t.Errorf("Expected SetField to not overwrite any key, got %v", err)
 	}
 
-	if !SetField(actual, "a.b", false) {
-		t.Errorf("Expected SetField to overwrite a.b")
+	if err := SetField(actual, "a.b.c.d.e", true); err != ErrFieldNotMap {
+		t.Errorf("Expected SetField to not overwrite any key, got %v", err)
 	}
 
-	if !SetField(actual, "a.d.c", true) {
-		t.Errorf("Expected SetField to create a.d.c key")
+	if err := SetField(actual, "a.b", false); err != nil {
+		t.Errorf("Expected SetField to overwrite a.b: %s", err)
+	}
+
+	if err := SetField(actual, "a.d.c", true); err != nil {
+		t.Errorf("Expected SetField to create a.d.c key: %s", err)
 	}
 
 	if !reflect.DeepEqual(expected, actual) {
@@ -170,6 +174,50 @@ func TestSetField(t *testing.T) {
 	}
 }
 
+func TestSetFieldDeepPath(t *testing.T) {
+	actual := map[string]interface{}{}
+
+	if err := SetField(actual, "Software.Ceph.OSD.metadata", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetField(actual, "Software.Ceph.OSD.ID", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"Software": map[string]interface{}{
+			"Ceph": map[string]interface{}{
+				"OSD": map[string]interface{}{
+					"metadata": "value",
+					"ID":       1,
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %+v actual %+v", expected, actual)
+	}
+
+	// a conflict deeper in the path must not create the missing levels
+	if err := SetField(actual, "Software.Ceph.OSD.ID.Sub.Key", 2); err != ErrFieldNotMap {
+		t.Errorf("Expected a conflict on Software.Ceph.OSD.ID, got %v", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %+v actual %+v", expected, actual)
+	}
+
+	if !DelField(actual, "Software.Ceph.OSD.metadata") || !DelField(actual, "Software.Ceph.OSD.ID") {
+		t.Error("Expected DelField to remove the OSD keys")
+	}
+
+	if len(actual) != 0 {
+		t.Errorf("Expected the empty parents to be removed, got %+v", actual)
+	}
+}
+
 func TestDelField(t *testing.T) {
 	actual := map[string]interface{}{
 		"a": map[string]interface{}{
@@ -205,3 +253,44 @@ func TestDelField(t *testing.T) {
 		t.Errorf("Expected %+v actual %+v", expected, actual)
 	}
 }
+
+func TestGetField(t *testing.T) {
+	obj := map[string]interface{}{
+		"CPU": []interface{}{
+			map[string]interface{}{"ModelName": "Xeon", "Cores": int64(4)},
+			map[string]interface{}{"ModelName": "Opteron"},
+		},
+		"Tags":  []string{"a", "b"},
+		"Index": map[string]interface{}{"0": "zero"},
+		"Name":  "node1",
+	}
+
+	tests := []struct {
+		key      string
+		expected interface{}
+	}{
+		{"CPU.0.ModelName", "Xeon"},
+		{"CPU.1.ModelName", "Opteron"},
+		{"CPU.*.ModelName", []interface{}{"Xeon", "Opteron"}},
+		{"CPU.ModelName", []interface{}{"Xeon", "Opteron"}},
+		{"CPU.*.Cores", []interface{}{int64(4)}},
+		{"Tags.1", "b"},
+		{"Tags.*", []interface{}{"a", "b"}},
+		{"Index.0", "zero"},
+	}
+
+	for _, test := range tests {
+		actual, err := GetField(obj, test.key)
+		if err != nil {
+			t.Errorf("Expected %s to be found, got: %s", test.key, err)
+		} else if !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("Expected %s to be %+v, actual %+v", test.key, test.expected, actual)
+		}
+	}
+
+	for _, key := range []string{"CPU.2.ModelName", "CPU.0.Unknown", "Tags.-1", "Name.0", "Unknown"} {
+		if value, err := GetField(obj, key); err == nil {
+			t.Errorf("Expected %s to not be found, got: %+v", key, value)
+		}
+	}
+}
@@ -23,17 +23,23 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	// Viper remote client need to be internally initialized
 	_ "github.com/spf13/viper/remote"
+	"gopkg.in/yaml.v2"
 
 	"github.com/skydive-project/skydive/common"
 )
@@ -43,6 +49,24 @@ var ErrNoAnalyzerSpecified = errors.New("No analyzer specified in the configurat
 
 var (
 	cfg           *viper.Viper
+	cfgBackend    string
+	cfgPaths      []string
+	defaultValues = make(map[string]interface{})
+	// sections accepted at the top level of the configuration in addition
+	// to the ones having default values
+	extraSections = []string{"analyzers", "dpdk", "graph", "openstack"}
+	// keys read with GetDuration, given either as a Go duration or as a
+	// number of seconds
+	durationKeys = []string{
+		"agent.capture.stats_update",
+		"agent.host_update",
+		"agent.metadata.lshw_timeout",
+		"agent.pressure.update",
+		"agent.smart.update",
+		"agent.topology.mounts.update",
+		"agent.topology.netlink.metrics_update",
+		"agent.topology.socketinfo.host_update",
+	}
 	relocationMap = map[string][]string{
 		"agent.auth.api.backend":         {"auth.type"},
 		"agent.auth.cluster.password":    {"auth.analyzer_password"},
@@ -71,21 +95,41 @@ func init() {
 	cfg = viper.New()
 
 	cfg.SetDefault("agent.auth.api.backend", "noauth")
-	cfg.SetDefault("agent.capture.stats_update", 1)
+	cfg.SetDefault("agent.bmc.enabled", true)
+	cfg.SetDefault("agent.bmc.channels", []string{"1"})
+	cfg.SetDefault("agent.capture.stats_update", "1s")
+	cfg.SetDefault("agent.cloud_init.keys", []string{"ds", "v1.cloud_name", "v1.region", "v1.local_hostname"})
+	cfg.SetDefault("agent.cpu_flags", []string{"vmx", "svm", "avx2", "avx512f", "aes", "sse4_2", "hypervisor"})
 	cfg.SetDefault("agent.flow.probes", []string{"gopacket", "pcapsocket"})
 	cfg.SetDefault("agent.flow.pcapsocket.bind_address", "127.0.0.1")
 	cfg.SetDefault("agent.flow.pcapsocket.min_port", 8100)
 	cfg.SetDefault("agent.flow.pcapsocket.max_port", 8132)
+	cfg.SetDefault("agent.host_update", "30s")
 	cfg.SetDefault("agent.listen", "127.0.0.1:8081")
+	cfg.SetDefault("agent.lldp.switch_nodes", false)
+	cfg.SetDefault("agent.metadata.cpu_detail", "socket")
+	cfg.SetDefault("agent.metadata.exclude", []string{})
+	cfg.SetDefault("agent.metadata.hash_excluded", false)
+	cfg.SetDefault("agent.metadata.kernel_modules", []string{"openvswitch", "vfio_pci", "kvm", "kvm_intel", "kvm_amd", "vhost_net", "tun", "bridge", "br_netfilter", "bonding", "8021q", "vxlan", "geneve", "ip_gre", "nf_conntrack", "sctp"})
+	cfg.SetDefault("agent.metadata.lshw_timeout", "60s")
+	cfg.SetDefault("agent.metadata.sysctls", []string{"net.ipv4.ip_forward", "net.bridge.bridge-nf-call-iptables", "net.core.rmem_max", "net.core.wmem_max", "fs.file-max"})
+	cfg.SetDefault("agent.pressure.update", "60s")
+	cfg.SetDefault("agent.smart.update", "1h")
+	cfg.SetDefault("agent.swap.max_used", 50)
+	cfg.SetDefault("agent.time_sync.max_offset", 100)
 	cfg.SetDefault("agent.topology.probes", []string{"ovsdb"})
-	cfg.SetDefault("agent.topology.netlink.metrics_update", 30)
+	cfg.SetDefault("agent.topology.mounts.bind_mounts", false)
+	cfg.SetDefault("agent.topology.mounts.exclude_fstypes", []string{"tmpfs", "devtmpfs", "proc", "sysfs", "cgroup", "cgroup2", "devpts", "mqueue", "debugfs", "tracefs", "securityfs", "pstore", "bpf", "configfs", "fusectl", "hugetlbfs", "autofs", "binfmt_misc", "rpc_pipefs", "nsfs", "overlay", "squashfs"})
+	cfg.SetDefault("agent.topology.mounts.update", "30s")
+	cfg.SetDefault("agent.topology.netlink.metrics_update", "30s")
 	cfg.SetDefault("agent.topology.neutron.domain_name", "Default")
 	cfg.SetDefault("agent.topology.neutron.endpoint_type", "public")
 	cfg.SetDefault("agent.topology.neutron.ssl_insecure", false)
 	cfg.SetDefault("agent.topology.neutron.region_name", "RegionOne")
 	cfg.SetDefault("agent.topology.neutron.tenant_name", "service")
 	cfg.SetDefault("agent.topology.neutron.username", "neutron")
-	cfg.SetDefault("agent.topology.socketinfo.host_update", 10)
+	cfg.SetDefault("agent.topology.socketinfo.host_update", "10s")
+	cfg.SetDefault("agent.tuned.verify", false)
 	cfg.SetDefault("agent.X509_servername", "")
 
 	cfg.SetDefault("analyzer.auth.cluster.backend", "noauth")
@@ -125,6 +169,8 @@ func init() {
 	cfg.SetDefault("http.ws.pong_timeout", 5)
 	cfg.SetDefault("http.ws.queue_size", 10000)
 	cfg.SetDefault("http.ws.enable_write_compression", true)
+	cfg.SetDefault("http.ws.enable_compression", false)
+	cfg.SetDefault("http.ws.compression_level", 1)
 
 	cfg.SetDefault("k8s.config_file", "/etc/skydive/kubeconfig")
 
@@ -169,6 +215,10 @@ func init() {
 
 	cfg.SetDefault("ui", map[string]interface{}{})
 
+	for _, key := range cfg.AllKeys() {
+		defaultValues[key] = cfg.Get(key)
+	}
+
 	replacer := strings.NewReplacer(".", "_", "-", "_")
 	cfg.SetEnvPrefix("SKYDIVE")
 	cfg.SetEnvKeyReplacer(replacer)
@@ -176,44 +226,62 @@ func init() {
 	cfg.SetTypeByDefaultValue(true)
 }
 
-func checkStrictPositiveInt(key string) error {
-	if value := cfg.GetInt(key); value <= 0 {
+func checkStrictPositiveInt(v *viper.Viper, key string) error {
+	if value := v.GetInt(key); value <= 0 {
 		return fmt.Errorf("invalid value for %s (%d)", key, value)
 	}
 
 	return nil
 }
 
-func checkPositiveInt(key string) error {
-	if value := cfg.GetInt(key); value < 0 {
+func checkPositiveInt(v *viper.Viper, key string) error {
+	if value := v.GetInt(key); value < 0 {
 		return fmt.Errorf("invalid value for %s (%d)", key, value)
 	}
 
 	return nil
 }
 
-func checkStrictRangeFloat(key string, min, max float64) error {
-	if value := cfg.GetFloat64(key); value <= min || value > max {
+func checkStrictRangeFloat(v *viper.Viper, key string, min, max float64) error {
+	if value := v.GetFloat64(key); value <= min || value > max {
 		return fmt.Errorf("invalid value for %s (%f)", key, value)
 	}
 
 	return nil
 }
 
-func checkConfig() error {
-	if err := checkStrictPositiveInt("flow.expire"); err != nil {
+func checkStrictPositiveDuration(v *viper.Viper, key string) error {
+	d, err := parseDuration(v.Get(realViperKey(v, key)))
+	if err != nil {
+		return fmt.Errorf("invalid duration for %s: %s", key, err)
+	}
+	if d <= 0 {
+		return fmt.Errorf("invalid value for %s (%s)", key, d)
+	}
+
+	return nil
+}
+
+func checkConfig(v *viper.Viper) error {
+	if err := checkStrictPositiveInt(v, "flow.expire"); err != nil {
 		return err
 	}
 
-	if err := checkStrictPositiveInt("flow.update"); err != nil {
+	for _, key := range durationKeys {
+		if err := checkStrictPositiveDuration(v, key); err != nil {
+			return err
+		}
+	}
+
+	if err := checkStrictPositiveInt(v, "flow.update"); err != nil {
 		return err
 	}
 
-	if err := checkPositiveInt("etcd.max_wal_files"); err != nil {
+	if err := checkPositiveInt(v, "etcd.max_wal_files"); err != nil {
 		return err
 	}
 
-	return checkPositiveInt("etcd.max_snap_files")
+	return checkPositiveInt(v, "etcd.max_snap_files")
 }
 
 func checkViperSupportedExts(ext string) bool {
@@ -225,19 +293,19 @@ func checkViperSupportedExts(ext string) bool {
 	return false
 }
 
-func setStorageDefaults() {
-	for key := range cfg.GetStringMap("storage") {
+func setStorageDefaults(v *viper.Viper) {
+	for key := range v.GetStringMap("storage") {
 		if key == "elasticsearch" || key == "orientdb" || key == "memory" {
 			continue
 		}
 
-		driver := cfg.GetString("storage." + key + ".driver")
+		driver := v.GetString("storage." + key + ".driver")
 		if driver == "" {
 			continue
 		}
 
-		for defkey, value := range cfg.GetStringMap("storage." + driver) {
-			cfg.SetDefault("storage."+key+"."+defkey, value)
+		for defkey, value := range v.GetStringMap("storage." + driver) {
+			v.SetDefault("storage."+key+"."+defkey, value)
 		}
 	}
 }
@@ -250,14 +318,218 @@ func InitConfig(backend string, paths []string) error {
 
 	cfg.SetConfigType("yaml")
 
+	if err := readConfig(cfg, backend, paths); err != nil {
+		return err
+	}
+
+	cfgBackend, cfgPaths = backend, paths
+
+	setStorageDefaults(cfg)
+
+	return checkConfig(cfg)
+}
+
+// valueKind returns the kind of a configuration value as read from YAML
+func valueKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}, []string:
+		return "list"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// compatibleKinds returns whether a value of the given kind can be used
+// for a key whose default value has the expected kind
+func compatibleKinds(expected string, kind string, value interface{}) bool {
+	if expected == kind || expected == "" || kind == "" || expected == "string" {
+		return true
+	}
+
+	// scalar values given as strings are converted when read, lists are
+	// split on spaces
+	if s, ok := value.(string); ok {
+		switch expected {
+		case "list":
+			return true
+		case "boolean":
+			_, err := strconv.ParseBool(s)
+			return err == nil
+		case "number":
+			_, err := strconv.ParseFloat(s, 64)
+			return err == nil
+		}
+	}
+
+	return false
+}
+
+// parseDuration converts a configuration value to a duration, bare numbers
+// are interpreted as seconds
+func parseDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case int64:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+		return time.ParseDuration(v)
+	}
+	return 0, fmt.Errorf("expected a duration or a number of seconds, got %v", value)
+}
+
+func isDurationKey(key string) bool {
+	for _, k := range durationKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// scalarParent returns the parent of a key having a default value that
+// is not a map
+func scalarParent(key string) string {
+	for i := strings.LastIndex(key, "."); i > 0; i = strings.LastIndex(key[:i], ".") {
+		if def, ok := defaultValues[key[:i]]; ok && valueKind(def) != "map" {
+			return key[:i]
+		}
+	}
+	return ""
+}
+
+// ValidateConfig reads a YAML configuration without applying it and reports
+// the unknown top level sections and the values whose type doesn't match
+// the one of the default value
+func ValidateConfig(r io.Reader) error {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(r); err != nil {
+		return err
+	}
+
+	sections := make(map[string]bool)
+	for _, section := range extraSections {
+		sections[section] = true
+	}
+	for key := range defaultValues {
+		sections[strings.SplitN(key, ".", 2)[0]] = true
+	}
+
+	var errs []string
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		if section := strings.SplitN(key, ".", 2)[0]; !sections[section] {
+			errs = append(errs, fmt.Sprintf("unknown key %s", key))
+			continue
+		}
+
+		// a sub key of a key expecting a scalar or a list
+		if parent := scalarParent(key); parent != "" {
+			errs = append(errs, fmt.Sprintf("invalid key %s, %s expects a %s", key, parent, valueKind(defaultValues[parent])))
+			continue
+		}
+
+		def, ok := defaultValues[key]
+		if !ok {
+			continue
+		}
+
+		value := v.Get(key)
+		if isDurationKey(key) {
+			if _, err := parseDuration(value); err != nil {
+				errs = append(errs, fmt.Sprintf("invalid duration for %s: %s", key, err))
+			}
+			continue
+		}
+
+		if expected, kind := valueKind(def), valueKind(value); !compatibleKinds(expected, kind, value) {
+			errs = append(errs, fmt.Sprintf("invalid value for %s, expected a %s, got a %s (%v)", key, expected, kind, value))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ReloadConfig reads again the configuration used by InitConfig, the
+// previous values are replaced so that removed keys are not kept. The
+// current configuration is left untouched if the new one is invalid.
+func ReloadConfig() error {
+	if cfgBackend == "" {
+		return errors.New("Configuration not initialized")
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := readConfig(v, cfgBackend, cfgPaths); err != nil {
+		return err
+	}
+
+	settings, err := yaml.Marshal(v.AllSettings())
+	if err != nil {
+		return err
+	}
+
+	// check the new values on top of the default ones before applying them
+	candidate := viper.New()
+	candidate.SetConfigType("yaml")
+	for key, value := range defaultValues {
+		candidate.SetDefault(key, value)
+	}
+	candidate.SetTypeByDefaultValue(true)
+	if err := candidate.ReadConfig(bytes.NewReader(settings)); err != nil {
+		return err
+	}
+	setStorageDefaults(candidate)
+
+	if err := checkConfig(candidate); err != nil {
+		return err
+	}
+
+	if err := cfg.ReadConfig(bytes.NewReader(settings)); err != nil {
+		return err
+	}
+	setStorageDefaults(cfg)
+
+	return nil
+}
+
+func readConfig(v *viper.Viper, backend string, paths []string) error {
 	switch backend {
 	case "file":
-		for _, path := range paths {
+		for i, path := range paths {
 			configFile, err := os.Open(path)
 			if err != nil {
 				return err
 			}
-			if err := cfg.MergeConfig(configFile); err != nil {
+
+			// the first file replaces the current configuration, the others are merged
+			if i == 0 {
+				err = v.ReadConfig(configFile)
+			} else {
+				err = v.MergeConfig(configFile)
+			}
+			configFile.Close()
+
+			if err != nil {
 				return err
 			}
 		}
@@ -270,19 +542,17 @@ func InitConfig(backend string, paths []string) error {
 		if err != nil {
 			return err
 		}
-		if err := cfg.AddRemoteProvider("etcd", fmt.Sprintf("%s://%s", u.Scheme, u.Host), u.Path); err != nil {
+		if err := v.AddRemoteProvider("etcd", fmt.Sprintf("%s://%s", u.Scheme, u.Host), u.Path); err != nil {
 			return err
 		}
-		if err := cfg.ReadRemoteConfig(); err != nil {
+		if err := v.ReadRemoteConfig(); err != nil {
 			return err
 		}
 	default:
 		return fmt.Errorf("Invalid backend: %s", backend)
 	}
 
-	setStorageDefaults()
-
-	return checkConfig()
+	return nil
 }
 
 // GetConfig get current config
@@ -361,7 +631,13 @@ func IsTLSenabled() bool {
 }
 
 func realKey(key string) string {
-	if cfg.IsSet(key) {
+	return realViperKey(cfg, key)
+}
+
+// realViperKey returns the key holding the value of a key in v, which is
+// either the key itself or one of its deprecated names
+func realViperKey(v *viper.Viper, key string) string {
+	if v.IsSet(key) {
 		return key
 	}
 
@@ -371,7 +647,7 @@ func realKey(key string) string {
 		return key
 	}
 	for _, depKey := range depKeys {
-		if cfg.IsSet(depKey) {
+		if v.IsSet(depKey) {
 			fmt.Fprintf(os.Stderr, "Config value '%s' is now deprecated. Please use '%s' instead\n", depKey, key)
 			return depKey
 		}
@@ -405,6 +681,23 @@ func GetInt(key string) int {
 	return cfg.GetInt(realKey(key))
 }
 
+// GetDuration returns a duration from the configuration, given either as a Go
+// duration ("90s", "5m") or as a number of seconds. The default value is
+// returned if the value can't be parsed. The key has to be one of the
+// durationKeys, so that a valid default value exists, it panics otherwise.
+func GetDuration(key string) time.Duration {
+	if !isDurationKey(key) {
+		panic(fmt.Sprintf("Configuration key '%s' is not registered as a duration", key))
+	}
+
+	d, err := parseDuration(cfg.Get(realKey(key)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid duration for '%s': %s\n", key, err)
+		d, _ = parseDuration(defaultValues[key])
+	}
+	return d
+}
+
 // GetString returns a string from the configuration
 func GetString(key string) string {
 	return cfg.GetString(realKey(key))
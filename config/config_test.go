@@ -24,7 +24,12 @@ package config
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	capturer "github.com/kami-zh/go-capturer"
 )
@@ -87,3 +92,173 @@ openstack:
 		t.Fatal("Relocation with default failed")
 	}
 }
+
+func TestValidateConfig(t *testing.T) {
+	valid := []byte(`
+analyzers:
+  - 127.0.0.1:8082
+agent:
+  listen: 8081
+  topology:
+    probes: netlink
+flow:
+  expire: "600"
+  update: 10
+ovs:
+  oflow:
+    enable: true
+`)
+
+	if err := ValidateConfig(bytes.NewBuffer(valid)); err != nil {
+		t.Fatalf("Configuration should be valid: %s", err)
+	}
+
+	invalid := []byte(`
+analyser:
+  listen: 127.0.0.1:8082
+flow:
+  expire: never
+ovs:
+  oflow:
+    enable: maybe
+agent:
+  topology:
+    probes:
+      netlink: true
+`)
+
+	err := ValidateConfig(bytes.NewBuffer(invalid))
+	if err == nil {
+		t.Fatal("Configuration should be invalid")
+	}
+
+	for _, key := range []string{"analyser.listen", "flow.expire", "ovs.oflow.enable", "agent.topology.probes.netlink"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Error should report %s: %s", key, err)
+		}
+	}
+
+	if err := ValidateConfig(bytes.NewBufferString("flow: [")); err == nil {
+		t.Fatal("Configuration should not be parsed")
+	}
+}
+
+func TestGetDuration(t *testing.T) {
+	defer Set("agent.host_update", defaultValues["agent.host_update"])
+
+	for value, expected := range map[interface{}]time.Duration{
+		"90s": 90 * time.Second,
+		"5m":  5 * time.Minute,
+		45:    45 * time.Second,
+		"1.5": 1500 * time.Millisecond,
+	} {
+		Set("agent.host_update", value)
+		if d := GetDuration("agent.host_update"); d != expected {
+			t.Errorf("Expected %s for %v, got %s", expected, value, d)
+		}
+	}
+
+	Set("agent.host_update", "never")
+	out := capturer.CaptureStderr(func() {
+		if d := GetDuration("agent.host_update"); d != 30*time.Second {
+			t.Errorf("Expected the default value, got %s", d)
+		}
+	})
+	if !strings.Contains(out, "agent.host_update") {
+		t.Errorf("A warning pointing at the key should have been raised: %s", out)
+	}
+
+	err := checkConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "agent.host_update") {
+		t.Errorf("Configuration check should report agent.host_update: %v", err)
+	}
+
+	Set("agent.host_update", "-5s")
+	if err := checkConfig(cfg); err == nil {
+		t.Error("Configuration check should refuse a negative duration")
+	}
+}
+
+func TestGetDurationUnregistered(t *testing.T) {
+	for _, key := range durationKeys {
+		if d, err := parseDuration(defaultValues[key]); err != nil || d <= 0 {
+			t.Errorf("Duration key %s should have a valid default value: %v", key, err)
+		}
+	}
+
+	defer func() {
+		if err := recover(); err == nil || !strings.Contains(fmt.Sprint(err), "agent.unknown_update") {
+			t.Errorf("An unregistered duration key should be reported, got: %v", err)
+		}
+	}()
+	GetDuration("agent.unknown_update")
+}
+
+func TestValidateConfigDuration(t *testing.T) {
+	valid := []byte(`
+agent:
+  host_update: 90s
+  smart:
+    update: 3600
+  topology:
+    mounts:
+      update: "1.5"
+`)
+
+	if err := ValidateConfig(bytes.NewBuffer(valid)); err != nil {
+		t.Fatalf("Configuration should be valid: %s", err)
+	}
+
+	invalid := []byte(`
+agent:
+  host_update: soon
+  smart:
+    update: [1h]
+`)
+
+	err := ValidateConfig(bytes.NewBuffer(invalid))
+	if err == nil {
+		t.Fatal("Configuration should be invalid")
+	}
+
+	for _, key := range []string{"agent.host_update", "agent.smart.update"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Error should report %s: %s", key, err)
+		}
+	}
+}
+
+func TestReloadConfigInvalid(t *testing.T) {
+	file, err := ioutil.TempFile("", "skydive-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	write := func(data string) {
+		if err := ioutil.WriteFile(file.Name(), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("flow:\n  expire: 300\n")
+	if err := InitConfig("file", []string{file.Name()}); err != nil {
+		t.Fatal(err)
+	}
+
+	write("flow:\n  expire: -1\n")
+	if err := ReloadConfig(); err == nil || !strings.Contains(err.Error(), "flow.expire") {
+		t.Errorf("Reload should refuse an invalid configuration: %v", err)
+	}
+	if expire := GetInt("flow.expire"); expire != 300 {
+		t.Errorf("Invalid configuration should not be applied, got %d", expire)
+	}
+
+	write("flow:\n  expire: 120\n")
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if expire := GetInt("flow.expire"); expire != 120 {
+		t.Errorf("Expected the reloaded value, got %d", expire)
+	}
+}
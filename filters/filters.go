@@ -23,7 +23,10 @@
 package filters
 
 import (
+	"fmt"
+	"net"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/pmylund/go-cache"
@@ -75,6 +78,12 @@ func (f *Filter) Eval(g Getter) bool {
 	if f.IPV4RangeFilter != nil {
 		return f.IPV4RangeFilter.Eval(g)
 	}
+	if f.IPV6RangeFilter != nil {
+		return f.IPV6RangeFilter.Eval(g)
+	}
+	if f.CIDRFilter != nil {
+		return f.CIDRFilter.Eval(g)
+	}
 
 	return true
 }
@@ -329,6 +338,86 @@ func NewIPV4RangeFilter(key, cidr string) (*IPV4RangeFilter, error) {
 	return &IPV4RangeFilter{Key: key, Value: cidr}, nil
 }
 
+// ipInNet returns whether the string is an ip, with or without prefix
+// length and zone, contained in the given network
+func ipInNet(s string, ipnet *net.IPNet) bool {
+	if i := strings.IndexByte(s, '/'); i != -1 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '%'); i != -1 {
+		s = s[:i]
+	}
+
+	ip := net.ParseIP(s)
+	return ip != nil && ipnet.Contains(ip)
+}
+
+// evalCIDR evaluates whether the field contains an ip of the cidr
+func evalCIDR(g Getter, key, cidr string) bool {
+	field, err := g.GetField(key)
+	if err != nil {
+		return false
+	}
+
+	// ignore error at this point should have been check in the contructor
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+
+	switch field := field.(type) {
+	case []interface{}:
+		for _, intf := range field {
+			if s, ok := intf.(string); ok && ipInNet(s, ipnet) {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range field {
+			if ipInNet(s, ipnet) {
+				return true
+			}
+		}
+	case string:
+		return ipInNet(field, ipnet)
+	}
+
+	return false
+}
+
+// Eval evaluates an ipv6 range filter
+func (r *IPV6RangeFilter) Eval(g Getter) bool {
+	return evalCIDR(g, r.Key, r.Value)
+}
+
+// NewIPV6RangeFilter creates a filter matching the ipv6 contained in the range
+func NewIPV6RangeFilter(key, cidr string) (*IPV6RangeFilter, error) {
+	ip, _, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if ip.To4() != nil {
+		return nil, fmt.Errorf("%s is not an ipv6 range", cidr)
+	}
+
+	return &IPV6RangeFilter{Key: key, Value: cidr}, nil
+}
+
+// Eval evaluates a cidr filter
+func (c *CIDRFilter) Eval(g Getter) bool {
+	return evalCIDR(g, c.Key, c.Value)
+}
+
+// NewCIDRFilter creates a filter matching the ip, of either family,
+// contained in the cidr
+func NewCIDRFilter(key, cidr string) (*CIDRFilter, error) {
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return nil, err
+	}
+
+	return &CIDRFilter{Key: key, Value: cidr}, nil
+}
+
 // NewBoolFilter creates a new boolean filter
 func NewBoolFilter(op BoolFilterOp, filters ...*Filter) *Filter {
 	boolFilter := &BoolFilter{
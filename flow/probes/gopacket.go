@@ -182,8 +182,7 @@ func (p *GoPacketProbe) run(g *graph.Graph, n *graph.Node, capture *types.Captur
 	statsDone := make(chan bool)
 
 	// Go routine to update the interface statistics
-	statsUpdate := config.GetInt("agent.capture.stats_update")
-	statsTicker := time.NewTicker(time.Duration(statsUpdate) * time.Second)
+	statsTicker := time.NewTicker(config.GetDuration("agent.capture.stats_update"))
 
 	switch capture.Type {
 	case "pcap":
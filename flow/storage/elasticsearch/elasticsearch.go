@@ -254,10 +254,18 @@ func (c *ElasticSearchStorage) SearchRawPackets(fsq filters.SearchQuery, packetF
 	}
 
 	// do not escape flow as ES use sub object in that case
-	mustQueries := []elastic.Query{es.FormatFilter(fsq.Filter, "Flow")}
+	flowQuery, err := es.FormatFilter(fsq.Filter, "Flow")
+	if err != nil {
+		return nil, err
+	}
+	mustQueries := []elastic.Query{flowQuery}
 
 	if packetFilter != nil {
-		mustQueries = append(mustQueries, es.FormatFilter(packetFilter, ""))
+		packetQuery, err := es.FormatFilter(packetFilter, "")
+		if err != nil {
+			return nil, err
+		}
+		mustQueries = append(mustQueries, packetQuery)
 	}
 
 	out, err := c.sendRequest("rawpacket", elastic.NewBoolQuery().Must(mustQueries...), fsq, rawpacketIndex.IndexWildcard())
@@ -294,8 +302,15 @@ func (c *ElasticSearchStorage) SearchMetrics(fsq filters.SearchQuery, metricFilt
 	}
 
 	// do not escape flow as ES use sub object in that case
-	flowQuery := es.FormatFilter(fsq.Filter, "Flow")
-	metricQuery := es.FormatFilter(metricFilter, "")
+	flowQuery, err := es.FormatFilter(fsq.Filter, "Flow")
+	if err != nil {
+		return nil, err
+	}
+
+	metricQuery, err := es.FormatFilter(metricFilter, "")
+	if err != nil {
+		return nil, err
+	}
 
 	query := elastic.NewBoolQuery().Must(flowQuery, metricQuery)
 	out, err := c.sendRequest("metric", query, fsq, metricIndex.IndexWildcard())
@@ -329,7 +344,12 @@ func (c *ElasticSearchStorage) SearchFlows(fsq filters.SearchQuery) (*flow.FlowS
 	}
 
 	// TODO: dedup and sort in order to remove duplicate flow UUID due to rolling index
-	out, err := c.sendRequest("flow", es.FormatFilter(fsq.Filter, ""), fsq, flowIndex.IndexWildcard())
+	query, err := es.FormatFilter(fsq.Filter, "")
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.sendRequest("flow", query, fsq, flowIndex.IndexWildcard())
 	if err != nil {
 		return nil, err
 	}
@@ -284,10 +284,19 @@ func (c *OrientDBStorage) SearchRawPackets(fsq filters.SearchQuery, packetFilter
 
 	where := false
 	if packetFilter != nil {
-		sql += " WHERE " + orient.FilterToExpression(packetFilter, nil)
+		packetConditional, err := orient.FilterToExpression(packetFilter, nil)
+		if err != nil {
+			return nil, err
+		}
+		sql += " WHERE " + packetConditional
 		where = true
 	}
-	if conditional := orient.FilterToExpression(filter, func(s string) string { return "Flow." + s }); conditional != "" {
+
+	conditional, err := orient.FilterToExpression(filter, func(s string) string { return "Flow." + s })
+	if err != nil {
+		return nil, err
+	}
+	if conditional != "" {
 		if where {
 			sql += " AND " + conditional
 		} else {
@@ -332,8 +341,17 @@ func (c *OrientDBStorage) SearchRawPackets(fsq filters.SearchQuery, packetFilter
 func (c *OrientDBStorage) SearchMetrics(fsq filters.SearchQuery, metricFilter *filters.Filter) (map[string][]common.Metric, error) {
 	filter := fsq.Filter
 	sql := "SELECT ABBytes, ABPackets, BABytes, BAPackets, Start, Last, Flow.UUID FROM FlowMetric"
-	sql += " WHERE " + orient.FilterToExpression(metricFilter, nil)
-	if conditional := orient.FilterToExpression(filter, func(s string) string { return "Flow." + s }); conditional != "" {
+	metricConditional, err := orient.FilterToExpression(metricFilter, nil)
+	if err != nil {
+		return nil, err
+	}
+	sql += " WHERE " + metricConditional
+
+	conditional, err := orient.FilterToExpression(filter, func(s string) string { return "Flow." + s })
+	if err != nil {
+		return nil, err
+	}
+	if conditional != "" {
 		sql += " AND " + conditional
 	}
 
@@ -26,6 +26,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -95,6 +96,9 @@ type WSConnStatus struct {
 	ConnectTime       time.Time
 	RemoteHost        string             `json:",omitempty"`
 	RemoteServiceType common.ServiceType `json:",omitempty"`
+	Compression       bool               `json:",omitempty"`
+	RawBytesSent      int64              // size of the messages sent
+	WireBytesSent     int64              // bytes sent on the network, after compression
 }
 
 func (s *WSConnState) MarshalJSON() ([]byte, error) {
@@ -135,7 +139,9 @@ type WSConn struct {
 	running       atomic.Value
 	pingTicker    *time.Ticker // only used by incoming connections
 	eventHandlers []WSSpeakerEventHandler
-	wsSpeaker     WSSpeaker // speaker owning the connection
+	wsSpeaker     WSSpeaker       // speaker owning the connection
+	netConn       *wsCountingConn // network connection of the websocket
+	rawBytesSent  int64
 }
 
 // wsIncomingClient is only used internally to handle incoming client. It embeds a WSConn.
@@ -147,8 +153,9 @@ type wsIncomingClient struct {
 // It embeds a WSConn.
 type WSClient struct {
 	*WSConn
-	Path     string
-	AuthOpts *AuthenticationOpts
+	Path       string
+	AuthOpts   *AuthenticationOpts
+	namespaces []string // namespaces requested at connection, wildcard if empty
 }
 
 // WSSpeakerEventHandler is the interface to be implement by the client events listeners.
@@ -202,7 +209,9 @@ func (c *WSConn) GetStatus() WSConnStatus {
 	status := c.WSConnStatus
 	status.State = new(WSConnState)
 	*status.State = WSConnState(atomic.LoadInt32((*int32)(c.State)))
-	return c.WSConnStatus
+	status.RawBytesSent = atomic.LoadInt64(&c.rawBytesSent)
+	status.WireBytesSent = c.netConn.bytesWritten()
+	return status
 }
 
 // WSSpeakerStructMessageHandler interface used to receive Struct messages.
@@ -273,6 +282,7 @@ func (c *WSConn) write(msg []byte) error {
 	if _, err = w.Write(msg); err != nil {
 		return err
 	}
+	atomic.AddInt64(&c.rawBytesSent, int64(len(msg)))
 
 	return w.Close()
 }
@@ -411,7 +421,9 @@ func (c *WSClient) scheme() string {
 	return "ws://"
 }
 
-func (c *WSClient) connect() {
+// connect dials the server and runs the connection until it is closed. It returns
+// an error if the connection could not be established.
+func (c *WSClient) connect() error {
 	var err error
 	endpoint := c.URL.String()
 	headers := http.Header{
@@ -422,19 +434,35 @@ func (c *WSClient) connect() {
 		"X-Websocket-Namespace": {WildcardNamespace},
 	}
 
+	c.RLock()
+	if len(c.namespaces) > 0 {
+		headers["X-Websocket-Namespace"] = append([]string{}, c.namespaces...)
+	}
+	c.RUnlock()
+
 	if c.AuthOpts != nil {
 		SetAuthHeaders(&headers, c.AuthOpts)
 	}
 
+	var netConn *wsCountingConn
 	d := websocket.Dialer{
-		Proxy:           http.ProxyFromEnvironment,
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
+		Proxy:             http.ProxyFromEnvironment,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: config.GetBool("http.ws.enable_compression"),
+		NetDial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			if err != nil {
+				return nil, err
+			}
+			netConn = &wsCountingConn{Conn: conn}
+			return netConn, nil
+		},
 	}
 	d.TLSClientConfig, err = getTLSConfig(false)
 	if err != nil {
 		logging.GetLogger().Errorf("Unable to create a WebSocket connection %s : %s", endpoint, err)
-		return
+		return err
 	}
 
 	var resp *http.Response
@@ -442,10 +470,16 @@ func (c *WSClient) connect() {
 
 	if err != nil {
 		logging.GetLogger().Errorf("Unable to create a WebSocket connection %s : %s", endpoint, err)
-		return
+		return err
 	}
 	c.conn.SetPingHandler(nil)
 	c.conn.EnableWriteCompression(config.GetBool("http.ws.enable_write_compression"))
+	setCompressionLevel(c.conn)
+
+	c.Lock()
+	c.netConn = netConn
+	c.Compression = isCompressionNegotiated(resp.Header)
+	c.Unlock()
 
 	atomic.StoreInt32((*int32)(c.State), common.RunningState)
 	defer atomic.StoreInt32((*int32)(c.State), common.StoppedState)
@@ -477,11 +511,13 @@ func (c *WSClient) connect() {
 
 	// in case of a handler disconnect the client directly
 	if !c.IsConnected() {
-		return
+		return nil
 	}
 
 	c.wg.Add(1)
 	c.run()
+
+	return nil
 }
 
 // Connect to the server - and reconnect if necessary
@@ -531,6 +567,9 @@ func newIncomingWSClient(conn *websocket.Conn, r *auth.AuthenticatedRequest) *ws
 
 	wsconn := newWSConn(host, clientType, clientProtocol, url, r.Header, queueSize)
 	wsconn.conn = conn
+	wsconn.netConn, _ = conn.UnderlyingConn().(*wsCountingConn)
+	wsconn.Compression = config.GetBool("http.ws.enable_compression") && isCompressionNegotiated(r.Header)
+	setCompressionLevel(conn)
 	wsconn.RemoteHost = getRequestParameter(&r.Request, "X-Host-ID")
 
 	// NOTE(safchain): fallback to remote addr if host id not provided
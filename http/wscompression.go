@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/logging"
+)
+
+// wsCountingConn counts the bytes written on the network connection of a
+// websocket, after compression
+type wsCountingConn struct {
+	net.Conn
+	written int64
+}
+
+func (c *wsCountingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.written, int64(n))
+	return n, err
+}
+
+func (c *wsCountingConn) bytesWritten() int64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.written)
+}
+
+// wsCountingResponseWriter makes the upgraded connections counting the
+// bytes written
+type wsCountingResponseWriter struct {
+	http.ResponseWriter
+	conn *wsCountingConn
+}
+
+func (w *wsCountingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("Connection can't be hijacked")
+	}
+
+	conn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.conn = &wsCountingConn{Conn: conn}
+	return w.conn, brw, nil
+}
+
+// isCompressionNegotiated returns whether the permessage-deflate extension
+// is part of the handshake headers
+func isCompressionNegotiated(header http.Header) bool {
+	for _, extensions := range header["Sec-Websocket-Extensions"] {
+		for _, extension := range strings.Split(extensions, ",") {
+			if strings.HasPrefix(strings.TrimSpace(extension), "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setCompressionLevel applies the configured compression level to the
+// connection, only used if compression was negotiated
+func setCompressionLevel(conn *websocket.Conn) {
+	if err := conn.SetCompressionLevel(config.GetInt("http.ws.compression_level")); err != nil {
+		logging.GetLogger().Errorf("Invalid WebSocket compression level: %s", err)
+	}
+}
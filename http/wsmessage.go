@@ -410,6 +410,12 @@ func newWSStructSpeaker(c WSSpeaker) *WSStructSpeaker {
 	return s
 }
 
+// wsStructUpgrader is implemented by the outgoing speakers that can be upgraded to a WSStructSpeaker.
+type wsStructUpgrader interface {
+	UpgradeToWSStructSpeaker() *WSStructSpeaker
+}
+
+// UpgradeToWSStructSpeaker returns a WSStructSpeaker wrapping the client.
 func (c *WSClient) UpgradeToWSStructSpeaker() *WSStructSpeaker {
 	s := newWSStructSpeaker(c)
 	c.Lock()
@@ -439,9 +445,9 @@ type WSStructClientPool struct {
 	*wsStructSpeakerPoolEventDispatcher
 }
 
-// AddClient adds a WSClient to the pool.
+// AddClient adds a WSClient or a ReconnectingSpeaker to the pool.
 func (a *WSStructClientPool) AddClient(c WSSpeaker) error {
-	if wc, ok := c.(*WSClient); ok {
+	if wc, ok := c.(wsStructUpgrader); ok {
 		speaker := wc.UpgradeToWSStructSpeaker()
 		a.WSClientPool.AddClient(speaker)
 		a.wsStructSpeakerPoolEventDispatcher.AddStructSpeaker(speaker)
@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package http
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+// WSBackoff defines the delays between the reconnection attempts of a ReconnectingSpeaker.
+// The delay grows exponentially from InitialInterval up to MaxInterval and is randomized
+// by plus or minus Jitter percent of its value.
+type WSBackoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64 // between 0 and 1
+	MaxAttempts     int     // 0 means retrying forever
+}
+
+// DefaultWSBackoff is the backoff used by the agents to reconnect to the analyzers.
+var DefaultWSBackoff = WSBackoff{
+	InitialInterval: 1 * time.Second,
+	MaxInterval:     30 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.2,
+}
+
+// Delay returns the delay to wait before the given attempt, starting at 1.
+func (b WSBackoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if b.MaxInterval > 0 && delay > float64(b.MaxInterval) {
+		delay = float64(b.MaxInterval)
+	}
+
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// WSReconnectEventHandler is the interface to be implemented by the listeners of
+// the reconnection events of a ReconnectingSpeaker.
+type WSReconnectEventHandler interface {
+	OnReconnect(attempt int)
+	OnGiveUp()
+}
+
+// ReconnectingSpeaker is a WSClient reconnecting to the server with an exponential
+// backoff when the connection fails or is lost. The namespaces requested at the
+// first connection are requested again at each reconnection.
+type ReconnectingSpeaker struct {
+	*WSClient
+	backoff         WSBackoff
+	reconnectLock   sync.RWMutex
+	reconnectEvents []WSReconnectEventHandler
+	stop            chan struct{}
+	stopOnce        sync.Once
+}
+
+// AddReconnectEventHandler registers a new reconnection event handler
+func (s *ReconnectingSpeaker) AddReconnectEventHandler(h WSReconnectEventHandler) {
+	s.reconnectLock.Lock()
+	s.reconnectEvents = append(s.reconnectEvents, h)
+	s.reconnectLock.Unlock()
+}
+
+// Subscribe adds namespaces to request at the next connections
+func (s *ReconnectingSpeaker) Subscribe(namespaces ...string) {
+	s.Lock()
+	s.namespaces = append(s.namespaces, namespaces...)
+	s.Unlock()
+}
+
+func (s *ReconnectingSpeaker) handlers() []WSReconnectEventHandler {
+	s.reconnectLock.RLock()
+	defer s.reconnectLock.RUnlock()
+	return append([]WSReconnectEventHandler{}, s.reconnectEvents...)
+}
+
+// wait returns false if the speaker was disconnected while waiting
+func (s *ReconnectingSpeaker) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return s.running.Load() == true
+	case <-s.stop:
+		return false
+	}
+}
+
+// Connect to the server and reconnect according to the backoff when the
+// connection fails or is lost.
+func (s *ReconnectingSpeaker) Connect() {
+	go func() {
+		attempt := 0
+		for s.running.Load() == true {
+			if attempt > 0 {
+				if s.backoff.MaxAttempts > 0 && attempt > s.backoff.MaxAttempts {
+					logging.GetLogger().Errorf("Giving up connecting to %s after %d attempts", s.URL.String(), attempt-1)
+					s.running.Store(false)
+					for _, h := range s.handlers() {
+						h.OnGiveUp()
+					}
+					return
+				}
+
+				if !s.wait(s.backoff.Delay(attempt)) {
+					return
+				}
+
+				for _, h := range s.handlers() {
+					h.OnReconnect(attempt)
+				}
+			}
+
+			if err := s.connect(); err != nil {
+				attempt++
+			} else {
+				// the connection was established, restart the backoff schedule
+				attempt = 1
+			}
+		}
+	}()
+}
+
+// Disconnect the speaker and stop reconnecting.
+func (s *ReconnectingSpeaker) Disconnect() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.WSClient.Disconnect()
+}
+
+// UpgradeToWSStructSpeaker returns a WSStructSpeaker wrapping the reconnecting speaker.
+func (s *ReconnectingSpeaker) UpgradeToWSStructSpeaker() *WSStructSpeaker {
+	ss := newWSStructSpeaker(s)
+	s.Lock()
+	s.wsSpeaker = ss
+	s.Unlock()
+	return ss
+}
+
+// NewReconnectingSpeaker returns a ReconnectingSpeaker using the given client and backoff.
+// The namespaces are requested at each connection, wildcard if none is given.
+func NewReconnectingSpeaker(c *WSClient, backoff WSBackoff, namespaces ...string) *ReconnectingSpeaker {
+	s := &ReconnectingSpeaker{
+		WSClient: c,
+		backoff:  backoff,
+		stop:     make(chan struct{}),
+	}
+	s.Subscribe(namespaces...)
+
+	c.Lock()
+	c.wsSpeaker = s
+	c.Unlock()
+
+	return s
+}
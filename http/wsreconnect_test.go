@@ -0,0 +1,234 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+)
+
+type fakeReconnectServerHandler struct {
+	common.RWMutex
+	DefaultWSSpeakerEventHandler
+	connected  int
+	namespaces []string
+}
+
+func (f *fakeReconnectServerHandler) OnConnected(c WSSpeaker) {
+	f.Lock()
+	f.connected++
+	f.namespaces = c.GetHeaders()["X-Websocket-Namespace"]
+	f.Unlock()
+}
+
+type fakeReconnectHandler struct {
+	common.RWMutex
+	attempts []int
+	times    []time.Time
+	gaveUp   bool
+}
+
+func (f *fakeReconnectHandler) OnReconnect(attempt int) {
+	f.Lock()
+	f.attempts = append(f.attempts, attempt)
+	f.times = append(f.times, time.Now())
+	f.Unlock()
+}
+
+func (f *fakeReconnectHandler) OnGiveUp() {
+	f.Lock()
+	f.gaveUp = true
+	f.Unlock()
+}
+
+func (f *fakeReconnectHandler) waitAttempts(n int) error {
+	return common.Retry(func() error {
+		f.RLock()
+		defer f.RUnlock()
+		if len(f.attempts) < n {
+			return fmt.Errorf("Expected %d reconnection attempts, got %v", n, f.attempts)
+		}
+		return nil
+	}, 50, 100*time.Millisecond)
+}
+
+func startReconnectServer(port int, handler WSSpeakerEventHandler) (*Server, *WSServer) {
+	httpserver := NewServer("myhost", common.AnalyzerService, "localhost", port, "")
+	go httpserver.ListenAndServe()
+
+	wsserver := NewWSServer(httpserver, "/wstest", NewNoAuthenticationBackend())
+	wsserver.AddEventHandler(handler)
+	wsserver.Start()
+
+	return httpserver, wsserver
+}
+
+func waitServerConnected(handler *fakeReconnectServerHandler, connected int) error {
+	return common.Retry(func() error {
+		handler.RLock()
+		defer handler.RUnlock()
+		if handler.connected != connected {
+			return fmt.Errorf("Server should have received %d OnConnected events: %d", connected, handler.connected)
+		}
+		return nil
+	}, 50, 100*time.Millisecond)
+}
+
+func TestWSBackoffDelay(t *testing.T) {
+	backoff := WSBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, delay := range expected {
+		if d := backoff.Delay(i + 1); d != delay {
+			t.Errorf("Wrong delay for attempt %d, expected %s got %s", i+1, delay, d)
+		}
+	}
+
+	backoff.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := backoff.Delay(3); d < 200*time.Millisecond || d > 600*time.Millisecond {
+			t.Fatalf("Delay out of the jitter bounds: %s", d)
+		}
+	}
+}
+
+func TestReconnectingSpeaker(t *testing.T) {
+	serverHandler := &fakeReconnectServerHandler{}
+	httpserver, wsserver := startReconnectServer(59997, serverHandler)
+
+	backoff := WSBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     400 * time.Millisecond,
+		Multiplier:      2,
+	}
+
+	wsclient := NewWSClient("myhost", common.AgentService, config.GetURL("ws", "localhost", 59997, "/wstest"), nil, http.Header{}, 1000)
+	speaker := NewReconnectingSpeaker(wsclient, backoff, "ns1", "ns2")
+	reconnectHandler := &fakeReconnectHandler{}
+	speaker.AddReconnectEventHandler(reconnectHandler)
+	speaker.Connect()
+	defer speaker.Disconnect()
+
+	if err := waitServerConnected(serverHandler, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// stop the server, the speaker should retry following the backoff schedule
+	httpserver.Stop()
+	wsserver.Stop()
+
+	if err := reconnectHandler.waitAttempts(4); err != nil {
+		t.Fatal(err)
+	}
+
+	reconnectHandler.RLock()
+	attempts := reconnectHandler.attempts[:4]
+	times := reconnectHandler.times[:4]
+	reconnectHandler.RUnlock()
+
+	if !reflect.DeepEqual(attempts, []int{1, 2, 3, 4}) {
+		t.Fatalf("Wrong reconnection attempts: %v", attempts)
+	}
+
+	for i := 1; i < len(times); i++ {
+		expected := backoff.Delay(i + 1)
+		if gap := times[i].Sub(times[i-1]); gap < expected*9/10 || gap > expected*2 {
+			t.Errorf("Wrong delay before attempt %d, expected %s got %s", i+1, expected, gap)
+		}
+	}
+
+	// restart the server, the speaker should reconnect with the same namespaces
+	httpserver, wsserver = startReconnectServer(59997, serverHandler)
+	defer httpserver.Stop()
+	defer wsserver.Stop()
+
+	if err := waitServerConnected(serverHandler, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	serverHandler.RLock()
+	namespaces := serverHandler.namespaces
+	serverHandler.RUnlock()
+
+	if !reflect.DeepEqual(namespaces, []string{"ns1", "ns2"}) {
+		t.Errorf("Namespaces not requested again after reconnection: %v", namespaces)
+	}
+
+	reconnectHandler.RLock()
+	defer reconnectHandler.RUnlock()
+	if reconnectHandler.gaveUp {
+		t.Error("Speaker should not have given up")
+	}
+}
+
+func TestReconnectingSpeakerGiveUp(t *testing.T) {
+	backoff := WSBackoff{
+		InitialInterval: 10 * time.Millisecond,
+		Multiplier:      2,
+		MaxAttempts:     3,
+	}
+
+	wsclient := NewWSClient("myhost", common.AgentService, config.GetURL("ws", "localhost", 59996, "/wstest"), nil, http.Header{}, 1000)
+	speaker := NewReconnectingSpeaker(wsclient, backoff)
+	reconnectHandler := &fakeReconnectHandler{}
+	speaker.AddReconnectEventHandler(reconnectHandler)
+	speaker.Connect()
+	defer speaker.Disconnect()
+
+	err := common.Retry(func() error {
+		reconnectHandler.RLock()
+		defer reconnectHandler.RUnlock()
+		if !reconnectHandler.gaveUp {
+			return errors.New("Speaker should have given up")
+		}
+		return nil
+	}, 20, 100*time.Millisecond)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconnectHandler.RLock()
+	defer reconnectHandler.RUnlock()
+	if !reflect.DeepEqual(reconnectHandler.attempts, []int{1, 2, 3}) {
+		t.Errorf("Wrong reconnection attempts: %v", reconnectHandler.attempts)
+	}
+}
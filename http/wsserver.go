@@ -29,6 +29,7 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/logging"
 	"github.com/skydive-project/skydive/rbac"
 )
@@ -83,7 +84,16 @@ func (s *WSServer) serveMessages(w http.ResponseWriter, r *auth.AuthenticatedReq
 	header.Set("X-Host-ID", s.server.Host)
 	header.Set("X-Service-Type", s.server.ServiceType.String())
 
-	conn, err := websocket.Upgrade(w, &r.Request, header, 1024, 1024)
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: config.GetBool("http.ws.enable_compression"),
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+	}
+
+	conn, err := upgrader.Upgrade(&wsCountingResponseWriter{ResponseWriter: w}, &r.Request, header)
 	if err != nil {
 		return
 	}
@@ -26,6 +26,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -153,3 +154,71 @@ func TestSubscription(t *testing.T) {
 		t.Error(err.Error())
 	}
 }
+
+type fakeCompressionHandler struct {
+	common.RWMutex
+	DefaultWSSpeakerEventHandler
+	speaker WSSpeaker
+}
+
+func (f *fakeCompressionHandler) OnConnected(c WSSpeaker) {
+	f.Lock()
+	f.speaker = c
+	f.Unlock()
+
+	c.SendRaw([]byte(strings.Repeat(`{"Name": "eth0", "Type": "device"}`, 1000)))
+}
+
+func TestCompression(t *testing.T) {
+	config.Set("http.ws.enable_compression", true)
+	defer config.Set("http.ws.enable_compression", false)
+
+	httpserver := NewServer("myhost", common.AnalyzerService, "localhost", 59998, "")
+
+	go httpserver.ListenAndServe()
+	defer httpserver.Stop()
+
+	wsserver := NewWSServer(httpserver, "/wstest", NewNoAuthenticationBackend())
+
+	serverHandler := &fakeCompressionHandler{}
+	wsserver.AddEventHandler(serverHandler)
+
+	wsserver.Start()
+	defer wsserver.Stop()
+
+	wsclient := NewWSClient("myhost", common.AgentService, config.GetURL("ws", "localhost", 59998, "/wstest"), nil, http.Header{}, 1000)
+	clientHandler := &fakeClientSubscriptionHandler{t: t}
+	wsclient.AddEventHandler(clientHandler)
+	wsclient.Connect()
+	defer wsclient.Disconnect()
+
+	err := common.Retry(func() error {
+		clientHandler.RLock()
+		defer clientHandler.RUnlock()
+		serverHandler.RLock()
+		defer serverHandler.RUnlock()
+
+		if clientHandler.received != 1 || serverHandler.speaker == nil {
+			return errors.New("Message not received by the client")
+		}
+
+		if !wsclient.GetStatus().Compression {
+			return errors.New("Compression not negotiated by the client")
+		}
+
+		status := serverHandler.speaker.GetStatus()
+		if !status.Compression {
+			return errors.New("Compression not negotiated by the server")
+		}
+
+		if status.WireBytesSent == 0 || status.WireBytesSent >= status.RawBytesSent {
+			return fmt.Errorf("Message not compressed, %d bytes sent for %d bytes of message", status.WireBytesSent, status.RawBytesSent)
+		}
+
+		return nil
+	}, 5, time.Second)
+
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
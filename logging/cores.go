@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package logging
+
+import (
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// extraCores holds the cores added with AddCore, they receive the entries
+// enabled by their own level whatever the level of the backends
+var extraCores = &dynamicCore{}
+
+type dynamicCore struct {
+	sync.RWMutex
+	cores []zapcore.Core
+}
+
+// fieldsCore applies the fields given to With to the cores of a dynamicCore
+type fieldsCore struct {
+	*dynamicCore
+	fields []zapcore.Field
+}
+
+func (d *dynamicCore) Enabled(lvl zapcore.Level) bool {
+	d.RLock()
+	defer d.RUnlock()
+
+	for _, core := range d.cores {
+		if core.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fieldsCore{dynamicCore: d, fields: fields}
+}
+
+func (d *dynamicCore) check(fields []zapcore.Field, ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	d.RLock()
+	defer d.RUnlock()
+
+	for _, core := range d.cores {
+		if len(fields) > 0 {
+			core = core.With(fields)
+		}
+		ce = core.Check(ent, ce)
+	}
+	return ce
+}
+
+func (d *dynamicCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return d.check(nil, ent, ce)
+}
+
+// Write is never called as Check adds the added cores to the entry
+func (d *dynamicCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return nil
+}
+
+func (d *dynamicCore) Sync() error {
+	d.RLock()
+	defer d.RUnlock()
+
+	var err error
+	for _, core := range d.cores {
+		if e := core.Sync(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (f *fieldsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fieldsCore{dynamicCore: f.dynamicCore, fields: append(append([]zapcore.Field{}, f.fields...), fields...)}
+}
+
+func (f *fieldsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return f.check(f.fields, ent, ce)
+}
+
+// AddCore adds a core to the logger, for instance to capture the logs of
+// a test. The core receives the entries enabled by its own level.
+func AddCore(core zapcore.Core) {
+	extraCores.Lock()
+	extraCores.cores = append(extraCores.cores, core)
+	extraCores.Unlock()
+}
+
+// RemoveCore removes a core added with AddCore
+func RemoveCore(core zapcore.Core) {
+	extraCores.Lock()
+	defer extraCores.Unlock()
+
+	for i, c := range extraCores.cores {
+		if c == core {
+			extraCores.cores = append(extraCores.cores[:i], extraCores.cores[i+1:]...)
+			return
+		}
+	}
+}
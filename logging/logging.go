@@ -279,6 +279,8 @@ func initLogger() (err error) {
 		}
 	}
 
+	backends = append(backends, extraCores)
+
 	newCore := zap.WrapCore(func(zapcore.Core) zapcore.Core {
 		return zapcore.NewTee(backends...)
 	})
@@ -26,6 +26,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 	"sync"
@@ -216,11 +217,27 @@ func (c *Client) start() error {
 	return nil
 }
 
-// FormatFilter creates a ElasticSearch request based on filters
-func FormatFilter(filter *filters.Filter, mapKey string) elastic.Query {
+// ipv4RangeQuery returns a regex query matching the IPv4 addresses of a CIDR
+func ipv4RangeQuery(key, cidr string) elastic.Query {
+	// NOTE(safchain) as for now the IP fields are not typed as IP
+	// use a regex
+
+	// ignore the error at this point it should have been catched earlier
+	regex, _ := common.IPV4CIDRToRegex(cidr)
+
+	// remove anchors as ES matches the whole string and doesn't support them
+	value := strings.TrimPrefix(regex, "^")
+	value = strings.TrimSuffix(value, "$")
+
+	return elastic.NewRegexpQuery(key, value)
+}
+
+// FormatFilter creates a ElasticSearch request based on filters. An error is
+// returned for the filters that can't be translated.
+func FormatFilter(filter *filters.Filter, mapKey string) (elastic.Query, error) {
 	// TODO: remove all this and replace with olivere/elastic queries
 	if filter == nil {
-		return nil
+		return nil, nil
 	}
 
 	prefix := mapKey
@@ -229,31 +246,37 @@ func FormatFilter(filter *filters.Filter, mapKey string) elastic.Query {
 	}
 
 	if f := filter.BoolFilter; f != nil {
-		queries := make([]elastic.Query, len(f.Filters))
-		for i, item := range f.Filters {
-			queries[i] = FormatFilter(item, mapKey)
+		var queries []elastic.Query
+		for _, item := range f.Filters {
+			query, err := FormatFilter(item, mapKey)
+			if err != nil {
+				return nil, err
+			}
+			if query != nil {
+				queries = append(queries, query)
+			}
 		}
 		boolQuery := elastic.NewBoolQuery()
 		switch f.Op {
 		case filters.BoolFilterOp_NOT:
-			return boolQuery.MustNot(queries...)
+			return boolQuery.MustNot(queries...), nil
 		case filters.BoolFilterOp_OR:
-			return boolQuery.Should(queries...)
+			return boolQuery.Should(queries...), nil
 		case filters.BoolFilterOp_AND:
-			return boolQuery.Must(queries...)
+			return boolQuery.Must(queries...), nil
 		default:
-			return nil
+			return nil, nil
 		}
 	}
 
 	if f := filter.TermStringFilter; f != nil {
-		return elastic.NewTermQuery(prefix+f.Key, f.Value)
+		return elastic.NewTermQuery(prefix+f.Key, f.Value), nil
 	}
 	if f := filter.TermInt64Filter; f != nil {
-		return elastic.NewTermQuery(prefix+f.Key, f.Value)
+		return elastic.NewTermQuery(prefix+f.Key, f.Value), nil
 	}
 	if f := filter.TermBoolFilter; f != nil {
-		return elastic.NewTermQuery(prefix+f.Key, f.Value)
+		return elastic.NewTermQuery(prefix+f.Key, f.Value), nil
 	}
 
 	if f := filter.RegexFilter; f != nil {
@@ -261,39 +284,40 @@ func FormatFilter(filter *filters.Filter, mapKey string) elastic.Query {
 		value := strings.TrimPrefix(f.Value, "^")
 		value = strings.TrimSuffix(value, "$")
 
-		return elastic.NewRegexpQuery(prefix+f.Key, value)
+		return elastic.NewRegexpQuery(prefix+f.Key, value), nil
 	}
 
 	if f := filter.IPV4RangeFilter; f != nil {
-		// NOTE(safchain) as for now the IP fields are not typed as IP
-		// use a regex
-
-		// ignore the error at this point it should have been catched earlier
-		regex, _ := common.IPV4CIDRToRegex(f.Value)
+		return ipv4RangeQuery(prefix+f.Key, f.Value), nil
+	}
 
-		// remove anchors as ES matches the whole string and doesn't support them
-		value := strings.TrimPrefix(regex, "^")
-		value = strings.TrimSuffix(value, "$")
+	if f := filter.IPV6RangeFilter; f != nil {
+		return nil, fmt.Errorf("IPv6 range filter on %s not supported by Elasticsearch", f.Key)
+	}
 
-		return elastic.NewRegexpQuery(prefix+f.Key, value)
+	if f := filter.CIDRFilter; f != nil {
+		if ip, _, err := net.ParseCIDR(f.Value); err == nil && ip.To4() != nil {
+			return ipv4RangeQuery(prefix+f.Key, f.Value), nil
+		}
+		return nil, fmt.Errorf("IPv6 CIDR filter on %s not supported by Elasticsearch", f.Key)
 	}
 
 	if f := filter.GtInt64Filter; f != nil {
-		return elastic.NewRangeQuery(prefix + f.Key).Gt(f.Value)
+		return elastic.NewRangeQuery(prefix + f.Key).Gt(f.Value), nil
 	}
 	if f := filter.LtInt64Filter; f != nil {
-		return elastic.NewRangeQuery(prefix + f.Key).Lt(f.Value)
+		return elastic.NewRangeQuery(prefix + f.Key).Lt(f.Value), nil
 	}
 	if f := filter.GteInt64Filter; f != nil {
-		return elastic.NewRangeQuery(prefix + f.Key).Gte(f.Value)
+		return elastic.NewRangeQuery(prefix + f.Key).Gte(f.Value), nil
 	}
 	if f := filter.LteInt64Filter; f != nil {
-		return elastic.NewRangeQuery(prefix + f.Key).Lte(f.Value)
+		return elastic.NewRangeQuery(prefix + f.Key).Lte(f.Value), nil
 	}
 	if f := filter.NullFilter; f != nil {
-		return elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery(prefix + f.Key))
+		return elastic.NewBoolQuery().MustNot(elastic.NewExistsQuery(prefix + f.Key)), nil
 	}
-	return nil
+	return nil, nil
 }
 
 // Index returns the skydive index
@@ -30,6 +30,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -189,8 +190,17 @@ func compressBody(body io.Reader) io.Reader {
 	return buffer
 }
 
-// FilterToExpression returns a OrientDB select expression based on filters
-func FilterToExpression(f *filters.Filter, formatter func(string) string) string {
+// ipv4RangeExpression returns a OrientDB expression matching the IPv4 addresses of a CIDR
+func ipv4RangeExpression(key, cidr string) string {
+	// ignore the error at this point it should have been catched earlier
+	regex, _ := common.IPV4CIDRToRegex(cidr)
+
+	return fmt.Sprintf(`%s MATCHES "%s"`, key, strings.Replace(regex, `\`, `\\`, -1))
+}
+
+// FilterToExpression returns a OrientDB select expression based on filters. An
+// error is returned for the filters that can't be translated.
+func FilterToExpression(f *filters.Filter, formatter func(string) string) (string, error) {
 	if formatter == nil {
 		formatter = func(s string) string { return s }
 	}
@@ -199,7 +209,11 @@ func FilterToExpression(f *filters.Filter, formatter func(string) string) string
 		keyword := ""
 		switch f.BoolFilter.Op {
 		case filters.BoolFilterOp_NOT:
-			return "NOT (" + FilterToExpression(f.BoolFilter.Filters[0], formatter) + ")"
+			expr, err := FilterToExpression(f.BoolFilter.Filters[0], formatter)
+			if err != nil {
+				return "", err
+			}
+			return "NOT (" + expr + ")", nil
 		case filters.BoolFilterOp_OR:
 			keyword = "OR"
 		case filters.BoolFilterOp_AND:
@@ -207,57 +221,69 @@ func FilterToExpression(f *filters.Filter, formatter func(string) string) string
 		}
 		var conditions []string
 		for _, item := range f.BoolFilter.Filters {
-			if expr := FilterToExpression(item, formatter); expr != "" {
-				conditions = append(conditions, "("+FilterToExpression(item, formatter)+")")
+			expr, err := FilterToExpression(item, formatter)
+			if err != nil {
+				return "", err
+			}
+			if expr != "" {
+				conditions = append(conditions, "("+expr+")")
 			}
 		}
-		return strings.Join(conditions, " "+keyword+" ")
+		return strings.Join(conditions, " "+keyword+" "), nil
 	}
 
 	if f.TermStringFilter != nil {
-		return fmt.Sprintf(`"%s" IN %s`, f.TermStringFilter.Value, formatter(f.TermStringFilter.Key))
+		return fmt.Sprintf(`"%s" IN %s`, f.TermStringFilter.Value, formatter(f.TermStringFilter.Key)), nil
 	}
 
 	if f.TermInt64Filter != nil {
-		return fmt.Sprintf(`%d IN %s`, f.TermInt64Filter.Value, formatter(f.TermInt64Filter.Key))
+		return fmt.Sprintf(`%d IN %s`, f.TermInt64Filter.Value, formatter(f.TermInt64Filter.Key)), nil
 	}
 
 	if f.TermBoolFilter != nil {
-		return fmt.Sprintf(`%s IN %s`, strconv.FormatBool(f.TermBoolFilter.Value), formatter(f.TermBoolFilter.Key))
+		return fmt.Sprintf(`%s IN %s`, strconv.FormatBool(f.TermBoolFilter.Value), formatter(f.TermBoolFilter.Key)), nil
 	}
 
 	if f.GtInt64Filter != nil {
-		return fmt.Sprintf("%v > %v", formatter(f.GtInt64Filter.Key), f.GtInt64Filter.Value)
+		return fmt.Sprintf("%v > %v", formatter(f.GtInt64Filter.Key), f.GtInt64Filter.Value), nil
 	}
 
 	if f.LtInt64Filter != nil {
-		return fmt.Sprintf("%v < %v", formatter(f.LtInt64Filter.Key), f.LtInt64Filter.Value)
+		return fmt.Sprintf("%v < %v", formatter(f.LtInt64Filter.Key), f.LtInt64Filter.Value), nil
 	}
 
 	if f.GteInt64Filter != nil {
-		return fmt.Sprintf("%v >= %v", formatter(f.GteInt64Filter.Key), f.GteInt64Filter.Value)
+		return fmt.Sprintf("%v >= %v", formatter(f.GteInt64Filter.Key), f.GteInt64Filter.Value), nil
 	}
 
 	if f.LteInt64Filter != nil {
-		return fmt.Sprintf("%v <= %v", formatter(f.LteInt64Filter.Key), f.LteInt64Filter.Value)
+		return fmt.Sprintf("%v <= %v", formatter(f.LteInt64Filter.Key), f.LteInt64Filter.Value), nil
 	}
 
 	if f.RegexFilter != nil {
-		return fmt.Sprintf(`%s MATCHES "%s"`, formatter(f.RegexFilter.Key), strings.Replace(f.RegexFilter.Value, `\`, `\\`, -1))
+		return fmt.Sprintf(`%s MATCHES "%s"`, formatter(f.RegexFilter.Key), strings.Replace(f.RegexFilter.Value, `\`, `\\`, -1)), nil
 	}
 
 	if f.NullFilter != nil {
-		return fmt.Sprintf("%s is NULL", formatter(f.NullFilter.Key))
+		return fmt.Sprintf("%s is NULL", formatter(f.NullFilter.Key)), nil
 	}
 
 	if f.IPV4RangeFilter != nil {
-		// ignore the error at this point it should have been catched earlier
-		regex, _ := common.IPV4CIDRToRegex(f.IPV4RangeFilter.Value)
+		return ipv4RangeExpression(formatter(f.IPV4RangeFilter.Key), f.IPV4RangeFilter.Value), nil
+	}
 
-		return fmt.Sprintf(`%s MATCHES "%s"`, formatter(f.IPV4RangeFilter.Key), strings.Replace(regex, `\`, `\\`, -1))
+	if f.IPV6RangeFilter != nil {
+		return "", fmt.Errorf("IPv6 range filter on %s not supported by OrientDB", f.IPV6RangeFilter.Key)
 	}
 
-	return ""
+	if f.CIDRFilter != nil {
+		if ip, _, err := net.ParseCIDR(f.CIDRFilter.Value); err == nil && ip.To4() != nil {
+			return ipv4RangeExpression(formatter(f.CIDRFilter.Key), f.CIDRFilter.Value), nil
+		}
+		return "", fmt.Errorf("IPv6 CIDR filter on %s not supported by OrientDB", f.CIDRFilter.Key)
+	}
+
+	return "", nil
 }
 
 // NewClient creates a new OrientDB database client
@@ -564,7 +590,11 @@ func (c *Client) Query(obj string, query *filters.SearchQuery, result interface{
 	filter := query.Filter
 
 	sql := "SELECT FROM " + obj
-	if conditional := FilterToExpression(filter, nil); conditional != "" {
+	conditional, err := FilterToExpression(filter, nil)
+	if err != nil {
+		return err
+	}
+	if conditional != "" {
 		sql += " WHERE " + conditional
 	}
 
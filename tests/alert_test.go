@@ -121,7 +121,7 @@ func TestAlertWebhook(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add alert-ns-webhook", true},
+			{Cmd: "ip netns add alert-ns-webhook", Check: true},
 		},
 
 		setupFunction: func(c *TestContext) error {
@@ -143,7 +143,7 @@ func TestAlertWebhook(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del alert-ns-webhook", true},
+			{Cmd: "ip netns del alert-ns-webhook", Check: true},
 		},
 
 		tearDownFunction: func(c *TestContext) error {
@@ -205,7 +205,7 @@ func TestAlertScript(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add alert-ns-script", true},
+			{Cmd: "ip netns add alert-ns-script", Check: true},
 		},
 
 		setupFunction: func(c *TestContext) error {
@@ -221,7 +221,7 @@ func TestAlertScript(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del alert-ns-script", true},
+			{Cmd: "ip netns del alert-ns-script", Check: true},
 		},
 
 		tearDownFunction: func(c *TestContext) error {
@@ -261,7 +261,7 @@ func TestAlertWithTimer(t *testing.T) {
 		retries: 1,
 
 		setupCmds: []helper.Cmd{
-			{"ip netns add alert-ns-timer", true},
+			{Cmd: "ip netns add alert-ns-timer", Check: true},
 		},
 
 		setupFunction: func(c *TestContext) error {
@@ -282,7 +282,7 @@ func TestAlertWithTimer(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del alert-ns-timer", true},
+			{Cmd: "ip netns del alert-ns-timer", Check: true},
 		},
 
 		tearDownFunction: func(c *TestContext) error {
@@ -333,7 +333,7 @@ func TestMultipleTriggering(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add alert-lo-down", true},
+			{Cmd: "ip netns add alert-lo-down", Check: true},
 		},
 
 		setupFunction: func(c *TestContext) error {
@@ -354,7 +354,7 @@ func TestMultipleTriggering(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del alert-lo-down", true},
+			{Cmd: "ip netns del alert-lo-down", Check: true},
 		},
 
 		tearDownFunction: func(c *TestContext) error {
@@ -367,10 +367,10 @@ func TestMultipleTriggering(t *testing.T) {
 		checks: []CheckFunction{func(c *CheckContext) error {
 			alertNumber := 0
 			cmd := []helper.Cmd{
-				{"ip netns exec alert-lo-down ip l set lo up", true},
+				{Cmd: "ip netns exec alert-lo-down ip l set lo up", Check: true},
 			}
 			downLo := []helper.Cmd{
-				{"ip netns exec alert-lo-down ip l set lo down", true},
+				{Cmd: "ip netns exec alert-lo-down ip l set lo down", Check: true},
 			}
 			for alertNumber < 2 {
 				_, m, err := ws.ReadMessage()
@@ -28,11 +28,11 @@ import (
 	"github.com/skydive-project/skydive/api/client"
 	"github.com/skydive-project/skydive/api/types"
 	g "github.com/skydive-project/skydive/gremlin"
-	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/tests/helper"
 )
 
 func TestAlertAPI(t *testing.T) {
-	client, err := client.NewCrudClientFromConfig(&shttp.AuthenticationOpts{})
+	client, err := client.NewCrudClientFromConfig(helper.AuthenticationOpts())
 	if err != nil {
 		t.Fatal(err.Error())
 	}
@@ -81,7 +81,7 @@ func TestAlertAPI(t *testing.T) {
 }
 
 func TestCaptureAPI(t *testing.T) {
-	client, err := client.NewCrudClientFromConfig(&shttp.AuthenticationOpts{})
+	client, err := client.NewCrudClientFromConfig(helper.AuthenticationOpts())
 	if err != nil {
 		t.Fatal(err.Error())
 	}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	gclient "github.com/skydive-project/skydive/api/client"
+	"github.com/skydive-project/skydive/config"
+	g "github.com/skydive-project/skydive/gremlin"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/tests/helper"
+)
+
+func TestAuthentication(t *testing.T) {
+	if helper.Auth == "" {
+		t.Skip("Authentication not enabled, use -auth basic or -auth keystone")
+	}
+
+	for _, opts := range []*shttp.AuthenticationOpts{
+		{},
+		{Username: helper.AuthUsername, Password: "wrong"},
+		{Token: "invalid"},
+	} {
+		resp, err := gclient.NewGremlinQueryHelper(opts).Request(g.G.V(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected an unauthorized topology query with %+v, got: %s", opts, resp.Status)
+		}
+	}
+
+	analyzer := config.GetStringSlice("analyzers")[0]
+	if ws, err := helper.WSConnectWithOpts(analyzer, 2, nil, &helper.WSOpts{AuthOpts: &shttp.AuthenticationOpts{}}); err == nil {
+		helper.WSClose(ws)
+		t.Error("Unauthenticated websocket connection should be rejected")
+	}
+
+	token, err := helper.Login(helper.AuthUsername, helper.AuthPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gh := gclient.NewGremlinQueryHelper(&shttp.AuthenticationOpts{Token: token})
+	if _, err := gh.GetNodes(g.G.V()); err != nil {
+		t.Errorf("Authenticated topology query failed: %s", err)
+	}
+
+	ws, err := helper.WSConnectWithOpts(analyzer, 5, nil, &helper.WSOpts{AuthOpts: &shttp.AuthenticationOpts{Token: token}})
+	if err != nil {
+		t.Fatalf("Authenticated websocket connection failed: %s", err)
+	}
+	helper.WSClose(ws)
+
+	// the API helpers reuse the token of the test user
+	if _, err := helper.GremlinQuery(t, "G.V().Count()").GetInt(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -27,6 +27,7 @@ package tests
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -40,17 +41,21 @@ import (
 func TestOverview(t *testing.T) {
 	gopath := os.Getenv("GOPATH")
 	scale := gopath + "/src/github.com/skydive-project/skydive/scripts/scale.sh"
-	port := strings.Split(helper.AnalyzerListen, ":")[1]
+	analyzer, err := helper.AnalyzerServiceAddress(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(analyzer.Port)
 
 	os.Setenv("PATH", fmt.Sprintf("%s/bin:%s", gopath, os.Getenv("PATH")))
 	os.Setenv("ANALYZER_PORT", port)
 
 	setupCmds := []helper.Cmd{
-		{fmt.Sprintf("%s start 1 4 2", scale), true},
+		{Cmd: fmt.Sprintf("%s start 1 4 2", scale), Check: true},
 	}
 
 	tearDownCmds := []helper.Cmd{
-		{fmt.Sprintf("%s stop 1 4 2", scale), false},
+		{Cmd: fmt.Sprintf("%s stop 1 4 2", scale), Check: false},
 	}
 
 	helper.ExecCmds(t, setupCmds...)
@@ -33,11 +33,11 @@ import (
 func TestDockerSimple(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"docker run -d -t -i --name test-skydive-docker-simple busybox", false},
+			{Cmd: "docker run -d -t -i --name test-skydive-docker-simple busybox", Check: false},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"docker rm -f test-skydive-docker-simple", false},
+			{Cmd: "docker rm -f test-skydive-docker-simple", Check: false},
 		},
 
 		mode: Replay,
@@ -65,13 +65,13 @@ func TestDockerSimple(t *testing.T) {
 func TestDockerShareNamespace(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"docker run -d -t -i --name test-skydive-docker-share-ns busybox", false},
-			{"docker run -d -t -i --name test-skydive-docker-share-ns2 --net=container:test-skydive-docker-share-ns busybox", false},
+			{Cmd: "docker run -d -t -i --name test-skydive-docker-share-ns busybox", Check: false},
+			{Cmd: "docker run -d -t -i --name test-skydive-docker-share-ns2 --net=container:test-skydive-docker-share-ns busybox", Check: false},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"docker rm -f test-skydive-docker-share-ns", false},
-			{"docker rm -f test-skydive-docker-share-ns2", false},
+			{Cmd: "docker rm -f test-skydive-docker-share-ns", Check: false},
+			{Cmd: "docker rm -f test-skydive-docker-share-ns2", Check: false},
 		},
 
 		mode: Replay,
@@ -98,11 +98,11 @@ func TestDockerShareNamespace(t *testing.T) {
 func TestDockerNetHost(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"docker run -d -t -i --net=host --name test-skydive-docker-net-host busybox", false},
+			{Cmd: "docker run -d -t -i --net=host --name test-skydive-docker-net-host busybox", Check: false},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"docker rm -f test-skydive-docker-net-host", false},
+			{Cmd: "docker rm -f test-skydive-docker-net-host", Check: false},
 		},
 
 		mode: Replay,
@@ -137,11 +137,11 @@ func TestDockerNetHost(t *testing.T) {
 func TestDockerLabels(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"docker run -d -t -i --label a.b.c=123 --label a~b/c@d=456 --name test-skydive-docker-labels busybox", false},
+			{Cmd: "docker run -d -t -i --label a.b.c=123 --label a~b/c@d=456 --name test-skydive-docker-labels busybox", Check: false},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"docker rm -f test-skydive-docker-labels", false},
+			{Cmd: "docker rm -f test-skydive-docker-labels", Check: false},
 		},
 
 		mode: Replay,
@@ -36,7 +36,7 @@ import (
 )
 
 func delTestIndex(name string) error {
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("http://localhost:9200/skydive_%s*", name), nil)
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("http://%s/skydive_%s*", helper.ElasticsearchHost(), name), nil)
 	if err != nil {
 		return err
 	}
@@ -92,7 +92,7 @@ func TestRollingSimple(t *testing.T) {
 	}
 
 	cfg := es.Config{
-		ElasticHost:  "localhost:9200",
+		ElasticHost:  helper.ElasticsearchHost(),
 		EntriesLimit: 10,
 	}
 
@@ -35,22 +35,22 @@ import (
 func TestFlowsEBPF(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-ebpf", true},
+			{Cmd: "ovs-vsctl add-br br-ebpf", Check: true},
 
-			{"ip netns add src-vm", true},
-			{"ip link add src-vm-eth0 type veth peer name ebpf-src-eth0 netns src-vm", true},
-			{"ip link set src-vm-eth0 up", true},
-			{"ip netns exec src-vm ip link set ebpf-src-eth0 up", true},
-			{"ip netns exec src-vm ip address add 169.254.107.33/24 dev ebpf-src-eth0", true},
+			{Cmd: "ip netns add src-vm", Check: true},
+			{Cmd: "ip link add src-vm-eth0 type veth peer name ebpf-src-eth0 netns src-vm", Check: true},
+			{Cmd: "ip link set src-vm-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm ip link set ebpf-src-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm ip address add 169.254.107.33/24 dev ebpf-src-eth0", Check: true},
 
-			{"ip netns add dst-vm", true},
-			{"ip link add dst-vm-eth0 type veth peer name ebpf-dst-eth0 netns dst-vm", true},
-			{"ip link set dst-vm-eth0 up", true},
-			{"ip netns exec dst-vm ip link set ebpf-dst-eth0 up", true},
-			{"ip netns exec dst-vm ip address add 169.254.107.34/24 dev ebpf-dst-eth0", true},
+			{Cmd: "ip netns add dst-vm", Check: true},
+			{Cmd: "ip link add dst-vm-eth0 type veth peer name ebpf-dst-eth0 netns dst-vm", Check: true},
+			{Cmd: "ip link set dst-vm-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-vm ip link set ebpf-dst-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-vm ip address add 169.254.107.34/24 dev ebpf-dst-eth0", Check: true},
 
-			{"ovs-vsctl add-port br-ebpf src-vm-eth0", true},
-			{"ovs-vsctl add-port br-ebpf dst-vm-eth0", true},
+			{Cmd: "ovs-vsctl add-port br-ebpf src-vm-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-ebpf dst-vm-eth0", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -60,11 +60,11 @@ func TestFlowsEBPF(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-ebpf", true},
-			{"ip link del dst-vm-eth0", true},
-			{"ip link del src-vm-eth0", true},
-			{"ip netns del src-vm", true},
-			{"ip netns del dst-vm", true},
+			{Cmd: "ovs-vsctl del-br br-ebpf", Check: true},
+			{Cmd: "ip link del dst-vm-eth0", Check: true},
+			{Cmd: "ip link del src-vm-eth0", Check: true},
+			{Cmd: "ip netns del src-vm", Check: true},
+			{Cmd: "ip netns del dst-vm", Check: true},
 		},
 
 		captures: []TestCapture{
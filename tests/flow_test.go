@@ -46,10 +46,10 @@ import (
 func TestSFlowProbeNode(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-spn", true},
-			{"ovs-vsctl add-port br-spn spn-intf1 -- set interface spn-intf1 type=internal", true},
-			{"ip address add 169.254.33.33/24 dev spn-intf1", true},
-			{"ip link set spn-intf1 up", true},
+			{Cmd: "ovs-vsctl add-br br-spn", Check: true},
+			{Cmd: "ovs-vsctl add-port br-spn spn-intf1 -- set interface spn-intf1 type=internal", Check: true},
+			{Cmd: "ip address add 169.254.33.33/24 dev spn-intf1", Check: true},
+			{Cmd: "ip link set spn-intf1 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -59,7 +59,7 @@ func TestSFlowProbeNode(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-spn", true},
+			{Cmd: "ovs-vsctl del-br br-spn", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -93,12 +93,12 @@ func TestSFlowProbeNode(t *testing.T) {
 func TestSFlowNodeTIDOvsInternalNetNS(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-sntoin", true},
-			{"ovs-vsctl add-port br-sntoin sntoin-intf1 -- set interface sntoin-intf1 type=internal", true},
-			{"ip netns add sntoin-vm1", true},
-			{"ip link set sntoin-intf1 netns sntoin-vm1", true},
-			{"ip netns exec sntoin-vm1 ip address add 169.254.33.33/24 dev sntoin-intf1", true},
-			{"ip netns exec sntoin-vm1 ip link set sntoin-intf1 up", true},
+			{Cmd: "ovs-vsctl add-br br-sntoin", Check: true},
+			{Cmd: "ovs-vsctl add-port br-sntoin sntoin-intf1 -- set interface sntoin-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add sntoin-vm1", Check: true},
+			{Cmd: "ip link set sntoin-intf1 netns sntoin-vm1", Check: true},
+			{Cmd: "ip netns exec sntoin-vm1 ip address add 169.254.33.33/24 dev sntoin-intf1", Check: true},
+			{Cmd: "ip netns exec sntoin-vm1 ip link set sntoin-intf1 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -108,8 +108,8 @@ func TestSFlowNodeTIDOvsInternalNetNS(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del sntoin-vm1", true},
-			{"ovs-vsctl del-br br-sntoin", true},
+			{Cmd: "ip netns del sntoin-vm1", Check: true},
+			{Cmd: "ovs-vsctl del-br br-sntoin", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -143,30 +143,30 @@ func TestSFlowNodeTIDOvsInternalNetNS(t *testing.T) {
 func TestSFlowTwoNodeTID(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-stnt1", true},
-			{"ovs-vsctl add-port br-stnt1 stnt-intf1 -- set interface stnt-intf1 type=internal", true},
-			{"ip netns add stnt-vm1", true},
-			{"ip link set stnt-intf1 netns stnt-vm1", true},
-			{"ip netns exec stnt-vm1 ip address add 169.254.33.33/24 dev stnt-intf1", true},
-			{"ip netns exec stnt-vm1 ip link set stnt-intf1 up", true},
-
-			{"ovs-vsctl add-br br-stnt2", true},
-			{"ovs-vsctl add-port br-stnt2 stnt-intf2 -- set interface stnt-intf2 type=internal", true},
-			{"ip netns add stnt-vm2", true},
-			{"ip link set stnt-intf2 netns stnt-vm2", true},
-			{"ip netns exec stnt-vm2 ip address add 169.254.33.34/24 dev stnt-intf2", true},
-			{"ip netns exec stnt-vm2 ip link set stnt-intf2 up", true},
+			{Cmd: "ovs-vsctl add-br br-stnt1", Check: true},
+			{Cmd: "ovs-vsctl add-port br-stnt1 stnt-intf1 -- set interface stnt-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add stnt-vm1", Check: true},
+			{Cmd: "ip link set stnt-intf1 netns stnt-vm1", Check: true},
+			{Cmd: "ip netns exec stnt-vm1 ip address add 169.254.33.33/24 dev stnt-intf1", Check: true},
+			{Cmd: "ip netns exec stnt-vm1 ip link set stnt-intf1 up", Check: true},
+
+			{Cmd: "ovs-vsctl add-br br-stnt2", Check: true},
+			{Cmd: "ovs-vsctl add-port br-stnt2 stnt-intf2 -- set interface stnt-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add stnt-vm2", Check: true},
+			{Cmd: "ip link set stnt-intf2 netns stnt-vm2", Check: true},
+			{Cmd: "ip netns exec stnt-vm2 ip address add 169.254.33.34/24 dev stnt-intf2", Check: true},
+			{Cmd: "ip netns exec stnt-vm2 ip link set stnt-intf2 up", Check: true},
 
 			// interfaces used to link br-stnt1 and br-stnt2 without a patch
-			{"ovs-vsctl add-port br-stnt1 stnt-link1 -- set interface stnt-link1 type=internal", true},
-			{"ip link set stnt-link1 up", true},
-			{"ovs-vsctl add-port br-stnt2 stnt-link2 -- set interface stnt-link2 type=internal", true},
-			{"ip link set stnt-link2 up", true},
+			{Cmd: "ovs-vsctl add-port br-stnt1 stnt-link1 -- set interface stnt-link1 type=internal", Check: true},
+			{Cmd: "ip link set stnt-link1 up", Check: true},
+			{Cmd: "ovs-vsctl add-port br-stnt2 stnt-link2 -- set interface stnt-link2 type=internal", Check: true},
+			{Cmd: "ip link set stnt-link2 up", Check: true},
 
-			{"brctl addbr br-stnt-link", true},
-			{"ip link set br-stnt-link up", true},
-			{"brctl addif br-stnt-link stnt-link1", true},
-			{"brctl addif br-stnt-link stnt-link2", true},
+			{Cmd: "brctl addbr br-stnt-link", Check: true},
+			{Cmd: "ip link set br-stnt-link up", Check: true},
+			{Cmd: "brctl addif br-stnt-link stnt-link1", Check: true},
+			{Cmd: "brctl addif br-stnt-link stnt-link2", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -176,13 +176,13 @@ func TestSFlowTwoNodeTID(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del stnt-vm1", true},
-			{"ip netns del stnt-vm2", true},
-			{"ovs-vsctl del-br br-stnt1", true},
-			{"ovs-vsctl del-br br-stnt2", true},
-			{"sleep 1", true},
-			{"ip link set br-stnt-link down", true},
-			{"brctl delbr br-stnt-link", true},
+			{Cmd: "ip netns del stnt-vm1", Check: true},
+			{Cmd: "ip netns del stnt-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-stnt1", Check: true},
+			{Cmd: "ovs-vsctl del-br br-stnt2", Check: true},
+			{Cmd: "sleep 1", Check: true},
+			{Cmd: "ip link set br-stnt-link down", Check: true},
+			{Cmd: "brctl delbr br-stnt-link", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -241,21 +241,21 @@ func TestSFlowTwoNodeTID(t *testing.T) {
 func TestBPF(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"brctl addbr br-bpf", true},
-			{"ip link set br-bpf up", true},
-			{"ip netns add bpf-vm1", true},
-			{"ip link add name bpf-vm1-eth0 type veth peer name eth0 netns bpf-vm1", true},
-			{"ip link set bpf-vm1-eth0 up", true},
-			{"ip netns exec bpf-vm1 ip link set eth0 up", true},
-			{"ip netns exec bpf-vm1 ip address add 169.254.66.66/24 dev eth0", true},
-			{"brctl addif br-bpf bpf-vm1-eth0", true},
-
-			{"ip netns add bpf-vm2", true},
-			{"ip link add name bpf-vm2-eth0 type veth peer name eth0 netns bpf-vm2", true},
-			{"ip link set bpf-vm2-eth0 up", true},
-			{"ip netns exec bpf-vm2 ip link set eth0 up", true},
-			{"ip netns exec bpf-vm2 ip address add 169.254.66.67/24 dev eth0", true},
-			{"brctl addif br-bpf bpf-vm2-eth0", true},
+			{Cmd: "brctl addbr br-bpf", Check: true},
+			{Cmd: "ip link set br-bpf up", Check: true},
+			{Cmd: "ip netns add bpf-vm1", Check: true},
+			{Cmd: "ip link add name bpf-vm1-eth0 type veth peer name eth0 netns bpf-vm1", Check: true},
+			{Cmd: "ip link set bpf-vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec bpf-vm1 ip link set eth0 up", Check: true},
+			{Cmd: "ip netns exec bpf-vm1 ip address add 169.254.66.66/24 dev eth0", Check: true},
+			{Cmd: "brctl addif br-bpf bpf-vm1-eth0", Check: true},
+
+			{Cmd: "ip netns add bpf-vm2", Check: true},
+			{Cmd: "ip link add name bpf-vm2-eth0 type veth peer name eth0 netns bpf-vm2", Check: true},
+			{Cmd: "ip link set bpf-vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec bpf-vm2 ip link set eth0 up", Check: true},
+			{Cmd: "ip netns exec bpf-vm2 ip address add 169.254.66.67/24 dev eth0", Check: true},
+			{Cmd: "brctl addif br-bpf bpf-vm2-eth0", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -265,12 +265,12 @@ func TestBPF(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip link set br-bpf down", true},
-			{"brctl delbr br-bpf", true},
-			{"ip link del bpf-vm1-eth0", true},
-			{"ip link del bpf-vm2-eth0", true},
-			{"ip netns del bpf-vm1", true},
-			{"ip netns del bpf-vm2", true},
+			{Cmd: "ip link set br-bpf down", Check: true},
+			{Cmd: "brctl delbr br-bpf", Check: true},
+			{Cmd: "ip link del bpf-vm1-eth0", Check: true},
+			{Cmd: "ip link del bpf-vm2-eth0", Check: true},
+			{Cmd: "ip netns del bpf-vm1", Check: true},
+			{Cmd: "ip netns del bpf-vm2", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -299,21 +299,21 @@ func TestBPF(t *testing.T) {
 func TestPCAPProbe(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"brctl addbr br-pp", true},
-			{"ip link set br-pp up", true},
-			{"ip netns add pp-vm1", true},
-			{"ip link add name pp-vm1-eth0 type veth peer name eth0 netns pp-vm1", true},
-			{"ip link set pp-vm1-eth0 up", true},
-			{"ip netns exec pp-vm1 ip link set eth0 up", true},
-			{"ip netns exec pp-vm1 ip address add 169.254.66.66/24 dev eth0", true},
-			{"brctl addif br-pp pp-vm1-eth0", true},
-
-			{"ip netns add pp-vm2", true},
-			{"ip link add name pp-vm2-eth0 type veth peer name eth0 netns pp-vm2", true},
-			{"ip link set pp-vm2-eth0 up", true},
-			{"ip netns exec pp-vm2 ip link set eth0 up", true},
-			{"ip netns exec pp-vm2 ip address add 169.254.66.67/24 dev eth0", true},
-			{"brctl addif br-pp pp-vm2-eth0", true},
+			{Cmd: "brctl addbr br-pp", Check: true},
+			{Cmd: "ip link set br-pp up", Check: true},
+			{Cmd: "ip netns add pp-vm1", Check: true},
+			{Cmd: "ip link add name pp-vm1-eth0 type veth peer name eth0 netns pp-vm1", Check: true},
+			{Cmd: "ip link set pp-vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec pp-vm1 ip link set eth0 up", Check: true},
+			{Cmd: "ip netns exec pp-vm1 ip address add 169.254.66.66/24 dev eth0", Check: true},
+			{Cmd: "brctl addif br-pp pp-vm1-eth0", Check: true},
+
+			{Cmd: "ip netns add pp-vm2", Check: true},
+			{Cmd: "ip link add name pp-vm2-eth0 type veth peer name eth0 netns pp-vm2", Check: true},
+			{Cmd: "ip link set pp-vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec pp-vm2 ip link set eth0 up", Check: true},
+			{Cmd: "ip netns exec pp-vm2 ip address add 169.254.66.67/24 dev eth0", Check: true},
+			{Cmd: "brctl addif br-pp pp-vm2-eth0", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -323,12 +323,12 @@ func TestPCAPProbe(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip link set br-pp down", true},
-			{"brctl delbr br-pp", true},
-			{"ip link del pp-vm1-eth0", true},
-			{"ip link del pp-vm2-eth0", true},
-			{"ip netns del pp-vm1", true},
-			{"ip netns del pp-vm2", true},
+			{Cmd: "ip link set br-pp down", Check: true},
+			{Cmd: "brctl delbr br-pp", Check: true},
+			{Cmd: "ip link del pp-vm1-eth0", Check: true},
+			{Cmd: "ip link del pp-vm2-eth0", Check: true},
+			{Cmd: "ip netns del pp-vm1", Check: true},
+			{Cmd: "ip netns del pp-vm2", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -362,19 +362,19 @@ func TestPCAPProbe(t *testing.T) {
 func TestSFlowSrcDstPath(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-ssdp", true},
+			{Cmd: "ovs-vsctl add-br br-ssdp", Check: true},
 
-			{"ovs-vsctl add-port br-ssdp ssdp-intf1 -- set interface ssdp-intf1 type=internal", true},
-			{"ip netns add ssdp-vm1", true},
-			{"ip link set ssdp-intf1 netns ssdp-vm1", true},
-			{"ip netns exec ssdp-vm1 ip address add 169.254.33.33/24 dev ssdp-intf1", true},
-			{"ip netns exec ssdp-vm1 ip link set ssdp-intf1 up", true},
+			{Cmd: "ovs-vsctl add-port br-ssdp ssdp-intf1 -- set interface ssdp-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add ssdp-vm1", Check: true},
+			{Cmd: "ip link set ssdp-intf1 netns ssdp-vm1", Check: true},
+			{Cmd: "ip netns exec ssdp-vm1 ip address add 169.254.33.33/24 dev ssdp-intf1", Check: true},
+			{Cmd: "ip netns exec ssdp-vm1 ip link set ssdp-intf1 up", Check: true},
 
-			{"ovs-vsctl add-port br-ssdp ssdp-intf2 -- set interface ssdp-intf2 type=internal", true},
-			{"ip netns add ssdp-vm2", true},
-			{"ip link set ssdp-intf2 netns ssdp-vm2", true},
-			{"ip netns exec ssdp-vm2 ip address add 169.254.33.34/24 dev ssdp-intf2", true},
-			{"ip netns exec ssdp-vm2 ip link set ssdp-intf2 up", true},
+			{Cmd: "ovs-vsctl add-port br-ssdp ssdp-intf2 -- set interface ssdp-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add ssdp-vm2", Check: true},
+			{Cmd: "ip link set ssdp-intf2 netns ssdp-vm2", Check: true},
+			{Cmd: "ip netns exec ssdp-vm2 ip address add 169.254.33.34/24 dev ssdp-intf2", Check: true},
+			{Cmd: "ip netns exec ssdp-vm2 ip link set ssdp-intf2 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -384,9 +384,9 @@ func TestSFlowSrcDstPath(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del ssdp-vm1", true},
-			{"ip netns del ssdp-vm2", true},
-			{"ovs-vsctl del-br br-ssdp", true},
+			{Cmd: "ip netns del ssdp-vm1", Check: true},
+			{Cmd: "ip netns del ssdp-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-ssdp", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -438,10 +438,10 @@ func TestSFlowSrcDstPath(t *testing.T) {
 func TestFlowGremlin(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-fg", true},
-			{"ovs-vsctl add-port br-fg fg-intf1 -- set interface fg-intf1 type=internal", true},
-			{"ip address add 169.254.33.33/24 dev fg-intf1", true},
-			{"ip link set fg-intf1 up", true},
+			{Cmd: "ovs-vsctl add-br br-fg", Check: true},
+			{Cmd: "ovs-vsctl add-port br-fg fg-intf1 -- set interface fg-intf1 type=internal", Check: true},
+			{Cmd: "ip address add 169.254.33.33/24 dev fg-intf1", Check: true},
+			{Cmd: "ip link set fg-intf1 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -449,7 +449,7 @@ func TestFlowGremlin(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-fg", true},
+			{Cmd: "ovs-vsctl del-br br-fg", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -516,19 +516,19 @@ func TestFlowGremlin(t *testing.T) {
 func TestFlowMetrics(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-fm", true},
+			{Cmd: "ovs-vsctl add-br br-fm", Check: true},
 
-			{"ovs-vsctl add-port br-fm fm-intf1 -- set interface fm-intf1 type=internal", true},
-			{"ip netns add fm-vm1", true},
-			{"ip link set fm-intf1 netns fm-vm1", true},
-			{"ip netns exec fm-vm1 ip address add 169.254.33.33/24 dev fm-intf1", true},
-			{"ip netns exec fm-vm1 ip link set fm-intf1 up", true},
+			{Cmd: "ovs-vsctl add-port br-fm fm-intf1 -- set interface fm-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add fm-vm1", Check: true},
+			{Cmd: "ip link set fm-intf1 netns fm-vm1", Check: true},
+			{Cmd: "ip netns exec fm-vm1 ip address add 169.254.33.33/24 dev fm-intf1", Check: true},
+			{Cmd: "ip netns exec fm-vm1 ip link set fm-intf1 up", Check: true},
 
-			{"ovs-vsctl add-port br-fm fm-intf2 -- set interface fm-intf2 type=internal", true},
-			{"ip netns add fm-vm2", true},
-			{"ip link set fm-intf2 netns fm-vm2", true},
-			{"ip netns exec fm-vm2 ip address add 169.254.33.34/24 dev fm-intf2", true},
-			{"ip netns exec fm-vm2 ip link set fm-intf2 up", true},
+			{Cmd: "ovs-vsctl add-port br-fm fm-intf2 -- set interface fm-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add fm-vm2", Check: true},
+			{Cmd: "ip link set fm-intf2 netns fm-vm2", Check: true},
+			{Cmd: "ip netns exec fm-vm2 ip address add 169.254.33.34/24 dev fm-intf2", Check: true},
+			{Cmd: "ip netns exec fm-vm2 ip link set fm-intf2 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -539,9 +539,9 @@ func TestFlowMetrics(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del fm-vm1", true},
-			{"ip netns del fm-vm2", true},
-			{"ovs-vsctl del-br br-fm", true},
+			{Cmd: "ip netns del fm-vm1", Check: true},
+			{Cmd: "ip netns del fm-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-fm", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -665,19 +665,19 @@ func TestFlowMetrics(t *testing.T) {
 func TestFlowMetricsStep(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-fms", true},
+			{Cmd: "ovs-vsctl add-br br-fms", Check: true},
 
-			{"ovs-vsctl add-port br-fms fms-intf1 -- set interface fms-intf1 type=internal", true},
-			{"ip netns add fms-vm1", true},
-			{"ip link set fms-intf1 netns fms-vm1", true},
-			{"ip netns exec fms-vm1 ip address add 169.254.33.33/24 dev fms-intf1", true},
-			{"ip netns exec fms-vm1 ip link set fms-intf1 up", true},
+			{Cmd: "ovs-vsctl add-port br-fms fms-intf1 -- set interface fms-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add fms-vm1", Check: true},
+			{Cmd: "ip link set fms-intf1 netns fms-vm1", Check: true},
+			{Cmd: "ip netns exec fms-vm1 ip address add 169.254.33.33/24 dev fms-intf1", Check: true},
+			{Cmd: "ip netns exec fms-vm1 ip link set fms-intf1 up", Check: true},
 
-			{"ovs-vsctl add-port br-fms fms-intf2 -- set interface fms-intf2 type=internal", true},
-			{"ip netns add fms-vm2", true},
-			{"ip link set fms-intf2 netns fms-vm2", true},
-			{"ip netns exec fms-vm2 ip address add 169.254.33.34/24 dev fms-intf2", true},
-			{"ip netns exec fms-vm2 ip link set fms-intf2 up", true},
+			{Cmd: "ovs-vsctl add-port br-fms fms-intf2 -- set interface fms-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add fms-vm2", Check: true},
+			{Cmd: "ip link set fms-intf2 netns fms-vm2", Check: true},
+			{Cmd: "ip netns exec fms-vm2 ip address add 169.254.33.34/24 dev fms-intf2", Check: true},
+			{Cmd: "ip netns exec fms-vm2 ip link set fms-intf2 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -688,9 +688,9 @@ func TestFlowMetricsStep(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del fms-vm1", true},
-			{"ip netns del fms-vm2", true},
-			{"ovs-vsctl del-br br-fms", true},
+			{Cmd: "ip netns del fms-vm1", Check: true},
+			{Cmd: "ip netns del fms-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-fms", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -764,19 +764,19 @@ func TestFlowMetricsStep(t *testing.T) {
 func TestFlowHops(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-fh", true},
+			{Cmd: "ovs-vsctl add-br br-fh", Check: true},
 
-			{"ovs-vsctl add-port br-fh fh-intf1 -- set interface fh-intf1 type=internal", true},
-			{"ip netns add fh-vm1", true},
-			{"ip link set fh-intf1 netns fh-vm1", true},
-			{"ip netns exec fh-vm1 ip address add 169.254.33.33/24 dev fh-intf1", true},
-			{"ip netns exec fh-vm1 ip link set fh-intf1 up", true},
+			{Cmd: "ovs-vsctl add-port br-fh fh-intf1 -- set interface fh-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add fh-vm1", Check: true},
+			{Cmd: "ip link set fh-intf1 netns fh-vm1", Check: true},
+			{Cmd: "ip netns exec fh-vm1 ip address add 169.254.33.33/24 dev fh-intf1", Check: true},
+			{Cmd: "ip netns exec fh-vm1 ip link set fh-intf1 up", Check: true},
 
-			{"ovs-vsctl add-port br-fh fh-intf2 -- set interface fh-intf2 type=internal", true},
-			{"ip netns add fh-vm2", true},
-			{"ip link set fh-intf2 netns fh-vm2", true},
-			{"ip netns exec fh-vm2 ip address add 169.254.33.34/24 dev fh-intf2", true},
-			{"ip netns exec fh-vm2 ip link set fh-intf2 up", true},
+			{Cmd: "ovs-vsctl add-port br-fh fh-intf2 -- set interface fh-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add fh-vm2", Check: true},
+			{Cmd: "ip link set fh-intf2 netns fh-vm2", Check: true},
+			{Cmd: "ip netns exec fh-vm2 ip address add 169.254.33.34/24 dev fh-intf2", Check: true},
+			{Cmd: "ip netns exec fh-vm2 ip link set fh-intf2 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -787,9 +787,9 @@ func TestFlowHops(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del fh-vm1", true},
-			{"ip netns del fh-vm2", true},
-			{"ovs-vsctl del-br br-fh", true},
+			{Cmd: "ip netns del fh-vm1", Check: true},
+			{Cmd: "ip netns del fh-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-fh", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -858,19 +858,19 @@ func TestIPv6FlowHopsIPv6(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-ipv6fh", true},
+			{Cmd: "ovs-vsctl add-br br-ipv6fh", Check: true},
 
-			{"ovs-vsctl add-port br-ipv6fh ipv6fh-intf1 -- set interface ipv6fh-intf1 type=internal", true},
-			{"ip netns add ipv6fh-vm1", true},
-			{"ip link set ipv6fh-intf1 netns ipv6fh-vm1", true},
-			{"ip netns exec ipv6fh-vm1 ip address add fd49:37c8:5229::1/48 dev ipv6fh-intf1", true},
-			{"ip netns exec ipv6fh-vm1 ip link set ipv6fh-intf1 up", true},
+			{Cmd: "ovs-vsctl add-port br-ipv6fh ipv6fh-intf1 -- set interface ipv6fh-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add ipv6fh-vm1", Check: true},
+			{Cmd: "ip link set ipv6fh-intf1 netns ipv6fh-vm1", Check: true},
+			{Cmd: "ip netns exec ipv6fh-vm1 ip address add fd49:37c8:5229::1/48 dev ipv6fh-intf1", Check: true},
+			{Cmd: "ip netns exec ipv6fh-vm1 ip link set ipv6fh-intf1 up", Check: true},
 
-			{"ovs-vsctl add-port br-ipv6fh ipv6fh-intf2 -- set interface ipv6fh-intf2 type=internal", true},
-			{"ip netns add ipv6fh-vm2", true},
-			{"ip link set ipv6fh-intf2 netns ipv6fh-vm2", true},
-			{"ip netns exec ipv6fh-vm2 ip address add fd49:37c8:5229::2/48 dev ipv6fh-intf2", true},
-			{"ip netns exec ipv6fh-vm2 ip link set ipv6fh-intf2 up", true},
+			{Cmd: "ovs-vsctl add-port br-ipv6fh ipv6fh-intf2 -- set interface ipv6fh-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add ipv6fh-vm2", Check: true},
+			{Cmd: "ip link set ipv6fh-intf2 netns ipv6fh-vm2", Check: true},
+			{Cmd: "ip netns exec ipv6fh-vm2 ip address add fd49:37c8:5229::2/48 dev ipv6fh-intf2", Check: true},
+			{Cmd: "ip netns exec ipv6fh-vm2 ip link set ipv6fh-intf2 up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -881,9 +881,9 @@ func TestIPv6FlowHopsIPv6(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del ipv6fh-vm1", true},
-			{"ip netns del ipv6fh-vm2", true},
-			{"ovs-vsctl del-br br-ipv6fh", true},
+			{Cmd: "ip netns del ipv6fh-vm1", Check: true},
+			{Cmd: "ip netns del ipv6fh-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-ipv6fh", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -953,21 +953,21 @@ func TestICMP(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-icmp", true},
+			{Cmd: "ovs-vsctl add-br br-icmp", Check: true},
 
-			{"ovs-vsctl add-port br-icmp icmp-intf1 -- set interface icmp-intf1 type=internal", true},
-			{"ip netns add icmp-vm1", true},
-			{"ip link set icmp-intf1 netns icmp-vm1", true},
-			{"ip netns exec icmp-vm1 ip address add 10.0.0.1/24 dev icmp-intf1", true},
-			{"ip netns exec icmp-vm1 ip address add fd49:37c8:5229::1/48 dev icmp-intf1", true},
-			{"ip netns exec icmp-vm1 ip link set icmp-intf1 up", true},
+			{Cmd: "ovs-vsctl add-port br-icmp icmp-intf1 -- set interface icmp-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add icmp-vm1", Check: true},
+			{Cmd: "ip link set icmp-intf1 netns icmp-vm1", Check: true},
+			{Cmd: "ip netns exec icmp-vm1 ip address add 10.0.0.1/24 dev icmp-intf1", Check: true},
+			{Cmd: "ip netns exec icmp-vm1 ip address add fd49:37c8:5229::1/48 dev icmp-intf1", Check: true},
+			{Cmd: "ip netns exec icmp-vm1 ip link set icmp-intf1 up", Check: true},
 
-			{"ovs-vsctl add-port br-icmp icmp-intf2 -- set interface icmp-intf2 type=internal", true},
-			{"ip netns add icmp-vm2", true},
-			{"ip link set icmp-intf2 netns icmp-vm2", true},
-			{"ip netns exec icmp-vm2 ip address add 10.0.0.2/24 dev icmp-intf2", true},
-			{"ip netns exec icmp-vm2 ip address add fd49:37c8:5229::2/48 dev icmp-intf2", true},
-			{"ip netns exec icmp-vm2 ip link set icmp-intf2 up", true},
+			{Cmd: "ovs-vsctl add-port br-icmp icmp-intf2 -- set interface icmp-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add icmp-vm2", Check: true},
+			{Cmd: "ip link set icmp-intf2 netns icmp-vm2", Check: true},
+			{Cmd: "ip netns exec icmp-vm2 ip address add 10.0.0.2/24 dev icmp-intf2", Check: true},
+			{Cmd: "ip netns exec icmp-vm2 ip address add fd49:37c8:5229::2/48 dev icmp-intf2", Check: true},
+			{Cmd: "ip netns exec icmp-vm2 ip link set icmp-intf2 up", Check: true},
 		},
 
 		injections: []TestInjection{
@@ -988,9 +988,9 @@ func TestICMP(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del icmp-vm1", true},
-			{"ip netns del icmp-vm2", true},
-			{"ovs-vsctl del-br br-icmp", true},
+			{Cmd: "ip netns del icmp-vm1", Check: true},
+			{Cmd: "ip netns del icmp-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-icmp", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1116,45 +1116,45 @@ func testFlowTunnel(t *testing.T, bridge string, tunnelType string, ipv6 bool, I
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"sudo ovs-vsctl add-br " + bridge, true},
+			{Cmd: "sudo ovs-vsctl add-br " + bridge, Check: true},
 
-			{"sudo ip netns add tunnel-vm1", true},
-			{"sudo ip link add tunnel-vm1-eth0 type veth peer name eth0 netns tunnel-vm1", true},
-			{"sudo ip link set tunnel-vm1-eth0 up", true},
+			{Cmd: "sudo ip netns add tunnel-vm1", Check: true},
+			{Cmd: "sudo ip link add tunnel-vm1-eth0 type veth peer name eth0 netns tunnel-vm1", Check: true},
+			{Cmd: "sudo ip link set tunnel-vm1-eth0 up", Check: true},
 
-			{"sudo ip netns exec tunnel-vm1 ip link set eth0 up", true},
-			{fmt.Sprintf("sudo ip netns exec tunnel-vm1 ip address add %s%s dev eth0", tunnelIP1, tunnelPrefix), true},
+			{Cmd: "sudo ip netns exec tunnel-vm1 ip link set eth0 up", Check: true},
+			{Cmd: fmt.Sprintf("sudo ip netns exec tunnel-vm1 ip address add %s%s dev eth0", tunnelIP1, tunnelPrefix), Check: true},
 
-			{"sudo ip netns add tunnel-vm2", true},
-			{"sudo ip link add tunnel-vm2-eth0 type veth peer name eth0 netns tunnel-vm2", true},
-			{"sudo ip link set tunnel-vm2-eth0 up", true},
-			{"sudo ip netns exec tunnel-vm2 ip link set eth0 up", true},
-			{fmt.Sprintf("sudo ip netns exec tunnel-vm2 ip address add %s%s dev eth0", tunnelIP2, tunnelPrefix), true},
+			{Cmd: "sudo ip netns add tunnel-vm2", Check: true},
+			{Cmd: "sudo ip link add tunnel-vm2-eth0 type veth peer name eth0 netns tunnel-vm2", Check: true},
+			{Cmd: "sudo ip link set tunnel-vm2-eth0 up", Check: true},
+			{Cmd: "sudo ip netns exec tunnel-vm2 ip link set eth0 up", Check: true},
+			{Cmd: fmt.Sprintf("sudo ip netns exec tunnel-vm2 ip address add %s%s dev eth0", tunnelIP2, tunnelPrefix), Check: true},
 
-			{fmt.Sprintf("sudo ovs-vsctl add-port %s tunnel-vm1-eth0", bridge), true},
-			{fmt.Sprintf("sudo ovs-vsctl add-port %s tunnel-vm2-eth0", bridge), true},
+			{Cmd: fmt.Sprintf("sudo ovs-vsctl add-port %s tunnel-vm1-eth0", bridge), Check: true},
+			{Cmd: fmt.Sprintf("sudo ovs-vsctl add-port %s tunnel-vm2-eth0", bridge), Check: true},
 
-			{tunnel1Add, true},
-			{"sudo ip netns exec tunnel-vm1 ip link set tunnel up", true},
-			{"sudo ip netns exec tunnel-vm1 ip link add name dummy0 type dummy", true},
-			{"sudo ip netns exec tunnel-vm1 ip link set dummy0 up", true},
-			{fmt.Sprintf("sudo ip netns exec tunnel-vm1 ip a add %s%s dev dummy0", IP1, prefix), true},
-			{fmt.Sprintf("sudo ip netns exec tunnel-vm1 ip r add %s dev tunnel", addrRange), true},
+			{Cmd: tunnel1Add, Check: true},
+			{Cmd: "sudo ip netns exec tunnel-vm1 ip link set tunnel up", Check: true},
+			{Cmd: "sudo ip netns exec tunnel-vm1 ip link add name dummy0 type dummy", Check: true},
+			{Cmd: "sudo ip netns exec tunnel-vm1 ip link set dummy0 up", Check: true},
+			{Cmd: fmt.Sprintf("sudo ip netns exec tunnel-vm1 ip a add %s%s dev dummy0", IP1, prefix), Check: true},
+			{Cmd: fmt.Sprintf("sudo ip netns exec tunnel-vm1 ip r add %s dev tunnel", addrRange), Check: true},
 
-			{tunnel2Add, true},
-			{"sudo ip netns exec tunnel-vm2 ip link set tunnel up", true},
-			{"sudo ip netns exec tunnel-vm2 ip link add name dummy0 type dummy", true},
-			{"sudo ip netns exec tunnel-vm2 ip link set dummy0 up", true},
-			{fmt.Sprintf("sudo ip netns exec tunnel-vm2 ip a add %s%s dev dummy0", IP2, prefix), true},
-			{fmt.Sprintf("sudo ip netns exec tunnel-vm2 ip r add %s dev tunnel", addrRange), true},
+			{Cmd: tunnel2Add, Check: true},
+			{Cmd: "sudo ip netns exec tunnel-vm2 ip link set tunnel up", Check: true},
+			{Cmd: "sudo ip netns exec tunnel-vm2 ip link add name dummy0 type dummy", Check: true},
+			{Cmd: "sudo ip netns exec tunnel-vm2 ip link set dummy0 up", Check: true},
+			{Cmd: fmt.Sprintf("sudo ip netns exec tunnel-vm2 ip a add %s%s dev dummy0", IP2, prefix), Check: true},
+			{Cmd: fmt.Sprintf("sudo ip netns exec tunnel-vm2 ip r add %s dev tunnel", addrRange), Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip link del tunnel-vm1-eth0", true},
-			{"ip link del tunnel-vm2-eth0", true},
-			{"ip netns del tunnel-vm1", true},
-			{"ip netns del tunnel-vm2", true},
-			{"ovs-vsctl del-br " + bridge, true},
+			{Cmd: "ip link del tunnel-vm1-eth0", Check: true},
+			{Cmd: "ip link del tunnel-vm2-eth0", Check: true},
+			{Cmd: "ip netns del tunnel-vm1", Check: true},
+			{Cmd: "ip netns del tunnel-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br " + bridge, Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1219,7 +1219,7 @@ func TestReplayCapture(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-rc", true},
+			{Cmd: "ovs-vsctl add-br br-rc", Check: true},
 		},
 
 		settleFunction: func(c *TestContext) error {
@@ -1234,11 +1234,12 @@ func TestReplayCapture(t *testing.T) {
 		},
 
 		setupFunction: func(c *TestContext) error {
-			return helper.SendPCAPFile("pcaptraces/eth-ip4-arp-dns-req-http-google.pcap", capture.PCAPSocket)
+			// the pcapsocket probe of the agent only listens in plain TCP
+			return helper.SendPCAPFileWithTransport("pcaptraces/eth-ip4-arp-dns-req-http-google.pcap", capture.PCAPSocket, helper.PCAPTransportTCP)
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-rc", true},
+			{Cmd: "ovs-vsctl del-br br-rc", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1318,28 +1319,28 @@ func TestPcapInject(t *testing.T) {
 func TestFlowVLANSegmentation(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"sudo ovs-vsctl add-br br-vlan", true},
+			{Cmd: "sudo ovs-vsctl add-br br-vlan", Check: true},
 
-			{"sudo ip netns add vlan-vm1", true},
-			{"sudo ip link add vlan-vm1-eth0 type veth peer name eth0 netns vlan-vm1", true},
-			{"sudo ip link set vlan-vm1-eth0 up", true},
+			{Cmd: "sudo ip netns add vlan-vm1", Check: true},
+			{Cmd: "sudo ip link add vlan-vm1-eth0 type veth peer name eth0 netns vlan-vm1", Check: true},
+			{Cmd: "sudo ip link set vlan-vm1-eth0 up", Check: true},
 
-			{"sudo ip netns exec vlan-vm1 ip link set eth0 up", true},
-			{"sudo ip netns exec vlan-vm1 ip link add link eth0 name vlan type vlan id 8", true},
-			{"sudo ip netns exec vlan-vm1 ip address add 172.16.0.1/24 dev vlan", true},
+			{Cmd: "sudo ip netns exec vlan-vm1 ip link set eth0 up", Check: true},
+			{Cmd: "sudo ip netns exec vlan-vm1 ip link add link eth0 name vlan type vlan id 8", Check: true},
+			{Cmd: "sudo ip netns exec vlan-vm1 ip address add 172.16.0.1/24 dev vlan", Check: true},
 
-			{"sudo ip netns add vlan-vm2", true},
-			{"sudo ip link add vlan-vm2-eth0 type veth peer name eth0 netns vlan-vm2", true},
-			{"sudo ip link set vlan-vm2-eth0 up", true},
-			{"sudo ip netns exec vlan-vm2 ip link set eth0 up", true},
-			{"sudo ip netns exec vlan-vm2 ip link add link eth0 name vlan type vlan id 8", true},
-			{"sudo ip netns exec vlan-vm2 ip address add 172.16.0.2/24 dev vlan", true},
+			{Cmd: "sudo ip netns add vlan-vm2", Check: true},
+			{Cmd: "sudo ip link add vlan-vm2-eth0 type veth peer name eth0 netns vlan-vm2", Check: true},
+			{Cmd: "sudo ip link set vlan-vm2-eth0 up", Check: true},
+			{Cmd: "sudo ip netns exec vlan-vm2 ip link set eth0 up", Check: true},
+			{Cmd: "sudo ip netns exec vlan-vm2 ip link add link eth0 name vlan type vlan id 8", Check: true},
+			{Cmd: "sudo ip netns exec vlan-vm2 ip address add 172.16.0.2/24 dev vlan", Check: true},
 
-			{"sudo ovs-vsctl add-port br-vlan vlan-vm1-eth0", true},
-			{"sudo ovs-vsctl add-port br-vlan vlan-vm2-eth0", true},
+			{Cmd: "sudo ovs-vsctl add-port br-vlan vlan-vm1-eth0", Check: true},
+			{Cmd: "sudo ovs-vsctl add-port br-vlan vlan-vm2-eth0", Check: true},
 
-			{"sudo ip netns exec vlan-vm1 ip l set vlan up", true},
-			{"sudo ip netns exec vlan-vm2 ip l set vlan up", true},
+			{Cmd: "sudo ip netns exec vlan-vm1 ip l set vlan up", Check: true},
+			{Cmd: "sudo ip netns exec vlan-vm2 ip l set vlan up", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -1349,11 +1350,11 @@ func TestFlowVLANSegmentation(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"sudo ip link del vlan-vm1-eth0", true},
-			{"sudo ip link del vlan-vm2-eth0", true},
-			{"sudo ip netns del vlan-vm1", true},
-			{"sudo ip netns del vlan-vm2", true},
-			{"sudo ovs-vsctl del-br br-vlan", true},
+			{Cmd: "sudo ip link del vlan-vm1-eth0", Check: true},
+			{Cmd: "sudo ip link del vlan-vm2-eth0", Check: true},
+			{Cmd: "sudo ip netns del vlan-vm1", Check: true},
+			{Cmd: "sudo ip netns del vlan-vm2", Check: true},
+			{Cmd: "sudo ovs-vsctl del-br br-vlan", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1398,39 +1399,39 @@ func TestFlowVLANSegmentation(t *testing.T) {
 func TestSort(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-int", true},
+			{Cmd: "ovs-vsctl add-br br-int", Check: true},
 
-			{"ip netns add dst-vm", true},
-			{"ip link add dst-vm-eth0 type veth peer name dst-eth0 netns dst-vm", true},
-			{"ip link set dst-vm-eth0 up", true},
-			{"ip netns exec dst-vm ip link set dst-eth0 up", true},
-			{"ip netns exec dst-vm ip address add 169.254.34.33/24 dev dst-eth0", true},
+			{Cmd: "ip netns add dst-vm", Check: true},
+			{Cmd: "ip link add dst-vm-eth0 type veth peer name dst-eth0 netns dst-vm", Check: true},
+			{Cmd: "ip link set dst-vm-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-vm ip link set dst-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-vm ip address add 169.254.34.33/24 dev dst-eth0", Check: true},
 
-			{"ip netns add src-vm1", true},
-			{"ip link add src-vm1-eth0 type veth peer name src1-eth0 netns src-vm1", true},
-			{"ip link set src-vm1-eth0 up", true},
-			{"ip netns exec src-vm1 ip link set src1-eth0 up", true},
-			{"ip netns exec src-vm1 ip address add 169.254.34.34/24 dev src1-eth0", true},
+			{Cmd: "ip netns add src-vm1", Check: true},
+			{Cmd: "ip link add src-vm1-eth0 type veth peer name src1-eth0 netns src-vm1", Check: true},
+			{Cmd: "ip link set src-vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm1 ip link set src1-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm1 ip address add 169.254.34.34/24 dev src1-eth0", Check: true},
 
-			{"ip netns add src-vm2", true},
-			{"ip link add src-vm2-eth0 type veth peer name src2-eth0 netns src-vm2", true},
-			{"ip link set src-vm2-eth0 up", true},
-			{"ip netns exec src-vm2 ip link set src2-eth0 up", true},
-			{"ip netns exec src-vm2 ip address add 169.254.34.35/24 dev src2-eth0", true},
+			{Cmd: "ip netns add src-vm2", Check: true},
+			{Cmd: "ip link add src-vm2-eth0 type veth peer name src2-eth0 netns src-vm2", Check: true},
+			{Cmd: "ip link set src-vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm2 ip link set src2-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm2 ip address add 169.254.34.35/24 dev src2-eth0", Check: true},
 
-			{"ovs-vsctl add-port br-int dst-vm-eth0", true},
-			{"ovs-vsctl add-port br-int src-vm1-eth0", true},
-			{"ovs-vsctl add-port br-int src-vm2-eth0", true},
+			{Cmd: "ovs-vsctl add-port br-int dst-vm-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-int src-vm1-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-int src-vm2-eth0", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-int", true},
-			{"ip link del dst-vm-eth0", true},
-			{"ip link del src-vm1-eth0", true},
-			{"ip link del src-vm2-eth0", true},
-			{"ip netns del dst-vm", true},
-			{"ip netns del src-vm1", true},
-			{"ip netns del src-vm2", true},
+			{Cmd: "ovs-vsctl del-br br-int", Check: true},
+			{Cmd: "ip link del dst-vm-eth0", Check: true},
+			{Cmd: "ip link del src-vm1-eth0", Check: true},
+			{Cmd: "ip link del src-vm2-eth0", Check: true},
+			{Cmd: "ip netns del dst-vm", Check: true},
+			{Cmd: "ip netns del src-vm1", Check: true},
+			{Cmd: "ip netns del src-vm2", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1485,29 +1486,29 @@ func TestSort(t *testing.T) {
 func TestFlowSumStep(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-sum", true},
+			{Cmd: "ovs-vsctl add-br br-sum", Check: true},
 
-			{"ip netns add vm1", true},
-			{"ip link add vm1-eth0 type veth peer name intf1 netns vm1", true},
-			{"ip link set vm1-eth0 up", true},
-			{"ip netns exec vm1 ip address add 169.254.34.33/24 dev intf1", true},
-			{"ip netns exec vm1 ip link set intf1 up", true},
+			{Cmd: "ip netns add vm1", Check: true},
+			{Cmd: "ip link add vm1-eth0 type veth peer name intf1 netns vm1", Check: true},
+			{Cmd: "ip link set vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec vm1 ip address add 169.254.34.33/24 dev intf1", Check: true},
+			{Cmd: "ip netns exec vm1 ip link set intf1 up", Check: true},
 
-			{"ip netns add vm2", true},
-			{"ip link add vm2-eth0 type veth peer name intf2 netns vm2", true},
-			{"ip link set vm2-eth0 up", true},
-			{"ip netns exec vm2 ip address add 169.254.34.34/24 dev intf2", true},
-			{"ip netns exec vm2 ip link set intf2 up", true},
+			{Cmd: "ip netns add vm2", Check: true},
+			{Cmd: "ip link add vm2-eth0 type veth peer name intf2 netns vm2", Check: true},
+			{Cmd: "ip link set vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec vm2 ip address add 169.254.34.34/24 dev intf2", Check: true},
+			{Cmd: "ip netns exec vm2 ip link set intf2 up", Check: true},
 
-			{"ip netns add vm3", true},
-			{"ip link add vm3-eth0 type veth peer name intf3 netns vm3", true},
-			{"ip link set vm3-eth0 up", true},
-			{"ip netns exec vm3 ip address add 169.254.34.35/24 dev intf3", true},
-			{"ip netns exec vm3 ip link set intf3 up", true},
+			{Cmd: "ip netns add vm3", Check: true},
+			{Cmd: "ip link add vm3-eth0 type veth peer name intf3 netns vm3", Check: true},
+			{Cmd: "ip link set vm3-eth0 up", Check: true},
+			{Cmd: "ip netns exec vm3 ip address add 169.254.34.35/24 dev intf3", Check: true},
+			{Cmd: "ip netns exec vm3 ip link set intf3 up", Check: true},
 
-			{"ovs-vsctl add-port br-sum vm1-eth0", true},
-			{"ovs-vsctl add-port br-sum vm2-eth0", true},
-			{"ovs-vsctl add-port br-sum vm3-eth0", true},
+			{Cmd: "ovs-vsctl add-port br-sum vm1-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-sum vm2-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-sum vm3-eth0", Check: true},
 		},
 
 		settleFunction: func(c *TestContext) (err error) {
@@ -1530,13 +1531,13 @@ func TestFlowSumStep(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-sum", true},
-			{"ip link del vm1-eth0", true},
-			{"ip link del vm2-eth0", true},
-			{"ip link del vm3-eth0", true},
-			{"ip netns del vm1", true},
-			{"ip netns del vm2", true},
-			{"ip netns del vm3", true},
+			{Cmd: "ovs-vsctl del-br br-sum", Check: true},
+			{Cmd: "ip link del vm1-eth0", Check: true},
+			{Cmd: "ip link del vm2-eth0", Check: true},
+			{Cmd: "ip link del vm3-eth0", Check: true},
+			{Cmd: "ip netns del vm1", Check: true},
+			{Cmd: "ip netns del vm2", Check: true},
+			{Cmd: "ip netns del vm3", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1566,30 +1567,30 @@ func TestFlowSumStep(t *testing.T) {
 func TestFlowCaptureNodeStep(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-fcn", true},
+			{Cmd: "ovs-vsctl add-br br-fcn", Check: true},
 
-			{"ip netns add cns-vm1", true},
-			{"ip link add cns-vm1-eth0 type veth peer name intf1 netns cns-vm1", true},
-			{"ip link set cns-vm1-eth0 up", true},
-			{"ip netns exec cns-vm1 ip link set intf1 up", true},
-			{"ip netns exec cns-vm1 ip address add 169.254.38.33/24 dev intf1", true},
+			{Cmd: "ip netns add cns-vm1", Check: true},
+			{Cmd: "ip link add cns-vm1-eth0 type veth peer name intf1 netns cns-vm1", Check: true},
+			{Cmd: "ip link set cns-vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec cns-vm1 ip link set intf1 up", Check: true},
+			{Cmd: "ip netns exec cns-vm1 ip address add 169.254.38.33/24 dev intf1", Check: true},
 
-			{"ip netns add cns-vm2", true},
-			{"ip link add cns-vm2-eth0 type veth peer name intf2 netns cns-vm2", true},
-			{"ip link set cns-vm2-eth0 up", true},
-			{"ip netns exec cns-vm2 ip link set intf2 up", true},
-			{"ip netns exec cns-vm2 ip address add 169.254.38.34/24 dev intf2", true},
+			{Cmd: "ip netns add cns-vm2", Check: true},
+			{Cmd: "ip link add cns-vm2-eth0 type veth peer name intf2 netns cns-vm2", Check: true},
+			{Cmd: "ip link set cns-vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec cns-vm2 ip link set intf2 up", Check: true},
+			{Cmd: "ip netns exec cns-vm2 ip address add 169.254.38.34/24 dev intf2", Check: true},
 
-			{"ovs-vsctl add-port br-fcn cns-vm1-eth0", true},
-			{"ovs-vsctl add-port br-fcn cns-vm2-eth0", true},
+			{Cmd: "ovs-vsctl add-port br-fcn cns-vm1-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-fcn cns-vm2-eth0", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-fcn", true},
-			{"ip link del cns-vm1-eth0", true},
-			{"ip link del cns-vm2-eth0", true},
-			{"ip netns del cns-vm1", true},
-			{"ip netns del cns-vm2", true},
+			{Cmd: "ovs-vsctl del-br br-fcn", Check: true},
+			{Cmd: "ip link del cns-vm1-eth0", Check: true},
+			{Cmd: "ip link del cns-vm2-eth0", Check: true},
+			{Cmd: "ip netns del cns-vm1", Check: true},
+			{Cmd: "ip netns del cns-vm2", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1626,30 +1627,30 @@ func TestFlowCaptureNodeStep(t *testing.T) {
 func TestFlowsWithShortestPath(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-spt", true},
+			{Cmd: "ovs-vsctl add-br br-spt", Check: true},
 
-			{"ip netns add src-vm", true},
-			{"ip link add src-vm-eth0 type veth peer name spt-src-eth0 netns src-vm", true},
-			{"ip link set src-vm-eth0 up", true},
-			{"ip netns exec src-vm ip link set spt-src-eth0 up", true},
-			{"ip netns exec src-vm ip address add 169.254.37.33/24 dev spt-src-eth0", true},
+			{Cmd: "ip netns add src-vm", Check: true},
+			{Cmd: "ip link add src-vm-eth0 type veth peer name spt-src-eth0 netns src-vm", Check: true},
+			{Cmd: "ip link set src-vm-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm ip link set spt-src-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-vm ip address add 169.254.37.33/24 dev spt-src-eth0", Check: true},
 
-			{"ip netns add dst-vm", true},
-			{"ip link add dst-vm-eth0 type veth peer name spt-dst-eth0 netns dst-vm", true},
-			{"ip link set dst-vm-eth0 up", true},
-			{"ip netns exec dst-vm ip link set spt-dst-eth0 up", true},
-			{"ip netns exec dst-vm ip address add 169.254.37.34/24 dev spt-dst-eth0", true},
+			{Cmd: "ip netns add dst-vm", Check: true},
+			{Cmd: "ip link add dst-vm-eth0 type veth peer name spt-dst-eth0 netns dst-vm", Check: true},
+			{Cmd: "ip link set dst-vm-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-vm ip link set spt-dst-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-vm ip address add 169.254.37.34/24 dev spt-dst-eth0", Check: true},
 
-			{"ovs-vsctl add-port br-spt src-vm-eth0", true},
-			{"ovs-vsctl add-port br-spt dst-vm-eth0", true},
+			{Cmd: "ovs-vsctl add-port br-spt src-vm-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-spt dst-vm-eth0", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-spt", true},
-			{"ip link del dst-vm-eth0", true},
-			{"ip link del src-vm-eth0", true},
-			{"ip netns del src-vm", true},
-			{"ip netns del dst-vm", true},
+			{Cmd: "ovs-vsctl del-br br-spt", Check: true},
+			{Cmd: "ip link del dst-vm-eth0", Check: true},
+			{Cmd: "ip link del src-vm-eth0", Check: true},
+			{Cmd: "ip netns del src-vm", Check: true},
+			{Cmd: "ip netns del dst-vm", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1736,30 +1737,30 @@ func getRawPackets(gh *gclient.GremlinQueryHelper, query g.QueryString) ([]gopac
 func TestRawPackets(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"brctl addbr br-rp", true},
-			{"ip link set br-rp up", true},
-			{"ip netns add rp-vm1", true},
-			{"ip link add name rp-vm1-eth0 type veth peer name eth0 netns rp-vm1", true},
-			{"ip link set rp-vm1-eth0 up", true},
-			{"ip netns exec rp-vm1 ip link set eth0 up", true},
-			{"ip netns exec rp-vm1 ip address add 169.254.122.66/24 dev eth0", true},
-			{"brctl addif br-rp rp-vm1-eth0", true},
-
-			{"ip netns add rp-vm2", true},
-			{"ip link add name rp-vm2-eth0 type veth peer name eth0 netns rp-vm2", true},
-			{"ip link set rp-vm2-eth0 up", true},
-			{"ip netns exec rp-vm2 ip link set eth0 up", true},
-			{"ip netns exec rp-vm2 ip address add 169.254.122.67/24 dev eth0", true},
-			{"brctl addif br-rp rp-vm2-eth0", true},
+			{Cmd: "brctl addbr br-rp", Check: true},
+			{Cmd: "ip link set br-rp up", Check: true},
+			{Cmd: "ip netns add rp-vm1", Check: true},
+			{Cmd: "ip link add name rp-vm1-eth0 type veth peer name eth0 netns rp-vm1", Check: true},
+			{Cmd: "ip link set rp-vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec rp-vm1 ip link set eth0 up", Check: true},
+			{Cmd: "ip netns exec rp-vm1 ip address add 169.254.122.66/24 dev eth0", Check: true},
+			{Cmd: "brctl addif br-rp rp-vm1-eth0", Check: true},
+
+			{Cmd: "ip netns add rp-vm2", Check: true},
+			{Cmd: "ip link add name rp-vm2-eth0 type veth peer name eth0 netns rp-vm2", Check: true},
+			{Cmd: "ip link set rp-vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec rp-vm2 ip link set eth0 up", Check: true},
+			{Cmd: "ip netns exec rp-vm2 ip address add 169.254.122.67/24 dev eth0", Check: true},
+			{Cmd: "brctl addif br-rp rp-vm2-eth0", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip link set br-rp down", true},
-			{"brctl delbr br-rp", true},
-			{"ip link del rp-vm1-eth0", true},
-			{"ip link del rp-vm2-eth0", true},
-			{"ip netns del rp-vm1", true},
-			{"ip netns del rp-vm2", true},
+			{Cmd: "ip link set br-rp down", Check: true},
+			{Cmd: "brctl delbr br-rp", Check: true},
+			{Cmd: "ip link del rp-vm1-eth0", Check: true},
+			{Cmd: "ip link del rp-vm2-eth0", Check: true},
+			{Cmd: "ip netns del rp-vm1", Check: true},
+			{Cmd: "ip netns del rp-vm2", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1831,30 +1832,30 @@ func TestRawPackets(t *testing.T) {
 func TestFlowsWithIpv4Range(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-ipr", true},
+			{Cmd: "ovs-vsctl add-br br-ipr", Check: true},
 
-			{"ip netns add src-ipr", true},
-			{"ip link add src-ipr-eth0 type veth peer name ipr-src-eth0 netns src-ipr", true},
-			{"ip link set src-ipr-eth0 up", true},
-			{"ip netns exec src-ipr ip link set ipr-src-eth0 up", true},
-			{"ip netns exec src-ipr ip address add 169.254.40.33/24 dev ipr-src-eth0", true},
+			{Cmd: "ip netns add src-ipr", Check: true},
+			{Cmd: "ip link add src-ipr-eth0 type veth peer name ipr-src-eth0 netns src-ipr", Check: true},
+			{Cmd: "ip link set src-ipr-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-ipr ip link set ipr-src-eth0 up", Check: true},
+			{Cmd: "ip netns exec src-ipr ip address add 169.254.40.33/24 dev ipr-src-eth0", Check: true},
 
-			{"ip netns add dst-ipr", true},
-			{"ip link add dst-ipr-eth0 type veth peer name ipr-dst-eth0 netns dst-ipr", true},
-			{"ip link set dst-ipr-eth0 up", true},
-			{"ip netns exec dst-ipr ip link set ipr-dst-eth0 up", true},
-			{"ip netns exec dst-ipr ip address add 169.254.40.34/24 dev ipr-dst-eth0", true},
+			{Cmd: "ip netns add dst-ipr", Check: true},
+			{Cmd: "ip link add dst-ipr-eth0 type veth peer name ipr-dst-eth0 netns dst-ipr", Check: true},
+			{Cmd: "ip link set dst-ipr-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-ipr ip link set ipr-dst-eth0 up", Check: true},
+			{Cmd: "ip netns exec dst-ipr ip address add 169.254.40.34/24 dev ipr-dst-eth0", Check: true},
 
-			{"ovs-vsctl add-port br-ipr src-ipr-eth0", true},
-			{"ovs-vsctl add-port br-ipr dst-ipr-eth0", true},
+			{Cmd: "ovs-vsctl add-port br-ipr src-ipr-eth0", Check: true},
+			{Cmd: "ovs-vsctl add-port br-ipr dst-ipr-eth0", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-ipr", true},
-			{"ip link del dst-ipr-eth0", true},
-			{"ip link del src-ipr-eth0", true},
-			{"ip netns del src-ipr", true},
-			{"ip netns del dst-ipr", true},
+			{Cmd: "ovs-vsctl del-br br-ipr", Check: true},
+			{Cmd: "ip link del dst-ipr-eth0", Check: true},
+			{Cmd: "ip link del src-ipr-eth0", Check: true},
+			{Cmd: "ip netns del src-ipr", Check: true},
+			{Cmd: "ip netns del dst-ipr", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1887,14 +1888,14 @@ func TestFlowsWithIpv4Range(t *testing.T) {
 func TestOvsMirror(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-omir", true},
-			{"ovs-vsctl add-port br-omir omir-if1 -- set interface omir-if1 type=internal", true},
-			{"ip address add 169.254.93.33/24 dev omir-if1", true},
-			{"ip link set omir-if1 up", true},
+			{Cmd: "ovs-vsctl add-br br-omir", Check: true},
+			{Cmd: "ovs-vsctl add-port br-omir omir-if1 -- set interface omir-if1 type=internal", Check: true},
+			{Cmd: "ip address add 169.254.93.33/24 dev omir-if1", Check: true},
+			{Cmd: "ip link set omir-if1 up", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-omir", true},
+			{Cmd: "ovs-vsctl del-br br-omir", Check: true},
 		},
 
 		captures: []TestCapture{
@@ -1953,21 +1954,21 @@ func TestOvsMirror(t *testing.T) {
 func TestSFlowCapture(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-sfct", true},
+			{Cmd: "ovs-vsctl add-br br-sfct", Check: true},
 
-			{"ovs-vsctl add-port br-sfct sfct-intf1 -- set interface sfct-intf1 type=internal", true},
-			{"ip netns add sfct-vm1", true},
-			{"ip link set sfct-intf1 netns sfct-vm1", true},
-			{"ip netns exec sfct-vm1 ip address add 169.254.29.11/24 dev sfct-intf1", true},
-			{"ip netns exec sfct-vm1 ip link set sfct-intf1 up", true},
+			{Cmd: "ovs-vsctl add-port br-sfct sfct-intf1 -- set interface sfct-intf1 type=internal", Check: true},
+			{Cmd: "ip netns add sfct-vm1", Check: true},
+			{Cmd: "ip link set sfct-intf1 netns sfct-vm1", Check: true},
+			{Cmd: "ip netns exec sfct-vm1 ip address add 169.254.29.11/24 dev sfct-intf1", Check: true},
+			{Cmd: "ip netns exec sfct-vm1 ip link set sfct-intf1 up", Check: true},
 
-			{"ovs-vsctl add-port br-sfct sfct-intf2 -- set interface sfct-intf2 type=internal", true},
-			{"ip netns add sfct-vm2", true},
-			{"ip link set sfct-intf2 netns sfct-vm2", true},
-			{"ip netns exec sfct-vm2 ip address add 169.254.29.12/24 dev sfct-intf2", true},
-			{"ip netns exec sfct-vm2 ip link set sfct-intf2 up", true},
+			{Cmd: "ovs-vsctl add-port br-sfct sfct-intf2 -- set interface sfct-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add sfct-vm2", Check: true},
+			{Cmd: "ip link set sfct-intf2 netns sfct-vm2", Check: true},
+			{Cmd: "ip netns exec sfct-vm2 ip address add 169.254.29.12/24 dev sfct-intf2", Check: true},
+			{Cmd: "ip netns exec sfct-vm2 ip link set sfct-intf2 up", Check: true},
 
-			{"ovs-vsctl --id=@sflow create sflow agent=lo target=\"127.0.0.1:6343\" header=128 sampling=1 polling=0 -- set bridge br-sfct sflow=@sflow", true},
+			{Cmd: "ovs-vsctl --id=@sflow create sflow agent=lo target=\\\"127.0.0.1:6343\\\" header=128 sampling=1 polling=0 -- set bridge br-sfct sflow=@sflow", Check: true},
 		},
 
 		injections: []TestInjection{{
@@ -1977,9 +1978,9 @@ func TestSFlowCapture(t *testing.T) {
 		}},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del sfct-vm1", true},
-			{"ip netns del sfct-vm2", true},
-			{"ovs-vsctl del-br br-sfct", true},
+			{Cmd: "ip netns del sfct-vm1", Check: true},
+			{Cmd: "ip netns del sfct-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-sfct", Check: true},
 		},
 
 		captures: []TestCapture{
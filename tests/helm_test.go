@@ -39,11 +39,11 @@ func TestHelmInstall(t *testing.T) {
 	test := &Test{
 		retries: 3,
 		setupCmds: []helper.Cmd{
-			{"helm delete --purge " + relName, false},
-			{"helm install --name " + relName + " " + chartPath, true},
+			{Cmd: "helm delete --purge " + relName, Check: false},
+			{Cmd: "helm install --name " + relName + " " + chartPath, Check: true},
 		},
 		tearDownCmds: []helper.Cmd{
-			{"helm delete --purge " + relName, true},
+			{Cmd: "helm delete --purge " + relName, Check: true},
 		},
 		checks: []CheckFunction{
 			func(c *CheckContext) error {
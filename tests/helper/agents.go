@@ -0,0 +1,135 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+// AgentProcess is an additional agent started in standalone mode. Each agent
+// runs in its own process with a generated configuration using a distinct
+// host_id and listen port.
+type AgentProcess struct {
+	Index      int
+	HostID     string
+	Port       int
+	ConfigFile string
+	daemon
+}
+
+// agentParams returns the template parameters of the agent of the given
+// index, the agent 0 being the one running in the test process
+func agentParams(index int) (HelperParams, error) {
+	sa, err := analyzerListenAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := agentPort(index)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	return HelperParams{
+		"HostID":    fmt.Sprintf("%s-agent%d", hostname, index),
+		"AgentAddr": sa.Addr,
+		"AgentPort": port,
+	}, nil
+}
+
+// NewAgentProcess generates the configuration of the agent of the given index,
+// params are added to the parameters of the configuration template
+func NewAgentProcess(conf string, index int, params HelperParams) (*AgentProcess, error) {
+	p, err := agentParams(index)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range params {
+		p[k] = v
+	}
+
+	filename, err := writeConfig(conf, p)
+	if err != nil {
+		return nil, err
+	}
+
+	hostID := p["HostID"].(string)
+	return &AgentProcess{
+		Index:      index,
+		HostID:     hostID,
+		Port:       p["AgentPort"].(int),
+		ConfigFile: filename,
+		daemon:     daemon{name: "agent " + hostID, service: "agent", configFile: filename},
+	}, nil
+}
+
+// Start launches the agent, the output is written to a log file next to
+// the configuration file
+func (a *AgentProcess) Start() error {
+	return a.start()
+}
+
+// Stop terminates the agent and its children, killing them if they don't
+// exit in time. Stopping an agent that is not running is a no-op.
+func (a *AgentProcess) Stop() error {
+	return a.stop()
+}
+
+// StartAgents starts the agents of index 1 to count, the agent of index 0
+// being the one running in the test process. If one of them fails to start,
+// the agents already started are stopped.
+func StartAgents(conf string, count int, params HelperParams) ([]*AgentProcess, error) {
+	var agents []*AgentProcess
+	for i := 1; i <= count; i++ {
+		agent, err := NewAgentProcess(conf, i, params)
+		if err == nil {
+			err = agent.Start()
+		}
+		if err != nil {
+			StopAgents(agents)
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+
+	return agents, nil
+}
+
+// StopAgents stops all the agents, returning the last error
+func StopAgents(agents []*AgentProcess) (err error) {
+	for _, agent := range agents {
+		if e := agent.Stop(); e != nil {
+			logging.GetLogger().Error(e)
+			err = e
+		}
+	}
+	return
+}
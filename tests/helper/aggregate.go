@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"github.com/skydive-project/skydive/flow"
+)
+
+// FlowKeyFunc returns the key of the group of a flow aggregated by
+// AggregateFlows
+type FlowKeyFunc func(f *flow.Flow) string
+
+// ByNodeTID groups the flows by the TID of the node they were captured on
+func ByNodeTID(f *flow.Flow) string {
+	return f.NodeTID
+}
+
+// ByApplication groups the flows by application
+func ByApplication(f *flow.Flow) string {
+	return f.Application
+}
+
+// ByLayersPath groups the flows by their layers, like Ethernet/IPv4/TCP
+func ByLayersPath(f *flow.Flow) string {
+	return f.LayersPath
+}
+
+// ByIPPair groups the flows by their network addresses, as "A -> B". The
+// flows are not reoriented, the flows from B to A being grouped under
+// "B -> A". The flows without network layer are grouped under "".
+func ByIPPair(f *flow.Flow) string {
+	if f.Network == nil {
+		return ""
+	}
+	return f.Network.A + " -> " + f.Network.B
+}
+
+// AggregateFlows groups the flows by key and sums the packets and bytes of
+// each direction, the aggregated metric starting with the first flow and
+// ending with the last one. The flows without metric are counted as empty.
+func AggregateFlows(flows []*flow.Flow, key FlowKeyFunc) map[string]flow.FlowMetric {
+	aggregates := make(map[string]flow.FlowMetric)
+	for _, f := range flows {
+		k := key(f)
+		a, found := aggregates[k]
+
+		if m := f.Metric; m != nil {
+			a.ABPackets += m.ABPackets
+			a.ABBytes += m.ABBytes
+			a.BAPackets += m.BAPackets
+			a.BABytes += m.BABytes
+
+			if !found || m.Start < a.Start {
+				a.Start = m.Start
+			}
+			if m.Last > a.Last {
+				a.Last = m.Last
+			}
+		}
+
+		aggregates[k] = a
+	}
+	return aggregates
+}
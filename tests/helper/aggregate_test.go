@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func aggregateTestFlows() []*flow.Flow {
+	network := func(a, b string) *flow.FlowLayer {
+		return &flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: a, B: b}
+	}
+
+	return []*flow.Flow{
+		{NodeTID: "tid1", Application: "TCP", Network: network("10.0.0.1", "10.0.0.2"),
+			Metric: &flow.FlowMetric{ABPackets: 3, ABBytes: 300, BAPackets: 2, BABytes: 120, Start: 1000, Last: 2000}},
+		{NodeTID: "tid1", Application: "UDP", Network: network("10.0.0.1", "10.0.0.2"),
+			Metric: &flow.FlowMetric{ABPackets: 1, ABBytes: 100, Start: 500, Last: 600}},
+		{NodeTID: "tid2", Application: "TCP", Network: network("10.0.0.2", "10.0.0.1"),
+			Metric: &flow.FlowMetric{ABPackets: 4, ABBytes: 400, BAPackets: 4, BABytes: 400, Start: 1500, Last: 3000}},
+		{NodeTID: "tid2", Application: "ARP"},
+	}
+}
+
+func TestAggregateFlows(t *testing.T) {
+	flows := aggregateTestFlows()
+
+	expected := map[string]flow.FlowMetric{
+		"tid1": {ABPackets: 4, ABBytes: 400, BAPackets: 2, BABytes: 120, Start: 500, Last: 2000},
+		"tid2": {ABPackets: 4, ABBytes: 400, BAPackets: 4, BABytes: 400, Start: 1500, Last: 3000},
+	}
+	if aggregates := AggregateFlows(flows, ByNodeTID); !reflect.DeepEqual(aggregates, expected) {
+		t.Errorf("Expected aggregates by node %+v, got %+v", expected, aggregates)
+	}
+
+	expected = map[string]flow.FlowMetric{
+		"10.0.0.1 -> 10.0.0.2": {ABPackets: 4, ABBytes: 400, BAPackets: 2, BABytes: 120, Start: 500, Last: 2000},
+		"10.0.0.2 -> 10.0.0.1": {ABPackets: 4, ABBytes: 400, BAPackets: 4, BABytes: 400, Start: 1500, Last: 3000},
+		"":                     {},
+	}
+	if aggregates := AggregateFlows(flows, ByIPPair); !reflect.DeepEqual(aggregates, expected) {
+		t.Errorf("Expected aggregates by IP pair %+v, got %+v", expected, aggregates)
+	}
+
+	aggregates := AggregateFlows(flows, ByApplication)
+	if tcp := aggregates["TCP"]; tcp.ABBytes != 700 || tcp.BABytes != 520 {
+		t.Errorf("Expected 700/520 TCP bytes, got %+v", tcp)
+	}
+	if _, found := aggregates["ARP"]; !found || len(aggregates) != 3 {
+		t.Errorf("Expected TCP, UDP and ARP aggregates, got %+v", aggregates)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/logging"
+)
+
+const analyzerReadyTimeout = 30 * time.Second
+
+// AnalyzerProcess is an additional analyzer started in standalone mode. The
+// analyzers listen on consecutive ports after the one of analyzer.listen and
+// share the etcd of the analyzer running in the test process.
+type AnalyzerProcess struct {
+	Index      int
+	HostID     string
+	Port       int
+	ConfigFile string
+	daemon
+}
+
+// standaloneAnalyzers are the analyzers started by StartAnalyzers, indexed
+// from 1
+var standaloneAnalyzers []*AnalyzerProcess
+
+// AnalyzerServiceAddress returns the listen address of the analyzer of the
+// given index, 0 being the analyzer running in the test process
+func AnalyzerServiceAddress(index int) (common.ServiceAddress, error) {
+	sa, err := analyzerListenAddress()
+	if err != nil {
+		return sa, err
+	}
+	if index < 0 || index >= AnalyzerCount {
+		return sa, fmt.Errorf("No analyzer of index %d, %d analyzers configured", index, AnalyzerCount)
+	}
+
+	sa.Port, err = analyzerPort(index)
+	return sa, err
+}
+
+// AnalyzerEndpoint returns the address:port of the analyzer of the given
+// index, as expected by WSConnect
+func AnalyzerEndpoint(index int) (string, error) {
+	sa, err := AnalyzerServiceAddress(index)
+	if err != nil {
+		return "", err
+	}
+	if sa.Addr == "0.0.0.0" {
+		sa.Addr = "127.0.0.1"
+	}
+	return net.JoinHostPort(sa.Addr, strconv.Itoa(sa.Port)), nil
+}
+
+// analyzerAddresses returns the addresses of all the analyzers, listed in
+// the analyzers section of the configuration
+func analyzerAddresses() (addresses []string, err error) {
+	for i := 0; i < AnalyzerCount; i++ {
+		port, err := analyzerPort(i)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, fmt.Sprintf("127.0.0.1:%d", port))
+	}
+	return addresses, nil
+}
+
+// NewAnalyzerProcess generates the configuration of the analyzer of the given
+// index, params are added to the parameters of the configuration template
+func NewAnalyzerProcess(conf string, index int, params HelperParams) (*AnalyzerProcess, error) {
+	sa, err := AnalyzerServiceAddress(index)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	etcdServers, err := etcdServerURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	p := HelperParams{
+		"HostID":       fmt.Sprintf("%s-analyzer%d", hostname, index),
+		"AnalyzerPort": sa.Port,
+		"EmbeddedEtcd": "false",
+		"EtcdServers":  etcdServers,
+	}
+	for k, v := range params {
+		p[k] = v
+	}
+
+	filename, err := writeConfig(conf, p)
+	if err != nil {
+		return nil, err
+	}
+
+	hostID := p["HostID"].(string)
+	return &AnalyzerProcess{
+		Index:      index,
+		HostID:     hostID,
+		Port:       sa.Port,
+		ConfigFile: filename,
+		daemon:     daemon{name: "analyzer " + hostID, service: "analyzer", configFile: filename},
+	}, nil
+}
+
+// Start launches the analyzer and waits for its API to answer
+func (a *AnalyzerProcess) Start() error {
+	if err := a.start(); err != nil {
+		return err
+	}
+
+	err := Retry(analyzerReadyTimeout, time.Second, func() error {
+		_, err := queryAnalyzerTopology(a.Index, "G.V().Count()", nil)
+		return err
+	})
+	if err != nil {
+		a.stop()
+		return fmt.Errorf("Analyzer %s not ready: %s", a.HostID, err)
+	}
+	return nil
+}
+
+// Stop terminates the analyzer, killing it if it doesn't exit in time.
+// Stopping an analyzer that is not running is a no-op.
+func (a *AnalyzerProcess) Stop() error {
+	return a.stop()
+}
+
+// Restart stops and starts the analyzer with the same configuration
+func (a *AnalyzerProcess) Restart() error {
+	if err := a.Stop(); err != nil {
+		logging.GetLogger().Error(err)
+	}
+	return a.Start()
+}
+
+// StartAnalyzers starts the analyzers of index 1 to count, the analyzer of
+// index 0 being the one running in the test process. If one of them fails to
+// start, the analyzers already started are stopped.
+func StartAnalyzers(conf string, count int, params HelperParams) ([]*AnalyzerProcess, error) {
+	var analyzers []*AnalyzerProcess
+	for i := 1; i <= count; i++ {
+		analyzer, err := NewAnalyzerProcess(conf, i, params)
+		if err == nil {
+			err = analyzer.Start()
+		}
+		if err != nil {
+			StopAnalyzers(analyzers)
+			return nil, err
+		}
+		analyzers = append(analyzers, analyzer)
+	}
+
+	standaloneAnalyzers = analyzers
+	return analyzers, nil
+}
+
+// StopAnalyzers stops all the analyzers, returning the last error
+func StopAnalyzers(analyzers []*AnalyzerProcess) (err error) {
+	for _, analyzer := range analyzers {
+		if e := analyzer.Stop(); e != nil {
+			logging.GetLogger().Error(e)
+			err = e
+		}
+	}
+	return
+}
+
+// GetAnalyzer returns the analyzer process of the given index started by
+// StartAnalyzers. The analyzer of index 0 runs in the test process and
+// embeds etcd, it can't be stopped.
+func GetAnalyzer(index int) (*AnalyzerProcess, error) {
+	if index < 1 || index > len(standaloneAnalyzers) {
+		return nil, fmt.Errorf("No analyzer process of index %d", index)
+	}
+	return standaloneAnalyzers[index-1], nil
+}
+
+// StopAnalyzer stops the analyzer process of the given index
+func StopAnalyzer(index int) error {
+	a, err := GetAnalyzer(index)
+	if err != nil {
+		return err
+	}
+	return a.Stop()
+}
+
+// RestartAnalyzer restarts the analyzer process of the given index
+func RestartAnalyzer(index int) error {
+	a, err := GetAnalyzer(index)
+	if err != nil {
+		return err
+	}
+	return a.Restart()
+}
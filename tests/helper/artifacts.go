@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// configDir is the directory of artifactsDir where the configuration files
+// rendered by the helper and the logs of the standalone daemons are written
+const configDir = "_config"
+
+// Artifacts manages the directory where the files of a test are written,
+// named after the test. The directory is removed by Close if the test passed,
+// unless -keep-artifacts is given.
+type Artifacts struct {
+	t   testing.TB
+	dir string
+}
+
+// artifacts are the managers of the running tests, indexed by test name
+var artifacts = struct {
+	sync.Mutex
+	tests map[string]*Artifacts
+}{tests: make(map[string]*Artifacts)}
+
+// TestArtifacts returns the artifact manager of the test, creating its
+// directory with a copy of the rendered configuration files on first use
+func TestArtifacts(t testing.TB) (*Artifacts, error) {
+	artifacts.Lock()
+	defer artifacts.Unlock()
+
+	if a, ok := artifacts.tests[t.Name()]; ok {
+		return a, nil
+	}
+
+	dir := filepath.Join(artifactsDir, unsafePathChars.ReplaceAllString(t.Name(), "_"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Failed to create artifact directory %s: %s", dir, err)
+	}
+	a := &Artifacts{t: t, dir: dir}
+
+	for _, configFile := range configFiles {
+		data, err := ioutil.ReadFile(configFile)
+		if err == nil {
+			_, err = a.WriteFile("config-"+filepath.Base(configFile)+".yml", data)
+		}
+		if err != nil {
+			t.Logf("Failed to copy configuration file %s: %s", configFile, err)
+		}
+	}
+
+	artifacts.tests[t.Name()] = a
+	return a, nil
+}
+
+// Dir returns the artifact directory of the test
+func (a *Artifacts) Dir() string {
+	return a.dir
+}
+
+// Path returns the path of an artifact, the characters not allowed in file
+// names being replaced
+func (a *Artifacts) Path(name string) string {
+	return filepath.Join(a.dir, unsafePathChars.ReplaceAllString(name, "_"))
+}
+
+// WriteFile writes an artifact and returns its path
+func (a *Artifacts) WriteFile(name string, data []byte) (string, error) {
+	path := a.Path(name)
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+// Keep returns whether the artifacts are kept, the test having failed or
+// -keep-artifacts being given
+func (a *Artifacts) Keep() bool {
+	return keepArtifacts || a.t.Failed()
+}
+
+// Close removes the artifact directory if the artifacts are not kept and
+// logs its path otherwise. It is meant to be deferred at the beginning of
+// the test, DumpOnFailure calling it.
+func (a *Artifacts) Close() {
+	artifacts.Lock()
+	delete(artifacts.tests, a.t.Name())
+	artifacts.Unlock()
+
+	if a.Keep() {
+		a.t.Logf("Test artifacts written to %s", a.dir)
+		return
+	}
+
+	if err := os.RemoveAll(a.dir); err != nil {
+		a.t.Logf("Failed to remove artifact directory %s: %s", a.dir, err)
+	}
+}
+
+// configFile creates a file in the configuration directory of artifactsDir
+func configFile(prefix string) (*os.File, error) {
+	dir := filepath.Join(artifactsDir, configDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return ioutil.TempFile(dir, prefix)
+}
+
+// PruneArtifacts removes the configuration files rendered by the helper and
+// the logs of the standalone daemons if all the tests passed, unless
+// -keep-artifacts is given. It is meant to be called by TestMain once the
+// daemons are stopped.
+func PruneArtifacts(passed bool) {
+	if !passed || keepArtifacts {
+		return
+	}
+	os.RemoveAll(filepath.Join(artifactsDir, configDir))
+}
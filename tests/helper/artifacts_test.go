@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactsPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedDir, savedKeep := artifactsDir, keepArtifacts
+	defer func() { artifactsDir, keepArtifacts = savedDir, savedKeep }()
+	artifactsDir = dir
+
+	for _, keep := range []bool{false, true} {
+		keepArtifacts = keep
+		tb := &recordingTB{TB: t}
+
+		a, err := TestArtifacts(tb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a2, _ := TestArtifacts(tb); a2 != a {
+			t.Error("Expected the same artifact manager for the same test")
+		}
+		if filepath.Dir(a.Dir()) != dir {
+			t.Errorf("Expected the artifact directory in %s, got %s", dir, a.Dir())
+		}
+
+		path, err := a.WriteFile("ns1/eth0.pcap", []byte("pcap"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(path) != a.Dir() {
+			t.Errorf("Expected %s in the artifact directory", path)
+		}
+
+		a.Close()
+		if _, err := os.Stat(a.Dir()); os.IsNotExist(err) == keep {
+			t.Errorf("Unexpected artifact directory state with keep=%v: %v", keep, err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skydive-project/skydive/config"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/logging"
+)
+
+// Credentials of the user allowed by the authentication fixtures
+const (
+	AuthUsername = "admin"
+	AuthPassword = "password"
+)
+
+var (
+	authLock     sync.Mutex
+	authHtpasswd string
+	authToken    string
+	keystoneMock *KeystoneMock
+)
+
+// WriteHtpasswd writes an htpasswd file holding the SHA1 hashed passwords
+// of the users
+func WriteHtpasswd(filename string, users map[string]string) error {
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var content []byte
+	for _, name := range names {
+		hash := sha1.Sum([]byte(users[name]))
+		line := fmt.Sprintf("%s:{SHA}%s\n", name, base64.StdEncoding.EncodeToString(hash[:]))
+		content = append(content, line...)
+	}
+
+	return ioutil.WriteFile(filename, content, 0600)
+}
+
+// KeystoneMock is a keystone v3 identity endpoint issuing and validating
+// tokens for a set of users
+type KeystoneMock struct {
+	sync.RWMutex
+	Project string
+	Domain  string
+	server  *httptest.Server
+	users   map[string]string
+	tokens  map[string]string
+}
+
+type keystoneName struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type keystoneAuthRequest struct {
+	Auth struct {
+		Identity struct {
+			Password struct {
+				User struct {
+					Name     string `json:"name"`
+					Password string `json:"password"`
+				} `json:"user"`
+			} `json:"password"`
+		} `json:"identity"`
+	} `json:"auth"`
+}
+
+// NewKeystoneMock starts a keystone endpoint with the users and their
+// passwords, the tokens being scoped to the admin project of the Default
+// domain like the keystone backend expects by default
+func NewKeystoneMock(users map[string]string) *KeystoneMock {
+	k := &KeystoneMock{
+		Project: "admin",
+		Domain:  "Default",
+		users:   users,
+		tokens:  make(map[string]string),
+	}
+	k.server = httptest.NewServer(k)
+	return k
+}
+
+// AuthURL returns the identity endpoint to set in the configuration
+func (k *KeystoneMock) AuthURL() string {
+	return k.server.URL + "/v3/"
+}
+
+// Close stops the endpoint
+func (k *KeystoneMock) Close() {
+	k.server.Close()
+}
+
+func (k *KeystoneMock) tokenBody(user string) map[string]interface{} {
+	domain := keystoneName{ID: "default", Name: k.Domain}
+	return map[string]interface{}{
+		"token": map[string]interface{}{
+			"methods":    []string{"password"},
+			"expires_at": time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05.000000Z"),
+			"issued_at":  time.Now().UTC().Format("2006-01-02T15:04:05.000000Z"),
+			"user":       map[string]interface{}{"id": user, "name": user, "domain": domain},
+			"project":    map[string]interface{}{"id": k.Project, "name": k.Project, "domain": domain},
+			"roles":      []keystoneName{{ID: "admin", Name: "admin"}},
+			"catalog":    []interface{}{},
+		},
+	}
+}
+
+func (k *KeystoneMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v3/auth/tokens" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var user string
+	switch r.Method {
+	case "POST":
+		var req keystoneAuthRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user = req.Auth.Identity.Password.User.Name
+		if password, ok := k.users[user]; !ok || password != req.Auth.Identity.Password.User.Password {
+			http.Error(w, "The request you have made requires authentication.", http.StatusUnauthorized)
+			return
+		}
+
+		b := make([]byte, 16)
+		rand.Read(b)
+		token := hex.EncodeToString(b)
+
+		k.Lock()
+		k.tokens[token] = user
+		k.Unlock()
+
+		w.Header().Set("X-Subject-Token", token)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+	case "GET":
+		k.RLock()
+		user = k.tokens[r.Header.Get("X-Subject-Token")]
+		k.RUnlock()
+
+		if user == "" {
+			http.Error(w, "Could not find token.", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(k.tokenBody(user))
+}
+
+// setAuthParams provisions the authentication backend of the run if needed
+// and sets the parameters of the configuration template
+func setAuthParams(params HelperParams) error {
+	authLock.Lock()
+	defer authLock.Unlock()
+
+	params["Auth"] = Auth
+	users := map[string]string{AuthUsername: AuthPassword}
+
+	switch Auth {
+	case "":
+	case "basic":
+		if authHtpasswd == "" {
+			dir, err := ioutil.TempDir("", "skydive-auth")
+			if err != nil {
+				return err
+			}
+
+			filename := filepath.Join(dir, "htpasswd")
+			if err := WriteHtpasswd(filename, users); err != nil {
+				os.RemoveAll(dir)
+				return err
+			}
+			authHtpasswd = filename
+		}
+		params["AuthHtpasswd"] = authHtpasswd
+	case "keystone":
+		if keystoneMock == nil {
+			keystoneMock = NewKeystoneMock(users)
+		}
+		params["AuthKeystoneURL"] = keystoneMock.AuthURL()
+	default:
+		return fmt.Errorf("Unknown authentication backend: %s", Auth)
+	}
+
+	return nil
+}
+
+// Login authenticates against the login endpoint of the analyzer and
+// returns the token it hands back
+func Login(username, password string) (string, error) {
+	sa, err := AnalyzerServiceAddress(0)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{}
+	if config.IsTLSenabled() {
+		tlsConfig, err := wsTLSConfig(nil)
+		if err != nil {
+			return "", err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	u := config.GetURL("http", sa.Addr, sa.Port, "/login")
+	resp, err := client.PostForm(u.String(), url.Values{"username": {username}, "password": {password}})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Failed to login as %s: %s", username, resp.Status)
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "authtok" {
+			return cookie.Value, nil
+		}
+	}
+	return "", fmt.Errorf("No token returned when logging in as %s", username)
+}
+
+// authTokenOpts returns the token of the test user, logging in the first
+// time. The credentials are returned if the login fails.
+func authTokenOpts() *shttp.AuthenticationOpts {
+	authLock.Lock()
+	defer authLock.Unlock()
+
+	if authToken == "" {
+		token, err := Login(AuthUsername, AuthPassword)
+		if err != nil {
+			logging.GetLogger().Debugf("Using credentials instead of a token: %s", err)
+			return &shttp.AuthenticationOpts{Username: AuthUsername, Password: AuthPassword}
+		}
+		authToken = token
+	}
+
+	return &shttp.AuthenticationOpts{Token: authToken}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	auth "github.com/abbot/go-http-auth"
+	shttp "github.com/skydive-project/skydive/http"
+)
+
+func TestWriteHtpasswd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-auth-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "htpasswd")
+	if err := WriteHtpasswd(filename, map[string]string{"admin": "password", "user": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := shttp.NewBasicAuthenticationBackend("basic", auth.HtpasswdFileProvider(filename), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backend.Authenticate("user", "secret"); err != nil {
+		t.Errorf("Expected user to be authenticated: %s", err)
+	}
+	if _, err := backend.Authenticate("admin", "secret"); err == nil {
+		t.Error("Expected wrong password to be rejected")
+	}
+}
+
+func TestKeystoneMock(t *testing.T) {
+	k := NewKeystoneMock(map[string]string{"admin": "password"})
+	defer k.Close()
+
+	login := func(password string) (*http.Response, error) {
+		var req keystoneAuthRequest
+		req.Auth.Identity.Password.User.Name = "admin"
+		req.Auth.Identity.Password.User.Password = password
+		body, _ := json.Marshal(req)
+		return http.Post(k.AuthURL()+"auth/tokens", "application/json", bytes.NewReader(body))
+	}
+
+	resp, err := login("wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected wrong password to be rejected, got: %s", resp.Status)
+	}
+
+	if resp, err = login("password"); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	token := resp.Header.Get("X-Subject-Token")
+	if resp.StatusCode != http.StatusCreated || token == "" {
+		t.Fatalf("Expected a token to be issued, got: %s", resp.Status)
+	}
+
+	validate := func(token string) *http.Response {
+		req, _ := http.NewRequest("GET", k.AuthURL()+"auth/tokens", nil)
+		req.Header.Set("X-Subject-Token", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp = validate(token)
+	defer resp.Body.Close()
+
+	var body struct {
+		Token struct {
+			User struct {
+				Name string `json:"name"`
+			} `json:"user"`
+			Project struct {
+				Name   string `json:"name"`
+				Domain struct {
+					Name string `json:"name"`
+				} `json:"domain"`
+			} `json:"project"`
+		} `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Token.User.Name != "admin" || body.Token.Project.Name != "admin" || body.Token.Project.Domain.Name != "Default" {
+		t.Errorf("Unexpected token: %+v", body)
+	}
+
+	invalid := validate("invalid")
+	invalid.Body.Close()
+	if invalid.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected unknown token to be rejected, got: %s", invalid.Status)
+	}
+}
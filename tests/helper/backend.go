@@ -0,0 +1,188 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+const (
+	backendReadyTimeout  = 60 * time.Second
+	backendReadyInterval = time.Second
+	orientDBDatabase     = "Skydive"
+)
+
+// Addresses of the storage backends, replaced by the ones of the containers
+// started by ProvisionBackends
+var (
+	elasticsearchHost = "127.0.0.1:9200"
+	orientDBAddr      = "http://127.0.0.1:2480"
+)
+
+var backendClient = &http.Client{Timeout: 5 * time.Second}
+
+func backendRequest(method, url string, body io.Reader, username, password string) (int, []byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := backendClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+// waitForElasticsearch waits for the health of the cluster to be yellow or green
+func waitForElasticsearch(host string, timeout time.Duration) error {
+	return Retry(timeout, backendReadyInterval, func() error {
+		code, data, err := backendRequest("GET", "http://"+host+"/_cluster/health", nil, "", "")
+		if err != nil {
+			return err
+		}
+		if code != http.StatusOK {
+			return fmt.Errorf("Elasticsearch health check returned %d: %s", code, string(data))
+		}
+
+		var health struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(data, &health); err != nil {
+			return err
+		}
+		if health.Status != "yellow" && health.Status != "green" {
+			return fmt.Errorf("Elasticsearch cluster health is %s", health.Status)
+		}
+		return nil
+	})
+}
+
+// cleanElasticsearch removes the skydive indices left by previous runs and
+// installs the index template if one was given
+func cleanElasticsearch(host string) error {
+	code, data, err := backendRequest("DELETE", "http://"+host+"/skydive_*", nil, "", "")
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK && code != http.StatusNotFound {
+		return fmt.Errorf("Failed to delete Elasticsearch indices, got %d: %s", code, string(data))
+	}
+
+	if elasticsearchTemplate == "" {
+		return nil
+	}
+
+	template, err := ioutil.ReadFile(elasticsearchTemplate)
+	if err != nil {
+		return err
+	}
+
+	code, data, err = backendRequest("PUT", "http://"+host+"/_template/skydive", bytes.NewReader(template), "", "")
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("Failed to install Elasticsearch template %s, got %d: %s", elasticsearchTemplate, code, string(data))
+	}
+	return nil
+}
+
+// waitForOrientDB waits for the OrientDB server to answer requests
+func waitForOrientDB(addr string, timeout time.Duration) error {
+	return Retry(timeout, backendReadyInterval, func() error {
+		code, data, err := backendRequest("GET", addr+"/listDatabases", nil, "", "")
+		if err != nil {
+			return err
+		}
+		if code != http.StatusOK {
+			return fmt.Errorf("OrientDB returned %d: %s", code, string(data))
+		}
+		return nil
+	})
+}
+
+// cleanOrientDB removes the database left by previous runs, it is created
+// again by the analyzer
+func cleanOrientDB(addr, database, username, password string) error {
+	code, data, err := backendRequest("GET", addr+"/database/"+database, nil, username, password)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return nil
+	}
+
+	code, data, err = backendRequest("DELETE", addr+"/database/"+database, nil, username, password)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK && code != http.StatusNoContent {
+		return fmt.Errorf("Failed to delete OrientDB database %s, got %d: %s", database, code, string(data))
+	}
+	return nil
+}
+
+// prepareBackends waits for the storage backends used by the analyzer to be
+// ready and removes the data of the previous runs
+func prepareBackends(params HelperParams) error {
+	if FlowBackend == "elasticsearch" || TopologyBackend == "elasticsearch" {
+		logging.GetLogger().Debugf("Waiting for Elasticsearch at %s", elasticsearchHost)
+		if err := waitForElasticsearch(elasticsearchHost, backendReadyTimeout); err != nil {
+			return fmt.Errorf("Elasticsearch not ready: %s", err)
+		}
+		if err := cleanElasticsearch(elasticsearchHost); err != nil {
+			return err
+		}
+	}
+
+	if FlowBackend == "orientdb" || TopologyBackend == "orientdb" {
+		logging.GetLogger().Debugf("Waiting for OrientDB at %s", orientDBAddr)
+		if err := waitForOrientDB(orientDBAddr, backendReadyTimeout); err != nil {
+			return fmt.Errorf("OrientDB not ready: %s", err)
+		}
+		password, _ := params["OrientDBRootPassword"].(string)
+		if err := cleanOrientDB(orientDBAddr, orientDBDatabase, "root", password); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
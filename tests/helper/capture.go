@@ -0,0 +1,178 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+	shttp "github.com/skydive-project/skydive/http"
+)
+
+const (
+	captureActiveTimeout  = 30 * time.Second
+	captureActiveInterval = 500 * time.Millisecond
+)
+
+// CaptureOptions describes the parameters of a capture started by StartCapture
+type CaptureOptions struct {
+	BPFFilter      string
+	Type           string
+	HeaderSize     int
+	ExtraTCPMetric bool
+	RawPacketLimit int
+	Port           int
+	// Timeout is the time to wait for the capture to be active,
+	// 30 seconds if not set
+	Timeout time.Duration
+}
+
+// CaptureHandle is a capture created by StartCapture
+type CaptureHandle struct {
+	Capture *types.Capture
+	t       testing.TB
+	client  *shttp.CrudClient
+}
+
+func newAnalyzerCrudClient() (*shttp.CrudClient, error) {
+	sa, err := AnalyzerServiceAddress(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return shttp.NewCrudClient(config.GetURL("http", sa.Addr, sa.Port, "/api/"), AuthenticationOpts())
+}
+
+// checkCaptureActive returns an error if one of the capturable interfaces
+// matching the capture query has not started the capture
+func checkCaptureActive(capture *types.Capture) error {
+	result := &GremlinResult{query: capture.GremlinQuery}
+	if result.data, result.err = queryTopology(capture.GremlinQuery); result.err != nil {
+		return result.err
+	}
+
+	nodes, err := result.GetNodes()
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) == 0 {
+		return fmt.Errorf("No node matching capture %s", capture.GremlinQuery)
+	}
+
+	for _, node := range nodes {
+		tp, err := node.GetFieldString("Type")
+		if err != nil || !common.IsCaptureAllowed(tp) {
+			continue
+		}
+
+		captureID, err := node.GetFieldString("Capture.ID")
+		if err != nil {
+			return fmt.Errorf("Node %s matched the capture but capture is not enabled", node.ID)
+		}
+		if captureID != capture.ID() {
+			return fmt.Errorf("Node %s matches multiple captures", node.ID)
+		}
+
+		if state, _ := node.GetFieldString("Capture.State"); state != "active" {
+			return fmt.Errorf("Capture %s is not active on node %s", capture.ID(), node.ID)
+		}
+	}
+
+	return nil
+}
+
+// StartCapture creates a capture through the analyzer API and waits for the
+// matching interfaces to report an active capture. The capture has to be
+// removed by calling Delete, usually with a defer.
+func StartCapture(t testing.TB, gremlinQuery string, opts CaptureOptions) *CaptureHandle {
+	client, err := newAnalyzerCrudClient()
+	if err != nil {
+		t.Fatalf("Failed to create client: %s", err)
+	}
+
+	capture := types.NewCapture(gremlinQuery, opts.BPFFilter)
+	capture.Type = opts.Type
+	capture.HeaderSize = opts.HeaderSize
+	capture.ExtraTCPMetric = opts.ExtraTCPMetric
+	capture.RawPacketLimit = opts.RawPacketLimit
+	capture.Port = opts.Port
+
+	if err := client.Create("capture", capture); err != nil {
+		t.Fatalf("Failed to create capture %s: %s", gremlinQuery, err)
+	}
+
+	handle := &CaptureHandle{Capture: capture, t: t, client: client}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = captureActiveTimeout
+	}
+
+	if err := Retry(timeout, captureActiveInterval, func() error { return checkCaptureActive(capture) }); err != nil {
+		handle.Delete()
+		t.Fatalf("Capture %s did not become active: %s", gremlinQuery, err)
+	}
+
+	return handle
+}
+
+// ID returns the identifier of the capture
+func (h *CaptureHandle) ID() string {
+	return h.Capture.ID()
+}
+
+// PCAPSocket returns the address of the socket of a pcapsocket capture,
+// waiting for the agent to allocate it
+func (h *CaptureHandle) PCAPSocket() (string, error) {
+	if h.client == nil {
+		return "", fmt.Errorf("Capture %s was deleted", h.Capture.ID())
+	}
+
+	err := Retry(captureActiveTimeout, captureActiveInterval, func() error {
+		if err := h.client.Get("capture", h.Capture.ID(), h.Capture); err != nil {
+			return err
+		}
+		if h.Capture.PCAPSocket == "" {
+			return fmt.Errorf("Capture %s has no PCAP socket", h.Capture.ID())
+		}
+		return nil
+	})
+	return h.Capture.PCAPSocket, err
+}
+
+// Delete removes the capture, deleting an already removed capture is a no-op
+func (h *CaptureHandle) Delete() {
+	if h.client == nil {
+		return
+	}
+
+	if err := h.client.Delete("capture", h.Capture.ID()); err != nil {
+		h.t.Errorf("Failed to delete capture %s: %s", h.Capture.ID(), err)
+	}
+	h.client = nil
+}
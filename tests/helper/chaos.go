@@ -0,0 +1,263 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// tcpEstablished is the state of the established connections in /proc/net/tcp
+const tcpEstablished = "01"
+
+// TCPConnection is an established TCP connection
+type TCPConnection struct {
+	Local  *net.TCPAddr
+	Remote *net.TCPAddr
+}
+
+func (c *TCPConnection) String() string {
+	return fmt.Sprintf("%s -> %s", c.Local, c.Remote)
+}
+
+// parseProcNetAddress parses an address of /proc/net/tcp or /proc/net/tcp6,
+// the IP being written as 32 bits words in host byte order
+func parseProcNetAddress(s string) (*net.TCPAddr, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid address %s", s)
+	}
+
+	b, err := hex.DecodeString(fields[0])
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, fmt.Errorf("invalid address %s", s)
+	}
+	for i := 0; i < len(b); i += 4 {
+		b[i], b[i+1], b[i+2], b[i+3] = b[i+3], b[i+2], b[i+1], b[i]
+	}
+
+	port, err := strconv.ParseUint(fields[1], 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in address %s", s)
+	}
+
+	return &net.TCPAddr{IP: net.IP(b), Port: int(port)}, nil
+}
+
+// parseProcNetTCP returns the established connections listed in the content
+// of /proc/net/tcp or /proc/net/tcp6 whose remote port is the given one
+func parseProcNetTCP(data string, port int) (connections []*TCPConnection, err error) {
+	lines := strings.Split(data, "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+
+		c := &TCPConnection{}
+		if c.Local, err = parseProcNetAddress(fields[1]); err != nil {
+			return nil, err
+		}
+		if c.Remote, err = parseProcNetAddress(fields[2]); err != nil {
+			return nil, err
+		}
+		if c.Remote.Port == port {
+			connections = append(connections, c)
+		}
+	}
+	return connections, nil
+}
+
+// AnalyzerConnections returns the established TCP connections to the
+// analyzer of the given index, like the websocket connections of the agents
+func AnalyzerConnections(index int) ([]*TCPConnection, error) {
+	port, err := analyzerPort(index)
+	if err != nil {
+		return nil, err
+	}
+
+	var connections []*TCPConnection
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		c, err := parseProcNetTCP(string(data), port)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %s: %s", file, err)
+		}
+		connections = append(connections, c...)
+	}
+	return connections, nil
+}
+
+// CloseAnalyzerConnections abruptly closes the established TCP connections
+// to the analyzer of the given index, the peers receiving a reset. The
+// connections of the agents and of the test clients are closed.
+func CloseAnalyzerConnections(index int) error {
+	port, err := analyzerPort(index)
+	if err != nil {
+		return err
+	}
+
+	before, err := AnalyzerConnections(index)
+	if err != nil {
+		return err
+	}
+
+	if _, err := runCommand("ss", "-K", "-t", "state", "established", "dport", "=", fmt.Sprintf(":%d", port)); err != nil {
+		return err
+	}
+
+	// ss doesn't fail if the kernel can't destroy sockets, the new
+	// connections of the agents having other local addresses
+	after, err := AnalyzerConnections(index)
+	if err != nil {
+		return err
+	}
+	for _, a := range after {
+		for _, b := range before {
+			if a.String() == b.String() {
+				return fmt.Errorf("Connection %s not closed, the kernel may not support destroying sockets", a)
+			}
+		}
+	}
+	return nil
+}
+
+// analyzerDropRules returns the iptables rules dropping the packets to and
+// from the port of an analyzer
+func analyzerDropRules(port int) [][]string {
+	p := strconv.Itoa(port)
+	return [][]string{
+		{"OUTPUT", "-p", "tcp", "--dport", p, "-j", "DROP"},
+		{"OUTPUT", "-p", "tcp", "--sport", p, "-j", "DROP"},
+	}
+}
+
+// DropAnalyzerTraffic drops the TCP packets to and from the analyzer of
+// the given index with iptables during the given duration. The rules are
+// removed when it returns, the connections being kept open.
+func DropAnalyzerTraffic(index int, duration time.Duration) (err error) {
+	port, err := analyzerPort(index)
+	if err != nil {
+		return err
+	}
+
+	var inserted [][]string
+	defer func() {
+		for _, rule := range inserted {
+			if _, e := runCommand(append([]string{"iptables", "-w", "-D"}, rule...)...); e != nil && err == nil {
+				err = e
+			}
+		}
+	}()
+
+	for _, rule := range analyzerDropRules(port) {
+		if _, err := runCommand(append([]string{"iptables", "-w", "-I"}, rule...)...); err != nil {
+			return err
+		}
+		inserted = append(inserted, rule)
+	}
+
+	time.Sleep(duration)
+	return nil
+}
+
+// PauseAnalyzer stops the process of the standalone analyzer of the given
+// index with SIGSTOP during the given duration and resumes it with SIGCONT.
+// The analyzer of index 0 runs in the test process and can't be paused.
+func PauseAnalyzer(index int, duration time.Duration) error {
+	a, err := GetAnalyzer(index)
+	if err != nil {
+		return err
+	}
+
+	if err := a.signal(syscall.SIGSTOP); err != nil {
+		return err
+	}
+	time.Sleep(duration)
+	return a.signal(syscall.SIGCONT)
+}
+
+// ResyncExpectation describes the state expected by ExpectResync once the
+// disruption is over
+type ResyncExpectation struct {
+	// Topology selects the subgraph expected to be identical after the
+	// disruption, once normalized
+	Topology TopologyNormalizeOpts
+	// Captures are expected to be active again
+	Captures []*CaptureHandle
+	// Timeout is the time given to the analyzer and the agents to
+	// resynchronize
+	Timeout time.Duration
+}
+
+// ExpectResync takes a snapshot of the topology, calls disrupt to break the
+// connections between the agents and the analyzer, like with
+// DropAnalyzerTraffic, and fails the test if the topology doesn't become
+// identical to the snapshot or if the captures don't become active again
+func ExpectResync(t *testing.T, e ResyncExpectation, disrupt func() error) {
+	before, err := currentTopology(&e.Topology)
+	if err != nil {
+		t.Fatalf("Failed to get the topology: %s", err)
+	}
+
+	if err := disrupt(); err != nil {
+		t.Fatalf("Failed to disrupt the connections: %s", err)
+	}
+
+	var diff []string
+	err = Retry(e.Timeout, time.Second, func() error {
+		diff = nil
+		after, err := currentTopology(&e.Topology)
+		if err != nil {
+			return err
+		}
+		if diff = diffTopology(before, after); len(diff) != 0 {
+			return fmt.Errorf("%d differences", len(diff))
+		}
+		return nil
+	})
+
+	switch {
+	case len(diff) != 0:
+		t.Errorf("Topology not resynchronized after the disruption:\n    %s", strings.Join(diff, "\n    "))
+	case err != nil:
+		t.Errorf("Failed to get the topology after the disruption: %s", err)
+	}
+
+	for _, capture := range e.Captures {
+		if err := Retry(e.Timeout, captureActiveInterval, func() error { return checkCaptureActive(capture.Capture) }); err != nil {
+			t.Errorf("Capture %s not active after the disruption: %s", capture.ID(), err)
+		}
+	}
+}
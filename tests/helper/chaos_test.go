@@ -0,0 +1,60 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+)
+
+const procNetTCPFixture = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:FB34 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:D431 0100007F:FB34 01 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:FB34 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 0100007F:D432 0100007F:FB34 06 00000000:00000000 03:00000000 00000000     0        0 0 3 0000000000000000
+`
+
+const procNetTCP6Fixture = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:D433 00000000000000000000000001000000:FB34 01 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 20 4 30 10 -1
+`
+
+func TestParseProcNetTCP(t *testing.T) {
+	connections, err := parseProcNetTCP(procNetTCPFixture, 64308)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connections) != 1 || connections[0].String() != "127.0.0.1:54321 -> 127.0.0.1:64308" {
+		t.Errorf("Expected the established connection to port 64308, got %v", connections)
+	}
+
+	connections, err = parseProcNetTCP(procNetTCP6Fixture, 64308)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connections) != 1 || connections[0].String() != "[::1]:54323 -> [::1]:64308" {
+		t.Errorf("Expected the established IPv6 connection to port 64308, got %v", connections)
+	}
+
+	if _, err := parseProcNetTCP("header\n 0: 0100007F 0100007F:FB34 01", 64308); err == nil {
+		t.Error("Expected an error for an invalid address")
+	}
+}
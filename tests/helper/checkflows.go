@@ -0,0 +1,125 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/flow"
+	g "github.com/skydive-project/skydive/gremlin"
+)
+
+const (
+	checkFlowsTimeout  = 30 * time.Second
+	checkFlowsInterval = time.Second
+)
+
+// CheckFlowsSpec describes a flow test run by CheckFlows
+type CheckFlowsSpec struct {
+	// CaptureGremlin selects the captured interfaces
+	CaptureGremlin string
+	Capture        CaptureOptions
+	// Traffic generates the packets once the capture is active
+	Traffic func() error
+	// Since keeps the flows updated at most this duration before the
+	// traffic started, only the ones updated afterwards if zero
+	Since time.Duration
+	// Filter selects the flows checked against the expectations
+	Filter func([]*flow.Flow) []*flow.Flow
+	// Expect holds the expected flows
+	Expect []FlowExpectation
+	// AllowUnexpected ignores the flows matching no expectation
+	AllowUnexpected bool
+	// Retry is the time given to the flows to match, 30 seconds if zero
+	Retry time.Duration
+}
+
+// checkFlowsQuery returns the query of the flows of the captured
+// interfaces updated since the given time
+func checkFlowsQuery(captureGremlin string, since time.Time) string {
+	return g.QueryString(captureGremlin).Flows().Has("Last", g.Gte(common.UnixMillis(since))).String()
+}
+
+// match returns an error describing the flows if they don't match the
+// expectations of the spec
+func (spec *CheckFlowsSpec) match(flows []*flow.Flow) error {
+	if spec.Filter != nil {
+		flows = spec.Filter(flows)
+	}
+
+	unmatched, unexpected := MatchFlows(flows, spec.Expect)
+	if spec.AllowUnexpected {
+		unexpected = nil
+	}
+	if len(unmatched) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d unmatched, %d unexpected:\n%s", len(unmatched), len(unexpected), mismatchReport(flows, unmatched, unexpected))
+}
+
+// CheckFlows starts a capture, generates the traffic and waits for the
+// flows of the captured interfaces to match the expectations, failing the
+// test with the mismatches and all the flows otherwise. The capture is
+// deleted before returning the flows.
+func CheckFlows(t *testing.T, spec CheckFlowsSpec) []*flow.Flow {
+	capture := StartCapture(t, spec.CaptureGremlin, spec.Capture)
+	defer capture.Delete()
+
+	start := time.Now()
+	if spec.Traffic != nil {
+		if err := spec.Traffic(); err != nil {
+			t.Fatalf("Failed to generate traffic: %s", err)
+		}
+	}
+
+	timeout := spec.Retry
+	if timeout == 0 {
+		timeout = checkFlowsTimeout
+	}
+
+	query := checkFlowsQuery(spec.CaptureGremlin, start.Add(-spec.Since))
+	t.Logf("Checking flows of %s", query)
+
+	var flows []*flow.Flow
+	err := Retry(timeout, checkFlowsInterval, func() error {
+		result := &GremlinResult{query: query}
+		if result.data, result.err = queryTopology(query); result.err != nil {
+			return result.err
+		}
+
+		var err error
+		if flows, err = result.GetFlows(); err != nil {
+			return err
+		}
+		return spec.match(flows)
+	})
+	if err != nil {
+		t.Fatalf("Flows of %s don't match the expectations after %s, %s\nflows:\n%s", spec.CaptureGremlin, timeout, err, FlowsToString(flows))
+	}
+
+	return flows
+}
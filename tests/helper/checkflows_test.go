@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func TestCheckFlowsQuery(t *testing.T) {
+	since := time.Unix(1500000000, 0)
+	query := checkFlowsQuery(`G.V().Has("Name", "eth0")`, since)
+
+	expected := `G.V().Has("Name", "eth0").Flows().Has("Last", Gte(1500000000000))`
+	if query != expected {
+		t.Errorf("Expected query %s, got %s", expected, query)
+	}
+}
+
+func TestCheckFlowsMatch(t *testing.T) {
+	tcp := FlowExpectation{Proto: flow.FlowProtocol_IPV4, A: "10.0.0.1", B: "10.0.0.2", Transport: flow.FlowProtocol_TCP, PortB: 80}
+	reply := FlowExpectation{Proto: flow.FlowProtocol_IPV4, A: "10.0.0.2", B: "10.0.0.1", Transport: flow.FlowProtocol_TCP, PortA: 80}
+	udp := FlowExpectation{Proto: flow.FlowProtocol_IPV4, A: "10.0.0.10", Transport: flow.FlowProtocol_UDP}
+
+	onlyIPv4 := func(flows []*flow.Flow) (filtered []*flow.Flow) {
+		for _, f := range flows {
+			if f.Network != nil && f.Network.Protocol == flow.FlowProtocol_IPV4 {
+				filtered = append(filtered, f)
+			}
+		}
+		return filtered
+	}
+
+	tests := []struct {
+		name string
+		spec CheckFlowsSpec
+		err  bool
+	}{
+		{"unexpected", CheckFlowsSpec{Expect: []FlowExpectation{tcp, reply, udp}}, true},
+		{"allow unexpected", CheckFlowsSpec{Expect: []FlowExpectation{tcp, reply, udp}, AllowUnexpected: true}, false},
+		{"filter", CheckFlowsSpec{Expect: []FlowExpectation{tcp, reply, udp}, Filter: onlyIPv4}, false},
+		{"subset", CheckFlowsSpec{Expect: []FlowExpectation{tcp, udp}, Filter: onlyIPv4, AllowUnexpected: true}, false},
+		{"missing", CheckFlowsSpec{Expect: []FlowExpectation{tcp, reply, udp, {Proto: flow.FlowProtocol_IPV6, Transport: flow.FlowProtocol_TCP}}, AllowUnexpected: true}, true},
+	}
+
+	for _, test := range tests {
+		err := test.spec.match(testFlows())
+		if test.err && err == nil {
+			t.Errorf("%s: expected a mismatch", test.name)
+		} else if !test.err && err != nil {
+			t.Errorf("%s: unexpected mismatch: %s", test.name, err)
+		}
+	}
+
+	spec := CheckFlowsSpec{Expect: []FlowExpectation{tcp}, Filter: onlyIPv4}
+	if err := spec.match(testFlows()); err == nil || !strings.Contains(err.Error(), "0 unmatched, 2 unexpected") {
+		t.Errorf("Expected 2 unexpected flows, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"testing"
+)
+
+// CleanupStack holds the functions undoing the setup actions of a test. Each
+// action registers its own undo function once done, so that a setup failing
+// halfway only undoes what was actually set up. Cleanup is meant to be
+// deferred right after the creation of the stack.
+type CleanupStack struct {
+	t   testing.TB
+	fns []func() error
+}
+
+// NewCleanupStack returns an empty cleanup stack for the test
+func NewCleanupStack(t testing.TB) *CleanupStack {
+	return &CleanupStack{t: t}
+}
+
+// Register pushes a function undoing a setup action
+func (s *CleanupStack) Register(fn func() error) {
+	s.fns = append(s.fns, fn)
+}
+
+// ExecCmds executes the commands like ExecCmds, registering the Undo command
+// of each command that succeeded
+func (s *CleanupStack) ExecCmds(cmds ...Cmd) (e error) {
+	for _, cmd := range cmds {
+		if err := ExecCmds(s.t, cmd); err != nil {
+			e = err
+			continue
+		}
+
+		if cmd.Undo != "" {
+			undo := Cmd{Cmd: cmd.Undo, Timeout: cmd.Timeout, Env: cmd.Env, Dir: cmd.Dir}
+			s.Register(func() error {
+				output, err := execCmd(undo)
+				if err != nil {
+					return fmt.Errorf("cmd : (%s) returned %s %s", undo.Cmd, err, string(output))
+				}
+				return nil
+			})
+		}
+	}
+	return
+}
+
+// Cleanup calls the registered functions in the reverse order of their
+// registration. The errors are logged so that they don't hide the failure
+// of the test. The stack is empty once done.
+func (s *CleanupStack) Cleanup() {
+	for i := len(s.fns) - 1; i >= 0; i-- {
+		if err := s.fns[i](); err != nil {
+			s.t.Logf("Cleanup error: %s", err)
+		}
+	}
+	s.fns = nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// recordingTB records the logs instead of writing them to the test output
+type recordingTB struct {
+	testing.TB
+	logs []string
+}
+
+func (r *recordingTB) Logf(format string, args ...interface{}) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+func TestCleanupStack(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	stack := NewCleanupStack(tb)
+
+	var calls []int
+	for i := 0; i < 3; i++ {
+		i := i
+		stack.Register(func() error {
+			calls = append(calls, i)
+			if i == 1 {
+				return errors.New("undo failed")
+			}
+			return nil
+		})
+	}
+
+	stack.Cleanup()
+	if !reflect.DeepEqual(calls, []int{2, 1, 0}) {
+		t.Errorf("expected undo functions to be called in reverse order, got %v", calls)
+	}
+	if len(tb.logs) != 1 {
+		t.Errorf("expected the undo error to be logged, got %v", tb.logs)
+	}
+
+	// the stack is empty once cleaned up
+	stack.Cleanup()
+	if len(calls) != 3 {
+		t.Errorf("expected undo functions to be called once, got %v", calls)
+	}
+}
+
+func TestCleanupStackExecCmds(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	stack := NewCleanupStack(tb)
+
+	dir, err := ioutil.TempDir("", "skydive-cleanup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stack.ExecCmds(
+		Cmd{Cmd: "mkdir " + dir + "/a", Undo: "rmdir " + dir + "/a"},
+		Cmd{Cmd: "false", Undo: "touch " + dir + "/not-undone"},
+		Cmd{Cmd: "mkdir " + dir + "/a/b", Undo: "rmdir " + dir + "/a/b"},
+	)
+	stack.Cleanup()
+
+	if _, err := os.Stat(dir + "/a"); !os.IsNotExist(err) {
+		t.Errorf("expected the commands to be undone in reverse order: %v %v", err, tb.logs)
+	}
+	if _, err := os.Stat(dir + "/not-undone"); !os.IsNotExist(err) {
+		t.Error("expected the failed command not to be undone")
+	}
+}
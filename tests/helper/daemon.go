@@ -0,0 +1,130 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+const daemonStopTimeout = 10 * time.Second
+
+// daemonLogs maps the log files of the started daemons to their names
+var daemonLogs = struct {
+	sync.RWMutex
+	names map[string]string
+}{names: make(map[string]string)}
+
+// daemonLogFiles returns the log files of the daemons started so far,
+// mapped to the names of the daemons
+func daemonLogFiles() map[string]string {
+	daemonLogs.RLock()
+	defer daemonLogs.RUnlock()
+
+	files := make(map[string]string, len(daemonLogs.names))
+	for file, name := range daemonLogs.names {
+		files[file] = name
+	}
+	return files
+}
+
+// daemon is a skydive process started with a generated configuration, its
+// output is written to a log file next to the configuration file
+type daemon struct {
+	name       string
+	service    string
+	configFile string
+	cmd        *exec.Cmd
+	done       chan error
+}
+
+func (d *daemon) start() error {
+	if d.cmd != nil {
+		return fmt.Errorf("%s already started", d.name)
+	}
+
+	logFile, err := os.OpenFile(d.configFile+".log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	daemonLogs.Lock()
+	daemonLogs.names[logFile.Name()] = d.name
+	daemonLogs.Unlock()
+
+	cmd := exec.Command(agentBinary, d.service, "-c", d.configFile)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	logging.GetLogger().Debugf("Starting %s with %s", d.name, d.configFile)
+	ports.release()
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("Failed to start %s: %s", d.name, err)
+	}
+
+	d.cmd = cmd
+	d.done = make(chan error, 1)
+	go func() {
+		d.done <- cmd.Wait()
+		logFile.Close()
+	}()
+
+	return nil
+}
+
+// signal sends the signal to the process and its children
+func (d *daemon) signal(sig syscall.Signal) error {
+	if d.cmd == nil {
+		return fmt.Errorf("%s is not running", d.name)
+	}
+	return syscall.Kill(-d.cmd.Process.Pid, sig)
+}
+
+// stop terminates the process and its children, killing them if they don't
+// exit in time. Stopping a process that is not running is a no-op.
+func (d *daemon) stop() error {
+	if d.cmd == nil {
+		return nil
+	}
+	defer func() { d.cmd = nil }()
+
+	pgid := -d.cmd.Process.Pid
+	syscall.Kill(pgid, syscall.SIGTERM)
+
+	select {
+	case <-d.done:
+		return nil
+	case <-time.After(daemonStopTimeout):
+		syscall.Kill(pgid, syscall.SIGKILL)
+		<-d.done
+		return fmt.Errorf("%s killed after %s", d.name, daemonStopTimeout)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+const (
+	dockerClientAPIVersion = "1.22"
+	containerTimeout       = 30 * time.Second
+	containerInterval      = 200 * time.Millisecond
+)
+
+// ContainerOptions describes a container started by StartContainer
+type ContainerOptions struct {
+	Image       string
+	Cmd         []string
+	Labels      map[string]string
+	NetworkMode string
+	// Networks are the test networks the container is attached to
+	Networks   []string
+	Privileged bool
+}
+
+// Container is a container started by StartContainer
+type Container struct {
+	ID   string
+	Name string
+	PID  int
+	t    *testing.T
+	cli  *client.Client
+}
+
+func newDockerClient() (*client.Client, error) {
+	return client.NewClient(config.GetString("docker.url"), dockerClientAPIVersion, nil, nil)
+}
+
+func pullImage(ctx context.Context, cli *client.Client, image string) error {
+	reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(ioutil.Discard, reader)
+	return err
+}
+
+// StartContainer creates and starts a container through the Docker API, attaches
+// it to the given networks and waits for it to be running. The container has to
+// be removed by calling Remove, usually with a defer so that it also happens
+// when the test panics.
+func StartContainer(t *testing.T, name string, opts ContainerOptions) *Container {
+	cli, err := newDockerClient()
+	if err != nil {
+		t.Fatalf("Failed to create Docker client: %s", err)
+	}
+
+	if opts.Image == "" {
+		opts.Image = "busybox"
+	}
+
+	cmd := opts.Cmd
+	if len(cmd) == 0 {
+		cmd = []string{"sh"}
+	}
+
+	containerConfig := &container.Config{
+		Image:     opts.Image,
+		Cmd:       cmd,
+		Labels:    opts.Labels,
+		Tty:       true,
+		OpenStdin: true,
+	}
+	hostConfig := &container.HostConfig{
+		NetworkMode: container.NetworkMode(opts.NetworkMode),
+		Privileged:  opts.Privileged,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerTimeout)
+	defer cancel()
+
+	resp, err := cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, name)
+	if client.IsErrImageNotFound(err) {
+		if err = pullImage(ctx, cli, opts.Image); err != nil {
+			t.Fatalf("Failed to pull image %s: %s", opts.Image, err)
+		}
+		resp, err = cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, name)
+	}
+	if err != nil {
+		t.Fatalf("Failed to create container %s: %s", name, err)
+	}
+
+	c := &Container{ID: resp.ID, Name: name, t: t, cli: cli}
+
+	for _, net := range opts.Networks {
+		if err := cli.NetworkConnect(ctx, net, c.ID, &network.EndpointSettings{}); err != nil {
+			c.Remove()
+			t.Fatalf("Failed to attach container %s to network %s: %s", name, net, err)
+		}
+	}
+
+	if err := cli.ContainerStart(ctx, c.ID, types.ContainerStartOptions{}); err != nil {
+		c.Remove()
+		t.Fatalf("Failed to start container %s: %s", name, err)
+	}
+
+	err = RetryCtx(ctx, containerInterval, func() error {
+		info, err := cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+
+		if info.State == nil || !info.State.Running || info.State.Pid == 0 {
+			return fmt.Errorf("Container %s is not running", name)
+		}
+
+		c.PID = info.State.Pid
+		return nil
+	})
+	if err != nil {
+		c.Remove()
+		t.Fatalf("Container %s did not start: %s", name, err)
+	}
+
+	return c
+}
+
+// Remove kills and removes the container, removing an already removed
+// container is a no-op
+func (c *Container) Remove() {
+	if c.cli == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerTimeout)
+	defer cancel()
+
+	if err := c.cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
+		c.t.Errorf("Failed to remove container %s: %s", c.Name, err)
+	}
+	c.cli.Close()
+	c.cli = nil
+}
+
+// WaitForContainerNode waits for the docker probe to create the node of the
+// container and returns it
+func WaitForContainerNode(t *testing.T, name string) *graph.Node {
+	query := fmt.Sprintf("G.V().Has('Type', 'container', 'Docker.ContainerName', '/%s')", name)
+
+	var node *graph.Node
+	RetryT(t, containerTimeout, containerInterval, func() error {
+		result := &GremlinResult{query: query}
+		if result.data, result.err = queryTopology(query); result.err != nil {
+			return result.err
+		}
+
+		nodes, err := result.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		if len(nodes) != 1 {
+			return fmt.Errorf("Expected one node for container %s, got %d", name, len(nodes))
+		}
+
+		node = nodes[0]
+		return nil
+	})
+
+	return node
+}
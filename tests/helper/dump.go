@@ -0,0 +1,210 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/skydive-project/skydive/config"
+	g "github.com/skydive-project/skydive/gremlin"
+)
+
+const logTailLines = 500
+
+var unsafePathChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// graphScopes are the queries selecting the nodes of the topology of the
+// running tests, indexed by test name
+var graphScopes = struct {
+	sync.Mutex
+	tests map[string]string
+}{tests: make(map[string]string)}
+
+// SetGraphScope restricts the topology of the test, dumped by DumpOnFailure
+// and compared by AssertTopologyMatches, to the subgraph of the nodes
+// selected by the query, like G.V().Has('Name', 'test-br').Descendants().
+// The whole topology is still dumped if -graph.full is given.
+func SetGraphScope(t testing.TB, query interface{}) {
+	graphScopes.Lock()
+	graphScopes.tests[t.Name()] = g.NewQueryStringFromArgument(query).String()
+	graphScopes.Unlock()
+}
+
+// graphScope returns the scope set by the test, empty if none
+func graphScope(t testing.TB) string {
+	graphScopes.Lock()
+	defer graphScopes.Unlock()
+	return graphScopes.tests[t.Name()]
+}
+
+// clearGraphScope removes the scope set by the test
+func clearGraphScope(t testing.TB) {
+	graphScopes.Lock()
+	delete(graphScopes.tests, t.Name())
+	graphScopes.Unlock()
+}
+
+// subGraphQuery returns the query of the subgraph of the nodes selected by
+// the scope, the whole graph if empty
+func subGraphQuery(scope string) string {
+	if scope == "" {
+		return g.G.String()
+	}
+	return scope + ".SubGraph()"
+}
+
+// dumpGraph returns the subgraph selected by the scope in the format
+// selected by -graph.output and the extension of the file it should be
+// written to
+func dumpGraph(scope string) ([]byte, string, error) {
+	query := subGraphQuery(scope)
+
+	switch GraphOutputFormat {
+	case "dot", "ascii":
+		header := http.Header{"Accept": {"vnd.graphviz"}}
+		data, err := queryTopologyWithHeader(query, header)
+		if err != nil || GraphOutputFormat == "dot" {
+			return data, "dot", err
+		}
+
+		cmd := exec.Command("graph-easy", "--as_ascii")
+		cmd.Stdin = bytes.NewReader(append(data, '\n'))
+		if output, err := cmd.CombinedOutput(); err == nil {
+			return output, "txt", nil
+		}
+		// keep the dot output if graph-easy is not available
+		return data, "dot", nil
+	case "graphml", "gexf":
+		data, err := queryTopology(query)
+		if err != nil {
+			return nil, "", err
+		}
+
+		convert := GraphToGraphML
+		if GraphOutputFormat == "gexf" {
+			convert = GraphToGEXF
+		}
+
+		output, err := convert(data)
+		return []byte(output), GraphOutputFormat, err
+	default:
+		data, err := queryTopology(query)
+		return data, "json", err
+	}
+}
+
+// tailFile returns the last lines of a file
+func tailFile(filename string, lines int) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	all := bytes.Split(data, []byte("\n"))
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return bytes.Join(all, []byte("\n")), nil
+}
+
+// logFiles returns the log files of the analyzer and of the agents
+func logFiles() (files []string) {
+	for _, backend := range config.GetStringSlice("logging.backends") {
+		if backend == "file" {
+			files = append(files, config.GetString("logging.file.path"))
+		}
+	}
+
+	// the output of the additional agents is written next to their config
+	for _, configFile := range configFiles {
+		if _, err := os.Stat(configFile + ".log"); err == nil {
+			files = append(files, configFile+".log")
+		}
+	}
+
+	return files
+}
+
+// DumpOnFailure writes the topology, the flows and the end of the logs to
+// the artifact directory of the test if it failed, panicked or if
+// -keep-artifacts is given, and closes the artifact manager of the test.
+// The topology is restricted to the scope set by SetGraphScope. It is meant
+// to be deferred at the beginning of the test.
+func DumpOnFailure(t *testing.T) {
+	if err := recover(); err != nil {
+		t.Fail()
+		defer panic(err)
+	}
+
+	scope := graphScope(t)
+	clearGraphScope(t)
+	if GraphOutputFull {
+		scope = ""
+	}
+
+	a, err := TestArtifacts(t)
+	if err != nil {
+		t.Log(err)
+		return
+	}
+	defer a.Close()
+
+	if !a.Keep() {
+		return
+	}
+
+	var errs []string
+	write := func(name string, data []byte, err error) {
+		if err == nil {
+			_, err = a.WriteFile(name, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+
+	data, ext, err := dumpGraph(scope)
+	write("topology."+ext, data, err)
+
+	data, err = queryTopology(g.G.Flows().Sort(g.DESC, "Last").String())
+	write("flows.json", data, err)
+
+	for _, logFile := range logFiles() {
+		data, err = tailFile(logFile, logTailLines)
+		write("log-"+filepath.Base(logFile), data, err)
+	}
+
+	if len(errs) > 0 {
+		t.Logf("Some artifacts could not be written: %s", strings.Join(errs, ", "))
+	}
+}
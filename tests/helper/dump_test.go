@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+
+	g "github.com/skydive-project/skydive/gremlin"
+)
+
+func TestGraphScope(t *testing.T) {
+	if query := subGraphQuery(graphScope(t)); query != "G" {
+		t.Errorf("Expected the whole graph to be queried without scope, got %s", query)
+	}
+
+	SetGraphScope(t, g.G.V().Has("Name", "test-br"))
+	if query := subGraphQuery(graphScope(t)); query != `G.V().Has("Name", "test-br").SubGraph()` {
+		t.Errorf("Expected the subgraph of the scope to be queried, got %s", query)
+	}
+
+	SetGraphScope(t, "G.V().Has('Name', 'test-br').Descendants()")
+	if query := subGraphQuery(graphScope(t)); query != "G.V().Has('Name', 'test-br').Descendants().SubGraph()" {
+		t.Errorf("Expected the last scope to be queried, got %s", query)
+	}
+
+	clearGraphScope(t)
+	if scope := graphScope(t); scope != "" {
+		t.Errorf("Expected the scope to be cleared, got %s", scope)
+	}
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/embed"
+	"github.com/coreos/etcd/pkg/types"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+const etcdMemberReadyTimeout = 30 * time.Second
+
+// EtcdMember is a member of the etcd cluster started by StartEtcdCluster,
+// listening on loopback ports
+type EtcdMember struct {
+	Name      string
+	ClientURL string
+	PeerURL   string
+	dataDir   string
+	etcd      *embed.Etcd
+}
+
+// etcdCluster is the cluster started by StartEtcdCluster, used by the
+// analyzers instead of an embedded etcd
+var etcdCluster struct {
+	sync.Mutex
+	members []*EtcdMember
+}
+
+// initialCluster returns the peers of the cluster in the etcd syntax
+func initialCluster(members []*EtcdMember) string {
+	var peers []string
+	for _, m := range members {
+		peers = append(peers, m.Name+"="+m.PeerURL)
+	}
+	return strings.Join(peers, ",")
+}
+
+// start starts the member, joining the other members of the cluster. A
+// stopped member rejoins the cluster with its data directory.
+func (m *EtcdMember) start(cluster string) error {
+	clientURL, err := url.Parse(m.ClientURL)
+	if err != nil {
+		return err
+	}
+	peerURL, err := url.Parse(m.PeerURL)
+	if err != nil {
+		return err
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Name = m.Name
+	cfg.Dir = m.dataDir
+	cfg.LCUrls, cfg.ACUrls = types.URLs{*clientURL}, types.URLs{*clientURL}
+	cfg.LPUrls, cfg.APUrls = types.URLs{*peerURL}, types.URLs{*peerURL}
+	cfg.InitialCluster = cluster
+	cfg.InitialClusterToken = "skydive-tests"
+	cfg.ClusterState = embed.ClusterStateFlagNew
+
+	ports.release()
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return fmt.Errorf("Failed to start etcd member %s: %s", m.Name, err)
+	}
+	m.etcd = e
+	return nil
+}
+
+// waitReady waits for the member to join the cluster
+func (m *EtcdMember) waitReady() error {
+	select {
+	case <-m.etcd.Server.ReadyNotify():
+		return nil
+	case err := <-m.etcd.Err():
+		return fmt.Errorf("Etcd member %s failed: %s", m.Name, err)
+	case <-time.After(etcdMemberReadyTimeout):
+		return fmt.Errorf("Etcd member %s not ready after %s", m.Name, etcdMemberReadyTimeout)
+	}
+}
+
+// stop stops the member, keeping its data directory
+func (m *EtcdMember) stop() {
+	if m.etcd != nil {
+		m.etcd.Close()
+		m.etcd = nil
+	}
+}
+
+// StartEtcdCluster starts an etcd cluster of the given number of members on
+// loopback ports allocated dynamically. The generated configurations use the
+// cluster instead of the etcd embedded by the analyzer.
+func StartEtcdCluster(count int) (err error) {
+	etcdCluster.Lock()
+	defer etcdCluster.Unlock()
+
+	if len(etcdCluster.members) > 0 {
+		return fmt.Errorf("Etcd cluster already started")
+	}
+
+	var members []*EtcdMember
+	defer func() {
+		if err != nil {
+			for _, m := range members {
+				m.stop()
+				os.RemoveAll(m.dataDir)
+			}
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		m := &EtcdMember{Name: fmt.Sprintf("skydive-test-%d", i)}
+		members = append(members, m)
+
+		// the peer port is the one following the client port
+		port, err := ports.allocate(m.Name, 2)
+		if err != nil {
+			return err
+		}
+		m.ClientURL = fmt.Sprintf("http://127.0.0.1:%d", port)
+		m.PeerURL = fmt.Sprintf("http://127.0.0.1:%d", port+1)
+
+		if m.dataDir, err = ioutil.TempDir("", "skydive-etcd"); err != nil {
+			return err
+		}
+	}
+
+	// the members are all started before waiting as a member is only ready
+	// once a quorum is reached
+	cluster := initialCluster(members)
+	for _, m := range members {
+		if err := m.start(cluster); err != nil {
+			return err
+		}
+	}
+	for _, m := range members {
+		if err := m.waitReady(); err != nil {
+			return err
+		}
+	}
+
+	logging.GetLogger().Infof("Etcd cluster started: %s", cluster)
+	etcdCluster.members = members
+	return nil
+}
+
+// StopEtcdCluster stops the members of the cluster and removes their data
+func StopEtcdCluster() {
+	etcdCluster.Lock()
+	defer etcdCluster.Unlock()
+
+	for _, m := range etcdCluster.members {
+		m.stop()
+		os.RemoveAll(m.dataDir)
+	}
+	etcdCluster.members = nil
+}
+
+// GetEtcdMember returns the member of the given index, starting from 0
+func GetEtcdMember(index int) (*EtcdMember, error) {
+	etcdCluster.Lock()
+	defer etcdCluster.Unlock()
+
+	if index < 0 || index >= len(etcdCluster.members) {
+		return nil, fmt.Errorf("No etcd member of index %d, %d members started", index, len(etcdCluster.members))
+	}
+	return etcdCluster.members[index], nil
+}
+
+// StopEtcdMember stops the member of the given index, stopping a stopped
+// member is a no-op
+func StopEtcdMember(index int) error {
+	m, err := GetEtcdMember(index)
+	if err != nil {
+		return err
+	}
+
+	etcdCluster.Lock()
+	defer etcdCluster.Unlock()
+
+	m.stop()
+	return nil
+}
+
+// StartEtcdMember starts again the stopped member of the given index and
+// waits for it to rejoin the cluster, which requires a quorum
+func StartEtcdMember(index int) error {
+	m, err := GetEtcdMember(index)
+	if err != nil {
+		return err
+	}
+
+	etcdCluster.Lock()
+	defer etcdCluster.Unlock()
+
+	if m.etcd != nil {
+		return fmt.Errorf("Etcd member %s already started", m.Name)
+	}
+	if err := m.start(initialCluster(etcdCluster.members)); err != nil {
+		return err
+	}
+	return m.waitReady()
+}
+
+// etcdServerURLs returns the etcd servers used by the analyzers: the members
+// of the cluster if started, the server given by -etcd.server, or the etcd
+// embedded by the analyzer of the test process
+func etcdServerURLs() ([]string, error) {
+	etcdCluster.Lock()
+	defer etcdCluster.Unlock()
+
+	if len(etcdCluster.members) > 0 {
+		var urls []string
+		for _, m := range etcdCluster.members {
+			urls = append(urls, m.ClientURL)
+		}
+		return urls, nil
+	}
+	if etcdServer != "" {
+		return []string{etcdServer}, nil
+	}
+
+	port, err := embeddedEtcdPort()
+	if err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("http://localhost:%d", port)}, nil
+}
+
+// embeddedEtcd returns whether the analyzer of the test process embeds etcd
+func embeddedEtcd() bool {
+	etcdCluster.Lock()
+	defer etcdCluster.Unlock()
+
+	return etcdServer == "" && len(etcdCluster.members) == 0
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/skydive-project/skydive/flow"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/logging"
+)
+
+const flowCollectorTimeout = 10 * time.Second
+
+// ErrFlowCollectorClosed is returned when waiting on a closed collector
+var ErrFlowCollectorClosed = errors.New("flow collector closed")
+
+// FlowCollector accumulates the flows published on the flow namespace of a
+// websocket endpoint, keeping the latest version of each flow
+type FlowCollector struct {
+	sync.RWMutex
+	conn     *websocket.Conn
+	protocol string
+	validate bool
+	flows    map[string]*flow.Flow
+	updated  chan struct{}
+	err      error
+	quit     chan struct{}
+}
+
+// NewFlowCollector subscribes to the flow namespace of the endpoint
+func NewFlowCollector(endpoint string) (*FlowCollector, error) {
+	return NewFlowCollectorWithOpts(endpoint, &WSOpts{})
+}
+
+// NewFlowCollectorWithOpts subscribes to the flow namespace of the endpoint
+// using the given connection options
+func NewFlowCollectorWithOpts(endpoint string, opts *WSOpts) (*FlowCollector, error) {
+	o := *opts
+	o.Namespaces = []string{flow.Namespace}
+	if o.Protocol == "" {
+		o.Protocol = shttp.JsonProtocol
+	}
+
+	ctx, cancel := context.WithTimeout(watchdogContext(), flowCollectorTimeout)
+	defer cancel()
+
+	conn, err := WSConnectCtx(ctx, endpoint, wsRetryInterval, nil, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newFlowCollector(o.Protocol)
+	c.validate = o.ValidateMessages
+	c.conn = conn
+	go c.run()
+
+	return c, nil
+}
+
+func newFlowCollector(protocol string) *FlowCollector {
+	return &FlowCollector{
+		protocol: protocol,
+		flows:    make(map[string]*flow.Flow),
+		updated:  make(chan struct{}),
+		quit:     make(chan struct{}),
+	}
+}
+
+func (c *FlowCollector) run() {
+	for {
+		_, b, err := c.conn.ReadMessage()
+		if err != nil {
+			c.stop(err)
+			return
+		}
+
+		msg := DecodeWSStructMessage(b, c.protocol)
+		if msg == nil || msg.Namespace != flow.Namespace {
+			continue
+		}
+
+		if c.validate {
+			if err := ValidateWSMessage(msg); err != nil {
+				c.stop(err)
+				c.conn.Close()
+				return
+			}
+		}
+
+		flows, err := decodeFlowMessage(msg)
+		if err != nil {
+			logging.GetLogger().Debugf("Unable to decode flow message %s: %s", msg.Type, err)
+			continue
+		}
+		c.update(flows)
+	}
+}
+
+// decodeFlowMessage returns the flows of a message which may hold a single
+// flow or a list of flows, like the initial sync
+func decodeFlowMessage(msg *shttp.WSStructMessage) ([]*flow.Flow, error) {
+	var flows []*flow.Flow
+	if err := msg.UnmarshalObj(&flows); err == nil {
+		return flows, nil
+	}
+
+	f := new(flow.Flow)
+	if err := msg.UnmarshalObj(f); err != nil {
+		return nil, err
+	}
+	if f.UUID == "" {
+		return nil, fmt.Errorf("no flow UUID")
+	}
+	return []*flow.Flow{f}, nil
+}
+
+// update stores the flows unless a more recent version is already known
+// and wakes up the waiters
+func (c *FlowCollector) update(flows []*flow.Flow) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, f := range flows {
+		if f == nil || f.UUID == "" {
+			continue
+		}
+		if known, ok := c.flows[f.UUID]; ok && known.Last > f.Last {
+			continue
+		}
+		c.flows[f.UUID] = f
+	}
+
+	close(c.updated)
+	c.updated = make(chan struct{})
+}
+
+func (c *FlowCollector) stop(err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.err == nil {
+		c.err = err
+		close(c.quit)
+	}
+}
+
+// Flows returns the flows collected so far
+func (c *FlowCollector) Flows() []*flow.Flow {
+	c.RLock()
+	defer c.RUnlock()
+
+	flows := make([]*flow.Flow, 0, len(c.flows))
+	for _, f := range c.flows {
+		flows = append(flows, f)
+	}
+	return flows
+}
+
+// WaitFor blocks until a flow matching the predicate has been collected
+// and returns it
+func (c *FlowCollector) WaitFor(predicate func(*flow.Flow) bool, timeout time.Duration) (*flow.Flow, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		c.RLock()
+		for _, f := range c.flows {
+			if predicate(f) {
+				c.RUnlock()
+				return f, nil
+			}
+		}
+		updated, err := c.updated, c.err
+		c.RUnlock()
+
+		if err != nil {
+			return nil, fmt.Errorf("No matching flow: %s", err)
+		}
+
+		select {
+		case <-updated:
+		case <-c.quit:
+		case <-timer.C:
+			return nil, fmt.Errorf("No matching flow after %s, got %d flows", timeout, len(c.Flows()))
+		}
+	}
+}
+
+// Close closes the connection, waiting on the collector fails afterwards
+func (c *FlowCollector) Close() {
+	c.stop(ErrFlowCollectorClosed)
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func TestFlowCollector(t *testing.T) {
+	c := newFlowCollector("json")
+
+	syncMsg := DecodeWSStructMessageJSON([]byte(`{"Namespace":"Flow","Type":"SyncReply","Obj":[{"UUID":"a","Last":2},{"UUID":"b","Last":1}]}`))
+	flows, err := decodeFlowMessage(syncMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.update(flows)
+
+	// an older version of a flow is ignored
+	update := DecodeWSStructMessageJSON([]byte(`{"Namespace":"Flow","Type":"FlowUpdated","Obj":{"UUID":"a","Last":1,"Application":"old"}}`))
+	if flows, err = decodeFlowMessage(update); err != nil {
+		t.Fatal(err)
+	}
+	c.update(flows)
+
+	if flows := c.Flows(); len(flows) != 2 {
+		t.Fatalf("Expected 2 flows, got: %+v", flows)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.update([]*flow.Flow{{UUID: "b", Last: 3, Application: "DNS"}})
+	}()
+
+	f, err := c.WaitFor(func(f *flow.Flow) bool { return f.Application == "DNS" }, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.UUID != "b" {
+		t.Fatalf("Expected flow b, got: %+v", f)
+	}
+
+	if _, err := c.WaitFor(func(f *flow.Flow) bool { return f.Application == "old" }, 100*time.Millisecond); err == nil {
+		t.Fatal("Stale flow version should have been ignored")
+	}
+
+	c.Close()
+	if _, err := c.WaitFor(func(f *flow.Flow) bool { return false }, 5*time.Second); err == nil {
+		t.Fatal("Waiting on a closed collector should fail")
+	}
+}
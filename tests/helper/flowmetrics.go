@@ -0,0 +1,181 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+// MetricExpectation describes the metrics checked by ValidateFlowMetrics,
+// zero fields disabling the corresponding checks
+type MetricExpectation struct {
+	// Minimum packets and bytes counted from A to B and from B to A
+	MinPacketsAB int64
+	MinPacketsBA int64
+	MinBytesAB   int64
+	MinBytesBA   int64
+	// PacketSize is the size of the frames, the bytes of each direction
+	// having to be about its packets times the size
+	PacketSize int64
+	// Tolerance is the relative difference allowed between the bytes and
+	// their expected value, like 0.1 for 10%
+	Tolerance float64
+	// Symmetric requires the same packets and about the same bytes in
+	// both directions, like for echo traffic
+	Symmetric bool
+	// CaptureLength is the size the packets are truncated to by the
+	// capture, like its HeaderSize. When the length of the packets on the
+	// wire is not known, only the captured bytes are counted and the
+	// expected bytes are capped to CaptureLength per packet.
+	CaptureLength int64
+}
+
+// capture returns the bytes counted for packets frames of the given size
+func (e *MetricExpectation) capture(packets, size int64) int64 {
+	if e.CaptureLength != 0 && size > packets*e.CaptureLength {
+		return packets * e.CaptureLength
+	}
+	return size
+}
+
+// within returns whether the value is the expected one, given the tolerance
+func (e *MetricExpectation) within(value, expected int64) bool {
+	return math.Abs(float64(value-expected)) <= e.Tolerance*float64(expected)
+}
+
+// metricErrors returns the inconsistencies of a metric, like negative
+// counters or a metric ending before it starts
+func metricErrors(name string, m *flow.FlowMetric) (errors []string) {
+	counters := []struct {
+		direction     string
+		packets, size int64
+	}{
+		{"AB", m.ABPackets, m.ABBytes},
+		{"BA", m.BAPackets, m.BABytes},
+	}
+	for _, c := range counters {
+		switch {
+		case c.packets < 0 || c.size < 0:
+			errors = append(errors, fmt.Sprintf("%s: negative %s counters, %d packets and %d bytes", name, c.direction, c.packets, c.size))
+		case c.packets == 0 && c.size != 0:
+			errors = append(errors, fmt.Sprintf("%s: %d %s bytes without packets", name, c.size, c.direction))
+		case c.packets != 0 && c.size == 0:
+			errors = append(errors, fmt.Sprintf("%s: %d %s packets without bytes", name, c.packets, c.direction))
+		}
+	}
+
+	if m.Last < m.Start {
+		errors = append(errors, fmt.Sprintf("%s: Last %d before Start %d", name, m.Last, m.Start))
+	}
+	return
+}
+
+// flowMetricErrors returns the reasons why the metrics of the flow don't
+// fulfill the expectation
+func flowMetricErrors(f *flow.Flow, e MetricExpectation) (errors []string) {
+	m := f.Metric
+	if m == nil {
+		return []string{"no Metric"}
+	}
+	errors = metricErrors("Metric", m)
+
+	if m.Start < f.Start || (f.Last != 0 && m.Last > f.Last) {
+		errors = append(errors, fmt.Sprintf("Metric: [%d,%d] outside of the flow [%d,%d]", m.Start, m.Last, f.Start, f.Last))
+	}
+
+	directions := []struct {
+		name                string
+		packets, size       int64
+		minPackets, minSize int64
+	}{
+		{"AB", m.ABPackets, m.ABBytes, e.MinPacketsAB, e.MinBytesAB},
+		{"BA", m.BAPackets, m.BABytes, e.MinPacketsBA, e.MinBytesBA},
+	}
+	for _, d := range directions {
+		if d.packets < d.minPackets {
+			errors = append(errors, fmt.Sprintf("Metric: %sPackets %d, expected at least %d", d.name, d.packets, d.minPackets))
+		}
+		if min := e.capture(d.packets, d.minSize); d.size < min {
+			errors = append(errors, fmt.Sprintf("Metric: %sBytes %d, expected at least %d", d.name, d.size, min))
+		}
+		if e.PacketSize != 0 {
+			if expected := e.capture(d.packets, d.packets*e.PacketSize); !e.within(d.size, expected) {
+				errors = append(errors, fmt.Sprintf("Metric: %sBytes %d, expected %d for %d packets of %d bytes (tolerance %g)",
+					d.name, d.size, expected, d.packets, e.PacketSize, e.Tolerance))
+			}
+		}
+	}
+
+	if e.Symmetric {
+		if m.ABPackets != m.BAPackets {
+			errors = append(errors, fmt.Sprintf("Metric: asymmetric packets, %d AB and %d BA", m.ABPackets, m.BAPackets))
+		}
+		if !e.within(m.BABytes, m.ABBytes) {
+			errors = append(errors, fmt.Sprintf("Metric: asymmetric bytes, %d AB and %d BA (tolerance %g)", m.ABBytes, m.BABytes, e.Tolerance))
+		}
+	}
+
+	// LastUpdateMetric holds what was counted since the previous update
+	if lm := f.LastUpdateMetric; lm != nil {
+		errors = append(errors, metricErrors("LastUpdateMetric", lm)...)
+
+		if lm.ABPackets > m.ABPackets || lm.ABBytes > m.ABBytes || lm.BAPackets > m.BAPackets || lm.BABytes > m.BABytes {
+			errors = append(errors, fmt.Sprintf("LastUpdateMetric: counters %d/%d AB %d/%d BA greater than the ones of Metric %d/%d AB %d/%d BA",
+				lm.ABPackets, lm.ABBytes, lm.BAPackets, lm.BABytes, m.ABPackets, m.ABBytes, m.BAPackets, m.BABytes))
+		}
+		if lm.Start < f.Start {
+			errors = append(errors, fmt.Sprintf("LastUpdateMetric: Start %d before the start of the flow %d", lm.Start, f.Start))
+		}
+	}
+
+	return
+}
+
+// ValidateFlowMetrics fails the test if the metrics of a flow are not
+// consistent or don't fulfill the expectation, reporting every failure of
+// each flow
+func ValidateFlowMetrics(t *testing.T, flows []*flow.Flow, e MetricExpectation) {
+	var b bytes.Buffer
+	failed := 0
+	for _, f := range flows {
+		errors := flowMetricErrors(f, e)
+		if len(errors) == 0 {
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(&b, "flow %s:\n", describeFlow(f))
+		for _, err := range errors {
+			fmt.Fprintf(&b, "    %s\n", err)
+		}
+	}
+
+	if failed != 0 {
+		t.Errorf("Invalid metrics for %d of %d flows:\n%s", failed, len(flows), b.String())
+	}
+}
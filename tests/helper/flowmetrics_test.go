@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func echoFlow(abBytes, baBytes int64) *flow.Flow {
+	return &flow.Flow{
+		UUID:  "echo",
+		Start: 1000,
+		Last:  3000,
+		Metric: &flow.FlowMetric{
+			ABPackets: 5, ABBytes: abBytes,
+			BAPackets: 5, BABytes: baBytes,
+			Start: 1000, Last: 3000,
+		},
+		LastUpdateMetric: &flow.FlowMetric{
+			ABPackets: 2, ABBytes: 2 * abBytes / 5,
+			BAPackets: 2, BABytes: 2 * baBytes / 5,
+			Start: 2000, Last: 3000,
+		},
+	}
+}
+
+func TestFlowMetricErrors(t *testing.T) {
+	echo := MetricExpectation{MinPacketsAB: 5, MinBytesAB: 490, PacketSize: 98, Tolerance: 0.1, Symmetric: true}
+
+	if errors := flowMetricErrors(echoFlow(490, 490), echo); len(errors) != 0 {
+		t.Errorf("Expected valid metrics, got %v", errors)
+	}
+
+	tests := []struct {
+		name     string
+		flow     *flow.Flow
+		modify   func(f *flow.Flow)
+		expected string
+	}{
+		{"min bytes", echoFlow(400, 400), nil, "ABBytes 400, expected at least 490"},
+		{"packet size", echoFlow(490, 300), nil, "BABytes 300, expected 490 for 5 packets of 98 bytes"},
+		{"asymmetric", echoFlow(490, 545), nil, "asymmetric bytes, 490 AB and 545 BA"},
+		{"last before start", echoFlow(490, 490), func(f *flow.Flow) { f.Metric.Last = 500 }, "Metric: Last 500 before Start 1000"},
+		{"last update", echoFlow(490, 490), func(f *flow.Flow) { f.LastUpdateMetric.BAPackets = 6 }, "LastUpdateMetric: counters"},
+	}
+
+	for _, test := range tests {
+		if test.modify != nil {
+			test.modify(test.flow)
+		}
+		errors := flowMetricErrors(test.flow, echo)
+		if !strings.Contains(strings.Join(errors, "\n"), test.expected) {
+			t.Errorf("%s: expected error '%s', got %v", test.name, test.expected, errors)
+		}
+	}
+}
+
+func TestFlowMetricErrorsTruncated(t *testing.T) {
+	// only the first 64 bytes of the 98 bytes frames are counted
+	f := echoFlow(5*64, 5*64)
+	e := MetricExpectation{MinBytesAB: 490, PacketSize: 98, Symmetric: true}
+
+	if errors := flowMetricErrors(f, e); len(errors) == 0 {
+		t.Error("Expected truncated byte counts to be reported")
+	}
+
+	e.CaptureLength = 64
+	if errors := flowMetricErrors(f, e); len(errors) != 0 {
+		t.Errorf("Expected truncated byte counts to be accepted, got %v", errors)
+	}
+}
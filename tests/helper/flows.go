@@ -0,0 +1,382 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+// FlowExpectation describes a flow expected by ExpectFlows. Endpoints match
+// in either direction, empty fields and zero bounds match any flow.
+type FlowExpectation struct {
+	// Proto is the protocol of the network layer, A and B its addresses.
+	// ETHERNET matches the addresses against the link layer.
+	Proto flow.FlowProtocol
+	A     string
+	B     string
+	// Transport is the protocol of the transport layer, PortA and PortB
+	// the ports of A and B
+	Transport   flow.FlowProtocol
+	PortA       int64
+	PortB       int64
+	Application string
+	// Bounds of the packets and bytes counted in both directions
+	MinPackets int64
+	MaxPackets int64
+	MinBytes   int64
+	MaxBytes   int64
+	// Directional counts, the packets being checked exactly and the bytes
+	// within ByteTolerance, a relative difference like 0.05. The flow is
+	// oriented like the expectation, these counts are not checked if
+	// Directional is false.
+	Directional   bool
+	ABPackets     int64
+	ABBytes       int64
+	BAPackets     int64
+	BABytes       int64
+	ByteTolerance float64
+}
+
+// Sizes of the headers of the frames generated by the traffic helpers
+const (
+	ethernetHeaderSize = 14
+	ipv4HeaderSize     = 20
+	ipv6HeaderSize     = 40
+	icmpHeaderSize     = 8
+	udpHeaderSize      = 8
+	tcpHeaderSize      = 20
+	// minFrameSize is the size up to which the frames are padded
+	minFrameSize = 60
+)
+
+func frameSize(size int64) int64 {
+	if size < minFrameSize {
+		return minFrameSize
+	}
+	return size
+}
+
+// networkExpectation returns the network protocol of the address, IPv4 or
+// IPv6, and the size of its header
+func networkExpectation(addr string) (flow.FlowProtocol, int64) {
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		return flow.FlowProtocol_IPV6, ipv6HeaderSize
+	}
+	return flow.FlowProtocol_IPV4, ipv4HeaderSize
+}
+
+// ICMPEchoExpectation returns the expectation of the flow generated by
+// SendICMPEcho or SendICMPv6Echo between the addresses a and b, the packets
+// and bytes of the requests and replies being counted exactly
+func ICMPEchoExpectation(a, b string, packets, payloadSize int) FlowExpectation {
+	proto, headerSize := networkExpectation(a)
+	count := 2 * int64(packets)
+	size := count * frameSize(ethernetHeaderSize+headerSize+icmpHeaderSize+int64(payloadSize))
+
+	return FlowExpectation{Proto: proto, A: a, B: b, MinPackets: count, MaxPackets: count, MinBytes: size, MaxBytes: size}
+}
+
+// TCPSessionExpectation returns the expectation of the flow generated by
+// SendTCPSession between the addresses a and b, given as host:port, the
+// packets and bytes of each direction being counted exactly
+func TCPSessionExpectation(a, b string, segments, payloadSize int) (FlowExpectation, error) {
+	e, err := parseTrafficEndpoints(a, b, true)
+	if err != nil {
+		return FlowExpectation{}, err
+	}
+
+	proto, headerSize := networkExpectation(e.ipA.String())
+	emptySize := frameSize(ethernetHeaderSize + headerSize + tcpHeaderSize)
+	dataSize := frameSize(ethernetHeaderSize + headerSize + tcpHeaderSize + int64(payloadSize))
+
+	// A sends the SYN, the ACK of the handshake, the data, its FIN and the
+	// last ACK, B the SYN-ACK, the ACK of each segment and its FIN
+	count := int64(segments)
+	abPackets, baPackets := count+4, count+2
+	abBytes := 4*emptySize + count*dataSize
+	baBytes := baPackets * emptySize
+
+	return FlowExpectation{
+		Proto:       proto,
+		A:           e.ipA.String(),
+		B:           e.ipB.String(),
+		Transport:   flow.FlowProtocol_TCP,
+		PortA:       int64(e.portA),
+		PortB:       int64(e.portB),
+		MinPackets:  abPackets + baPackets,
+		MaxPackets:  abPackets + baPackets,
+		MinBytes:    abBytes + baBytes,
+		MaxBytes:    abBytes + baBytes,
+		Directional: true,
+		ABPackets:   abPackets,
+		ABBytes:     abBytes,
+		BAPackets:   baPackets,
+		BABytes:     baBytes,
+	}, nil
+}
+
+// UDPFlowExpectation returns the expectation of the flow generated by
+// SendUDPFlow between the addresses a and b, given as host:port
+func UDPFlowExpectation(a, b string, packets, payloadSize int) (FlowExpectation, error) {
+	e, err := parseTrafficEndpoints(a, b, true)
+	if err != nil {
+		return FlowExpectation{}, err
+	}
+
+	proto, headerSize := networkExpectation(e.ipA.String())
+	count := int64(packets)
+	size := count * frameSize(ethernetHeaderSize+headerSize+udpHeaderSize+int64(payloadSize))
+
+	return FlowExpectation{
+		Proto:      proto,
+		A:          e.ipA.String(),
+		B:          e.ipB.String(),
+		Transport:  flow.FlowProtocol_UDP,
+		PortA:      int64(e.portA),
+		PortB:      int64(e.portB),
+		MinPackets: count,
+		MaxPackets: count,
+		MinBytes:   size,
+		MaxBytes:   size,
+	}, nil
+}
+
+func (e *FlowExpectation) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s <-> %s", e.Proto, orAny(e.A), orAny(e.B))
+	if e.Transport != flow.FlowProtocol_ETHERNET || e.PortA != 0 || e.PortB != 0 {
+		fmt.Fprintf(&b, " %s %d <-> %d", e.Transport, e.PortA, e.PortB)
+	}
+	if e.Application != "" {
+		fmt.Fprintf(&b, " app=%s", e.Application)
+	}
+	fmt.Fprintf(&b, " packets=[%d,%s] bytes=[%d,%s]", e.MinPackets, boundString(e.MaxPackets), e.MinBytes, boundString(e.MaxBytes))
+	if e.Directional {
+		fmt.Fprintf(&b, " AB=%d/%d BA=%d/%d", e.ABPackets, e.ABBytes, e.BAPackets, e.BABytes)
+	}
+	return b.String()
+}
+
+func orAny(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
+
+func boundString(max int64) string {
+	if max == 0 {
+		return "*"
+	}
+	return fmt.Sprintf("%d", max)
+}
+
+// describeFlow returns a one line summary of a flow
+func describeFlow(f *flow.Flow) string {
+	var b bytes.Buffer
+	b.WriteString(f.UUID)
+	if f.Link != nil {
+		fmt.Fprintf(&b, " %s %s <-> %s", f.Link.Protocol, f.Link.A, f.Link.B)
+	}
+	if f.Network != nil {
+		fmt.Fprintf(&b, " %s %s <-> %s", f.Network.Protocol, f.Network.A, f.Network.B)
+	}
+	if f.Transport != nil {
+		fmt.Fprintf(&b, " %s %d <-> %d", f.Transport.Protocol, f.Transport.A, f.Transport.B)
+	}
+	fmt.Fprintf(&b, " app=%s", f.Application)
+	if f.Metric != nil {
+		fmt.Fprintf(&b, " packets=%d bytes=%d AB=%d/%d BA=%d/%d", f.Metric.ABPackets+f.Metric.BAPackets, f.Metric.ABBytes+f.Metric.BABytes,
+			f.Metric.ABPackets, f.Metric.ABBytes, f.Metric.BAPackets, f.Metric.BABytes)
+	}
+	return b.String()
+}
+
+func inBounds(value, min, max int64) bool {
+	return value >= min && (max == 0 || value <= max)
+}
+
+// matchEndpoints returns whether the flow is between the endpoints of the
+// expectation, regardless of its metrics
+func (e *FlowExpectation) matchEndpoints(f *flow.Flow) bool {
+	matched, _ := e.orientation(f)
+	return matched
+}
+
+// orientation returns whether the flow is between the endpoints of the
+// expectation and whether its A endpoint is the B one of the expectation
+func (e *FlowExpectation) orientation(f *flow.Flow) (matched bool, reversed bool) {
+	layer := f.Network
+	if e.Proto == flow.FlowProtocol_ETHERNET {
+		layer = f.Link
+	}
+	if layer == nil || layer.Protocol != e.Proto {
+		return false, false
+	}
+
+	var portA, portB int64
+	if f.Transport != nil {
+		portA, portB = f.Transport.A, f.Transport.B
+	}
+	if e.Transport != flow.FlowProtocol_ETHERNET && (f.Transport == nil || f.Transport.Protocol != e.Transport) {
+		return false, false
+	}
+	if (e.PortA != 0 || e.PortB != 0) && f.Transport == nil {
+		return false, false
+	}
+
+	match := func(value, expected string) bool { return expected == "" || value == expected }
+	matchPort := func(value, expected int64) bool { return expected == 0 || value == expected }
+
+	forward := match(layer.A, e.A) && match(layer.B, e.B) && matchPort(portA, e.PortA) && matchPort(portB, e.PortB)
+	reverse := match(layer.A, e.B) && match(layer.B, e.A) && matchPort(portA, e.PortB) && matchPort(portB, e.PortA)
+	if !forward && !reverse {
+		return false, false
+	}
+
+	if e.Application != "" && !strings.EqualFold(f.Application, e.Application) {
+		return false, false
+	}
+	return true, !forward
+}
+
+// withinTolerance returns whether the value is the expected one, given the
+// relative tolerance
+func withinTolerance(value, expected int64, tolerance float64) bool {
+	diff := value - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) <= tolerance*float64(expected)
+}
+
+// matchDirections returns whether the counts of each direction of the flow
+// are the expected ones
+func (e *FlowExpectation) matchDirections(f *flow.Flow, reversed bool) bool {
+	var abPackets, abBytes, baPackets, baBytes int64
+	if m := f.Metric; m != nil {
+		abPackets, abBytes, baPackets, baBytes = m.ABPackets, m.ABBytes, m.BAPackets, m.BABytes
+	}
+	if reversed {
+		abPackets, abBytes, baPackets, baBytes = baPackets, baBytes, abPackets, abBytes
+	}
+
+	return abPackets == e.ABPackets && baPackets == e.BAPackets &&
+		withinTolerance(abBytes, e.ABBytes, e.ByteTolerance) && withinTolerance(baBytes, e.BABytes, e.ByteTolerance)
+}
+
+// Match returns whether the flow fulfills the expectation
+func (e *FlowExpectation) Match(f *flow.Flow) bool {
+	matched, reversed := e.orientation(f)
+	if !matched {
+		return false
+	}
+	if e.Directional && !e.matchDirections(f, reversed) {
+		return false
+	}
+
+	var packets, size int64
+	if f.Metric != nil {
+		packets = f.Metric.ABPackets + f.Metric.BAPackets
+		size = f.Metric.ABBytes + f.Metric.BABytes
+	}
+
+	return inBounds(packets, e.MinPackets, e.MaxPackets) && inBounds(size, e.MinBytes, e.MaxBytes)
+}
+
+// MatchFlows pairs each expectation with a distinct flow and returns the
+// expectations left unmatched and the flows that were not expected
+func MatchFlows(flows []*flow.Flow, expectations []FlowExpectation) (unmatched []FlowExpectation, unexpected []*flow.Flow) {
+	// owners maps a flow index to the index of the expectation it fulfills
+	owners := make([]int, len(flows))
+	for i := range owners {
+		owners[i] = -1
+	}
+
+	// augment looks for a flow for the expectation, possibly moving the
+	// flow of another expectation to an alternative one
+	var augment func(e int, visited []bool) bool
+	augment = func(e int, visited []bool) bool {
+		for i, f := range flows {
+			if visited[i] || !expectations[e].Match(f) {
+				continue
+			}
+			visited[i] = true
+			if owners[i] == -1 || augment(owners[i], visited) {
+				owners[i] = e
+				return true
+			}
+		}
+		return false
+	}
+
+	for e := range expectations {
+		if !augment(e, make([]bool, len(flows))) {
+			unmatched = append(unmatched, expectations[e])
+		}
+	}
+
+	for i, f := range flows {
+		if owners[i] == -1 {
+			unexpected = append(unexpected, f)
+		}
+	}
+
+	return unmatched, unexpected
+}
+
+// ExpectFlows fails the test if an expectation is not fulfilled by a distinct
+// flow or if a flow is not expected. The report lists, for each unmatched
+// expectation, the flows between its endpoints that failed on their metrics.
+func ExpectFlows(t *testing.T, flows []*flow.Flow, expectations []FlowExpectation) {
+	unmatched, unexpected := MatchFlows(flows, expectations)
+	if len(unmatched) == 0 && len(unexpected) == 0 {
+		return
+	}
+
+	t.Errorf("Flows don't match the expectations (%d unmatched, %d unexpected):\n%s", len(unmatched), len(unexpected), mismatchReport(flows, unmatched, unexpected))
+}
+
+// mismatchReport lists the unmatched expectations with their candidate
+// flows and the unexpected flows
+func mismatchReport(flows []*flow.Flow, unmatched []FlowExpectation, unexpected []*flow.Flow) string {
+	var b bytes.Buffer
+	for _, e := range unmatched {
+		fmt.Fprintf(&b, "unmatched expectation: %s\n", e.String())
+		for _, f := range flows {
+			if e.matchEndpoints(f) {
+				fmt.Fprintf(&b, "    candidate: %s\n", describeFlow(f))
+			}
+		}
+	}
+	for _, f := range unexpected {
+		fmt.Fprintf(&b, "unexpected flow: %s\n", describeFlow(f))
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/skydive-project/skydive/flow"
+	g "github.com/skydive-project/skydive/gremlin"
+)
+
+// flowEndpoints returns the protocols and the endpoints of a flow, the
+// network addresses being used if any and the ports added if any
+func flowEndpoints(f *flow.Flow) (proto, a, b string) {
+	layer := f.Network
+	if layer == nil {
+		layer = f.Link
+	}
+	if layer == nil {
+		return "-", "-", "-"
+	}
+
+	proto, a, b = layer.Protocol.String(), layer.A, layer.B
+	if f.Network != nil && f.Transport != nil {
+		proto += "/" + f.Transport.Protocol.String()
+		a = net.JoinHostPort(a, strconv.FormatInt(f.Transport.A, 10))
+		b = net.JoinHostPort(b, strconv.FormatInt(f.Transport.B, 10))
+	}
+	return proto, a, b
+}
+
+// flowBytes returns the bytes of both directions of a flow
+func flowBytes(f *flow.Flow) int64 {
+	if f.Metric == nil {
+		return 0
+	}
+	return f.Metric.ABBytes + f.Metric.BABytes
+}
+
+// formatFlowTable returns one aligned line per flow, sorted by bytes, the
+// capture nodes being named after the names map indexed by TID
+func formatFlowTable(flows []*flow.Flow, names map[string]string) string {
+	sorted := make([]*flow.Flow, len(flows))
+	copy(sorted, flows)
+	sort.SliceStable(sorted, func(i, j int) bool { return flowBytes(sorted[i]) > flowBytes(sorted[j]) })
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d flows:\n", len(flows))
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROTO\tA\t\tB\tAPP\tAB PKTS\tAB BYTES\tBA PKTS\tBA BYTES\tNODE")
+	for _, f := range sorted {
+		proto, a, z := flowEndpoints(f)
+
+		var m flow.FlowMetric
+		if f.Metric != nil {
+			m = *f.Metric
+		}
+
+		node := names[f.NodeTID]
+		if node == "" {
+			node = f.NodeTID
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t->\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			proto, a, z, f.Application, m.ABPackets, m.ABBytes, m.BAPackets, m.BABytes, node)
+	}
+	w.Flush()
+
+	return b.String()
+}
+
+// flowNodeNames returns the names of the nodes the flows were captured on,
+// indexed by TID. The names are left empty if the topology can't be queried.
+func flowNodeNames(flows []*flow.Flow) map[string]string {
+	names := make(map[string]string)
+	for _, f := range flows {
+		if f.NodeTID != "" {
+			names[f.NodeTID] = ""
+		}
+	}
+	if len(names) == 0 {
+		return names
+	}
+
+	result := &GremlinResult{query: g.G.V().HasKey("TID").String()}
+	if result.data, result.err = queryTopology(result.query); result.err != nil {
+		return names
+	}
+
+	nodes, err := result.GetNodes()
+	if err != nil {
+		return names
+	}
+	for _, node := range nodes {
+		tid, _ := node.GetFieldString("TID")
+		if _, found := names[tid]; found {
+			names[tid], _ = node.GetFieldString("Name")
+		}
+	}
+	return names
+}
+
+// FlowsToJSON returns the flows as indented JSON
+func FlowsToJSON(flows []*flow.Flow) string {
+	b, _ := json.MarshalIndent(flows, "", "\t")
+	return fmt.Sprintf("%d flows:\n%s\n", len(flows), string(b))
+}
+
+// FlowsToString returns one line per flow with its endpoints, application,
+// metrics and capture node, the flows with the most bytes first. The flows
+// are dumped in JSON if -flows.verbose is given.
+func FlowsToString(flows []*flow.Flow) string {
+	if FlowsVerbose {
+		return FlowsToJSON(flows)
+	}
+	return formatFlowTable(flows, flowNodeNames(flows))
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func TestFormatFlowTable(t *testing.T) {
+	flows := []*flow.Flow{
+		{NodeTID: "tid1", Application: "ARP", Link: &flow.FlowLayer{Protocol: flow.FlowProtocol_ETHERNET, A: "02:00:00:00:00:01", B: "ff:ff:ff:ff:ff:ff"}},
+		{NodeTID: "tid2", Application: "TCP",
+			Network:   &flow.FlowLayer{Protocol: flow.FlowProtocol_IPV6, A: "fd00::1", B: "fd00::2"},
+			Transport: &flow.TransportLayer{Protocol: flow.FlowProtocol_TCP, A: 47838, B: 80},
+			Metric:    &flow.FlowMetric{ABPackets: 10, ABBytes: 1000, BAPackets: 8, BABytes: 12000}},
+		{NodeTID: "tid1", Application: "ICMPv4",
+			Network: &flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: "10.0.0.1", B: "10.0.0.2"},
+			Metric:  &flow.FlowMetric{ABPackets: 1, ABBytes: 98, BAPackets: 1, BABytes: 98}},
+	}
+
+	lines := strings.Split(strings.TrimSpace(formatFlowTable(flows, map[string]string{"tid1": "eth0"})), "\n")
+	if len(lines) != 5 || lines[0] != "3 flows:" {
+		t.Fatalf("Expected a header and one line per flow, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	expected := [][]string{
+		{"IPV6/TCP", "[fd00::1]:47838", "->", "[fd00::2]:80", "TCP", "10", "1000", "8", "12000", "tid2"},
+		{"IPV4", "10.0.0.1", "->", "10.0.0.2", "ICMPv4", "1", "98", "1", "98", "eth0"},
+		{"ETHERNET", "02:00:00:00:00:01", "->", "ff:ff:ff:ff:ff:ff", "ARP", "0", "0", "0", "0", "eth0"},
+	}
+	for i, fields := range expected {
+		if line := strings.Fields(lines[i+2]); strings.Join(line, " ") != strings.Join(fields, " ") {
+			t.Errorf("Expected line %v, got %v", fields, line)
+		}
+	}
+
+	// the columns are aligned
+	column := strings.Index(lines[1], "APP")
+	for _, line := range lines[2:] {
+		if len(line) < column || line[column-1] != ' ' || line[column] == ' ' {
+			t.Errorf("Expected the application to start at column %d:\n%s\n%s", column, lines[1], line)
+		}
+	}
+}
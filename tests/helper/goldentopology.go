@@ -0,0 +1,382 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+// volatileMetadataKeys are the metadata keys removed from the topologies
+// compared by AssertTopologyMatches, their values changing between runs
+var volatileMetadataKeys = []string{
+	"Captures", "IfIndex", "LastUpdateMetric", "MAC", "Metric", "PeerIfIndex", "TID",
+}
+
+// TopologyNormalizeOpts describes the topology compared by
+// AssertTopologyMatches and how its volatile fields are normalized. The IDs
+// and the timestamps of the nodes and edges are never compared.
+type TopologyNormalizeOpts struct {
+	// Query selects the nodes of the compared subgraph, like
+	// G.V().Has('Type', 'libvirt'), the scope set by SetGraphScope or the
+	// whole topology being compared if empty. The edges between the
+	// selected nodes are compared too.
+	Query string
+	// IgnoredKeys are the dotted paths of the metadata keys removed, in
+	// addition to the volatile ones like MAC or IfIndex
+	IgnoredKeys []string
+	// Replacements maps the volatile strings of the metadata, like host
+	// names, to the placeholders stored in the golden file. The host name
+	// is replaced by $HOSTNAME unless mapped.
+	Replacements map[string]string
+	// Timeout is the time given to the topology to match the golden file
+	Timeout time.Duration
+}
+
+// goldenTopology is a normalized topology, the nodes being identified by
+// their type and name and the edges by the nodes they link
+type goldenTopology struct {
+	Nodes map[string]map[string]interface{}
+	Edges map[string]map[string]interface{}
+}
+
+// replacer returns the replacer of the volatile strings, the longest ones
+// being replaced first
+func (o *TopologyNormalizeOpts) replacer() *strings.Replacer {
+	replacements := make(map[string]string)
+	if hostname, err := os.Hostname(); err == nil {
+		replacements[hostname] = "$HOSTNAME"
+	}
+	for k, v := range o.Replacements {
+		replacements[k] = v
+	}
+
+	var olds []string
+	for old := range replacements {
+		if old != "" {
+			olds = append(olds, old)
+		}
+	}
+	sort.Slice(olds, func(i, j int) bool {
+		if len(olds[i]) != len(olds[j]) {
+			return len(olds[i]) > len(olds[j])
+		}
+		return olds[i] < olds[j]
+	})
+
+	var pairs []string
+	for _, old := range olds {
+		pairs = append(pairs, old, replacements[old])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// replaceValues applies the replacer to the strings of a metadata value
+func replaceValues(v interface{}, r *strings.Replacer) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Replace(v)
+	case map[string]interface{}:
+		for k, value := range v {
+			v[k] = replaceValues(value, r)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = replaceValues(value, r)
+		}
+	}
+	return v
+}
+
+// deleteMetadataKey removes the key of the given dotted path
+func deleteMetadataKey(m map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		var ok bool
+		if m, ok = m[k].(map[string]interface{}); !ok {
+			return
+		}
+	}
+	delete(m, keys[len(keys)-1])
+}
+
+// normalizeMetadata removes the ignored keys and replaces the volatile
+// strings of the metadata of an element
+func (o *TopologyNormalizeOpts) normalizeMetadata(m map[string]interface{}, r *strings.Replacer) map[string]interface{} {
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	for _, key := range append(volatileMetadataKeys, o.IgnoredKeys...) {
+		deleteMetadataKey(m, key)
+	}
+	return replaceValues(m, r).(map[string]interface{})
+}
+
+// canonicalJSON returns the JSON encoding of a value, the keys of the maps
+// being sorted
+func canonicalJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// labelElements returns the metadata of the elements keyed by their labels,
+// the elements sharing a label being numbered in the order of their metadata
+func labelElements(labels []string, metadata []map[string]interface{}) (map[string]map[string]interface{}, []string) {
+	indexes := make([]int, len(labels))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := indexes[i], indexes[j]
+		if labels[a] != labels[b] {
+			return labels[a] < labels[b]
+		}
+		return canonicalJSON(metadata[a]) < canonicalJSON(metadata[b])
+	})
+
+	elements := make(map[string]map[string]interface{})
+	unique := make([]string, len(labels))
+	count := make(map[string]int)
+	for _, i := range indexes {
+		label := labels[i]
+		if count[label]++; count[label] > 1 {
+			label = fmt.Sprintf("%s #%d", label, count[label])
+		}
+		elements[label] = metadata[i]
+		unique[i] = label
+	}
+	return elements, unique
+}
+
+// normalizeTopology returns the normalized topology of a graph returned by
+// the G or SubGraph Gremlin steps
+func normalizeTopology(data []byte, opts *TopologyNormalizeOpts) (*goldenTopology, error) {
+	dump, err := parseGraphDump(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r := opts.replacer()
+
+	var nodeLabels []string
+	var nodeMetadata []map[string]interface{}
+	for _, node := range dump.Nodes {
+		m := opts.normalizeMetadata(node.Metadata, r)
+		label := attributeValue(m["Type"])
+		if name := attributeValue(m["Name"]); name != "" {
+			label += ":" + name
+		}
+		nodeLabels = append(nodeLabels, label)
+		nodeMetadata = append(nodeMetadata, m)
+	}
+
+	nodes, uniqueLabels := labelElements(nodeLabels, nodeMetadata)
+	labelsByID := make(map[string]string)
+	for i, node := range dump.Nodes {
+		labelsByID[node.ID] = uniqueLabels[i]
+	}
+
+	var edgeLabels []string
+	var edgeMetadata []map[string]interface{}
+	for _, edge := range dump.Edges {
+		parent, foundParent := labelsByID[edge.Parent]
+		child, foundChild := labelsByID[edge.Child]
+		if !foundParent || !foundChild {
+			continue
+		}
+
+		m := opts.normalizeMetadata(edge.Metadata, r)
+		edgeLabels = append(edgeLabels, fmt.Sprintf("%s -[%s]-> %s", parent, attributeValue(m["RelationType"]), child))
+		edgeMetadata = append(edgeMetadata, m)
+	}
+
+	edges, _ := labelElements(edgeLabels, edgeMetadata)
+	return &goldenTopology{Nodes: nodes, Edges: edges}, nil
+}
+
+// diffMetadata returns the differences between the flattened metadata of
+// an element of the golden topology and of the current one
+func diffMetadata(element string, golden, current map[string]interface{}) (diff []string) {
+	flatGolden := make(map[string]interface{})
+	flattenMetadata("", golden, flatGolden)
+	flatCurrent := make(map[string]interface{})
+	flattenMetadata("", current, flatCurrent)
+
+	keys := make(map[string]bool)
+	for k := range flatGolden {
+		keys[k] = true
+	}
+	for k := range flatCurrent {
+		keys[k] = true
+	}
+
+	var sorted []string
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		g, inGolden := flatGolden[k]
+		c, inCurrent := flatCurrent[k]
+		switch {
+		case !inGolden:
+			diff = append(diff, fmt.Sprintf("%s: unexpected %s = %s", element, k, canonicalJSON(c)))
+		case !inCurrent:
+			diff = append(diff, fmt.Sprintf("%s: missing %s = %s", element, k, canonicalJSON(g)))
+		case canonicalJSON(g) != canonicalJSON(c):
+			diff = append(diff, fmt.Sprintf("%s: %s = %s, expected %s", element, k, canonicalJSON(c), canonicalJSON(g)))
+		}
+	}
+	return
+}
+
+// diffElements returns the differences between the nodes or the edges of
+// the golden topology and of the current one
+func diffElements(kind string, golden, current map[string]map[string]interface{}) (diff []string) {
+	labels := make(map[string]bool)
+	for label := range golden {
+		labels[label] = true
+	}
+	for label := range current {
+		labels[label] = true
+	}
+
+	var sorted []string
+	for label := range labels {
+		sorted = append(sorted, label)
+	}
+	sort.Strings(sorted)
+
+	for _, label := range sorted {
+		g, inGolden := golden[label]
+		c, inCurrent := current[label]
+		switch {
+		case !inGolden:
+			diff = append(diff, fmt.Sprintf("unexpected %s %s", kind, label))
+		case !inCurrent:
+			diff = append(diff, fmt.Sprintf("missing %s %s", kind, label))
+		default:
+			diff = append(diff, diffMetadata(kind+" "+label, g, c)...)
+		}
+	}
+	return
+}
+
+// diffTopology returns the node and edge level differences between the
+// golden topology and the current one
+func diffTopology(golden, current *goldenTopology) []string {
+	return append(diffElements("node", golden.Nodes, current.Nodes), diffElements("edge", golden.Edges, current.Edges)...)
+}
+
+// readGoldenTopology reads a golden file written by AssertTopologyMatches
+func readGoldenTopology(filename string) (*goldenTopology, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	golden := &goldenTopology{}
+	if err := common.JSONDecode(bytes.NewReader(data), golden); err != nil {
+		return nil, fmt.Errorf("Invalid golden file %s: %s", filename, err)
+	}
+	return golden, nil
+}
+
+// writeGoldenTopology writes the topology to the golden file
+func writeGoldenTopology(filename string, topology *goldenTopology) error {
+	data, err := json.MarshalIndent(topology, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// currentTopology returns the normalized subgraph selected by the query
+func currentTopology(opts *TopologyNormalizeOpts) (*goldenTopology, error) {
+	data, err := queryTopology(subGraphQuery(opts.Query))
+	if err != nil {
+		return nil, err
+	}
+	return normalizeTopology(data, opts)
+}
+
+// AssertTopologyMatches fails the test if the topology doesn't match the
+// golden file, once normalized by the options. The golden file is written
+// instead when -update-golden is given.
+func AssertTopologyMatches(t *testing.T, filename string, opts TopologyNormalizeOpts) {
+	if opts.Query == "" {
+		opts.Query = graphScope(t)
+	}
+
+	if UpdateGolden {
+		current, err := currentTopology(&opts)
+		if err == nil {
+			err = writeGoldenTopology(filename, current)
+		}
+		if err != nil {
+			t.Fatalf("Failed to update golden file %s: %s", filename, err)
+		}
+		t.Logf("Golden file %s updated", filename)
+		return
+	}
+
+	golden, err := readGoldenTopology(filename)
+	if err != nil {
+		t.Fatalf("Failed to read golden file, run with -update-golden to create it: %s", err)
+	}
+
+	var diff []string
+	err = Retry(opts.Timeout, time.Second, func() error {
+		diff = nil
+		current, err := currentTopology(&opts)
+		if err != nil {
+			return err
+		}
+		if diff = diffTopology(golden, current); len(diff) != 0 {
+			return fmt.Errorf("%d differences", len(diff))
+		}
+		return nil
+	})
+
+	switch {
+	case len(diff) != 0:
+		t.Errorf("Topology doesn't match golden file %s, run with -update-golden to update it:\n    %s", filename, strings.Join(diff, "\n    "))
+	case err != nil:
+		t.Errorf("Failed to get the topology: %s", err)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const goldenGraphFixture = `[{
+	"Nodes": [
+		{"ID": "n1", "Host": "host1", "CreatedAt": 1000, "Metadata": {"Name": "host1", "Type": "host"}},
+		{"ID": "n2", "Metadata": {"Name": "osd", "Type": "ceph", "MAC": "02:00:00:00:00:01", "Path": "/var/lib/host1/osd", "Disk": {"Size": 10, "Serial": "x1"}}},
+		{"ID": "n3", "Metadata": {"Name": "osd", "Type": "ceph", "MAC": "02:00:00:00:00:02", "Path": "/var/lib/host1/osd2"}}
+	],
+	"Edges": [
+		{"ID": "e1", "Parent": "n1", "Child": "n2", "Metadata": {"RelationType": "ownership"}},
+		{"ID": "e2", "Parent": "n1", "Child": "n3", "Metadata": {"RelationType": "ownership"}},
+		{"ID": "e3", "Parent": "n1", "Child": "n4", "Metadata": {"RelationType": "layer2"}}
+	]
+}]`
+
+func TestNormalizeTopology(t *testing.T) {
+	opts := &TopologyNormalizeOpts{
+		IgnoredKeys:  []string{"Disk.Serial"},
+		Replacements: map[string]string{"host1": "$HOST"},
+	}
+
+	topology, err := normalizeTopology([]byte(goldenGraphFixture), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nodes, edges []string
+	for label := range topology.Nodes {
+		nodes = append(nodes, label)
+	}
+	for label := range topology.Edges {
+		edges = append(edges, label)
+	}
+
+	expectedNodes := map[string]bool{"host:$HOST": true, "ceph:osd": true, "ceph:osd #2": true}
+	for _, label := range nodes {
+		if !expectedNodes[label] {
+			t.Errorf("Unexpected node %s, got %v", label, nodes)
+		}
+	}
+	if len(nodes) != len(expectedNodes) {
+		t.Errorf("Expected nodes %v, got %v", expectedNodes, nodes)
+	}
+
+	expectedEdges := map[string]bool{"host:$HOST -[ownership]-> ceph:osd": true, "host:$HOST -[ownership]-> ceph:osd #2": true}
+	for _, label := range edges {
+		if !expectedEdges[label] {
+			t.Errorf("Unexpected edge %s, got %v", label, edges)
+		}
+	}
+	if len(edges) != len(expectedEdges) {
+		t.Errorf("Expected edges %v, got %v", expectedEdges, edges)
+	}
+
+	osd := topology.Nodes["ceph:osd"]
+	if _, found := osd["MAC"]; found {
+		t.Error("Expected volatile MAC to be removed")
+	}
+	if disk := osd["Disk"].(map[string]interface{}); !reflect.DeepEqual(disk, map[string]interface{}{"Size": disk["Size"]}) {
+		t.Errorf("Expected Disk.Serial to be removed, got %v", disk)
+	}
+	if osd["Path"] != "/var/lib/$HOST/osd" {
+		t.Errorf("Expected the host name to be replaced, got %v", osd["Path"])
+	}
+}
+
+func TestDiffTopology(t *testing.T) {
+	opts := &TopologyNormalizeOpts{Replacements: map[string]string{"host1": "$HOST"}}
+	current, err := normalizeTopology([]byte(goldenGraphFixture), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "skydive-golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "testdata", "topology.json")
+	if err := writeGoldenTopology(filename, current); err != nil {
+		t.Fatal(err)
+	}
+
+	golden, err := readGoldenTopology(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := diffTopology(golden, current); len(diff) != 0 {
+		t.Errorf("Expected no difference with the written golden file, got %v", diff)
+	}
+
+	golden.Nodes["ceph:osd"]["Path"] = "/var/lib/osd"
+	delete(golden.Nodes, "ceph:osd #2")
+	golden.Nodes["ceph:mon"] = map[string]interface{}{"Name": "mon", "Type": "ceph"}
+	delete(golden.Edges, "host:$HOST -[ownership]-> ceph:osd #2")
+
+	expected := []string{
+		"missing node ceph:mon",
+		`node ceph:osd: Path = "/var/lib/$HOST/osd", expected "/var/lib/osd"`,
+		"unexpected node ceph:osd #2",
+		"unexpected edge host:$HOST -[ownership]-> ceph:osd #2",
+	}
+	if diff := diffTopology(golden, current); !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Expected differences %q, got %q", expected, diff)
+	}
+}
@@ -0,0 +1,376 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+// graphDumpElement is a node or an edge of a topology dump
+type graphDumpElement struct {
+	ID       string
+	Parent   string
+	Child    string
+	Metadata map[string]interface{}
+}
+
+type graphDump struct {
+	Nodes []*graphDumpElement
+	Edges []*graphDumpElement
+}
+
+// graphAttribute describes a flattened metadata key and its type
+type graphAttribute struct {
+	id   string
+	name string
+	kind string
+}
+
+// parseGraphDump decodes the result of the G Gremlin query
+func parseGraphDump(data []byte) (*graphDump, error) {
+	var dumps []*graphDump
+	if err := common.JSONDecode(bytes.NewReader(data), &dumps); err != nil {
+		return nil, err
+	}
+
+	if len(dumps) != 1 {
+		return nil, errors.New("Topology dump should contain exactly one graph")
+	}
+
+	return dumps[0], nil
+}
+
+// flattenMetadata returns the leaves of the metadata keyed by their dotted
+// path, lists are encoded in JSON
+func flattenMetadata(prefix string, m map[string]interface{}, flattened map[string]interface{}) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flattenMetadata(key, v, flattened)
+		case []interface{}:
+			b, _ := json.Marshal(v)
+			flattened[key] = string(b)
+		default:
+			flattened[key] = v
+		}
+	}
+}
+
+// attributeType returns the type of a metadata value, 'string' for the
+// values that are not booleans or numbers
+func attributeType(v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "long"
+		}
+		return "double"
+	}
+	return "string"
+}
+
+// attributeValue returns the string representation of a metadata value
+func attributeValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// flattenElements flattens the metadata of the elements and returns the
+// attributes sorted by name, keys with values of mixed types are strings
+func flattenElements(elements []*graphDumpElement, prefix string) ([]map[string]interface{}, []*graphAttribute, map[string]*graphAttribute) {
+	var values []map[string]interface{}
+	kinds := make(map[string]string)
+
+	for _, element := range elements {
+		flattened := make(map[string]interface{})
+		flattenMetadata("", element.Metadata, flattened)
+		values = append(values, flattened)
+
+		for k, v := range flattened {
+			kind := attributeType(v)
+			if previous, found := kinds[k]; found && previous != kind {
+				if (previous == "long" && kind == "double") || (previous == "double" && kind == "long") {
+					kind = "double"
+				} else {
+					kind = "string"
+				}
+			}
+			kinds[k] = kind
+		}
+	}
+
+	var names []string
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var attributes []*graphAttribute
+	byName := make(map[string]*graphAttribute)
+	for i, name := range names {
+		attribute := &graphAttribute{id: fmt.Sprintf("%s%d", prefix, i), name: name, kind: kinds[name]}
+		attributes = append(attributes, attribute)
+		byName[name] = attribute
+	}
+
+	return values, attributes, byName
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+func graphMLDataList(values map[string]interface{}, attributes map[string]*graphAttribute) (data []graphMLData) {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		data = append(data, graphMLData{Key: attributes[k].id, Value: attributeValue(values[k])})
+	}
+	return
+}
+
+// GraphToGraphML converts the result of the G Gremlin query to GraphML
+func GraphToGraphML(data []byte) (string, error) {
+	dump, err := parseGraphDump(data)
+	if err != nil {
+		return "", err
+	}
+
+	nodeValues, nodeAttributes, nodeAttributesByName := flattenElements(dump.Nodes, "n")
+	edgeValues, edgeAttributes, edgeAttributesByName := flattenElements(dump.Edges, "e")
+
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphMLGraph{ID: "G", EdgeDefault: "directed"},
+	}
+
+	for _, attribute := range nodeAttributes {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: attribute.id, For: "node", Name: attribute.name, Type: attribute.kind})
+	}
+	for _, attribute := range edgeAttributes {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: attribute.id, For: "edge", Name: attribute.name, Type: attribute.kind})
+	}
+
+	for i, node := range dump.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:   node.ID,
+			Data: graphMLDataList(nodeValues[i], nodeAttributesByName),
+		})
+	}
+
+	for i, edge := range dump.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     edge.ID,
+			Source: edge.Parent,
+			Target: edge.Child,
+			Data:   graphMLDataList(edgeValues[i], edgeAttributesByName),
+		})
+	}
+
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(b), nil
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexf struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+func gexfAttValueList(values map[string]interface{}, attributes map[string]*graphAttribute) (attValues []gexfAttValue) {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		attValues = append(attValues, gexfAttValue{For: attributes[k].id, Value: attributeValue(values[k])})
+	}
+	return
+}
+
+func gexfAttributeList(class string, attributes []*graphAttribute) gexfAttributes {
+	list := gexfAttributes{Class: class}
+	for _, attribute := range attributes {
+		list.Attributes = append(list.Attributes, gexfAttribute{ID: attribute.id, Title: attribute.name, Type: attribute.kind})
+	}
+	return list
+}
+
+// GraphToGEXF converts the result of the G Gremlin query to GEXF
+func GraphToGEXF(data []byte) (string, error) {
+	dump, err := parseGraphDump(data)
+	if err != nil {
+		return "", err
+	}
+
+	nodeValues, nodeAttributes, nodeAttributesByName := flattenElements(dump.Nodes, "n")
+	edgeValues, edgeAttributes, edgeAttributesByName := flattenElements(dump.Edges, "e")
+
+	doc := gexf{
+		XMLNS:   "http://www.gexf.net/1.2draft",
+		Version: "1.2",
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Attributes: []gexfAttributes{
+				gexfAttributeList("node", nodeAttributes),
+				gexfAttributeList("edge", edgeAttributes),
+			},
+		},
+	}
+
+	for i, node := range dump.Nodes {
+		label := attributeValue(nodeValues[i]["Name"])
+		if label == "" {
+			label = node.ID
+		}
+
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+			ID:        node.ID,
+			Label:     label,
+			AttValues: gexfAttValueList(nodeValues[i], nodeAttributesByName),
+		})
+	}
+
+	for i, edge := range dump.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:        edge.ID,
+			Source:    edge.Parent,
+			Target:    edge.Child,
+			Label:     attributeValue(edgeValues[i]["RelationType"]),
+			AttValues: gexfAttValueList(edgeValues[i], edgeAttributesByName),
+		})
+	}
+
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(b), nil
+}
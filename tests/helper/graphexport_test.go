@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const graphDumpFixture = `[{
+	"Nodes": [
+		{"ID": "n1", "Metadata": {"Name": "eth0\u0001", "Type": "device", "MTU": 1500, "Captured": true, "Neutron": {"PortID": "p<1>"}}},
+		{"ID": "n2", "Metadata": {"Name": "br-int", "Type": "ovsbridge", "Manager": "ovs", "MTU": 1500.5}}
+	],
+	"Edges": [
+		{"ID": "e1", "Parent": "n2", "Child": "n1", "Metadata": {"RelationType": "ownership"}}
+	]
+}]`
+
+func TestGraphToGraphML(t *testing.T) {
+	output, err := GraphToGraphML([]byte(graphDumpFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc graphML
+	if err := xml.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Invalid GraphML output: %s\n%s", err, output)
+	}
+
+	keys := make(map[string]string)
+	for _, key := range doc.Keys {
+		keys[key.For+":"+key.Name] = key.Type
+	}
+
+	expected := map[string]string{
+		"node:Captured":       "boolean",
+		"node:MTU":            "double",
+		"node:Manager":        "string",
+		"node:Name":           "string",
+		"node:Neutron.PortID": "string",
+		"node:Type":           "string",
+		"edge:RelationType":   "string",
+	}
+	for k, v := range expected {
+		if keys[k] != v {
+			t.Errorf("Expected key %s of type %s, got: %+v", k, v, keys)
+		}
+	}
+
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 {
+		t.Fatalf("Expected 2 nodes and 1 edge, got: %+v", doc.Graph)
+	}
+
+	if edge := doc.Graph.Edges[0]; edge.Source != "n2" || edge.Target != "n1" {
+		t.Errorf("Wrong edge endpoints: %+v", edge)
+	}
+
+	if strings.Contains(output, "\u0001") || !strings.Contains(output, "p&lt;1&gt;") {
+		t.Errorf("Values not escaped: %s", output)
+	}
+}
+
+func TestGraphToGEXF(t *testing.T) {
+	output, err := GraphToGEXF([]byte(graphDumpFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc gexf
+	if err := xml.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Invalid GEXF output: %s\n%s", err, output)
+	}
+
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 {
+		t.Fatalf("Expected 2 nodes and 1 edge, got: %+v", doc.Graph)
+	}
+
+	if label := doc.Graph.Nodes[1].Label; label != "br-int" {
+		t.Errorf("Expected node label br-int, got: %s", label)
+	}
+
+	if label := doc.Graph.Edges[0].Label; label != "ownership" {
+		t.Errorf("Expected edge label ownership, got: %s", label)
+	}
+}
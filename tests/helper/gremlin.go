@@ -0,0 +1,311 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/flow"
+	"github.com/skydive-project/skydive/gremlin"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// GremlinResult holds the result of a Gremlin query
+type GremlinResult struct {
+	query string
+	data  []byte
+	err   error
+}
+
+func newAnalyzerRestClient(index int) (*shttp.RestClient, error) {
+	sa, err := AnalyzerServiceAddress(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return shttp.NewRestClient(config.GetURL("http", sa.Addr, sa.Port, "/api/"), AuthenticationOpts())
+}
+
+func queryTopology(query string) ([]byte, error) {
+	return queryAnalyzerTopology(0, query, nil)
+}
+
+func queryTopologyWithHeader(query string, header http.Header) ([]byte, error) {
+	return queryAnalyzerTopology(0, query, header)
+}
+
+func queryAnalyzerTopology(index int, query string, header http.Header) ([]byte, error) {
+	client, err := newAnalyzerRestClient(index)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(types.TopologyParam{GremlinQuery: query})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Request("POST", "topology", bytes.NewReader(body), header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, string(data))
+	}
+
+	return data, nil
+}
+
+// GremlinQuery runs a Gremlin query against the topology API of the analyzer
+// listening on the address given by the analyzer.listen flag. The errors are
+// returned by the accessors of the result.
+func GremlinQuery(t *testing.T, query interface{}) *GremlinResult {
+	return GremlinQueryAnalyzer(t, 0, query)
+}
+
+// GremlinQueryAnalyzer runs a Gremlin query against the analyzer of the given
+// index in standalone mode, 0 being the analyzer running in the test process
+func GremlinQueryAnalyzer(t *testing.T, index int, query interface{}) *GremlinResult {
+	q := gremlin.NewQueryStringFromArgument(query).String()
+	t.Logf("Gremlin query on analyzer %d: %s", index, q)
+
+	result := &GremlinResult{query: q}
+	if result.data, result.err = queryAnalyzerTopology(index, q, nil); result.err != nil {
+		result.err = fmt.Errorf("Gremlin query '%s' failed: %s", q, result.err)
+	}
+
+	return result
+}
+
+// Err returns the error of the query
+func (r *GremlinResult) Err() error {
+	return r.err
+}
+
+// Decode decodes the result of the query into value
+func (r *GremlinResult) Decode(value interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if err := common.JSONDecode(bytes.NewReader(r.data), value); err != nil {
+		return fmt.Errorf("Unable to decode the result of the Gremlin query '%s': %s", r.query, err)
+	}
+	return nil
+}
+
+// values returns the elements of the result, flattening the nested lists
+// returned by some steps
+func (r *GremlinResult) values() ([]interface{}, error) {
+	var values []interface{}
+	if err := r.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	var flattened []interface{}
+	for _, value := range values {
+		if list, ok := value.([]interface{}); ok {
+			flattened = append(flattened, list...)
+		} else {
+			flattened = append(flattened, value)
+		}
+	}
+	return flattened, nil
+}
+
+// GetNodes returns the nodes of the result
+func (r *GremlinResult) GetNodes() ([]*graph.Node, error) {
+	values, err := r.values()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*graph.Node
+	for _, value := range values {
+		n := new(graph.Node)
+		if err := n.Decode(value); err != nil {
+			return nil, fmt.Errorf("Unable to decode node of the Gremlin query '%s': %s", r.query, err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// GetEdges returns the edges of the result
+func (r *GremlinResult) GetEdges() ([]*graph.Edge, error) {
+	values, err := r.values()
+	if err != nil {
+		return nil, err
+	}
+
+	var edges []*graph.Edge
+	for _, value := range values {
+		e := new(graph.Edge)
+		if err := e.Decode(value); err != nil {
+			return nil, fmt.Errorf("Unable to decode edge of the Gremlin query '%s': %s", r.query, err)
+		}
+		edges = append(edges, e)
+	}
+	return edges, nil
+}
+
+// GetFlows returns the flows of the result
+func (r *GremlinResult) GetFlows() ([]*flow.Flow, error) {
+	var flows []*flow.Flow
+	if err := r.Decode(&flows); err != nil {
+		return nil, err
+	}
+	return flows, nil
+}
+
+// GetInt returns the value of a query returning a single number like Count()
+func (r *GremlinResult) GetInt() (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	// the value may be wrapped in a single element list
+	var value json.Number
+	if err := common.JSONDecode(bytes.NewReader(r.data), &value); err != nil {
+		var values []json.Number
+		if err := r.Decode(&values); err != nil {
+			return 0, err
+		}
+		if len(values) != 1 {
+			return 0, fmt.Errorf("Result of the Gremlin query '%s' is not a single value: %s", r.query, string(r.data))
+		}
+		value = values[0]
+	}
+
+	i, err := value.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("Result of the Gremlin query '%s' is not an integer: %s", r.query, value)
+	}
+	return i, nil
+}
+
+// CountMode defines how the number of elements returned by a query is
+// compared to the expected one
+type CountMode int
+
+const (
+	// Exactly expects the exact number of elements
+	Exactly CountMode = iota
+	// AtLeast expects at least the number of elements
+	AtLeast
+)
+
+// waitInterval is the delay between two queries of the WaitFor helpers
+const waitInterval = 500 * time.Millisecond
+
+func (m CountMode) String() string {
+	if m == AtLeast {
+		return "at least"
+	}
+	return "exactly"
+}
+
+func (m CountMode) match(value, count int) bool {
+	if m == AtLeast {
+		return value >= count
+	}
+	return value == count
+}
+
+// waitForElements runs the query until the number of elements decoded by
+// decode matches the expected count, failing the test with the last result
+// when the timeout expires
+func waitForElements(t *testing.T, kind string, query string, count int, mode CountMode, timeout time.Duration, decode func(*GremlinResult) ([]string, error)) *GremlinResult {
+	var result *GremlinResult
+	err := Retry(timeout, waitInterval, func() error {
+		result = &GremlinResult{query: query}
+		if result.data, result.err = queryTopology(query); result.err != nil {
+			return result.err
+		}
+
+		elements, err := decode(result)
+		if err != nil {
+			return err
+		}
+
+		if !mode.match(len(elements), count) {
+			return fmt.Errorf("expected %s %d %s, got %d: [%s]", mode, count, kind, len(elements), strings.Join(elements, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Gremlin query '%s' did not return the expected %s within %s: %s", query, kind, timeout, err)
+	}
+
+	return result
+}
+
+// WaitForNodes runs the query until it returns the expected number of nodes,
+// failing the test with the last result if the timeout expires
+func WaitForNodes(t *testing.T, query string, count int, mode CountMode, timeout time.Duration) []*graph.Node {
+	result := waitForElements(t, "nodes", query, count, mode, timeout, func(r *GremlinResult) ([]string, error) {
+		nodes, err := r.GetNodes()
+		var elements []string
+		for _, n := range nodes {
+			elements = append(elements, n.String())
+		}
+		return elements, err
+	})
+
+	nodes, _ := result.GetNodes()
+	return nodes
+}
+
+// WaitForEdges runs the query until it returns the expected number of edges,
+// failing the test with the last result if the timeout expires
+func WaitForEdges(t *testing.T, query string, count int, mode CountMode, timeout time.Duration) []*graph.Edge {
+	result := waitForElements(t, "edges", query, count, mode, timeout, func(r *GremlinResult) ([]string, error) {
+		edges, err := r.GetEdges()
+		var elements []string
+		for _, e := range edges {
+			elements = append(elements, e.String())
+		}
+		return elements, err
+	})
+
+	edges, _ := result.GetEdges()
+	return edges
+}
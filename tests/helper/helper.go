@@ -24,132 +24,370 @@ package helper
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"html/template"
-	"io/ioutil"
+	"io"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/google/gopacket/pcapgo"
 	"github.com/gorilla/websocket"
-	"github.com/skydive-project/skydive/common"
 	"github.com/skydive-project/skydive/config"
 	"github.com/skydive-project/skydive/flow"
 	shttp "github.com/skydive-project/skydive/http"
 	"github.com/skydive-project/skydive/logging"
 )
 
+// Cmd is a command executed by ExecCmds. The command string is split
+// following the shell quoting rules but is not interpreted by a shell.
 type Cmd struct {
 	Cmd   string
 	Check bool
+	// Timeout kills the command and its children when expired, no timeout if 0
+	Timeout time.Duration
+	// Env holds variables added to the environment of the test
+	Env   []string
+	Dir   string
+	Stdin string
+	// Undo is the command reverting the command, registered by the
+	// ExecCmds method of CleanupStack once the command succeeded
+	Undo string
 }
 
 var (
 	Standalone        bool
+	TLS               bool
+	Auth              string
+	K8s               bool
+	AgentCount        int
+	AnalyzerCount     int
+	EtcdMembers       int
 	AgentTestsOnly    bool
 	NoOFTests         bool
 	GraphOutputFormat string
+	GraphOutputFull   bool
+	WSCompression     bool
 	TopologyBackend   string
 	FlowBackend       string
 	AnalyzerListen    string
+	UpdateGolden      bool
+	FlowsVerbose      bool
+	WatchdogTimeout   time.Duration
 
 	etcdServer     string
 	analyzerProbes string
+	agentBinary    string
+	agentPIDFile   string
+
+	elasticsearchTemplate string
+	artifactsDir          string
+	keepArtifacts         bool
+	provisionBackends     bool
+	logCaptureLevel       string
+
+	benchCorpus   string
+	benchRates    string
+	benchDuration time.Duration
+
+	// configFiles are the configuration files rendered by the helper
+	configFiles []string
 )
 
 type HelperParams map[string]interface{}
 
 func init() {
 	flag.BoolVar(&Standalone, "standalone", false, "Start an analyzer and an agent")
+	flag.IntVar(&AnalyzerCount, "standalone.analyzers", 1, "Number of analyzers started in standalone mode")
+	flag.IntVar(&AgentCount, "standalone.agents", 1, "Number of agents started in standalone mode")
+	flag.StringVar(&agentBinary, "standalone.agent.binary", "skydive", "Skydive binary used to start the additional agents")
+	flag.StringVar(&agentPIDFile, "agent.pidfile", "", "PID file of an agent started outside of the tests, restarted by RestartAgent instead of the in-process one")
+	flag.BoolVar(&TLS, "tls", false, "Enable TLS with generated certificates")
+	flag.StringVar(&Auth, "auth", "", "Enable the authentication of the analyzer API with the given backend (basic or keystone)")
+	flag.BoolVar(&K8s, "k8s", false, "Start a kind Kubernetes cluster watched by the k8s probe of the analyzers")
 	flag.BoolVar(&AgentTestsOnly, "agenttestsonly", false, "run agent test only")
 	flag.BoolVar(&NoOFTests, "nooftests", false, "dont't run OpenFlow tests")
 	flag.StringVar(&etcdServer, "etcd.server", "", "Etcd server")
-	flag.StringVar(&TopologyBackend, "analyzer.topology.backend", "memory", "Specify the graph storage backend used")
-	flag.StringVar(&GraphOutputFormat, "graph.output", "", "Graph output format (json, dot or ascii)")
-	flag.StringVar(&FlowBackend, "analyzer.flow.backend", "", "Specify the flow storage backend used")
-	flag.StringVar(&AnalyzerListen, "analyzer.listen", "0.0.0.0:64500", "Specify the analyzer listen address")
+	flag.IntVar(&EtcdMembers, "etcd.members", 1, "Number of members of the etcd cluster started in standalone mode, etcd being embedded by the analyzer if 1")
+	flag.StringVar(&TopologyBackend, "analyzer.topology.backend", "memory", "Specify the graph storage backend used, started in a container if prefixed by docker:, like docker:orientdb")
+	flag.StringVar(&GraphOutputFormat, "graph.output", "", "Graph output format (json, dot, ascii, graphml or gexf)")
+	flag.BoolVar(&GraphOutputFull, "graph.full", false, "Dump the whole graph, ignoring the scopes set by the tests")
+	flag.BoolVar(&WSCompression, "ws.compression", false, "Request the compression of the messages on the websocket connections of the tests")
+	flag.StringVar(&FlowBackend, "analyzer.flow.backend", "", "Specify the flow storage backend used, started in a container if prefixed by docker:, like docker:elasticsearch7")
+	flag.StringVar(&AnalyzerListen, "analyzer.listen", "", "Specify the analyzer listen address, the ports being allocated dynamically in standalone mode if empty")
 	flag.StringVar(&analyzerProbes, "analyzer.topology.probes", "", "Specify the analyzer probes to enable")
+	flag.StringVar(&artifactsDir, "artifacts.dir", filepath.Join(os.TempDir(), "skydive-test-artifacts"), "Directory where the artifacts of the tests are written")
+	flag.StringVar(&logCaptureLevel, "log-capture-level", "WARNING", "Level of the daemon logs reported by the failing tests (CRITICAL, ERROR, WARNING, INFO or DEBUG)")
+	flag.DurationVar(&WatchdogTimeout, "watchdog", 0, "Duration after which the tests run by RunTest are reported as stuck, disabled if 0")
+	flag.BoolVar(&FlowsVerbose, "flows.verbose", false, "Dump the flows of the failing assertions in JSON instead of one line per flow")
+	flag.BoolVar(&UpdateGolden, "update-golden", false, "Write the golden files compared by AssertTopologyMatches instead of comparing them")
+	flag.BoolVar(&keepArtifacts, "keep-artifacts", false, "Keep the artifacts of the passing tests")
+	flag.StringVar(&elasticsearchTemplate, "elasticsearch.template", "", "Index template installed in Elasticsearch before the tests")
+	flag.BoolVar(&provisionBackends, "provision-backends", false, "Start the storage backends used in containers")
+	flag.StringVar(&benchCorpus, "bench.corpus", "", "Comma separated list of pcap files replayed by the ingestion benchmarks, synthesized flows if empty")
+	flag.StringVar(&benchRates, "bench.rates", "1000,5000,10000", "Comma separated list of packet rates of the ingestion benchmarks")
+	flag.DurationVar(&benchDuration, "bench.duration", defaultIngestionStepDuration, "Duration of each rate of the ingestion benchmarks")
 	flag.Parse()
 }
 
-func InitConfig(conf string, params ...HelperParams) error {
-	f, err := ioutil.TempFile("", "skydive_agent")
+// writeConfig renders the configuration template with the test parameters
+// and returns the name of the written file
+func writeConfig(conf string, params HelperParams) (string, error) {
+	sa, err := AnalyzerServiceAddress(0)
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	if len(params) == 0 {
-		params = []HelperParams{make(HelperParams)}
+	if _, ok := params["AnalyzerAddr"]; !ok {
+		params["AnalyzerAddr"] = sa.Addr
 	}
-
-	sa, err := common.ServiceAddressFromString(AnalyzerListen)
-	if err != nil {
-		return err
+	if _, ok := params["AnalyzerPort"]; !ok {
+		params["AnalyzerPort"] = sa.Port
+	}
+	if _, ok := params["Analyzers"]; !ok {
+		if params["Analyzers"], err = analyzerAddresses(); err != nil {
+			return "", err
+		}
+	}
+	if _, ok := params["AgentAddr"]; !ok {
+		params["AgentAddr"] = sa.Addr
+	}
+	if _, ok := params["AgentPort"]; !ok {
+		if params["AgentPort"], err = agentPort(0); err != nil {
+			return "", err
+		}
+	}
+	if _, ok := params["EtcdPort"]; !ok {
+		if params["EtcdPort"], err = embeddedEtcdPort(); err != nil {
+			return "", err
+		}
 	}
-	params[0]["AnalyzerAddr"] = sa.Addr
-	params[0]["AnalyzerPort"] = sa.Port
-	params[0]["AgentAddr"] = sa.Addr
-	params[0]["AgentPort"] = sa.Port - 1
 
 	if testing.Verbose() {
-		params[0]["LogLevel"] = "DEBUG"
+		params["LogLevel"] = "DEBUG"
 	} else {
-		params[0]["LogLevel"] = "INFO"
+		params["LogLevel"] = "INFO"
 	}
-	if etcdServer != "" {
-		params[0]["EmbeddedEtcd"] = "false"
-		params[0]["EtcdServer"] = etcdServer
-	} else {
-		params[0]["EmbeddedEtcd"] = "true"
-		params[0]["EtcdServer"] = "http://localhost:12379"
+	if _, ok := params["EmbeddedEtcd"]; !ok {
+		params["EmbeddedEtcd"] = strconv.FormatBool(embeddedEtcd())
+	}
+	if _, ok := params["EtcdServers"]; !ok {
+		if params["EtcdServers"], err = etcdServerURLs(); err != nil {
+			return "", err
+		}
 	}
 	if FlowBackend != "" {
-		params[0]["FlowBackend"] = FlowBackend
+		params["FlowBackend"] = FlowBackend
 	}
 	if FlowBackend == "orientdb" || TopologyBackend == "orientdb" {
-		orientDBPassword := os.Getenv("ORIENTDB_ROOT_PASSWORD")
-		if orientDBPassword == "" {
-			orientDBPassword = "root"
-		}
-		params[0]["OrientDBRootPassword"] = orientDBPassword
+		params["OrientDBRootPassword"] = orientDBRootPassword()
 	}
+	params["ElasticsearchHost"] = elasticsearchHost
+	params["OrientDBAddr"] = orientDBAddr
 	if TopologyBackend != "" {
-		params[0]["TopologyBackend"] = TopologyBackend
+		params["TopologyBackend"] = TopologyBackend
 	}
 	if analyzerProbes != "" {
-		params[0]["AnalyzerProbes"] = strings.Split(analyzerProbes, ",")
+		params["AnalyzerProbes"] = strings.Split(analyzerProbes, ",")
 	}
 
-	tmpl, err := template.New("config").Parse(conf)
+	// keys used by the templates but only set for some backends or agents
+	for _, key := range []string{"FlowBackend", "TopologyBackend", "OrientDBRootPassword", "HostID"} {
+		if _, ok := params[key]; !ok {
+			params[key] = ""
+		}
+	}
+	if _, ok := params["AnalyzerProbes"]; !ok {
+		params["AnalyzerProbes"] = []string{}
+	}
+	setKindParams(params)
+	if err := setTLSParams(params, sa.Addr); err != nil {
+		return "", err
+	}
+	if err := setAuthParams(params); err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New("config").Option("missingkey=error").Parse(conf)
 	if err != nil {
-		return err
+		return "", err
 	}
 	buff := bytes.NewBufferString("")
-	tmpl.Execute(buff, params[0])
+	if err := tmpl.Execute(buff, params); err != nil {
+		return "", fmt.Errorf("Failed to render config template: %s", err)
+	}
+
+	if overlays, ok := params[configOverlaysParam].([]interface{}); ok {
+		data, err := applyConfigOverlays(buff.Bytes(), overlays)
+		if err != nil {
+			return "", fmt.Errorf("Failed to apply config overlay: %s", err)
+		}
+		buff = bytes.NewBuffer(data)
+	}
+
+	if err := config.ValidateConfig(bytes.NewReader(buff.Bytes())); err != nil {
+		return "", fmt.Errorf("%s\n%s", err, numberLines(buff.String()))
+	}
 
+	f, err := configFile("skydive_agent")
+	if err != nil {
+		return "", err
+	}
 	f.Write(buff.Bytes())
 	f.Close()
+	configFiles = append(configFiles, f.Name())
 
 	fmt.Printf("Config: %s\n", string(buff.Bytes()))
 
-	return config.InitConfig("file", []string{f.Name()})
+	return f.Name(), nil
+}
+
+// numberLines prefixes the lines of a text by their number
+func numberLines(text string) string {
+	var b bytes.Buffer
+	for i, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(&b, "%4d  %s\n", i+1, line)
+	}
+	return b.String()
+}
+
+// InitConfig renders the configuration template with the parameters, merged
+// in order, and loads it. Overlays added with WithConfig are merged over
+// the rendered configuration.
+func InitConfig(conf string, params ...HelperParams) error {
+	p := mergeParams(params)
+
+	filename, err := writeConfig(conf, p)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareBackends(p); err != nil {
+		return err
+	}
+
+	return config.InitConfig("file", []string{filename})
+}
+
+// splitCommand splits a command line into arguments, handling single
+// quotes, double quotes and backslash escapes like a shell
+func splitCommand(line string) ([]string, error) {
+	var args []string
+	var arg bytes.Buffer
+	var quote rune
+	inArg, escaped := false, false
+
+	for _, c := range line {
+		switch {
+		case escaped:
+			// within double quotes, backslash only escapes a few characters
+			if quote == '"' && !strings.ContainsRune("\\\"$`", c) {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("unterminated quote or escape in command: %s", line)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	return args, nil
+}
+
+// execCmd runs a command in its own process group so that the whole group
+// can be killed when the timeout or the test watchdog expires
+func execCmd(cmd Cmd) ([]byte, error) {
+	args, err := splitCommand(cmd.Cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := watchdogContext()
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	var output bytes.Buffer
+	command := exec.CommandContext(ctx, args[0], args[1:]...)
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	command.Stdout = &output
+	command.Stderr = &output
+	command.Dir = cmd.Dir
+	if len(cmd.Env) > 0 {
+		command.Env = append(os.Environ(), cmd.Env...)
+	}
+	if cmd.Stdin != "" {
+		command.Stdin = strings.NewReader(cmd.Stdin)
+	}
+
+	logging.GetLogger().Debugf("Executing command %+v", args)
+	if err := command.Start(); err != nil {
+		return nil, err
+	}
+
+	// children inheriting the output would keep Wait blocked, kill the group
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}()
+
+	err = command.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", cmd.Timeout)
+	}
+
+	return output.Bytes(), err
 }
 
-func ExecCmds(t *testing.T, cmds ...Cmd) (e error) {
+func ExecCmds(t testing.TB, cmds ...Cmd) (e error) {
 	for _, cmd := range cmds {
-		args := strings.Split(cmd.Cmd, " ")
-		command := exec.Command(args[0], args[1:]...)
-		logging.GetLogger().Debugf("Executing command %+v", args)
-		stdouterr, err := command.CombinedOutput()
+		stdouterr, err := execCmd(cmd)
 		if stdouterr != nil {
 			logging.GetLogger().Debugf("Command returned %s", string(stdouterr))
 		}
@@ -163,39 +401,345 @@ func ExecCmds(t *testing.T, cmds ...Cmd) (e error) {
 	return
 }
 
-func FilterIPv6AddrAnd(flows []*flow.Flow, A, B string) (r []*flow.Flow) {
+// ErrFatal aborts a retry loop when returned by the retried function, use
+// Fatal to abort while keeping the original error
+var ErrFatal = errors.New("fatal error")
+
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.err.Error()
+}
+
+// Fatal wraps an error so that it aborts a retry loop
+func Fatal(err error) error {
+	return &fatalError{err: err}
+}
+
+// IsFatal returns whether the error aborts a retry loop
+func IsFatal(err error) bool {
+	if err == ErrFatal {
+		return true
+	}
+	_, ok := err.(*fatalError)
+	return ok
+}
+
+func retry(ctx context.Context, interval time.Duration, factor int64, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if IsFatal(err) {
+			return err
+		}
+
+		logging.GetLogger().Debugf("Attempt %d failed: %s", attempt, err)
+
+		// the wait is capped so that a last attempt is made at the deadline
+		wait := interval
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return err
+			}
+			if remaining < wait {
+				wait = remaining
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() != context.DeadlineExceeded {
+				return err
+			}
+		case <-time.After(wait):
+		}
+		interval = time.Duration(factor * int64(interval))
+	}
+}
+
+// RetryCtx calls fn every interval until it succeeds, returns a fatal error
+// or the context is done. The last error is returned on failure.
+func RetryCtx(ctx context.Context, interval time.Duration, fn func() error) error {
+	return retry(ctx, interval, 1, fn)
+}
+
+// Retry calls fn every interval until it succeeds, returns a fatal error
+// or the timeout expires. The last error is returned on failure. The
+// timeout is bounded by the remaining budget of the test watchdog.
+func Retry(timeout, interval time.Duration, fn func() error) error {
+	ctx, cancel := context.WithTimeout(watchdogContext(), timeout)
+	defer cancel()
+
+	return retry(ctx, interval, 1, fn)
+}
+
+// RetryBackoff calls fn until it succeeds, returns a fatal error or the
+// timeout expires, bounded like the one of Retry. The delay between two
+// attempts starts at interval and is doubled after each attempt.
+func RetryBackoff(timeout, interval time.Duration, fn func() error) error {
+	ctx, cancel := context.WithTimeout(watchdogContext(), timeout)
+	defer cancel()
+
+	return retry(ctx, interval, 2, fn)
+}
+
+// RetryT calls fn like Retry and fails the test with the last error if it
+// doesn't succeed
+func RetryT(t *testing.T, timeout, interval time.Duration, fn func() error) {
+	if err := Retry(timeout, interval, fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RetryBackoffT calls fn like RetryBackoff and fails the test with the last
+// error if it doesn't succeed
+func RetryBackoffT(t *testing.T, timeout, interval time.Duration, fn func() error) {
+	if err := RetryBackoff(timeout, interval, fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// filterIPAddrAnd returns the flows of the given network protocol between
+// A and B in either direction, addresses are compared by prefix unless exact
+func filterIPAddrAnd(flows []*flow.Flow, protocol flow.FlowProtocol, A, B string, exact bool) (r []*flow.Flow) {
+	match := strings.HasPrefix
+	if exact {
+		match = func(s, addr string) bool { return s == addr }
+	}
+
 	for _, f := range flows {
-		if f.Network == nil || (f.Network.Protocol != flow.FlowProtocol_IPV6) {
+		if f.Network == nil || (f.Network.Protocol != protocol) {
 			continue
 		}
-		if strings.HasPrefix(f.Network.A, A) && strings.HasPrefix(f.Network.B, B) {
+		if (match(f.Network.A, A) && match(f.Network.B, B)) || (match(f.Network.A, B) && match(f.Network.B, A)) {
 			r = append(r, f)
 		}
-		if strings.HasPrefix(f.Network.A, B) && strings.HasPrefix(f.Network.B, A) {
+	}
+	return r
+}
+
+// FilterIPv4AddrAnd returns the IPv4 flows between the addresses prefixed
+// by A and B in either direction
+func FilterIPv4AddrAnd(flows []*flow.Flow, A, B string) []*flow.Flow {
+	return filterIPAddrAnd(flows, flow.FlowProtocol_IPV4, A, B, false)
+}
+
+// FilterIPv4AddrAndExact returns the IPv4 flows between A and B in either direction
+func FilterIPv4AddrAndExact(flows []*flow.Flow, A, B string) []*flow.Flow {
+	return filterIPAddrAnd(flows, flow.FlowProtocol_IPV4, A, B, true)
+}
+
+// FilterIPv6AddrAnd returns the IPv6 flows between the addresses prefixed
+// by A and B in either direction
+func FilterIPv6AddrAnd(flows []*flow.Flow, A, B string) []*flow.Flow {
+	return filterIPAddrAnd(flows, flow.FlowProtocol_IPV6, A, B, false)
+}
+
+// FilterIPv6AddrAndExact returns the IPv6 flows between A and B in either direction
+func FilterIPv6AddrAndExact(flows []*flow.Flow, A, B string) []*flow.Flow {
+	return filterIPAddrAnd(flows, flow.FlowProtocol_IPV6, A, B, true)
+}
+
+// FilterTransportFlows returns the flows of the given transport protocol
+// between the ports portA and portB in either direction, a port set to 0
+// matches any port
+func FilterTransportFlows(flows []*flow.Flow, proto flow.FlowProtocol, portA, portB int64) (r []*flow.Flow) {
+	match := func(port, expected int64) bool { return expected == 0 || port == expected }
+
+	for _, f := range flows {
+		if f.Transport == nil || f.Transport.Protocol != proto {
+			continue
+		}
+		if (match(f.Transport.A, portA) && match(f.Transport.B, portB)) || (match(f.Transport.A, portB) && match(f.Transport.B, portA)) {
 			r = append(r, f)
 		}
 	}
 	return r
 }
 
-func newWSClient(endpoint string) (*websocket.Conn, error) {
-	conn, err := net.Dial("tcp", endpoint)
-	if err != nil {
-		return nil, err
+// FilterFlowsByApplication returns the flows of the given application, the
+// comparison is case insensitive
+func FilterFlowsByApplication(flows []*flow.Flow, app string) (r []*flow.Flow) {
+	for _, f := range flows {
+		if strings.EqualFold(f.Application, app) {
+			r = append(r, f)
+		}
+	}
+	return r
+}
+
+// FilterFlowsByNodeTID returns the flows captured on the nodes of the given
+// TIDs, like the ones returned by NodeTIDFromGremlin
+func FilterFlowsByNodeTID(flows []*flow.Flow, tids ...string) (r []*flow.Flow) {
+	for _, f := range flows {
+		for _, tid := range tids {
+			if f.NodeTID == tid {
+				r = append(r, f)
+				break
+			}
+		}
+	}
+	return r
+}
+
+// AnyTunnelID matches the flows of any tunnel in FilterTunnelFlows
+const AnyTunnelID = -1
+
+// isTunnelFlow returns whether the flow carries packets encapsulated with
+// the given protocol, found in the layers following its network layer
+func isTunnelFlow(f *flow.Flow, encap string) bool {
+	layers := strings.Split(f.LayersPath, "/")
+	for i, layer := range layers {
+		if layer != "IPv4" && layer != "IPv6" {
+			continue
+		}
+		for _, l := range layers[i+1:] {
+			if strings.EqualFold(l, encap) {
+				return true
+			}
+		}
+		break
+	}
+	return false
+}
+
+// TunnelFlowPair is an inner flow and the outer flow of the tunnel carrying it
+type TunnelFlowPair struct {
+	Inner *flow.Flow
+	Outer *flow.Flow
+}
+
+// PairTunnelFlows returns the inner flows paired with their outer flow,
+// referenced by their ParentUUID. The inner flows whose outer flow is not
+// in the list are ignored.
+func PairTunnelFlows(flows []*flow.Flow) (pairs []TunnelFlowPair) {
+	byUUID := make(map[string]*flow.Flow)
+	for _, f := range flows {
+		byUUID[f.UUID] = f
+	}
+
+	for _, f := range flows {
+		if outer, found := byUUID[f.ParentUUID]; found && f.ParentUUID != "" {
+			pairs = append(pairs, TunnelFlowPair{Inner: f, Outer: outer})
+		}
+	}
+	return pairs
+}
+
+// FilterTunnelFlows returns the inner flows carried by the tunnels of the
+// given encapsulation, like VXLAN, GRE, Geneve or MPLS, whose VNI or key is
+// vni, AnyTunnelID matching any tunnel. The tunnel ID being recorded in the
+// Network.ID of the outer flows, they have to be in the list.
+func FilterTunnelFlows(flows []*flow.Flow, encap string, vni int64) (r []*flow.Flow) {
+	for _, pair := range PairTunnelFlows(flows) {
+		outer := pair.Outer
+		if !isTunnelFlow(outer, encap) || outer.Network == nil {
+			continue
+		}
+		if vni == AnyTunnelID || outer.Network.ID == vni {
+			r = append(r, pair.Inner)
+		}
+	}
+	return r
+}
+
+// AuthenticationOpts returns the credentials of the analyzer defined in
+// the test configuration, or the token of the test user when the
+// authentication is enabled
+func AuthenticationOpts() *shttp.AuthenticationOpts {
+	if Auth != "" {
+		return authTokenOpts()
+	}
+	return &shttp.AuthenticationOpts{
+		Username: config.GetString("analyzer.auth.cluster.username"),
+		Password: config.GetString("analyzer.auth.cluster.password"),
+	}
+}
+
+// wsRetryInterval is the delay between two connection attempts of WSConnect
+const wsRetryInterval = 100 * time.Millisecond
+
+// WSOpts defines the options of a websocket subscriber connection
+type WSOpts struct {
+	// AuthOpts holds the username and password or the token used to
+	// authenticate, the credentials of the test configuration are used if nil
+	AuthOpts *shttp.AuthenticationOpts
+	// Namespaces the connection subscribes to, all of them if empty
+	Namespaces []string
+	// Protocol used by the server to encode the messages, JSON if empty
+	Protocol string
+	// TLSClientCert presents the agent certificate when TLS is enabled
+	TLSClientCert bool
+	// ValidateMessages makes the collectors check every message against
+	// its schema with ValidateWSMessage, failing on the first violation
+	ValidateMessages bool
+	// Compression requests the permessage-deflate compression of the
+	// messages, always requested if the ws.compression flag is set
+	Compression bool
+	// HostID identifies the connection in the status of the analyzer,
+	// the remote address being used if empty
+	HostID string
+}
+
+func newWSClient(endpoint string, opts *WSOpts) (*websocket.Conn, error) {
+	return dialWS(endpoint, "/ws/subscriber", opts)
+}
+
+// dialWS opens a websocket connection to the given path of the endpoint
+func dialWS(endpoint string, path string, opts *WSOpts) (*websocket.Conn, error) {
+	if opts == nil {
+		opts = &WSOpts{}
+	}
+
+	d := websocket.Dialer{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: opts.Compression || WSCompression,
 	}
 
 	scheme := "ws"
 	if config.IsTLSenabled() == true {
 		scheme = "wss"
+
+		tlsConfig, err := wsTLSConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig.ServerName, _, err = net.SplitHostPort(endpoint); err != nil {
+			return nil, err
+		}
+		d.TLSClientConfig = tlsConfig
 	}
-	endpoint = fmt.Sprintf("%s://%s/ws/subscriber", scheme, endpoint)
-	u, err := url.Parse(endpoint)
-	if err != nil {
-		return nil, err
+	endpoint = fmt.Sprintf("%s://%s%s", scheme, endpoint, path)
+
+	authOpts := AuthenticationOpts()
+	if opts.AuthOpts != nil {
+		authOpts = opts.AuthOpts
 	}
 
-	wsConn, _, err := websocket.NewClient(conn, u, http.Header{"Origin": {endpoint}}, 1024, 1024)
+	headers := http.Header{"Origin": {endpoint}}
+	shttp.SetAuthHeaders(&headers, authOpts)
+	if len(opts.Namespaces) > 0 {
+		headers["X-Websocket-Namespace"] = opts.Namespaces
+	}
+	if opts.Protocol != "" {
+		headers.Set("X-Client-Protocol", opts.Protocol)
+	}
+	if opts.HostID != "" {
+		headers.Set("X-Host-ID", opts.HostID)
+	}
+
+	wsConn, resp, err := d.Dial(endpoint, headers)
 	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("%s: %s", err, resp.Status)
+		}
 		return nil, err
 	}
 
@@ -203,21 +747,32 @@ func newWSClient(endpoint string) (*websocket.Conn, error) {
 }
 
 func WSConnect(endpoint string, timeout int, onReady func(*websocket.Conn)) (*websocket.Conn, error) {
-	var ws *websocket.Conn
-	var err error
+	return WSConnectWithOpts(endpoint, timeout, onReady, nil)
+}
 
-	t := 0
-	for {
-		if t > timeout {
-			return nil, errors.New("Connection to Agent : timeout reached")
-		}
+// WSConnectWithOpts connects to the subscriber endpoint of the analyzer
+// using the given options
+func WSConnectWithOpts(endpoint string, timeout int, onReady func(*websocket.Conn), opts *WSOpts) (*websocket.Conn, error) {
+	ctx, cancel := context.WithTimeout(watchdogContext(), time.Duration(timeout)*time.Second)
+	defer cancel()
 
-		ws, err = newWSClient(endpoint)
-		if err == nil {
-			break
+	return WSConnectCtx(ctx, endpoint, wsRetryInterval, onReady, opts)
+}
+
+// WSConnectCtx connects to the subscriber endpoint of the analyzer, retrying
+// every interval until the context is done
+func WSConnectCtx(ctx context.Context, endpoint string, interval time.Duration, onReady func(*websocket.Conn), opts *WSOpts) (*websocket.Conn, error) {
+	var ws *websocket.Conn
+
+	err := RetryCtx(ctx, interval, func() (err error) {
+		ws, err = newWSClient(endpoint, opts)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("Connection to Agent : canceled: %s", err)
 		}
-		time.Sleep(1 * time.Second)
-		t++
+		return nil, fmt.Errorf("Connection to Agent : timeout reached: %s", err)
 	}
 
 	ready := false
@@ -240,6 +795,8 @@ func WSConnect(endpoint string, timeout int, onReady func(*websocket.Conn)) (*we
 }
 
 func WSClose(ws *websocket.Conn) error {
+	wsBufferedMessages(ws)
+
 	if err := ws.WriteControl(websocket.CloseMessage, nil, time.Now().Add(3*time.Second)); err != nil {
 		return err
 	}
@@ -262,7 +819,59 @@ func DecodeWSStructMessageJSON(b []byte) *shttp.WSStructMessage {
 	return msg
 }
 
+// DecodeWSStructMessageProtobuf decodes a structured message sent with the
+// protobuf protocol, nil is returned if the message is invalid
+func DecodeWSStructMessageProtobuf(b []byte) *shttp.WSStructMessage {
+	mProtobuf := shttp.WSStructMessageProtobuf{}
+	if err := proto.Unmarshal(b, &mProtobuf); err != nil {
+		return nil
+	}
+	msg := &shttp.WSStructMessage{
+		Protocol:    shttp.ProtobufProtocol,
+		Namespace:   mProtobuf.Namespace,
+		Type:        mProtobuf.Type,
+		UUID:        mProtobuf.UUID,
+		Status:      mProtobuf.Status,
+		ProtobufObj: mProtobuf.Obj,
+	}
+	return msg
+}
+
+// DecodeWSStructMessage decodes a structured message according to the
+// protocol of the connection
+func DecodeWSStructMessage(b []byte, protocol string) *shttp.WSStructMessage {
+	if protocol == shttp.ProtobufProtocol {
+		return DecodeWSStructMessageProtobuf(b)
+	}
+	return DecodeWSStructMessageJSON(b)
+}
+
+// PCAPTransport defines how the pcap files are sent to the pcapsocket probes
+type PCAPTransport int
+
+const (
+	// PCAPTransportAuto uses TLS if enabled in the test configuration
+	PCAPTransportAuto PCAPTransport = iota
+	// PCAPTransportTCP sends the file over plain TCP
+	PCAPTransportTCP
+	// PCAPTransportTLS sends the file over TLS, authenticated by the client
+	// certificate of the test configuration
+	PCAPTransportTLS
+)
+
+// SendPCAPFile sends a pcap file to a pcapsocket probe, over TLS if enabled
+// in the test configuration
 func SendPCAPFile(filename string, socket string) error {
+	return SendPCAPFileWithTransport(filename, socket, PCAPTransportAuto)
+}
+
+// SendPCAPFileWithTransport sends a pcap file to a pcapsocket probe using the
+// given transport
+func SendPCAPFileWithTransport(filename string, socket string, transport PCAPTransport) error {
+	if transport == PCAPTransportTLS || (transport == PCAPTransportAuto && config.IsTLSenabled()) {
+		return sendPCAPFileTLS(filename, socket)
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
 		return fmt.Errorf("Failed to open file %s: %s", filename, err.Error())
@@ -300,9 +909,101 @@ func SendPCAPFile(filename string, socket string) error {
 	return nil
 }
 
-func FlowsToString(flows []*flow.Flow) string {
-	s := fmt.Sprintf("%d flows:\n", len(flows))
-	b, _ := json.MarshalIndent(flows, "", "\t")
-	s += string(b) + "\n"
-	return s
+// sendPCAPFileTLS streams a pcap file to a pcapsocket probe over TLS, the
+// file being copied as sendfile can't be used on a TLS connection
+func sendPCAPFileTLS(filename string, socket string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("Failed to open file %s: %s", filename, err.Error())
+	}
+	defer file.Close()
+
+	tlsConfig, err := wsTLSConfig(&WSOpts{TLSClientCert: true})
+	if err != nil {
+		return fmt.Errorf("Failed to load TLS configuration: %s", err.Error())
+	}
+	if tlsConfig.ServerName, _, err = net.SplitHostPort(socket); err != nil {
+		return fmt.Errorf("Failed to parse address %s: %s", socket, err.Error())
+	}
+
+	conn, err := tls.Dial("tcp", socket, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to TLS socket %s: %s", socket, err.Error())
+	}
+	defer conn.Close()
+
+	if _, err := io.Copy(conn, file); err != nil {
+		return fmt.Errorf("Failed to send file %s to socket %s: %s", filename, socket, err.Error())
+	}
+
+	return nil
+}
+
+// PCAPReplayOptions defines how packets of a pcap file are paced when sent
+type PCAPReplayOptions struct {
+	// Speed is the replay speed factor, 2 replays twice faster than the
+	// capture, 0 is equivalent to 1
+	Speed float64
+	// MaxDuration limits the duration of the replay, the packets captured
+	// after are sent without delay, 0 means no limit
+	MaxDuration time.Duration
+}
+
+// pcapReplaySnaplen is the snapshot length of the replayed pcap stream
+const pcapReplaySnaplen = 65535
+
+// SendPCAPFileWithOptions sends the packets of a pcap file to a pcapsocket
+// probe, preserving the gaps between the packets scaled by the speed factor
+func SendPCAPFileWithOptions(filename string, socket string, opts PCAPReplayOptions) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("Failed to open file %s: %s", filename, err.Error())
+	}
+	defer file.Close()
+
+	reader, err := pcapgo.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read pcap file %s: %s", filename, err.Error())
+	}
+
+	conn, err := net.Dial("tcp", socket)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to TCP socket %s: %s", socket, err.Error())
+	}
+	defer conn.Close()
+
+	writer := pcapgo.NewWriter(conn)
+	if err := writer.WriteFileHeader(pcapReplaySnaplen, reader.LinkType()); err != nil {
+		return fmt.Errorf("Failed to write pcap header to socket %s: %s", socket, err.Error())
+	}
+
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	var first time.Time
+	start := time.Now()
+	for {
+		data, ci, err := reader.ReadPacketData()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read packet from %s: %s", filename, err.Error())
+		}
+
+		if first.IsZero() {
+			first = ci.Timestamp
+		}
+
+		offset := time.Duration(float64(ci.Timestamp.Sub(first)) / speed)
+		if opts.MaxDuration == 0 || offset <= opts.MaxDuration {
+			time.Sleep(time.Until(start.Add(offset)))
+		}
+
+		if err := writer.WritePacket(ci, data); err != nil {
+			return fmt.Errorf("Failed to send packet to socket %s: %s", socket, err.Error())
+		}
+	}
 }
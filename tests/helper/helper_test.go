@@ -0,0 +1,338 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/flow"
+	shttp "github.com/skydive-project/skydive/http"
+)
+
+func newTestFlow(uuid string, network *flow.FlowLayer, transport *flow.TransportLayer, app string) *flow.Flow {
+	return &flow.Flow{UUID: uuid, Network: network, Transport: transport, Application: app}
+}
+
+func testFlows() []*flow.Flow {
+	return []*flow.Flow{
+		newTestFlow("tcp-ab",
+			&flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: "10.0.0.1", B: "10.0.0.2"},
+			&flow.TransportLayer{Protocol: flow.FlowProtocol_TCP, A: 47838, B: 80}, "TCP"),
+		newTestFlow("tcp-ba",
+			&flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: "10.0.0.2", B: "10.0.0.1"},
+			&flow.TransportLayer{Protocol: flow.FlowProtocol_TCP, A: 80, B: 47840}, "TCP"),
+		newTestFlow("udp",
+			&flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: "10.0.0.10", B: "10.0.0.2"},
+			&flow.TransportLayer{Protocol: flow.FlowProtocol_UDP, A: 53, B: 80}, "UDP"),
+		newTestFlow("icmp6",
+			&flow.FlowLayer{Protocol: flow.FlowProtocol_IPV6, A: "fd49:37c8:5229::1", B: "fd49:37c8:5229::2"},
+			nil, "ICMPv6"),
+		newTestFlow("arp", nil, nil, "ARP"),
+	}
+}
+
+func flowUUIDs(flows []*flow.Flow) (uuids []string) {
+	for _, f := range flows {
+		uuids = append(uuids, f.UUID)
+	}
+	return uuids
+}
+
+func checkFlows(t *testing.T, name string, flows []*flow.Flow, expected ...string) {
+	uuids := flowUUIDs(flows)
+	if len(uuids) != len(expected) {
+		t.Fatalf("%s: expected flows %v, got %v", name, expected, uuids)
+	}
+	for i := range uuids {
+		if uuids[i] != expected[i] {
+			t.Fatalf("%s: expected flows %v, got %v", name, expected, uuids)
+		}
+	}
+}
+
+func TestFilterIPAddr(t *testing.T) {
+	flows := testFlows()
+
+	checkFlows(t, "IPv4 prefix", FilterIPv4AddrAnd(flows, "10.0.0.1", "10.0.0.2"), "tcp-ab", "tcp-ba", "udp")
+	checkFlows(t, "IPv4 exact", FilterIPv4AddrAndExact(flows, "10.0.0.1", "10.0.0.2"), "tcp-ab", "tcp-ba")
+	checkFlows(t, "IPv6 exact", FilterIPv6AddrAndExact(flows, "fd49:37c8:5229::2", "fd49:37c8:5229::1"), "icmp6")
+	checkFlows(t, "IPv6 on IPv4", FilterIPv6AddrAnd(flows, "10.0.0.1", "10.0.0.2"))
+}
+
+func TestFilterTransportFlows(t *testing.T) {
+	flows := testFlows()
+
+	checkFlows(t, "TCP both directions", FilterTransportFlows(flows, flow.FlowProtocol_TCP, 0, 80), "tcp-ab", "tcp-ba")
+	checkFlows(t, "TCP ports", FilterTransportFlows(flows, flow.FlowProtocol_TCP, 80, 47840), "tcp-ba")
+	checkFlows(t, "UDP", FilterTransportFlows(flows, flow.FlowProtocol_UDP, 80, 0), "udp")
+	checkFlows(t, "SCTP", FilterTransportFlows(flows, flow.FlowProtocol_SCTP, 0, 0))
+
+	// composition with the IP helpers
+	ipFlows := FilterIPv4AddrAndExact(flows, "10.0.0.1", "10.0.0.2")
+	checkFlows(t, "IP then TCP", FilterTransportFlows(ipFlows, flow.FlowProtocol_TCP, 47838, 80), "tcp-ab")
+}
+
+func TestFilterFlowsByApplication(t *testing.T) {
+	flows := testFlows()
+
+	checkFlows(t, "TCP", FilterFlowsByApplication(flows, "TCP"), "tcp-ab", "tcp-ba")
+	checkFlows(t, "ICMPv6", FilterFlowsByApplication(flows, "icmpv6"), "icmp6")
+	checkFlows(t, "SCTP", FilterFlowsByApplication(flows, "SCTP"))
+}
+
+func TestFilterFlowsByNodeTID(t *testing.T) {
+	flows := testFlows()
+	for i, f := range flows {
+		f.NodeTID = []string{"tid-1", "tid-2"}[i%2]
+	}
+
+	checkFlows(t, "single TID", FilterFlowsByNodeTID(flows, "tid-1"), "tcp-ab", "udp", "arp")
+	checkFlows(t, "several TIDs", FilterFlowsByNodeTID(flows, "tid-2", "tid-1"), "tcp-ab", "tcp-ba", "udp", "icmp6", "arp")
+	checkFlows(t, "unknown TID", FilterFlowsByNodeTID(flows, "tid-3"))
+}
+
+func TestFilterTunnelFlows(t *testing.T) {
+	tunnel := func(uuid, path string, id int64) *flow.Flow {
+		return &flow.Flow{UUID: uuid, LayersPath: path, Network: &flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: "192.168.0.1", B: "192.168.0.2", ID: id}}
+	}
+	inner := func(uuid, parent string) *flow.Flow {
+		return &flow.Flow{UUID: uuid, ParentUUID: parent, LayersPath: "Ethernet/IPv4/ICMPv4"}
+	}
+
+	flows := []*flow.Flow{
+		tunnel("vxlan-10", "Ethernet/IPv4/UDP/VXLAN", 10),
+		tunnel("vxlan-20", "Ethernet/IPv4/UDP/VXLAN", 20),
+		tunnel("gre", "Ethernet/IPv4/GRE", 10),
+		tunnel("gre-mpls", "Ethernet/IPv4/GRE/MPLS", 0),
+		inner("icmp-10", "vxlan-10"),
+		inner("icmp-20", "vxlan-20"),
+		inner("icmp-gre", "gre"),
+		inner("icmp-mpls", "gre-mpls"),
+		inner("orphan", "unknown"),
+	}
+
+	checkFlows(t, "VXLAN 10", FilterTunnelFlows(flows, "vxlan", 10), "icmp-10")
+	checkFlows(t, "any VXLAN", FilterTunnelFlows(flows, "VXLAN", AnyTunnelID), "icmp-10", "icmp-20")
+	checkFlows(t, "GRE", FilterTunnelFlows(flows, "gre", AnyTunnelID), "icmp-gre", "icmp-mpls")
+	checkFlows(t, "MPLS", FilterTunnelFlows(flows, "mpls", 0), "icmp-mpls")
+	checkFlows(t, "Geneve", FilterTunnelFlows(flows, "geneve", AnyTunnelID))
+
+	pairs := PairTunnelFlows(flows)
+	if len(pairs) != 4 || pairs[0].Inner.UUID != "icmp-10" || pairs[0].Outer.UUID != "vxlan-10" {
+		t.Errorf("Expected the inner flows to be paired with their outer flow, got %+v", pairs)
+	}
+}
+
+func TestMatchFlows(t *testing.T) {
+	flows := testFlows()
+	flows[0].Metric = &flow.FlowMetric{ABPackets: 6, BAPackets: 6, ABBytes: 600, BABytes: 400}
+	flows[1].Metric = &flow.FlowMetric{ABPackets: 1, BAPackets: 1, ABBytes: 60, BABytes: 60}
+
+	// both TCP flows match the first expectation but only tcp-ab matches the
+	// second one, the pairing has to use tcp-ba for the first one
+	expectations := []FlowExpectation{
+		{Proto: flow.FlowProtocol_IPV4, A: "10.0.0.1", B: "10.0.0.2", Transport: flow.FlowProtocol_TCP},
+		{Proto: flow.FlowProtocol_IPV4, A: "10.0.0.2", B: "10.0.0.1", PortA: 80, MinPackets: 10, MaxBytes: 1000},
+		{Proto: flow.FlowProtocol_IPV6, Application: "icmpv6"},
+		{Proto: flow.FlowProtocol_IPV4, A: "10.0.0.10", Application: "DNS"},
+	}
+
+	unmatched, unexpected := MatchFlows(flows, expectations)
+	if len(unmatched) != 1 || unmatched[0].Application != "DNS" {
+		t.Fatalf("Expected the DNS expectation to be unmatched, got: %+v", unmatched)
+	}
+	checkFlows(t, "unexpected", unexpected, "udp", "arp")
+
+	expectations[1].MaxBytes = 999
+	if unmatched, _ = MatchFlows(flows, expectations); len(unmatched) != 2 {
+		t.Fatalf("Expected the byte count to be out of bounds, got: %+v", unmatched)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := map[string][]string{
+		"ip netns exec vm1  ping -c 1 10.0.0.1": {"ip", "netns", "exec", "vm1", "ping", "-c", "1", "10.0.0.1"},
+		`sh -c 'echo "a b"; exit 1'`:            {"sh", "-c", `echo "a b"; exit 1`},
+		`echo "a \"b\" \c" d\ e ''`:             {"echo", `a "b" \c`, "d e", ""},
+	}
+
+	for line, expected := range tests {
+		args, err := splitCommand(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(args, expected) {
+			t.Fatalf("Splitting of %s failed, expected: %q, got: %q", line, expected, args)
+		}
+	}
+
+	for _, line := range []string{"", "echo 'a", `echo a\`} {
+		if _, err := splitCommand(line); err == nil {
+			t.Fatalf("Splitting of %s should fail", line)
+		}
+	}
+}
+
+func TestExecCmdTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := execCmd(Cmd{Cmd: "sh -c 'sleep 10 & sleep 10'", Timeout: 200 * time.Millisecond})
+	if err == nil {
+		t.Fatal("Command should have timed out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Command children were not killed, took %s", elapsed)
+	}
+
+	output, err := execCmd(Cmd{Cmd: "sh -c 'cat; echo $FOO; pwd'", Env: []string{"FOO=bar"}, Dir: "/", Stdin: "in\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "in\nbar\n/\n" {
+		t.Fatalf("Unexpected output: %q", string(output))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int
+	err := Retry(time.Second, time.Millisecond, func() error {
+		if attempts++; attempts < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("Retry should succeed after 3 attempts, got %d attempts and error: %v", attempts, err)
+	}
+
+	err = Retry(20*time.Millisecond, 5*time.Millisecond, func() error {
+		return errors.New("always failing")
+	})
+	if err == nil || err.Error() != "always failing" {
+		t.Fatalf("Retry should return the last error on timeout, got: %v", err)
+	}
+
+	attempts = 0
+	err = RetryBackoff(time.Second, time.Millisecond, func() error {
+		attempts++
+		return Fatal(errors.New("aborted"))
+	})
+	if !IsFatal(err) || err.Error() != "aborted" || attempts != 1 {
+		t.Fatalf("Retry should abort on a fatal error, got %d attempts and error: %v", attempts, err)
+	}
+
+	attempts = 0
+	if err = Retry(time.Second, time.Millisecond, func() error { attempts++; return ErrFatal }); err != ErrFatal || attempts != 1 {
+		t.Fatalf("Retry should abort on ErrFatal, got %d attempts and error: %v", attempts, err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var times []time.Time
+	RetryBackoff(100*time.Millisecond, 10*time.Millisecond, func() error {
+		times = append(times, time.Now())
+		return errors.New("always failing")
+	})
+
+	// attempts at 0, 10, 30, 70 and 100ms
+	if len(times) < 3 {
+		t.Fatalf("Expected at least 3 attempts with exponential backoff, got %d", len(times))
+	}
+	if first, last := times[1].Sub(times[0]), times[2].Sub(times[1]); last < first+first/2 {
+		t.Fatalf("Delay between attempts should increase exponentially, got %s then %s", first, last)
+	}
+}
+
+func TestRetryLastAttempt(t *testing.T) {
+	timeout := 200 * time.Millisecond
+
+	var last time.Duration
+	start := time.Now()
+	err := RetryBackoff(timeout, 20*time.Millisecond, func() error {
+		last = time.Since(start)
+		return errors.New("always failing")
+	})
+	if err == nil {
+		t.Fatal("RetryBackoff should fail")
+	}
+
+	// attempts at 0, 20, 60 and 140ms, the last one is made at the timeout
+	// instead of giving up before it
+	if last < timeout-20*time.Millisecond || last > timeout+100*time.Millisecond {
+		t.Fatalf("Last attempt should be made close to the timeout of %s, was made after %s", timeout, last)
+	}
+}
+
+func TestRetryCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := RetryCtx(ctx, time.Hour, func() error {
+		return errors.New("always failing")
+	})
+	if err == nil {
+		t.Fatal("RetryCtx should fail when the context is canceled")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("RetryCtx should return as soon as the context is canceled, returned after %s", elapsed)
+	}
+}
+
+func TestDecodeWSStructMessage(t *testing.T) {
+	type payload struct {
+		Name  string
+		Count int
+	}
+
+	for _, protocol := range []string{shttp.JsonProtocol, shttp.ProtobufProtocol} {
+		expected := shttp.NewWSStructMessage("Alert", "AlertEvent", payload{Name: "alert-1", Count: 3}, "e3a7c1d2")
+		expected.Status = 202
+
+		msg := DecodeWSStructMessage(expected.Bytes(protocol), protocol)
+		if msg == nil {
+			t.Fatalf("Decoding of a %s message failed", protocol)
+		}
+
+		if msg.Protocol != protocol || msg.Namespace != "Alert" || msg.Type != "AlertEvent" || msg.UUID != "e3a7c1d2" || msg.Status != 202 {
+			t.Fatalf("Decoding of a %s message failed, expected: %s, got: %s", protocol, expected.Debug(), msg.Debug())
+		}
+
+		var obj payload
+		if err := msg.UnmarshalObj(&obj); err != nil {
+			t.Fatal(err)
+		}
+
+		if obj.Name != "alert-1" || obj.Count != 3 {
+			t.Fatalf("Decoding of a %s message object failed, got: %+v", protocol, obj)
+		}
+	}
+
+	if msg := DecodeWSStructMessageProtobuf([]byte{0xff, 0xff}); msg != nil {
+		t.Fatalf("Decoding of an invalid protobuf message should fail, got: %s", msg.Debug())
+	}
+}
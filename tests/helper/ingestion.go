@@ -0,0 +1,369 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+const (
+	defaultIngestionStepDuration   = 10 * time.Second
+	defaultIngestionSampleInterval = time.Second
+	defaultIngestionDrainTimeout   = 30 * time.Second
+	defaultIngestionFlows          = 100
+)
+
+// IngestionOptions describes the load generated by RunIngestion
+type IngestionOptions struct {
+	// Corpus are the pcap files whose packets are replayed in loop,
+	// synthesized UDP flows are sent if empty
+	Corpus []string
+	// Rates are the packet rates, in packets per second, of the steps
+	Rates []int
+	// StepDuration is the time packets are sent at each rate, 10s if not set
+	StepDuration time.Duration
+	// Flows is the number of distinct synthesized flows, 100 if not set
+	Flows int
+	// SampleInterval is the delay between two samples of the flows known
+	// by the analyzer, 1s if not set
+	SampleInterval time.Duration
+	// DrainTimeout is the time left to the analyzer to account the packets
+	// of a step once sent, 30s if not set
+	DrainTimeout time.Duration
+}
+
+// IngestionSample is the state of the analyzer flows during a step
+type IngestionSample struct {
+	// Elapsed is the time since the beginning of the step
+	Elapsed time.Duration
+	Flows   int
+	Packets int64
+	// Backlog is the number of packets sent but not accounted yet
+	Backlog int64
+}
+
+// IngestionStep is the result of a step of RunIngestion
+type IngestionStep struct {
+	Rate int
+	// Sent is the number of packets sent, Dropped the ones never accounted
+	Sent    int64
+	Dropped int64
+	// Flows is the number of flows updated during the step
+	Flows       int
+	FlowsPerSec float64
+	// Latency is the time between the last packet sent and the analyzer
+	// accounting all of them, or the drain timeout if it never did
+	Latency time.Duration
+	Samples []IngestionSample
+}
+
+func (s *IngestionStep) String() string {
+	return fmt.Sprintf("rate=%d pps sent=%d dropped=%d flows=%d flows/s=%.1f latency=%s",
+		s.Rate, s.Sent, s.Dropped, s.Flows, s.FlowsPerSec, s.Latency)
+}
+
+// IngestionOptionsFromFlags returns the options of the ingestion benchmarks
+// defined by the bench.* flags
+func IngestionOptionsFromFlags() (opts IngestionOptions, err error) {
+	if benchCorpus != "" {
+		opts.Corpus = strings.Split(benchCorpus, ",")
+	}
+	for _, r := range strings.Split(benchRates, ",") {
+		rate, err := strconv.Atoi(strings.TrimSpace(r))
+		if err != nil || rate <= 0 {
+			return opts, fmt.Errorf("Invalid rate '%s' in -bench.rates", r)
+		}
+		opts.Rates = append(opts.Rates, rate)
+	}
+	opts.StepDuration = benchDuration
+	return opts, nil
+}
+
+// readCorpus returns the frames of the pcap files
+func readCorpus(files []string) (frames [][]byte, linkType layers.LinkType, err error) {
+	linkType = layers.LinkTypeEthernet
+	for _, filename := range files {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, linkType, err
+		}
+
+		reader, err := pcapgo.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, linkType, fmt.Errorf("Failed to read pcap file %s: %s", filename, err)
+		}
+		linkType = reader.LinkType()
+
+		for {
+			data, _, err := reader.ReadPacketData()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				file.Close()
+				return nil, linkType, fmt.Errorf("Failed to read packet from %s: %s", filename, err)
+			}
+			frames = append(frames, data)
+		}
+		file.Close()
+	}
+
+	if len(frames) == 0 {
+		return nil, linkType, fmt.Errorf("No packet in corpus %v", files)
+	}
+	return frames, linkType, nil
+}
+
+// synthesizeFlows returns one UDP frame for each of the flows
+func synthesizeFlows(count int) ([][]byte, error) {
+	var frames [][]byte
+	for i := 0; i < count; i++ {
+		src := fmt.Sprintf("10.201.%d.%d:%d", i/250%250, i%250+1, 1024+i/62500)
+		e, err := parseTrafficEndpoints(src, "10.202.0.1:5001", true)
+		if err != nil {
+			return nil, err
+		}
+
+		frame, err := forgeUDPFlow(e, 1, 64)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame...)
+	}
+	return frames, nil
+}
+
+// flowPackets returns the number of packets of each flow returned by the query
+func flowPackets(query string) (map[string]int64, error) {
+	result := &GremlinResult{query: query}
+	if result.data, result.err = queryTopology(query); result.err != nil {
+		return nil, result.err
+	}
+
+	flows, err := result.GetFlows()
+	if err != nil {
+		return nil, err
+	}
+
+	packets := make(map[string]int64)
+	for _, f := range flows {
+		if f.Metric != nil {
+			packets[f.UUID] = f.Metric.ABPackets + f.Metric.BAPackets
+		}
+	}
+	return packets, nil
+}
+
+// sampleFlows returns the number of flows updated since the baseline and
+// the number of packets they received since then
+func sampleFlows(query string, baseline map[string]int64) (int, int64, error) {
+	current, err := flowPackets(query)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var count int
+	var packets int64
+	for uuid, p := range current {
+		if delta := p - baseline[uuid]; delta > 0 {
+			count++
+			packets += delta
+		}
+	}
+	return count, packets, nil
+}
+
+// sendAtRate writes the frames in loop at the given rate until the duration
+// expires, counting the packets sent in sent
+func sendAtRate(writer *pcapgo.Writer, frames [][]byte, rate int, duration time.Duration, sent *int64) error {
+	interval := time.Second / time.Duration(rate)
+	start := time.Now()
+
+	for i := int64(0); ; i++ {
+		next := start.Add(time.Duration(i) * interval)
+		if next.Sub(start) >= duration {
+			return nil
+		}
+		time.Sleep(time.Until(next))
+
+		data := frames[i%int64(len(frames))]
+		ci := gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(data), Length: len(data)}
+		if err := writer.WritePacket(ci, data); err != nil {
+			return err
+		}
+		atomic.AddInt64(sent, 1)
+	}
+}
+
+// RunIngestion sends packets to the pcapsocket of a capture at each of the
+// rates of the options and samples the flows reported by the analyzer for
+// the nodes of the capture. The packets of a step are the ones added to the
+// flow metrics since the beginning of the step.
+func RunIngestion(h *CaptureHandle, opts IngestionOptions) ([]*IngestionStep, error) {
+	if opts.StepDuration == 0 {
+		opts.StepDuration = defaultIngestionStepDuration
+	}
+	if opts.SampleInterval == 0 {
+		opts.SampleInterval = defaultIngestionSampleInterval
+	}
+	if opts.DrainTimeout == 0 {
+		opts.DrainTimeout = defaultIngestionDrainTimeout
+	}
+	if opts.Flows == 0 {
+		opts.Flows = defaultIngestionFlows
+	}
+
+	var frames [][]byte
+	linkType := layers.LinkTypeEthernet
+	var err error
+	if len(opts.Corpus) > 0 {
+		frames, linkType, err = readCorpus(opts.Corpus)
+	} else {
+		frames, err = synthesizeFlows(opts.Flows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	socket, err := h.PCAPSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("tcp", socket)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to TCP socket %s: %s", socket, err)
+	}
+	defer conn.Close()
+
+	writer := pcapgo.NewWriter(conn)
+	if err := writer.WriteFileHeader(pcapReplaySnaplen, linkType); err != nil {
+		return nil, fmt.Errorf("Failed to write pcap header to socket %s: %s", socket, err)
+	}
+
+	query := h.Capture.GremlinQuery + ".Flows()"
+
+	var steps []*IngestionStep
+	for _, rate := range opts.Rates {
+		step := &IngestionStep{Rate: rate}
+
+		baseline, err := flowPackets(query)
+		if err != nil {
+			return steps, err
+		}
+		start := time.Now()
+
+		// sample the flows while sending and until all the packets are accounted
+		done := make(chan struct{})
+		samplerDone := make(chan error, 1)
+		var sendEnd time.Time
+		go func(sending <-chan struct{}) {
+			ticker := time.NewTicker(opts.SampleInterval)
+			defer ticker.Stop()
+
+			var drainDeadline <-chan time.Time
+			for {
+				select {
+				case <-sending:
+					drainDeadline = time.After(opts.DrainTimeout)
+					sending = nil
+					continue
+				case <-drainDeadline:
+					step.Latency = opts.DrainTimeout
+					samplerDone <- nil
+					return
+				case <-ticker.C:
+				}
+
+				flows, packets, err := sampleFlows(query, baseline)
+				if err != nil {
+					samplerDone <- err
+					return
+				}
+
+				sample := IngestionSample{
+					Elapsed: time.Since(start),
+					Flows:   flows,
+					Packets: packets,
+					Backlog: atomic.LoadInt64(&step.Sent) - packets,
+				}
+				if sending == nil {
+					if sample.Backlog <= 0 {
+						step.Latency = time.Since(sendEnd)
+						step.Samples = append(step.Samples, sample)
+						samplerDone <- nil
+						return
+					}
+				}
+				step.Samples = append(step.Samples, sample)
+			}
+		}(done)
+
+		err = sendAtRate(writer, frames, rate, opts.StepDuration, &step.Sent)
+		sendEnd = time.Now()
+		close(done)
+		if err != nil {
+			<-samplerDone
+			return steps, fmt.Errorf("Failed to send packets to socket %s: %s", socket, err)
+		}
+
+		if err := <-samplerDone; err != nil {
+			return steps, err
+		}
+
+		if len(step.Samples) > 0 {
+			last := step.Samples[len(step.Samples)-1]
+			step.Flows = last.Flows
+			if dropped := step.Sent - last.Packets; dropped > 0 {
+				step.Dropped = dropped
+			}
+		}
+		step.FlowsPerSec = float64(step.Flows) / sendEnd.Sub(start).Seconds()
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// IngestionReport returns a table of the results of RunIngestion
+func IngestionReport(steps []*IngestionStep) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%10s %10s %10s %10s %10s %12s\n", "rate", "sent", "dropped", "flows", "flows/s", "latency")
+	for _, s := range steps {
+		fmt.Fprintf(&b, "%10d %10d %10d %10d %10.1f %12s\n", s.Rate, s.Sent, s.Dropped, s.Flows, s.FlowsPerSec, s.Latency)
+	}
+	return b.String()
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+// Names of the objects deployed in the kind cluster
+const (
+	KindClusterName = "skydive-test"
+	KindNamespace   = "skydive-kind"
+	KindPod         = "skydive-kind-pod"
+	KindService     = "skydive-kind-service"
+)
+
+// kindManifests are the test objects deployed once the cluster is started
+const kindManifests = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: skydive-kind
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: skydive-kind-pod
+  namespace: skydive-kind
+  labels:
+    app: skydive-kind
+spec:
+  containers:
+  - name: skydive-kind-container
+    image: busybox
+    command: ["sleep", "3600"]
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: skydive-kind-service
+  namespace: skydive-kind
+spec:
+  selector:
+    app: skydive-kind
+  ports:
+  - port: 80
+`
+
+// kindUnavailableError is returned when kind or docker can't be used, the
+// Kubernetes tests are skipped in that case
+type kindUnavailableError struct {
+	err error
+}
+
+func (e kindUnavailableError) Error() string {
+	return "Kind cluster unavailable: " + e.err.Error()
+}
+
+// KindCluster is the Kubernetes cluster started by StartKindCluster
+type KindCluster struct {
+	Name       string
+	Kubeconfig string
+	dir        string
+}
+
+var kindCluster struct {
+	sync.Mutex
+	cluster *KindCluster
+	err     error
+}
+
+// kindAvailable checks that the kind and kubectl commands are installed
+// and that the docker daemon answers
+func kindAvailable() error {
+	for _, command := range []string{"kind", "kubectl", "docker"} {
+		if _, err := exec.LookPath(command); err != nil {
+			return kindUnavailableError{err}
+		}
+	}
+	if _, err := runCommand("docker", "info"); err != nil {
+		return kindUnavailableError{err}
+	}
+	return nil
+}
+
+// kubectl runs a kubectl command against the cluster
+func (c *KindCluster) kubectl(args ...string) (string, error) {
+	return runCommand(append([]string{"kubectl", "--kubeconfig", c.Kubeconfig}, args...)...)
+}
+
+// StartKindCluster starts a kind cluster, writing its kubeconfig in a
+// temporary directory, and deploys the test pod and service
+func StartKindCluster() (_ *KindCluster, err error) {
+	if err := kindAvailable(); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "skydive-kind")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &KindCluster{
+		Name:       KindClusterName,
+		Kubeconfig: filepath.Join(dir, "kubeconfig"),
+		dir:        dir,
+	}
+	defer func() {
+		if err != nil {
+			c.Stop()
+		}
+	}()
+
+	if _, err := runCommand("kind", "create", "cluster", "--name", c.Name, "--kubeconfig", c.Kubeconfig, "--wait", "5m"); err != nil {
+		return nil, fmt.Errorf("Failed to create kind cluster: %s", err)
+	}
+
+	manifests := filepath.Join(dir, "manifests.yaml")
+	if err := ioutil.WriteFile(manifests, []byte(kindManifests), 0644); err != nil {
+		return nil, err
+	}
+	if _, err := c.kubectl("apply", "-f", manifests); err != nil {
+		return nil, fmt.Errorf("Failed to deploy test objects: %s", err)
+	}
+	if _, err := c.kubectl("wait", "--for=condition=Ready", "--timeout=5m", "-n", KindNamespace, "pod/"+KindPod); err != nil {
+		return nil, fmt.Errorf("Test pod not ready: %s", err)
+	}
+
+	logging.GetLogger().Infof("Kind cluster %s started, kubeconfig: %s", c.Name, c.Kubeconfig)
+	return c, nil
+}
+
+// Stop deletes the cluster and its kubeconfig
+func (c *KindCluster) Stop() {
+	if _, err := runCommand("kind", "delete", "cluster", "--name", c.Name); err != nil {
+		logging.GetLogger().Errorf("Failed to delete kind cluster %s: %s", c.Name, err)
+	}
+	os.RemoveAll(c.dir)
+}
+
+// setKindParams starts the cluster of the run when enabled and sets the
+// parameters of the configuration template. The analyzers are configured
+// without the cluster if it can't be started, the tests requiring it fail
+// or are skipped.
+func setKindParams(params HelperParams) {
+	params["K8sConfigFile"] = ""
+	if !K8s {
+		return
+	}
+
+	kindCluster.Lock()
+	defer kindCluster.Unlock()
+
+	if kindCluster.cluster == nil && kindCluster.err == nil {
+		if kindCluster.cluster, kindCluster.err = StartKindCluster(); kindCluster.err != nil {
+			logging.GetLogger().Errorf("Kubernetes tests disabled: %s", kindCluster.err)
+		}
+	}
+	if kindCluster.cluster == nil {
+		return
+	}
+
+	params["K8sConfigFile"] = kindCluster.cluster.Kubeconfig
+
+	probes, _ := params["AnalyzerProbes"].([]string)
+	for _, probe := range probes {
+		if probe == "k8s" {
+			return
+		}
+	}
+	params["AnalyzerProbes"] = append(probes, "k8s")
+}
+
+// StopKindCluster deletes the cluster of the run, if any
+func StopKindCluster() {
+	kindCluster.Lock()
+	defer kindCluster.Unlock()
+
+	if kindCluster.cluster != nil {
+		kindCluster.cluster.Stop()
+		kindCluster.cluster = nil
+	}
+}
+
+// RequireKindCluster returns the cluster of the run. The test is skipped
+// when the cluster is not enabled or docker is not available and fails
+// if the cluster could not be started otherwise.
+func RequireKindCluster(t *testing.T) *KindCluster {
+	if !K8s {
+		t.Skip("Kubernetes cluster not enabled, use -k8s")
+	}
+
+	kindCluster.Lock()
+	defer kindCluster.Unlock()
+
+	switch err := kindCluster.err; {
+	case kindCluster.cluster != nil:
+	case err == nil:
+		t.Fatal("Kubernetes cluster not started, no configuration rendered")
+	default:
+		if _, ok := err.(kindUnavailableError); ok {
+			t.Skip(err)
+		}
+		t.Fatalf("Kubernetes cluster failed to start: %s", err)
+	}
+
+	return kindCluster.cluster
+}
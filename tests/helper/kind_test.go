@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetKindParams(t *testing.T) {
+	defer func(enabled bool) {
+		K8s = enabled
+		kindCluster.cluster, kindCluster.err = nil, nil
+	}(K8s)
+
+	K8s = false
+	params := HelperParams{"AnalyzerProbes": []string{}}
+	setKindParams(params)
+	if params["K8sConfigFile"] != "" {
+		t.Errorf("Expected no kubeconfig when disabled, got: %v", params["K8sConfigFile"])
+	}
+
+	K8s = true
+	kindCluster.cluster = &KindCluster{Name: KindClusterName, Kubeconfig: "/tmp/kubeconfig"}
+
+	params = HelperParams{"AnalyzerProbes": []string{"docker"}}
+	setKindParams(params)
+	if params["K8sConfigFile"] != "/tmp/kubeconfig" {
+		t.Errorf("Unexpected kubeconfig: %v", params["K8sConfigFile"])
+	}
+	if probes := params["AnalyzerProbes"]; !reflect.DeepEqual(probes, []string{"docker", "k8s"}) {
+		t.Errorf("Expected the k8s probe to be enabled, got: %v", probes)
+	}
+
+	setKindParams(params)
+	if probes := params["AnalyzerProbes"]; !reflect.DeepEqual(probes, []string{"docker", "k8s"}) {
+		t.Errorf("Expected the k8s probe to be enabled once, got: %v", probes)
+	}
+
+	if c := RequireKindCluster(t); c != kindCluster.cluster {
+		t.Errorf("Unexpected cluster: %+v", c)
+	}
+}
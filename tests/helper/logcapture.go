@@ -0,0 +1,188 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+// inProcessComponent tags the logs of the analyzer and the agent running in
+// the test process, which share the same logger
+const inProcessComponent = "in-process"
+
+// captureLevels maps the levels of -log-capture-level to the zap ones
+var captureLevels = map[string]zapcore.Level{
+	"CRITICAL": zapcore.DPanicLevel,
+	"ERROR":    zapcore.ErrorLevel,
+	"WARNING":  zapcore.WarnLevel,
+	"NOTICE":   zapcore.InfoLevel,
+	"INFO":     zapcore.InfoLevel,
+	"DEBUG":    zapcore.DebugLevel,
+}
+
+// syncBuffer is a buffer safe for concurrent writes
+type syncBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.String()
+}
+
+// LogCapture buffers the logs written while a test runs, by the logger of
+// the test process and by the daemons started by the helper, and reports
+// them with t.Logf if the test fails
+type LogCapture struct {
+	t       testing.TB
+	level   zapcore.Level
+	core    zapcore.Core
+	buffer  syncBuffer
+	offsets map[string]int64
+}
+
+// CaptureLogs starts capturing the logs of the level given by
+// -log-capture-level or above, whatever the level of the daemons. Stop has
+// to be deferred.
+func CaptureLogs(t testing.TB) *LogCapture {
+	level, ok := captureLevels[strings.ToUpper(logCaptureLevel)]
+	if !ok {
+		t.Fatalf("Invalid log capture level %s", logCaptureLevel)
+	}
+
+	c := &LogCapture{t: t, level: level, offsets: make(map[string]int64)}
+
+	// only the lines written from now on are reported
+	for file := range daemonLogFiles() {
+		if fi, err := os.Stat(file); err == nil {
+			c.offsets[file] = fi.Size()
+		}
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		MessageKey:     "message",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}
+	c.core = zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(&c.buffer), level)
+	logging.AddCore(c.core)
+
+	return c
+}
+
+// lineLevel returns the level of a line written by the console encoder,
+// ok being false for the lines without level like stack traces
+func lineLevel(line string) (level zapcore.Level, ok bool) {
+	fields := strings.SplitN(line, "\t", 3)
+	if len(fields) < 2 {
+		return level, false
+	}
+	err := level.UnmarshalText([]byte(strings.ToLower(fields[1])))
+	return level, err == nil
+}
+
+// filterLines returns the last lines of the level of the capture or above,
+// the lines without level following the level of the previous line
+func (c *LogCapture) filterLines(text string) (lines []string) {
+	keep := false
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if level, ok := lineLevel(line); ok {
+			keep = level >= c.level
+		}
+		if keep && line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > logTailLines {
+		lines = lines[len(lines)-logTailLines:]
+	}
+	return lines
+}
+
+// readFrom returns the content of the file from the offset
+func readFrom(file string, offset int64) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	_, err = b.ReadFrom(f)
+	return b.String(), err
+}
+
+// Stop stops capturing the logs and reports them, tagged with the name of
+// the component that wrote them, if the test failed. The logs of the daemons
+// started while the test ran are reported from their beginning.
+func (c *LogCapture) Stop() {
+	if c.core == nil {
+		return
+	}
+	logging.RemoveCore(c.core)
+	c.core = nil
+
+	if !c.t.Failed() {
+		return
+	}
+
+	for _, line := range c.filterLines(c.buffer.String()) {
+		c.t.Logf("[%s] %s", inProcessComponent, line)
+	}
+
+	for file, name := range daemonLogFiles() {
+		text, err := readFrom(file, c.offsets[file])
+		if err != nil {
+			c.t.Logf("Failed to read logs of %s: %s", name, err)
+			continue
+		}
+		for _, line := range c.filterLines(text) {
+			c.t.Logf("[%s] %s", name, line)
+		}
+	}
+}
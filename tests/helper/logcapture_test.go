@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogCaptureFilterLines(t *testing.T) {
+	c := &LogCapture{level: zapcore.WarnLevel}
+
+	text := "2018-05-04T10:00:00.000+0200\tINFO\tagent/agent.go:130 Start\thost:agent started\n" +
+		"2018-05-04T10:00:01.000+0200\tERROR\tflow/table.go:42 Update\thost:agent flow table error\n" +
+		"github.com/skydive-project/skydive/flow.(*Table).Update\n" +
+		"2018-05-04T10:00:02.000+0200\tDEBUG\tflow/table.go:50 Update\thost:agent updated\n" +
+		"\tflow/table.go:50\n" +
+		"2018-05-04T10:00:03.000+0200\tWARN\tagent/agent.go:140 Stop\thost:agent stopping\n"
+
+	expected := []string{
+		"2018-05-04T10:00:01.000+0200\tERROR\tflow/table.go:42 Update\thost:agent flow table error",
+		"github.com/skydive-project/skydive/flow.(*Table).Update",
+		"2018-05-04T10:00:03.000+0200\tWARN\tagent/agent.go:140 Stop\thost:agent stopping",
+	}
+	if lines := c.filterLines(text); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected lines %q, got %q", expected, lines)
+	}
+}
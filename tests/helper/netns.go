@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/vishvananda/netns"
+)
+
+// InNetNS runs the callback with the network namespace nsName active on the
+// thread of the calling goroutine, the root namespace being kept if nsName
+// is empty. The original namespace is restored afterwards, even if the
+// callback panics.
+func InNetNS(nsName string, fn func() error) (err error) {
+	if nsName == "" {
+		return fn()
+	}
+
+	runtime.LockOSThread()
+
+	origns, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("Failed to get the current namespace: %s", err)
+	}
+	defer origns.Close()
+
+	newns, err := netns.GetFromPath(filepath.Join(netnsRunPath, nsName))
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("Failed to open namespace %s: %s", nsName, err)
+	}
+	defer newns.Close()
+
+	if err := netns.Set(newns); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("Failed to switch to namespace %s: %s", nsName, err)
+	}
+
+	defer func() {
+		// the thread is left locked if it can't be restored so that no other
+		// goroutine is scheduled in the namespace
+		if restoreErr := netns.Set(origns); restoreErr != nil {
+			if err == nil {
+				err = fmt.Errorf("Failed to switch back from namespace %s: %s", nsName, restoreErr)
+			}
+			return
+		}
+		runtime.UnlockOSThread()
+	}()
+
+	return fn()
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/vishvananda/netns"
+)
+
+const testNetNS = "skydive-test-innetns"
+
+func TestInNetNS(t *testing.T) {
+	if _, err := runCommand("ip", "netns", "add", testNetNS); err != nil {
+		t.Skipf("Unable to create a network namespace: %s", err)
+	}
+	defer runCommand("ip", "netns", "del", testNetNS)
+
+	if _, err := runCommand(netnsArgs(testNetNS, "ip", "link", "set", "lo", "up")...); err != nil {
+		t.Fatal(err)
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origns, err := netns.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer origns.Close()
+
+	// the port of the socket bound inside the namespace is still available
+	// outside of it
+	var inside net.Listener
+	err = InNetNS(testNetNS, func() (err error) {
+		inside, err = net.Listen("tcp", "127.0.0.1:0")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inside.Close()
+
+	outside, err := net.Listen("tcp", inside.Addr().String())
+	if err != nil {
+		t.Fatalf("Socket bound in namespace %s is visible from the original one: %s", testNetNS, err)
+	}
+	outside.Close()
+
+	expected := errors.New("callback error")
+	if err := InNetNS(testNetNS, func() error { return expected }); err != expected {
+		t.Errorf("Expected the error of the callback, got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic of the callback to be propagated")
+			}
+		}()
+		InNetNS(testNetNS, func() error { panic("callback panic") })
+	}()
+
+	runtime.LockOSThread()
+	current, err := netns.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer current.Close()
+
+	if !current.Equal(origns) {
+		t.Errorf("Namespace not restored after a panic, expected %s, got %s", origns, current)
+	}
+}
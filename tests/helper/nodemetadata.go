@@ -0,0 +1,206 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/gremlin"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// assertMetadataTimeout is the time given to the node to match the
+// expectations of AssertNodeMetadata
+const assertMetadataTimeout = 30 * time.Second
+
+// MetadataMatcher matches a metadata value against something else than an
+// expected value
+type MetadataMatcher interface {
+	Match(value interface{}) bool
+	String() string
+}
+
+type numericBound struct {
+	op    string
+	bound interface{}
+	cmp   func(value, bound float64) bool
+}
+
+func (b *numericBound) Match(value interface{}) bool {
+	v, err := common.ToFloat64(value)
+	if err != nil {
+		return false
+	}
+	bound, err := common.ToFloat64(b.bound)
+	if err != nil {
+		return false
+	}
+	return b.cmp(v, bound)
+}
+
+func (b *numericBound) String() string {
+	return fmt.Sprintf("%s %v", b.op, b.bound)
+}
+
+// Gte matches the numbers greater than or equal to the bound
+func Gte(bound interface{}) MetadataMatcher {
+	return &numericBound{op: ">=", bound: bound, cmp: func(v, b float64) bool { return v >= b }}
+}
+
+// Lte matches the numbers lower than or equal to the bound
+func Lte(bound interface{}) MetadataMatcher {
+	return &numericBound{op: "<=", bound: bound, cmp: func(v, b float64) bool { return v <= b }}
+}
+
+// isNumber returns whether the value is a number, as decoded or as written
+// in a test
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case json.Number, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+// matchMetadataValue returns whether the value of a field fulfills the
+// expectation, the numbers being compared regardless of their types
+func matchMetadataValue(value, expected interface{}) bool {
+	if matcher, ok := expected.(MetadataMatcher); ok {
+		return matcher.Match(value)
+	}
+
+	if isNumber(value) && isNumber(expected) {
+		v, err1 := common.ToFloat64(value)
+		e, err2 := common.ToFloat64(expected)
+		return err1 == nil && err2 == nil && v == e
+	}
+
+	return reflect.DeepEqual(value, expected)
+}
+
+// metadataMismatches returns the fields of the node not fulfilling the
+// expectations, the keys being dotted paths into the metadata
+func metadataMismatches(n *graph.Node, expectations map[string]interface{}) []string {
+	var keys []string
+	for key := range expectations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var mismatches []string
+	for _, key := range keys {
+		expected := expectations[key]
+		value, err := n.GetField(key)
+		if err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected %v, %s", key, expected, err))
+		} else if !matchMetadataValue(value, expected) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected %v, got %v", key, expected, value))
+		}
+	}
+	return mismatches
+}
+
+// AssertNodeMetadata waits for the query to return a single node whose
+// metadata fulfill the expectations, failing the test with the candidates
+// or the metadata of the node otherwise. The expectations are keyed by
+// dotted paths like "Captures.State" and hold either the expected value or
+// a matcher like Gte.
+func AssertNodeMetadata(t *testing.T, query interface{}, expectations map[string]interface{}) *graph.Node {
+	q := gremlin.NewQueryStringFromArgument(query).String()
+
+	var node *graph.Node
+	err := Retry(assertMetadataTimeout, waitInterval, func() error {
+		node = nil
+
+		result := &GremlinResult{query: q}
+		if result.data, result.err = queryTopology(q); result.err != nil {
+			return result.err
+		}
+
+		nodes, err := result.GetNodes()
+		if err != nil {
+			return err
+		}
+		if len(nodes) != 1 {
+			var candidates []string
+			for _, n := range nodes {
+				candidates = append(candidates, n.String())
+			}
+			return fmt.Errorf("expected a single node, got %d: [%s]", len(nodes), strings.Join(candidates, ", "))
+		}
+		node = nodes[0]
+
+		if mismatches := metadataMismatches(node, expectations); len(mismatches) > 0 {
+			return fmt.Errorf("metadata mismatches:\n%s", strings.Join(mismatches, "\n"))
+		}
+		return nil
+	})
+	if err != nil {
+		metadata := "no node"
+		if node != nil {
+			metadata = node.Metadata().String()
+		}
+		t.Fatalf("Node of '%s' doesn't match the expectations within %s, %s\nmetadata: %s", q, assertMetadataTimeout, err, metadata)
+	}
+
+	return node
+}
+
+// NodeTIDFromGremlin waits for the query to return a single node having a
+// TID, like an interface, and returns it
+func NodeTIDFromGremlin(t *testing.T, query interface{}) string {
+	q := gremlin.NewQueryStringFromArgument(query).String()
+
+	var tid string
+	err := Retry(assertMetadataTimeout, waitInterval, func() error {
+		result := &GremlinResult{query: q}
+		if result.data, result.err = queryTopology(q); result.err != nil {
+			return result.err
+		}
+
+		nodes, err := result.GetNodes()
+		if err != nil {
+			return err
+		}
+		if len(nodes) != 1 {
+			return fmt.Errorf("expected a single node, got %d", len(nodes))
+		}
+
+		if tid, err = nodes[0].GetFieldString("TID"); err != nil {
+			return fmt.Errorf("no TID for node %s", nodes[0].ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to get the TID of the node of '%s' within %s: %s", q, assertMetadataTimeout, err)
+	}
+
+	return tid
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+func decodeTestNode(t *testing.T, data string) *graph.Node {
+	var value interface{}
+	if err := common.JSONDecode(bytes.NewReader([]byte(data)), &value); err != nil {
+		t.Fatal(err)
+	}
+
+	n := new(graph.Node)
+	if err := n.Decode(value); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMetadataMismatches(t *testing.T) {
+	n := decodeTestNode(t, `{"ID": "node1", "Host": "host1", "Metadata": {"Name": "eth0", "MTU": 1500, "Neutron": {"PortID": "1234"}, "Metric": {"RxPackets": 42}}}`)
+
+	tests := []struct {
+		name         string
+		expectations map[string]interface{}
+		mismatches   []string
+	}{
+		{"match", map[string]interface{}{"Host": "host1", "Name": "eth0", "MTU": 1500, "Neutron.PortID": "1234"}, nil},
+		{"bounds", map[string]interface{}{"Metric.RxPackets": Gte(40), "MTU": Lte(9000)}, nil},
+		{"wrong value", map[string]interface{}{"Name": "eth1", "MTU": int64(9000)}, []string{"MTU", "Name"}},
+		{"out of bounds", map[string]interface{}{"Metric.RxPackets": Gte(50), "MTU": Lte(1000)}, []string{"MTU", "Metric.RxPackets"}},
+		{"not a number", map[string]interface{}{"Name": Gte(0)}, []string{"Name"}},
+		{"missing", map[string]interface{}{"Neutron.NetworkID": "5678", "Type": "veth"}, []string{"Neutron.NetworkID", "Type"}},
+	}
+
+	for _, test := range tests {
+		mismatches := metadataMismatches(n, test.expectations)
+		if len(mismatches) != len(test.mismatches) {
+			t.Errorf("%s: expected mismatches of %v, got %v", test.name, test.mismatches, mismatches)
+			continue
+		}
+		for i, key := range test.mismatches {
+			if !strings.HasPrefix(mismatches[i], key+":") {
+				t.Errorf("%s: expected a mismatch of %s, got %s", test.name, key, mismatches[i])
+			}
+		}
+	}
+}
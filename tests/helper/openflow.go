@@ -0,0 +1,231 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	g "github.com/skydive-project/skydive/gremlin"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// OFDefaultPriority is the priority of the rules added without priority
+const OFDefaultPriority = 32768
+
+// ofVersions are the OpenFlow versions tried, in order, to talk to a bridge
+var ofVersions = []string{"OpenFlow13", "OpenFlow10"}
+
+// ofBridgeVersions caches the OpenFlow version negotiated with each bridge
+var ofBridgeVersions = struct {
+	sync.Mutex
+	versions map[string]string
+}{versions: make(map[string]string)}
+
+// OFRule is an OpenFlow rule of a bridge. Packets and Bytes are only set
+// by DumpOFRules.
+type OFRule struct {
+	Cookie   uint64
+	Table    int
+	Priority int
+	// Match is the match part of the rule, like "ip,nw_dst=10.0.0.1"
+	Match   string
+	Actions string
+	Packets int64
+	Bytes   int64
+}
+
+// filter returns the table, priority and match of the rule
+func (r *OFRule) filter() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "table=%d", r.Table)
+	if r.Priority != 0 {
+		fmt.Fprintf(&b, ",priority=%d", r.Priority)
+	}
+	if r.Match != "" {
+		fmt.Fprintf(&b, ",%s", r.Match)
+	}
+	return b.String()
+}
+
+// String returns the rule in the ovs-ofctl flow syntax
+func (r *OFRule) String() string {
+	return fmt.Sprintf("cookie=0x%x,%s,actions=%s", r.Cookie, r.filter(), r.Actions)
+}
+
+// ofctl runs an ovs-ofctl command on a bridge, using the most recent
+// OpenFlow version supported by the bridge
+func ofctl(command, bridge string, args ...string) (string, error) {
+	ofBridgeVersions.Lock()
+	version, known := ofBridgeVersions.versions[bridge]
+	ofBridgeVersions.Unlock()
+
+	versions := ofVersions
+	if known {
+		versions = []string{version}
+	}
+
+	var err error
+	for _, version := range versions {
+		var output string
+		output, err = runCommand(append([]string{"ovs-ofctl", "-O", version, command, bridge}, args...)...)
+		if err == nil {
+			ofBridgeVersions.Lock()
+			ofBridgeVersions.versions[bridge] = version
+			ofBridgeVersions.Unlock()
+			return output, nil
+		}
+		if !strings.Contains(err.Error(), "version negotiation failed") {
+			return "", err
+		}
+	}
+	return "", err
+}
+
+// AddOFRule adds a rule to a bridge
+func AddOFRule(bridge string, rule OFRule) error {
+	_, err := ofctl("add-flow", bridge, rule.String())
+	return err
+}
+
+// DelOFRule deletes the rule of a bridge having exactly the table, the
+// priority and the match of the given rule
+func DelOFRule(bridge string, rule OFRule) error {
+	_, err := ofctl("del-flows", bridge, "--strict", rule.filter())
+	return err
+}
+
+// DelOFRulesByCookie deletes all the rules of a bridge having the cookie
+func DelOFRulesByCookie(bridge string, cookie uint64) error {
+	_, err := ofctl("del-flows", bridge, fmt.Sprintf("cookie=0x%x/-1", cookie))
+	return err
+}
+
+// DumpOFRules returns the rules of a bridge
+func DumpOFRules(bridge string) ([]OFRule, error) {
+	output, err := ofctl("dump-flows", bridge)
+	if err != nil {
+		return nil, err
+	}
+	return parseOFRules(output)
+}
+
+// splitOFFields splits the fields of a dumped rule on the separator,
+// ignoring the separators between parentheses like in learn() actions
+func splitOFFields(line, sep string) (fields []string) {
+	depth, start := 0, 0
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '(':
+			depth++
+		case line[i] == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(line[i:], sep):
+			fields = append(fields, line[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(fields, line[start:])
+}
+
+// parseOFRule parses a rule of the ovs-ofctl dump-flows output, like
+// " cookie=0x2, duration=3.5s, table=0, n_packets=0, n_bytes=0, priority=10,ip actions=drop"
+func parseOFRule(line string) (*OFRule, error) {
+	fields := splitOFFields(strings.TrimSpace(line), ", ")
+	last := splitOFFields(fields[len(fields)-1], " actions=")
+	if len(last) != 2 {
+		return nil, fmt.Errorf("no actions in rule '%s'", line)
+	}
+
+	rule := &OFRule{Priority: OFDefaultPriority, Actions: last[1]}
+
+	var err error
+	for _, field := range fields[:len(fields)-1] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "cookie":
+			rule.Cookie, err = strconv.ParseUint(kv[1], 0, 64)
+		case "table":
+			rule.Table, err = strconv.Atoi(kv[1])
+		case "n_packets":
+			rule.Packets, err = strconv.ParseInt(kv[1], 10, 64)
+		case "n_bytes":
+			rule.Bytes, err = strconv.ParseInt(kv[1], 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid field '%s' in rule '%s': %s", field, line, err)
+		}
+	}
+
+	// the match may start with the priority, omitted if it is the default one
+	var match []string
+	if last[0] != "" {
+		for _, field := range splitOFFields(last[0], ",") {
+			if strings.HasPrefix(field, "priority=") {
+				if rule.Priority, err = strconv.Atoi(strings.TrimPrefix(field, "priority=")); err != nil {
+					return nil, fmt.Errorf("invalid priority in rule '%s': %s", line, err)
+				}
+				continue
+			}
+			match = append(match, field)
+		}
+	}
+	rule.Match = strings.Join(match, ",")
+
+	return rule, nil
+}
+
+// parseOFRules parses the output of ovs-ofctl dump-flows, the rules being
+// the lines starting with a space
+func parseOFRules(output string) ([]OFRule, error) {
+	var rules []OFRule
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, " ") {
+			continue
+		}
+
+		rule, err := parseOFRule(line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// WaitForOFRuleNode waits for the ovs probe to report the rule having the
+// cookie and returns its node
+func WaitForOFRuleNode(t *testing.T, cookie uint64, timeout time.Duration) *graph.Node {
+	query := g.G.V().Has("Type", "ofrule", "cookie", fmt.Sprintf("0x%x", cookie))
+	return WaitForNodes(t, query.String(), 1, Exactly, timeout)[0]
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOFRules(t *testing.T) {
+	output := `OFPST_FLOW reply (OF1.3) (xid=0x2):
+ cookie=0x0, duration=10.116s, table=0, n_packets=12, n_bytes=840, priority=1 actions=resubmit(,1)
+ cookie=0x2a, duration=3.5s, table=1, n_packets=0, n_bytes=0, priority=10,ip,nw_dst=10.0.0.1 actions=drop
+ cookie=0x3, duration=1.2s, table=1, n_packets=0, n_bytes=0, in_port=1 actions=learn(table=2,NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[]),output:2
+`
+
+	rules, err := parseOFRules(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []OFRule{
+		{Cookie: 0, Table: 0, Priority: 1, Actions: "resubmit(,1)", Packets: 12, Bytes: 840},
+		{Cookie: 0x2a, Table: 1, Priority: 10, Match: "ip,nw_dst=10.0.0.1", Actions: "drop"},
+		{Cookie: 3, Table: 1, Priority: OFDefaultPriority, Match: "in_port=1", Actions: "learn(table=2,NXM_OF_ETH_DST[]=NXM_OF_ETH_SRC[],output:NXM_OF_IN_PORT[]),output:2"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %+v, got %+v", expected, rules)
+	}
+
+	if _, err := parseOFRules(" cookie=0x0, duration=1s, table=0\n"); err == nil {
+		t.Error("expected an error for a rule without actions")
+	}
+}
+
+func TestOFRuleString(t *testing.T) {
+	rule := OFRule{Cookie: 0x2a, Table: 1, Priority: 10, Match: "ip,nw_dst=10.0.0.1", Actions: "drop"}
+	if s := rule.String(); s != "cookie=0x2a,table=1,priority=10,ip,nw_dst=10.0.0.1,actions=drop" {
+		t.Errorf("unexpected rule %s", s)
+	}
+
+	rule = OFRule{Actions: "normal"}
+	if s := rule.String(); s != "cookie=0x0,table=0,actions=normal" {
+		t.Errorf("unexpected rule %s", s)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+
+	"github.com/ghodss/yaml"
+)
+
+// configOverlaysParam is the parameter holding the overlays added by WithConfig
+const configOverlaysParam = "ConfigOverlays"
+
+// WithConfig returns the parameters adding an overlay to the configuration
+// rendered by InitConfig. The overlay is a YAML string or a map, merged over
+// the configuration: scalars and lists are replaced, maps are merged.
+func WithConfig(overlay interface{}) HelperParams {
+	return HelperParams{configOverlaysParam: []interface{}{overlay}}
+}
+
+// mergeParams merges the parameters in order, the overlays being appended
+func mergeParams(params []HelperParams) HelperParams {
+	merged := make(HelperParams)
+	for _, p := range params {
+		for k, v := range p {
+			if k == configOverlaysParam {
+				if overlays, ok := merged[k].([]interface{}); ok {
+					v = append(overlays, v.([]interface{})...)
+				}
+			}
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// decodeOverlay returns the overlay as a map whose nested maps are all of
+// type map[string]interface{}
+func decodeOverlay(overlay interface{}) (map[string]interface{}, error) {
+	var data []byte
+	switch o := overlay.(type) {
+	case string:
+		data = []byte(o)
+	case []byte:
+		data = o
+	default:
+		var err error
+		if data, err = yaml.Marshal(o); err != nil {
+			return nil, fmt.Errorf("Invalid config overlay %v: %s", overlay, err)
+		}
+	}
+
+	m := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("Invalid config overlay: %s\n%s", err, numberLines(string(data)))
+	}
+	return m, nil
+}
+
+// mergeConfig merges the overlay into the base configuration
+func mergeConfig(base, overlay map[string]interface{}) {
+	for k, v := range overlay {
+		if vm, ok := v.(map[string]interface{}); ok {
+			if bm, ok := base[k].(map[string]interface{}); ok {
+				mergeConfig(bm, vm)
+				continue
+			}
+		}
+		base[k] = v
+	}
+}
+
+// applyConfigOverlays merges the overlays over the rendered configuration
+func applyConfigOverlays(conf []byte, overlays []interface{}) ([]byte, error) {
+	base := make(map[string]interface{})
+	if err := yaml.Unmarshal(conf, &base); err != nil {
+		return nil, err
+	}
+
+	for _, overlay := range overlays {
+		m, err := decodeOverlay(overlay)
+		if err != nil {
+			return nil, err
+		}
+		mergeConfig(base, m)
+	}
+
+	return yaml.Marshal(base)
+}
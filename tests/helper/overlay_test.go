@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+const baseTestConfig = `
+analyzer:
+  listen: 127.0.0.1:8082
+  topology:
+    backend: memory
+    probes:
+      - k8s
+flow:
+  expire: 600
+  update: 10
+`
+
+func TestApplyConfigOverlays(t *testing.T) {
+	overlays := []interface{}{
+		`
+analyzer:
+  topology:
+    probes:
+      - istio
+flow:
+  update: 5
+`,
+		map[string]interface{}{
+			"analyzer": map[string]interface{}{
+				"topology": map[string]interface{}{"backend": "elasticsearch"},
+			},
+			"logging": map[string]interface{}{"level": "DEBUG"},
+		},
+	}
+
+	data, err := applyConfigOverlays([]byte(baseTestConfig), overlays)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual, expected map[string]interface{}
+	if err := yaml.Unmarshal(data, &actual); err != nil {
+		t.Fatal(err)
+	}
+	yaml.Unmarshal([]byte(`
+analyzer:
+  listen: 127.0.0.1:8082
+  topology:
+    backend: elasticsearch
+    probes:
+      - istio
+flow:
+  expire: 600
+  update: 5
+logging:
+  level: DEBUG
+`), &expected)
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected config %v, got %v", expected, actual)
+	}
+}
+
+func TestApplyConfigOverlaysReplaceMap(t *testing.T) {
+	// a scalar replaces a map and the other way around
+	data, err := applyConfigOverlays([]byte(baseTestConfig), []interface{}{"flow: disabled\nanalyzer:\n  listen:\n    addr: 0.0.0.0\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual map[string]interface{}
+	if err := yaml.Unmarshal(data, &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual["flow"] != "disabled" {
+		t.Errorf("expected flow to be replaced, got %v", actual["flow"])
+	}
+	listen := actual["analyzer"].(map[string]interface{})["listen"]
+	if !reflect.DeepEqual(listen, map[string]interface{}{"addr": "0.0.0.0"}) {
+		t.Errorf("expected listen to be replaced, got %v", listen)
+	}
+}
+
+func TestInvalidConfigOverlay(t *testing.T) {
+	if _, err := applyConfigOverlays([]byte(baseTestConfig), []interface{}{"analyzer: [unterminated"}); err == nil {
+		t.Error("expected an error for an invalid overlay")
+	}
+}
+
+func TestMergeParams(t *testing.T) {
+	params := mergeParams([]HelperParams{
+		{"HostID": "host1"},
+		WithConfig("flow:\n  update: 5\n"),
+		{"HostID": "host2"},
+		WithConfig("flow:\n  expire: 10\n"),
+	})
+
+	if params["HostID"] != "host2" {
+		t.Errorf("expected the last HostID, got %v", params["HostID"])
+	}
+	if overlays := params[configOverlaysParam].([]interface{}); len(overlays) != 2 {
+		t.Errorf("expected 2 overlays, got %v", overlays)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+// PCAPFlowOptions describes how the flows expected from a pcap file are
+// computed by PCAPFlowExpectations
+type PCAPFlowOptions struct {
+	// ByteTolerance is the relative difference allowed on the bytes
+	// counted by skydive, like 0.05
+	ByteTolerance float64
+	// IgnoreNonIP ignores the packets without IP layer, like ARP or LLDP
+	IgnoreNonIP bool
+	// L3KeyMode tells that the flows are not distinguished by their
+	// ethernet addresses and are oriented by their IP addresses, like
+	// with the L3 key mode of the agent
+	L3KeyMode bool
+}
+
+// pcapFlowKey identifies a conversation regardless of its direction
+type pcapFlowKey struct {
+	link, network, transport, icmp string
+}
+
+// pcapFlow counts the packets of a conversation, A being the source of its
+// first packet
+type pcapFlow struct {
+	expectation FlowExpectation
+	linkA       string
+}
+
+// unordered returns the pair of endpoints in a canonical order
+func unordered(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// icmpKey returns the ICMP type, code and identifier distinguishing the
+// ICMP flows, requests and replies belonging to the same flow
+func icmpKey(packet gopacket.Packet) string {
+	if l, ok := packet.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4); ok {
+		typ := l.TypeCode.Type()
+		if typ == layers.ICMPv4TypeEchoReply {
+			typ = layers.ICMPv4TypeEchoRequest
+		}
+		return fmt.Sprintf("icmp4/%d/%d/%d", typ, l.TypeCode.Code(), l.Id)
+	}
+
+	if l, ok := packet.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6); ok {
+		typ, id := l.TypeCode.Type(), 0
+		if typ == layers.ICMPv6TypeEchoRequest || typ == layers.ICMPv6TypeEchoReply {
+			typ = layers.ICMPv6TypeEchoRequest
+			if len(l.TypeBytes) >= 2 {
+				id = int(l.TypeBytes[0])<<8 | int(l.TypeBytes[1])
+			}
+		}
+		return fmt.Sprintf("icmp6/%d/%d/%d", typ, l.TypeCode.Code(), id)
+	}
+
+	return ""
+}
+
+// pcapTransport returns the transport protocol and the ports of the packet,
+// the layers being looked up in the order used by the agent
+func pcapTransport(packet gopacket.Packet) (flow.FlowProtocol, int64, int64, bool) {
+	if l, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP); ok {
+		return flow.FlowProtocol_UDP, int64(l.SrcPort), int64(l.DstPort), true
+	}
+	if l, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP); ok {
+		return flow.FlowProtocol_TCP, int64(l.SrcPort), int64(l.DstPort), true
+	}
+	if l, ok := packet.Layer(layers.LayerTypeSCTP).(*layers.SCTP); ok {
+		return flow.FlowProtocol_SCTP, int64(l.SrcPort), int64(l.DstPort), true
+	}
+	return flow.FlowProtocol_ETHERNET, 0, 0, false
+}
+
+// pcapFlowCounter computes the flows of the packets of a pcap file
+type pcapFlowCounter struct {
+	opts  PCAPFlowOptions
+	flows map[pcapFlowKey]*pcapFlow
+	order []pcapFlowKey
+}
+
+func newPCAPFlowCounter(opts PCAPFlowOptions) *pcapFlowCounter {
+	return &pcapFlowCounter{opts: opts, flows: make(map[pcapFlowKey]*pcapFlow)}
+}
+
+// add counts a packet, the bytes being the captured length like the agent
+func (c *pcapFlowCounter) add(packet gopacket.Packet, length int64) {
+	var srcMAC, dstMAC string
+	if l, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		srcMAC, dstMAC = l.SrcMAC.String(), l.DstMAC.String()
+	}
+
+	e := FlowExpectation{Directional: true, ByteTolerance: c.opts.ByteTolerance}
+	if l, ok := packet.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		e.Proto, e.A, e.B = flow.FlowProtocol_IPV4, l.SrcIP.String(), l.DstIP.String()
+	} else if l, ok := packet.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		e.Proto, e.A, e.B = flow.FlowProtocol_IPV6, l.SrcIP.String(), l.DstIP.String()
+	} else if c.opts.IgnoreNonIP || srcMAC == "" {
+		return
+	} else {
+		e.Proto, e.A, e.B = flow.FlowProtocol_ETHERNET, srcMAC, dstMAC
+	}
+
+	var key pcapFlowKey
+	if e.Proto != flow.FlowProtocol_ETHERNET {
+		key.network = unordered(e.A, e.B)
+		if transport, portA, portB, ok := pcapTransport(packet); ok {
+			e.Transport, e.PortA, e.PortB = transport, portA, portB
+			key.transport = fmt.Sprintf("%s/%s", transport, unordered(fmt.Sprint(portA), fmt.Sprint(portB)))
+		}
+		key.icmp = icmpKey(packet)
+	}
+	if srcMAC != "" && (!c.opts.L3KeyMode || e.Proto == flow.FlowProtocol_ETHERNET) {
+		key.link = unordered(srcMAC, dstMAC)
+	}
+
+	f, found := c.flows[key]
+	if !found {
+		f = &pcapFlow{expectation: e, linkA: srcMAC}
+		c.flows[key] = f
+		c.order = append(c.order, key)
+	}
+
+	// the agent orients the flows by their ethernet addresses, unless
+	// they are keyed by their IP addresses
+	forward := e.A == f.expectation.A && e.PortA == f.expectation.PortA
+	if srcMAC != "" && !c.opts.L3KeyMode {
+		forward = srcMAC == f.linkA
+	}
+
+	if forward {
+		f.expectation.ABPackets++
+		f.expectation.ABBytes += length
+	} else {
+		f.expectation.BAPackets++
+		f.expectation.BABytes += length
+	}
+}
+
+// expectations returns the expectations of the flows, in the order of
+// their first packet
+func (c *pcapFlowCounter) expectations() (expectations []FlowExpectation) {
+	for _, key := range c.order {
+		e := c.flows[key].expectation
+		e.MinPackets = e.ABPackets + e.BAPackets
+		e.MaxPackets = e.MinPackets
+		expectations = append(expectations, e)
+	}
+	return
+}
+
+// PCAPFlowExpectations returns the expectations of the flows of a pcap
+// file, computed from its packets: one flow per conversation with the
+// packets and bytes of each direction
+func PCAPFlowExpectations(filename string, opts PCAPFlowOptions) ([]FlowExpectation, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open file %s: %s", filename, err)
+	}
+	defer file.Close()
+
+	reader, err := pcapgo.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read pcap file %s: %s", filename, err)
+	}
+
+	counter := newPCAPFlowCounter(opts)
+	for {
+		data, ci, err := reader.ReadPacketData()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read packet from %s: %s", filename, err)
+		}
+
+		packet := gopacket.NewPacket(data, reader.LinkType(), gopacket.Default)
+		counter.add(packet, int64(ci.CaptureLength))
+	}
+
+	return counter.expectations(), nil
+}
+
+// ExpectPCAPFlows fails the test if the flows are not the ones of the pcap
+// file, like with ExpectFlows
+func ExpectPCAPFlows(t *testing.T, flows []*flow.Flow, filename string, opts PCAPFlowOptions) {
+	expectations, err := PCAPFlowExpectations(filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ExpectFlows(t, flows, expectations)
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+// writePCAP writes the frames to a pcap file of the directory
+func writePCAP(t *testing.T, dir string, frames [][]byte) string {
+	filename := filepath.Join(dir, "test.pcap")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := pcapgo.NewWriter(file)
+	writer.WriteFileHeader(65535, layers.LinkTypeEthernet)
+	for _, frame := range frames {
+		ci := gopacket.CaptureInfo{CaptureLength: len(frame), Length: len(frame)}
+		if err := writer.WritePacket(ci, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filename
+}
+
+// vlanFrame returns an UDP datagram from A to B tagged with the VLAN
+func vlanFrame(t *testing.T, vlan uint16) []byte {
+	buffer := gopacket.NewSerializeBuffer()
+	ip := &layers.IPv4{Version: 4, SrcIP: net.ParseIP("10.0.0.2"), DstIP: net.ParseIP("10.0.0.1"), Protocol: layers.IPProtocolUDP, TTL: 64}
+	udp := &layers.UDP{SrcPort: 53, DstPort: 4000}
+	udp.SetNetworkLayerForChecksum(ip)
+	err := gopacket.SerializeLayers(buffer, trafficSerializeOptions,
+		&layers.Ethernet{SrcMAC: trafficMACB, DstMAC: trafficMACA, EthernetType: layers.EthernetTypeDot1Q},
+		&layers.Dot1Q{VLANIdentifier: vlan, Type: layers.EthernetTypeIPv4},
+		ip, udp, gopacket.Payload(make([]byte, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestPCAPFlowExpectations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-pcapflows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var frames [][]byte
+	for _, addrs := range [][]string{{"10.0.0.1:4000", "10.0.0.2:53"}, {"[fd00::1]:4000", "[fd00::2]:53"}} {
+		e, err := parseTrafficEndpoints(addrs[0], addrs[1], true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		udp, err := forgeUDPFlow(e, 3, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		icmp, err := forgeICMPEcho(e, 2, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, append(udp, icmp...)...)
+	}
+	frames = append(frames, vlanFrame(t, 8))
+
+	expectations, err := PCAPFlowExpectations(writePCAP(t, dir, frames), PCAPFlowOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expectations) != 4 {
+		t.Fatalf("Expected 4 flows, got %d: %v", len(expectations), expectations)
+	}
+
+	udp := expectations[0]
+	if udp.Transport != flow.FlowProtocol_UDP || udp.A != "10.0.0.1" || udp.PortA != 4000 || udp.PortB != 53 {
+		t.Errorf("Unexpected UDP flow %s", udp.String())
+	}
+	// the tagged reply belongs to the UDP flow
+	if udp.ABPackets != 3 || udp.BAPackets != 1 || udp.ABBytes != 3*int64(len(frames[0])) || udp.BABytes != int64(len(frames[len(frames)-1])) {
+		t.Errorf("Unexpected UDP counts %s", udp.String())
+	}
+
+	icmp6 := expectations[3]
+	if icmp6.Proto != flow.FlowProtocol_IPV6 || icmp6.ABPackets != 2 || icmp6.BAPackets != 2 || icmp6.MinPackets != 4 {
+		t.Errorf("Unexpected ICMPv6 flow %s", icmp6.String())
+	}
+}
+
+func TestDirectionalExpectation(t *testing.T) {
+	e := FlowExpectation{
+		Proto: flow.FlowProtocol_IPV4, A: "10.0.0.1", B: "10.0.0.2",
+		Directional: true, ABPackets: 3, ABBytes: 300, BAPackets: 1, BABytes: 100, ByteTolerance: 0.1,
+	}
+
+	f := &flow.Flow{
+		Network: &flow.FlowLayer{Protocol: flow.FlowProtocol_IPV4, A: "10.0.0.2", B: "10.0.0.1"},
+		Metric:  &flow.FlowMetric{ABPackets: 1, ABBytes: 105, BAPackets: 3, BABytes: 290},
+	}
+	if !e.Match(f) {
+		t.Errorf("Expected the reversed flow %s to match %s", describeFlow(f), e.String())
+	}
+
+	f.Metric.ABBytes = 120
+	if e.Match(f) {
+		t.Errorf("Expected the flow %s not to match %s", describeFlow(f), e.String())
+	}
+}
+
+func TestPCAPFlowExpectationsTraces(t *testing.T) {
+	for _, trace := range []string{"simple-tcpv4.pcap", "simple-tcpv6.pcap", "icmpv4-4vlanQinQ-id-8-10-20-30.pcap"} {
+		filename := filepath.Join("..", "..", "flow", "pcaptraces", trace)
+		expectations, err := PCAPFlowExpectations(filename, PCAPFlowOptions{IgnoreNonIP: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expectations) == 0 {
+			t.Errorf("Expected flows in %s", trace)
+		}
+		for _, e := range expectations {
+			if e.Proto == flow.FlowProtocol_ETHERNET || e.MinPackets == 0 {
+				t.Errorf("Unexpected flow %s in %s", e.String(), trace)
+			}
+		}
+	}
+}
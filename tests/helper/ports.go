@@ -0,0 +1,213 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/logging"
+)
+
+const (
+	defaultAnalyzerListen  = "0.0.0.0:64500"
+	defaultEtcdPort        = 12379
+	portAllocationAttempts = 10
+	bindRetries            = 3
+)
+
+// portRange is a range of consecutive ports
+type portRange struct {
+	first, count int
+}
+
+// portAllocator hands out free ports, reserved by keeping a listener bound
+// on them until the services are about to start
+type portAllocator struct {
+	sync.Mutex
+	ports     map[string]portRange
+	listeners []net.Listener
+}
+
+var ports = &portAllocator{ports: make(map[string]portRange)}
+
+// reserve binds a listener on a free port, on the given port if not zero
+func (a *portAllocator) reserve(port int) (int, error) {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return 0, err
+	}
+	a.listeners = append(a.listeners, l)
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// allocate returns the first of count consecutive free ports allocated for
+// the key, the same ports being returned for the same key until reset
+func (a *portAllocator) allocate(key string, count int) (int, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if r, ok := a.ports[key]; ok {
+		return r.first, nil
+	}
+
+	// a released port may be handed out again by the system before the
+	// service it was allocated for binds it
+	allocated := make(map[int]bool)
+	for _, r := range a.ports {
+		for i := 0; i < r.count; i++ {
+			allocated[r.first+i] = true
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt < portAllocationAttempts; attempt++ {
+		var port int
+		if port, err = a.reserve(0); err != nil {
+			return 0, err
+		}
+
+		i := 0
+		for ; i < count; i++ {
+			if allocated[port+i] {
+				err = fmt.Errorf("port %d already allocated", port+i)
+				break
+			}
+			if i > 0 {
+				if _, err = a.reserve(port + i); err != nil {
+					break
+				}
+			}
+		}
+		if i == count {
+			logging.GetLogger().Debugf("Allocated port %d for %s", port, key)
+			a.ports[key] = portRange{first: port, count: count}
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("Failed to allocate %d consecutive ports for %s: %s", count, key, err)
+}
+
+// release closes the listeners so that the services can bind the ports,
+// the allocated ports being kept
+func (a *portAllocator) release() {
+	a.Lock()
+	defer a.Unlock()
+
+	for _, l := range a.listeners {
+		l.Close()
+	}
+	a.listeners = nil
+}
+
+// reset releases and forgets the allocated ports
+func (a *portAllocator) reset() {
+	a.release()
+
+	a.Lock()
+	a.ports = make(map[string]portRange)
+	a.Unlock()
+}
+
+// ReleasePorts releases the ports allocated for the services of the test
+// process, it has to be called just before the services start. The ports of
+// the standalone daemons are released when they are started.
+func ReleasePorts() {
+	ports.release()
+}
+
+// isBindError returns whether the error is due to a port already in use
+func isBindError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "address already in use")
+}
+
+// RetryOnBindError calls fn, allocating new ports and calling it again if it
+// failed because a port was taken between its allocation and its use
+func RetryOnBindError(fn func() error) (err error) {
+	for i := 0; i < bindRetries; i++ {
+		if err = fn(); !isBindError(err) {
+			return err
+		}
+		logging.GetLogger().Warningf("Port already in use, allocating new ports: %s", err)
+		ports.reset()
+	}
+	return err
+}
+
+// analyzerListenAddress returns the address given by -analyzer.listen. If
+// not given, the ports are allocated in standalone mode, the analyzer being
+// expected on the default port otherwise.
+func analyzerListenAddress() (common.ServiceAddress, error) {
+	switch {
+	case AnalyzerListen != "":
+		return common.ServiceAddressFromString(AnalyzerListen)
+	case Standalone:
+		return common.ServiceAddress{Addr: "0.0.0.0", Port: 0}, nil
+	default:
+		return common.ServiceAddressFromString(defaultAnalyzerListen)
+	}
+}
+
+// analyzerPort returns the port of the analyzer of the given index, the
+// analyzers using consecutive ports after the one of -analyzer.listen or
+// allocated ports if its port is 0
+func analyzerPort(index int) (int, error) {
+	sa, err := analyzerListenAddress()
+	if err != nil {
+		return 0, err
+	}
+	if sa.Port != 0 {
+		return sa.Port + index, nil
+	}
+	return ports.allocate(fmt.Sprintf("analyzer%d", index), 1)
+}
+
+// agentPort returns the port of the agent of the given index, the agents
+// using consecutive ports before the one of -analyzer.listen or allocated
+// ports if its port is 0
+func agentPort(index int) (int, error) {
+	sa, err := analyzerListenAddress()
+	if err != nil {
+		return 0, err
+	}
+	if sa.Port != 0 {
+		return sa.Port - 1 - index, nil
+	}
+	return ports.allocate(fmt.Sprintf("agent%d", index), 1)
+}
+
+// embeddedEtcdPort returns the client port of the etcd embedded by the
+// analyzer, the peer port being the next one
+func embeddedEtcdPort() (int, error) {
+	sa, err := analyzerListenAddress()
+	if err != nil {
+		return 0, err
+	}
+	if sa.Port != 0 {
+		return defaultEtcdPort, nil
+	}
+	return ports.allocate("etcd", 2)
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestPortAllocator(t *testing.T) {
+	a := &portAllocator{ports: make(map[string]portRange)}
+	defer a.reset()
+
+	etcd, err := a.allocate("etcd", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port, _ := a.allocate("etcd", 2); port != etcd {
+		t.Errorf("Expected port %d to be allocated again for etcd, got %d", etcd, port)
+	}
+
+	agent, err := a.allocate("agent0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent == etcd || agent == etcd+1 {
+		t.Errorf("Port %d allocated for both etcd and the agent", agent)
+	}
+
+	// the ports are reserved until released
+	if l, err := net.Listen("tcp", ":"+strconv.Itoa(etcd+1)); err == nil {
+		l.Close()
+		t.Errorf("Expected port %d to be reserved", etcd+1)
+	}
+
+	a.release()
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(etcd+1))
+	if err != nil {
+		t.Fatalf("Expected port %d to be released: %s", etcd+1, err)
+	}
+	l.Close()
+
+	if port, _ := a.allocate("etcd", 2); port != etcd {
+		t.Errorf("Expected released port %d to stay allocated, got %d", etcd, port)
+	}
+}
+
+func TestRetryOnBindError(t *testing.T) {
+	calls := 0
+	err := RetryOnBindError(func() error {
+		if calls++; calls == 1 {
+			return errors.New("listen tcp :64500: bind: address already in use")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Expected a successful second call, got %d calls: %v", calls, err)
+	}
+
+	calls = 0
+	err = RetryOnBindError(func() error {
+		calls++
+		return errors.New("permission denied")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected other errors not to be retried, got %d calls: %v", calls, err)
+	}
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+const (
+	// provisionPrefix is the prefix of the backend flags requesting the
+	// backend to be started in a container
+	provisionPrefix         = "docker:"
+	provisionTimeout        = 5 * time.Minute
+	provisionReadyTimeout   = 2 * time.Minute
+	provisionContainerName  = "skydive-test-"
+	defaultOrientDBPassword = "root"
+)
+
+// backendImage describes the container of a storage backend
+type backendImage struct {
+	backend string
+	image   string
+	port    nat.Port
+	env     []string
+}
+
+// elasticsearchEnv is the environment of the Elasticsearch containers
+var elasticsearchEnv = []string{"discovery.type=single-node", "ES_JAVA_OPTS=-Xms512m -Xmx512m"}
+
+// backendImages are the pinned images of the backends, indexed by the name
+// given after the docker: prefix. The unversioned names are the images
+// provisioned by -provision-backends.
+var backendImages = map[string]backendImage{
+	"elasticsearch":  {"elasticsearch", "elasticsearch:5.6.16", "9200/tcp", elasticsearchEnv},
+	"elasticsearch5": {"elasticsearch", "elasticsearch:5.6.16", "9200/tcp", elasticsearchEnv},
+	"elasticsearch6": {"elasticsearch", "docker.elastic.co/elasticsearch/elasticsearch-oss:6.8.23", "9200/tcp", elasticsearchEnv},
+	"elasticsearch7": {"elasticsearch", "docker.elastic.co/elasticsearch/elasticsearch-oss:7.10.2", "9200/tcp", elasticsearchEnv},
+	"orientdb":       {"orientdb", "orientdb:2.2.37", "2480/tcp", nil},
+}
+
+// backendContainer is a container started by ProvisionBackends
+type backendContainer struct {
+	name string
+	id   string
+}
+
+// provisionedBackends are the containers started by ProvisionBackends
+var provisionedBackends []*backendContainer
+
+// orientDBRootPassword returns the password of the OrientDB root user, given
+// by the ORIENTDB_ROOT_PASSWORD environment variable
+func orientDBRootPassword() string {
+	if password := os.Getenv("ORIENTDB_ROOT_PASSWORD"); password != "" {
+		return password
+	}
+	return defaultOrientDBPassword
+}
+
+// resolveBackend returns the backend named by the value of a backend flag
+// and the name of its image if it has to be provisioned, because of the
+// docker: prefix or of -provision-backends
+func resolveBackend(value string, provisionAll bool) (string, string, error) {
+	name := strings.TrimPrefix(value, provisionPrefix)
+	if name == value && !provisionAll {
+		return value, "", nil
+	}
+
+	image, ok := backendImages[name]
+	if !ok {
+		if name == value {
+			// the backends without container, like memory, are kept as is
+			return value, "", nil
+		}
+		return "", "", fmt.Errorf("Unknown backend %s, expected one of elasticsearch, elasticsearch5, elasticsearch6, elasticsearch7 or orientdb", value)
+	}
+	return image.backend, name, nil
+}
+
+// startBackendContainer starts the container of a backend, its port being
+// published on a random port of the loopback address, and returns the
+// address of this port
+func startBackendContainer(ctx context.Context, cli *client.Client, name string, image backendImage) (string, error) {
+	containerName := provisionContainerName + name
+
+	// remove the container left by an interrupted run
+	cli.ContainerRemove(ctx, containerName, types.ContainerRemoveOptions{Force: true})
+
+	containerConfig := &container.Config{
+		Image:        image.image,
+		Env:          image.env,
+		ExposedPorts: nat.PortSet{image.port: struct{}{}},
+	}
+	hostConfig := &container.HostConfig{
+		PortBindings: nat.PortMap{image.port: []nat.PortBinding{{HostIP: "127.0.0.1"}}},
+	}
+
+	resp, err := cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, containerName)
+	if client.IsErrImageNotFound(err) {
+		logging.GetLogger().Infof("Pulling image %s", image.image)
+		if err = pullImage(ctx, cli, image.image); err != nil {
+			return "", fmt.Errorf("Failed to pull image %s: %s", image.image, err)
+		}
+		resp, err = cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, containerName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to create container %s: %s", containerName, err)
+	}
+	provisionedBackends = append(provisionedBackends, &backendContainer{name: containerName, id: resp.ID})
+
+	if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		return "", fmt.Errorf("Failed to start container %s: %s", containerName, err)
+	}
+
+	info, err := cli.ContainerInspect(ctx, resp.ID)
+	if err != nil {
+		return "", err
+	}
+	if info.NetworkSettings == nil || len(info.NetworkSettings.Ports[image.port]) == 0 {
+		return "", fmt.Errorf("Port %s of container %s is not published", image.port, containerName)
+	}
+
+	binding := info.NetworkSettings.Ports[image.port][0]
+	return fmt.Sprintf("127.0.0.1:%s", binding.HostPort), nil
+}
+
+// ProvisionBackends starts the containers of the storage backends requested
+// with the docker: prefix, or of all the backends used if -provision-backends
+// is given, and waits for them to be ready. The backend flags are replaced by
+// the names of the backends and their endpoints are set in the configuration.
+// The containers are removed by StopProvisionedBackends.
+func ProvisionBackends() error {
+	flowBackend, flowImage, err := resolveBackend(FlowBackend, provisionBackends)
+	if err != nil {
+		return err
+	}
+	topologyBackend, topologyImage, err := resolveBackend(TopologyBackend, provisionBackends)
+	if err != nil {
+		return err
+	}
+
+	images := make(map[string]string)
+	for _, name := range []string{flowImage, topologyImage} {
+		if name == "" {
+			continue
+		}
+		backend := backendImages[name].backend
+		if previous, ok := images[backend]; ok && backendImages[previous].image != backendImages[name].image {
+			return fmt.Errorf("Flows and topology can't use different %s images, %s and %s", backend, previous, name)
+		}
+		images[backend] = name
+	}
+	FlowBackend, TopologyBackend = flowBackend, topologyBackend
+
+	if len(images) == 0 {
+		return nil
+	}
+
+	cli, err := newDockerClient()
+	if err != nil {
+		return fmt.Errorf("Failed to create Docker client: %s", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+	defer cancel()
+
+	for backend, name := range images {
+		image := backendImages[name]
+		if backend == "orientdb" {
+			image.env = []string{"ORIENTDB_ROOT_PASSWORD=" + orientDBRootPassword()}
+		}
+
+		logging.GetLogger().Infof("Starting %s backend with image %s", backend, image.image)
+		addr, err := startBackendContainer(ctx, cli, name, image)
+		if err != nil {
+			return err
+		}
+
+		switch backend {
+		case "elasticsearch":
+			elasticsearchHost = addr
+			err = waitForElasticsearch(elasticsearchHost, provisionReadyTimeout)
+		case "orientdb":
+			orientDBAddr = "http://" + addr
+			err = waitForOrientDB(orientDBAddr, provisionReadyTimeout)
+		}
+		if err != nil {
+			return fmt.Errorf("%s container %s not ready: %s", backend, image.image, err)
+		}
+	}
+
+	return nil
+}
+
+// StopProvisionedBackends removes the containers started by
+// ProvisionBackends
+func StopProvisionedBackends() {
+	if len(provisionedBackends) == 0 {
+		return
+	}
+
+	cli, err := newDockerClient()
+	if err != nil {
+		logging.GetLogger().Errorf("Failed to create Docker client: %s", err)
+		return
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerTimeout)
+	defer cancel()
+
+	for _, c := range provisionedBackends {
+		if err := cli.ContainerRemove(ctx, c.id, types.ContainerRemoveOptions{Force: true}); err != nil {
+			logging.GetLogger().Errorf("Failed to remove container %s: %s", c.name, err)
+		}
+	}
+	provisionedBackends = nil
+}
+
+// ElasticsearchHost returns the address of the Elasticsearch backend, the
+// provisioned one if any
+func ElasticsearchHost() string {
+	return elasticsearchHost
+}
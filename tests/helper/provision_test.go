@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+)
+
+func TestResolveBackend(t *testing.T) {
+	tests := []struct {
+		value        string
+		provisionAll bool
+		backend      string
+		image        string
+		err          bool
+	}{
+		{"", false, "", "", false},
+		{"memory", true, "memory", "", false},
+		{"elasticsearch", false, "elasticsearch", "", false},
+		{"elasticsearch", true, "elasticsearch", "elasticsearch", false},
+		{"docker:elasticsearch7", false, "elasticsearch", "elasticsearch7", false},
+		{"docker:orientdb", false, "orientdb", "orientdb", false},
+		{"docker:memory", false, "", "", true},
+		{"docker:elasticsearch8", true, "", "", true},
+	}
+
+	for _, test := range tests {
+		backend, image, err := resolveBackend(test.value, test.provisionAll)
+		if test.err {
+			if err == nil {
+				t.Errorf("Expected an error for %s", test.value)
+			}
+			continue
+		}
+		if err != nil || backend != test.backend || image != test.image {
+			t.Errorf("Expected backend %s with image %s for %s, got %s with image %s: %v", test.backend, test.image, test.value, backend, image, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+const (
+	recorderStartDelay  = 500 * time.Millisecond
+	recorderStopTimeout = 5 * time.Second
+)
+
+// TrafficRecorder records the packets of an interface with tcpdump while
+// the test runs. The pcap file is written to the artifact directory of the
+// test and removed if the artifacts are not kept.
+type TrafficRecorder struct {
+	t        testing.TB
+	intf     string
+	filename string
+	cmd      *exec.Cmd
+	output   bytes.Buffer
+	done     chan error
+}
+
+// RecordTraffic starts recording the packets of an interface of the namespace
+// ns, the root namespace being used if ns is empty. Stop has to be deferred so
+// that the capture is flushed even if the test panics.
+func RecordTraffic(t testing.TB, ns, intf string) *TrafficRecorder {
+	a, err := TestArtifacts(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := intf + ".pcap"
+	if ns != "" {
+		name = fmt.Sprintf("%s-%s.pcap", ns, intf)
+	}
+	filename := a.Path(name)
+
+	// packets are written as soon as received so that nothing is lost
+	// when tcpdump is terminated
+	args := []string{"tcpdump", "-U", "-n", "-i", intf, "-w", filename}
+	if ns != "" {
+		args = netnsArgs(ns, args...)
+	}
+
+	r := &TrafficRecorder{t: t, intf: intf, filename: filename, done: make(chan error, 1)}
+	r.cmd = exec.Command(args[0], args[1:]...)
+	r.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	r.cmd.Stdout = &r.output
+	r.cmd.Stderr = &r.output
+
+	logging.GetLogger().Debugf("Executing command %+v", args)
+	if err := r.cmd.Start(); err != nil {
+		t.Fatalf("Failed to record traffic of %s: %s", intf, err)
+	}
+	go func() { r.done <- r.cmd.Wait() }()
+
+	// tcpdump needs some time to open the interface
+	select {
+	case err := <-r.done:
+		os.Remove(filename)
+		t.Fatalf("Failed to record traffic of %s: %v: %s", intf, err, r.output.String())
+	case <-time.After(recorderStartDelay):
+	}
+
+	return r
+}
+
+// Stop terminates tcpdump and removes the pcap file unless the artifacts of
+// the test are kept or the test is panicking, Stop being deferred.
+// Stopping a stopped recorder is a no-op.
+func (r *TrafficRecorder) Stop() {
+	err := recover()
+	r.stop(err != nil)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (r *TrafficRecorder) stop(panicking bool) {
+	if r.cmd == nil {
+		return
+	}
+	defer func() { r.cmd = nil }()
+
+	pgid := -r.cmd.Process.Pid
+	syscall.Kill(pgid, syscall.SIGTERM)
+	select {
+	case <-r.done:
+	case <-time.After(recorderStopTimeout):
+		syscall.Kill(pgid, syscall.SIGKILL)
+		<-r.done
+	}
+
+	if panicking {
+		r.t.Logf("Traffic of %s recorded to %s", r.intf, r.filename)
+		return
+	}
+
+	a, err := TestArtifacts(r.t)
+	if err != nil {
+		r.t.Log(err)
+		return
+	}
+	if !a.Keep() {
+		os.Remove(r.filename)
+		return
+	}
+	r.t.Logf("Traffic of %s recorded to %s", r.intf, r.filename)
+}
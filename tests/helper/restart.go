@@ -0,0 +1,254 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/config"
+	g "github.com/skydive-project/skydive/gremlin"
+	"github.com/skydive-project/skydive/logging"
+)
+
+const agentRestartTimeout = 30 * time.Second
+
+// AgentService is the agent running in the test process
+type AgentService interface {
+	Start()
+	Stop()
+}
+
+// inProcessAgent is the agent restarted by RestartAgent, an agent can't be
+// started again once stopped so a new one is created on restart
+var inProcessAgent struct {
+	sync.Mutex
+	agent    AgentService
+	newAgent func() (AgentService, error)
+}
+
+// RegisterAgent registers the agent running in the test process and the
+// function creating a new one with the same configuration
+func RegisterAgent(agent AgentService, newAgent func() (AgentService, error)) {
+	inProcessAgent.Lock()
+	inProcessAgent.agent, inProcessAgent.newAgent = agent, newAgent
+	inProcessAgent.Unlock()
+}
+
+// AgentRestartOpts describes how RestartAgentWithOpts checks the analyzer
+// view of the restarted agent
+type AgentRestartOpts struct {
+	// KeepHostGraph is set when the analyzer keeps the nodes of the agent
+	// once disconnected, by default they are expected to be deleted
+	KeepHostGraph bool
+	// Timeout of each step of the restart, 30 seconds if zero
+	Timeout time.Duration
+}
+
+// RestartAgent restarts the agent, see RestartAgentWithOpts
+func RestartAgent(t *testing.T) {
+	RestartAgentWithOpts(t, AgentRestartOpts{})
+}
+
+// RestartAgentWithOpts stops the agent, waits for the analyzer to delete its
+// host node, starts a new agent with the same configuration and waits for
+// it to register again with at least the nodes it had. The agent is the one
+// of the PID file given by -agent.pidfile or else the in-process one, its
+// host_id being the one of the test configuration.
+func RestartAgentWithOpts(t *testing.T, opts AgentRestartOpts) {
+	if opts.Timeout == 0 {
+		opts.Timeout = agentRestartTimeout
+	}
+
+	hostID := config.GetString("host_id")
+	hostQuery := g.G.V().Has("Type", "host", "Name", hostID)
+	WaitForNodes(t, hostQuery.String(), 1, Exactly, opts.Timeout)
+	nodes := len(WaitForNodes(t, hostQuery.Out().String(), 0, AtLeast, opts.Timeout))
+
+	logging.GetLogger().Infof("Restarting agent %s owning %d nodes", hostID, nodes)
+
+	start, err := stopAgent()
+	if err != nil {
+		t.Fatalf("Failed to stop agent %s: %s", hostID, err)
+	}
+
+	if !opts.KeepHostGraph {
+		WaitForNodes(t, hostQuery.String(), 0, Exactly, opts.Timeout)
+	}
+
+	if err := start(); err != nil {
+		t.Fatalf("Failed to start agent %s: %s", hostID, err)
+	}
+
+	WaitForNodes(t, hostQuery.String(), 1, Exactly, opts.Timeout)
+	WaitForNodes(t, hostQuery.Out().String(), nodes, AtLeast, opts.Timeout)
+}
+
+// stopAgent stops the agent and returns the function starting a new one
+func stopAgent() (func() error, error) {
+	if agentPIDFile != "" {
+		return stopExternalAgent(agentPIDFile)
+	}
+
+	inProcessAgent.Lock()
+	defer inProcessAgent.Unlock()
+
+	if inProcessAgent.agent == nil {
+		return nil, fmt.Errorf("No agent registered, the agent is only started in standalone mode")
+	}
+	inProcessAgent.agent.Stop()
+	inProcessAgent.agent = nil
+
+	return func() error {
+		agent, err := inProcessAgent.newAgent()
+		if err != nil {
+			return err
+		}
+		agent.Start()
+
+		inProcessAgent.Lock()
+		inProcessAgent.agent = agent
+		inProcessAgent.Unlock()
+		return nil
+	}, nil
+}
+
+// externalProcess holds what is needed to start a process again
+type externalProcess struct {
+	args []string
+	dir  string
+	env  []string
+}
+
+// readExternalProcess reads the command line, the working directory and the
+// environment of a process from /proc
+func readExternalProcess(pid int) (*externalProcess, error) {
+	proc := filepath.Join("/proc", strconv.Itoa(pid))
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(proc, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	environ, err := ioutil.ReadFile(filepath.Join(proc, "environ"))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.Readlink(filepath.Join(proc, "cwd"))
+	if err != nil {
+		return nil, err
+	}
+
+	split := func(b []byte) []string {
+		if b = bytes.TrimRight(b, "\x00"); len(b) == 0 {
+			return nil
+		}
+		return strings.Split(string(b), "\x00")
+	}
+
+	p := &externalProcess{args: split(cmdline), dir: dir, env: split(environ)}
+	if len(p.args) == 0 {
+		return nil, fmt.Errorf("No command line for process %d", pid)
+	}
+	return p, nil
+}
+
+// readPIDFile returns the PID written in the file
+func readPIDFile(pidFile string) (int, error) {
+	b, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid PID file %s: %s", pidFile, err)
+	}
+	return pid, nil
+}
+
+// stopExternalAgent terminates the agent of the PID file, killing it if it
+// doesn't exit in time, and returns the function starting it again with the
+// same command line. The output of the new agent is written to a log file
+// next to the PID file, which is updated with the new PID.
+func stopExternalAgent(pidFile string) (func() error, error) {
+	pid, err := readPIDFile(pidFile)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := readExternalProcess(pid)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read agent process %d: %s", pid, err)
+	}
+
+	// the agent is not a child of the test process, its exit is detected by
+	// signaling it until it no longer exists
+	exited := func() error {
+		if err := syscall.Kill(pid, 0); err != syscall.ESRCH {
+			return fmt.Errorf("Agent process %d still running", pid)
+		}
+		return nil
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return nil, err
+	}
+	if err := Retry(daemonStopTimeout, 100*time.Millisecond, exited); err != nil {
+		syscall.Kill(pid, syscall.SIGKILL)
+		if err := Retry(daemonStopTimeout, 100*time.Millisecond, exited); err != nil {
+			return nil, err
+		}
+	}
+
+	return func() error {
+		logFile, err := os.OpenFile(pidFile+".log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.Command(p.args[0], p.args[1:]...)
+		cmd.Dir, cmd.Env = p.dir, p.env
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Stdout, cmd.Stderr = logFile, logFile
+
+		if err := cmd.Start(); err != nil {
+			logFile.Close()
+			return err
+		}
+		go func() {
+			cmd.Wait()
+			logFile.Close()
+		}()
+
+		return ioutil.WriteFile(pidFile, []byte(fmt.Sprintf("%d\n", cmd.Process.Pid)), 0644)
+	}, nil
+}
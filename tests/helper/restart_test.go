@@ -0,0 +1,62 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadExternalProcess(t *testing.T) {
+	p, err := readExternalProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.args) != len(os.Args) || p.args[0] != os.Args[0] {
+		t.Errorf("Expected command line %v, got %v", os.Args, p.args)
+	}
+	if wd, _ := os.Getwd(); p.dir != wd {
+		t.Errorf("Expected working directory %s, got %s", wd, p.dir)
+	}
+}
+
+func TestReadPIDFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-pidfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pidFile := filepath.Join(dir, "agent.pid")
+	ioutil.WriteFile(pidFile, []byte("1234\n"), 0644)
+	if pid, err := readPIDFile(pidFile); err != nil || pid != 1234 {
+		t.Errorf("Expected PID 1234, got %d: %v", pid, err)
+	}
+
+	ioutil.WriteFile(pidFile, []byte("agent"), 0644)
+	if _, err := readPIDFile(pidFile); err == nil {
+		t.Error("Expected an error for an invalid PID file")
+	}
+}
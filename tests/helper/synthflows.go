@@ -0,0 +1,211 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/flow"
+)
+
+// SyntheticFlow builds a flow without capturing any packet
+type SyntheticFlow struct {
+	flow *flow.Flow
+}
+
+// NewSyntheticFlow returns a builder of a flow captured on the given node,
+// starting now
+func NewSyntheticFlow(nodeTID string) *SyntheticFlow {
+	f := flow.NewFlow()
+	f.Init(common.UnixMillis(time.Now()), nodeTID, flow.FlowUUIDs{})
+	return &SyntheticFlow{flow: f}
+}
+
+// NewTCPSession returns a builder of a TCP session between A and B, with
+// the packets of an established connection
+func NewTCPSession(nodeTID string, ipA, ipB string, portA, portB int64) *SyntheticFlow {
+	return NewSyntheticFlow(nodeTID).
+		Ethernet("02:00:00:00:00:01", "02:00:00:00:00:02").
+		IP(ipA, ipB).
+		TCP(portA, portB).
+		Metric(5, 370, 4, 300)
+}
+
+// NewDNSExchange returns a builder of a DNS query from a client to the port
+// 53 of a server and its answer
+func NewDNSExchange(nodeTID string, client, server string, clientPort int64) *SyntheticFlow {
+	return NewSyntheticFlow(nodeTID).
+		Ethernet("02:00:00:00:00:01", "02:00:00:00:00:02").
+		IP(client, server).
+		UDP(clientPort, 53).
+		Application("DNS").
+		Metric(1, 74, 1, 90)
+}
+
+// NewICMPEcho returns a builder of a ping from A to B with its reply
+func NewICMPEcho(nodeTID string, ipA, ipB string, id uint32) *SyntheticFlow {
+	return NewSyntheticFlow(nodeTID).
+		Ethernet("02:00:00:00:00:01", "02:00:00:00:00:02").
+		IP(ipA, ipB).
+		ICMP(flow.ICMPType_ECHO, 0, id).
+		Metric(1, 98, 1, 98)
+}
+
+// Ethernet sets the link layer of the flow
+func (s *SyntheticFlow) Ethernet(a, b string) *SyntheticFlow {
+	s.flow.Link = &flow.FlowLayer{Protocol: flow.FlowProtocol_ETHERNET, A: a, B: b}
+	return s
+}
+
+// IP sets the network layer of the flow, IPv4 or IPv6 depending on the
+// addresses
+func (s *SyntheticFlow) IP(a, b string) *SyntheticFlow {
+	protocol := flow.FlowProtocol_IPV4
+	if strings.Contains(a, ":") {
+		protocol = flow.FlowProtocol_IPV6
+	}
+	s.flow.Network = &flow.FlowLayer{Protocol: protocol, A: a, B: b}
+	return s
+}
+
+// TCP sets the transport layer of the flow
+func (s *SyntheticFlow) TCP(portA, portB int64) *SyntheticFlow {
+	s.flow.Transport = &flow.TransportLayer{Protocol: flow.FlowProtocol_TCP, A: portA, B: portB}
+	return s
+}
+
+// UDP sets the transport layer of the flow
+func (s *SyntheticFlow) UDP(portA, portB int64) *SyntheticFlow {
+	s.flow.Transport = &flow.TransportLayer{Protocol: flow.FlowProtocol_UDP, A: portA, B: portB}
+	return s
+}
+
+// ICMP sets the ICMP layer of the flow
+func (s *SyntheticFlow) ICMP(t flow.ICMPType, code uint32, id uint32) *SyntheticFlow {
+	s.flow.ICMP = &flow.ICMPLayer{Type: t, Code: code, ID: id}
+	return s
+}
+
+// Application sets the application of the flow, the last layer by default
+func (s *SyntheticFlow) Application(name string) *SyntheticFlow {
+	s.flow.Application = name
+	return s
+}
+
+// Metric sets the packets and bytes seen in both directions
+func (s *SyntheticFlow) Metric(abPackets, abBytes, baPackets, baBytes int64) *SyntheticFlow {
+	m := s.flow.Metric
+	m.ABPackets, m.ABBytes, m.BAPackets, m.BABytes = abPackets, abBytes, baPackets, baBytes
+	return s
+}
+
+// Between sets the time of the first and last packets of the flow
+func (s *SyntheticFlow) Between(start, last time.Time) *SyntheticFlow {
+	s.flow.Start, s.flow.Last = common.UnixMillis(start), common.UnixMillis(last)
+	s.flow.Metric.Start, s.flow.Metric.Last = s.flow.Start, s.flow.Last
+	return s
+}
+
+// Parent sets the flow encapsulating this one
+func (s *SyntheticFlow) Parent(uuid string) *SyntheticFlow {
+	s.flow.ParentUUID = uuid
+	return s
+}
+
+// layers returns the names of the layers of the flow, the application
+// being appended when it is not the last layer
+func (s *SyntheticFlow) layers() []string {
+	var path []string
+
+	f := s.flow
+	if f.Link != nil {
+		path = append(path, "Ethernet")
+	}
+	if f.Network != nil {
+		if f.Network.Protocol == flow.FlowProtocol_IPV6 {
+			path = append(path, "IPv6")
+		} else {
+			path = append(path, "IPv4")
+		}
+	}
+	if f.ICMP != nil && f.Network != nil {
+		if f.Network.Protocol == flow.FlowProtocol_IPV6 {
+			path = append(path, "ICMPv6")
+		} else {
+			path = append(path, "ICMPv4")
+		}
+	}
+	if f.Transport != nil {
+		path = append(path, f.Transport.Protocol.String())
+	}
+	if f.Application != "" && (len(path) == 0 || path[len(path)-1] != f.Application) {
+		path = append(path, f.Application)
+	}
+	return path
+}
+
+// Flow returns the flow with its layers path, tracking IDs and UUID
+// computed like the ones of a captured flow
+func (s *SyntheticFlow) Flow() *flow.Flow {
+	f := s.flow
+
+	path := s.layers()
+	f.LayersPath = strings.Join(path, "/")
+	if len(path) > 0 {
+		f.Application = path[len(path)-1]
+	}
+
+	m := *f.Metric
+	f.LastUpdateMetric = &m
+
+	key := fmt.Sprintf("%s-%v-%v-%v-%v", f.LayersPath, f.Link, f.Network, f.Transport, f.ICMP)
+	f.UpdateUUID(key, flow.FlowOpts{LayerKeyMode: flow.DefaultLayerKeyMode})
+
+	return f
+}
+
+// InjectFlows sends the flows to the flow ingestion endpoint of the
+// analyzer, as an agent would do
+func InjectFlows(endpoint string, flows ...*flow.Flow) error {
+	conn, err := dialWS(endpoint, "/ws/flow", nil)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to the flow endpoint: %s", err)
+	}
+	defer WSClose(conn)
+
+	for _, f := range flows {
+		data, err := f.GetData()
+		if err != nil {
+			return err
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			return fmt.Errorf("Failed to send flow %s: %s", f.UUID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyntheticFlows(t *testing.T) {
+	tcp := NewTCPSession("node1", "192.168.0.1", "192.168.0.2", 34567, 80).Flow()
+	if tcp.LayersPath != "Ethernet/IPv4/TCP" || tcp.Application != "TCP" {
+		t.Errorf("Unexpected TCP session flow: %s %s", tcp.LayersPath, tcp.Application)
+	}
+	if tcp.UUID == "" || tcp.TrackingID == "" || tcp.L3TrackingID == "" {
+		t.Errorf("TCP session flow IDs should be computed: %+v", tcp)
+	}
+	if tcp.NodeTID != "node1" || tcp.Metric.ABPackets == 0 || tcp.LastUpdateMetric.ABBytes != tcp.Metric.ABBytes {
+		t.Errorf("Unexpected TCP session flow: %+v", tcp)
+	}
+
+	dns := NewDNSExchange("node1", "fd00::1", "fd00::2", 45000).Flow()
+	if dns.LayersPath != "Ethernet/IPv6/UDP/DNS" || dns.Application != "DNS" || dns.Transport.B != 53 {
+		t.Errorf("Unexpected DNS exchange flow: %s %s", dns.LayersPath, dns.Application)
+	}
+
+	icmp := NewICMPEcho("node1", "192.168.0.1", "192.168.0.2", 1).Flow()
+	if icmp.LayersPath != "Ethernet/IPv4/ICMPv4" || icmp.Application != "ICMPv4" {
+		t.Errorf("Unexpected ICMP echo flow: %s %s", icmp.LayersPath, icmp.Application)
+	}
+
+	// the same session seen on another node keeps its tracking ID
+	other := NewTCPSession("node2", "192.168.0.1", "192.168.0.2", 34567, 80).Flow()
+	if other.TrackingID != tcp.TrackingID || other.UUID == tcp.UUID {
+		t.Errorf("Expected same tracking ID and different UUIDs: %s/%s %s/%s", tcp.TrackingID, other.TrackingID, tcp.UUID, other.UUID)
+	}
+
+	start := time.Now().Add(-time.Minute)
+	l3 := NewSyntheticFlow("node1").IP("10.0.0.1", "10.0.0.2").TCP(1, 2).Between(start, start.Add(time.Second)).Flow()
+	if l3.LayersPath != "IPv4/TCP" || l3.Metric.Last-l3.Metric.Start != 1000 {
+		t.Errorf("Unexpected L3 flow: %+v", l3)
+	}
+}
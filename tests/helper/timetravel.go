@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+	g "github.com/skydive-project/skydive/gremlin"
+)
+
+// TimeMarker is a point in time returned by Now, used to query the history
+// of the topology and of the flows
+type TimeMarker struct {
+	time.Time
+}
+
+// Now returns a marker of the current time
+func Now(t *testing.T) TimeMarker {
+	mark := TimeMarker{time.Now()}
+	t.Logf("Time marker %d (%s)", mark.millis(), mark.Format(time.RFC3339Nano))
+	return mark
+}
+
+func (m TimeMarker) millis() int64 {
+	return common.UnixMillis(m.Time)
+}
+
+// topologyHistorySupported returns whether the topology backend keeps the
+// history of the graph, the memory one only holding its current state
+func topologyHistorySupported() bool {
+	return TopologyBackend == "elasticsearch" || TopologyBackend == "orientdb"
+}
+
+// flowHistorySupported returns whether the flows are stored by a backend
+// that can be queried by time range
+func flowHistorySupported() bool {
+	return FlowBackend == "elasticsearch" || FlowBackend == "orientdb"
+}
+
+// SkipWithoutTopologyHistory skips the test if the topology backend
+// doesn't support history
+func SkipWithoutTopologyHistory(t *testing.T) {
+	if !topologyHistorySupported() {
+		t.Skipf("Topology history is not supported by the %s backend, run with -analyzer.topology.backend elasticsearch or orientdb", TopologyBackend)
+	}
+}
+
+// SkipWithoutFlowHistory skips the test if no flow backend supporting
+// history is used
+func SkipWithoutFlowHistory(t *testing.T) {
+	if !flowHistorySupported() {
+		t.Skipf("Flow history is not supported by the '%s' flow backend, run with -analyzer.flow.backend elasticsearch or orientdb", FlowBackend)
+	}
+}
+
+// historyQuery inserts the Context step right after the G step of the query
+func historyQuery(query interface{}, context string) (string, error) {
+	q := g.NewQueryStringFromArgument(query).String()
+	if q != "G" && !strings.HasPrefix(q, "G.") {
+		return "", fmt.Errorf("Query '%s' doesn't start with G", q)
+	}
+	if strings.HasPrefix(q, "G.Context(") || strings.HasPrefix(q, "G.At(") {
+		return "", fmt.Errorf("Query '%s' already has a time context", q)
+	}
+	return "G" + context + q[1:], nil
+}
+
+// topologyAtQuery returns the query run on the topology at the marker
+func topologyAtQuery(mark TimeMarker, query interface{}) (string, error) {
+	return historyQuery(query, fmt.Sprintf(".Context(%d)", mark.millis()))
+}
+
+// flowsBetweenQuery returns the query run on the flows seen between the
+// markers, the range being given as a duration before the end
+func flowsBetweenQuery(from, to TimeMarker, query interface{}) (string, error) {
+	if to.Before(from.Time) {
+		return "", fmt.Errorf("Marker %d is before marker %d", to.millis(), from.millis())
+	}
+	duration := time.Duration(to.millis()-from.millis()) * time.Millisecond
+	return historyQuery(query, fmt.Sprintf(".Context(%d, %s)", to.millis(), g.Quote(duration.String())))
+}
+
+// TopologyAt runs a Gremlin query, starting with G, on the topology as it
+// was at the marker. The test is skipped if the topology backend doesn't
+// support history.
+func TopologyAt(t *testing.T, mark TimeMarker, query interface{}) *GremlinResult {
+	SkipWithoutTopologyHistory(t)
+
+	q, err := topologyAtQuery(mark, query)
+	if err != nil {
+		return &GremlinResult{err: err}
+	}
+	return GremlinQuery(t, q)
+}
+
+// FlowsBetween runs a Gremlin query, starting with G and returning flows,
+// on the flows seen between the markers. The test is skipped if no flow
+// backend supporting history is used.
+func FlowsBetween(t *testing.T, from, to TimeMarker, query interface{}) *GremlinResult {
+	SkipWithoutFlowHistory(t)
+
+	q, err := flowsBetweenQuery(from, to, query)
+	if err != nil {
+		return &GremlinResult{err: err}
+	}
+	return GremlinQuery(t, q)
+}
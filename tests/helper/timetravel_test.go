@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+	"time"
+
+	g "github.com/skydive-project/skydive/gremlin"
+)
+
+func TestHistoryQueries(t *testing.T) {
+	from := TimeMarker{time.Unix(1500000000, 0)}
+	to := TimeMarker{from.Add(1500 * time.Millisecond)}
+
+	q, err := topologyAtQuery(from, g.G.V().Has("Name", "eth0"))
+	if expected := `G.Context(1500000000000).V().Has("Name", "eth0")`; err != nil || q != expected {
+		t.Errorf("Expected query %s, got %s: %v", expected, q, err)
+	}
+
+	q, err = flowsBetweenQuery(from, to, "G.Flows()")
+	if expected := `G.Context(1500000001500, "1.5s").Flows()`; err != nil || q != expected {
+		t.Errorf("Expected query %s, got %s: %v", expected, q, err)
+	}
+
+	if _, err := flowsBetweenQuery(to, from, g.G.Flows()); err == nil {
+		t.Error("Expected an error for markers in the wrong order")
+	}
+	if _, err := topologyAtQuery(from, g.G.At("-1s").V()); err == nil {
+		t.Error("Expected an error for a query with a time context")
+	}
+	if _, err := topologyAtQuery(from, "V()"); err == nil {
+		t.Error("Expected an error for a query not starting with G")
+	}
+}
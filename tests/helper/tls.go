@@ -0,0 +1,258 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+)
+
+const tlsCertificateValidity = 24 * time.Hour
+
+// TLSCertificates holds the paths of the certificates generated for a TLS
+// test run. The certificate files also contain the CA certificate so that
+// they can be used to verify the peer.
+type TLSCertificates struct {
+	CA         string
+	ServerCert string
+	ServerKey  string
+	ClientCert string
+	ClientKey  string
+}
+
+var tlsCertificates *TLSCertificates
+
+type tlsIdentity struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+func newTLSIdentity(template *x509.Certificate, parent *tlsIdentity) (*tlsIdentity, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(tlsCertificateValidity)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tlsIdentity{cert: cert, der: der, key: key}, nil
+}
+
+// write writes the certificate, followed by the CA certificate if given,
+// and the private key in PEM format
+func (i *tlsIdentity) write(certFile, keyFile string, ca *tlsIdentity) error {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.der})
+	if ca != nil {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.der})...)
+	}
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return err
+	}
+
+	if keyFile == "" {
+		return nil
+	}
+
+	der, err := x509.MarshalECPrivateKey(i.key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+}
+
+// tlsHostnames returns the names and addresses the server certificate is
+// valid for: localhost, the hostname and the local addresses
+func tlsHostnames(extraIPs ...string) (names []string, ips []net.IP) {
+	names = []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil {
+		names = append(names, hostname)
+	}
+
+	ips = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok {
+				ips = append(ips, ipnet.IP)
+			}
+		}
+	}
+	for _, extra := range extraIPs {
+		if ip := net.ParseIP(extra); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	return names, ips
+}
+
+// GenerateTLSCertificates creates a throwaway CA and the server and client
+// certificates it signs in the given directory
+func GenerateTLSCertificates(dir string, extraIPs ...string) (*TLSCertificates, error) {
+	ca, err := newTLSIdentity(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Skydive test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	names, ips := tlsHostnames(extraIPs...)
+	usage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+
+	server, err := newTLSIdentity(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "skydive-analyzer"},
+		DNSNames:    names,
+		IPAddresses: ips,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: usage,
+	}, ca)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newTLSIdentity(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "skydive-agent"},
+		DNSNames:    names,
+		IPAddresses: ips,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: usage,
+	}, ca)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := &TLSCertificates{
+		CA:         filepath.Join(dir, "ca.crt"),
+		ServerCert: filepath.Join(dir, "analyzer.crt"),
+		ServerKey:  filepath.Join(dir, "analyzer.key"),
+		ClientCert: filepath.Join(dir, "agent.crt"),
+		ClientKey:  filepath.Join(dir, "agent.key"),
+	}
+
+	if err := ca.write(certs.CA, "", nil); err != nil {
+		return nil, err
+	}
+	if err := server.write(certs.ServerCert, certs.ServerKey, ca); err != nil {
+		return nil, err
+	}
+	if err := client.write(certs.ClientCert, certs.ClientKey, ca); err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
+
+// setTLSParams generates the certificates of the run if needed and sets the
+// parameters of the configuration template
+func setTLSParams(params HelperParams, listenAddr string) error {
+	params["TLS"] = TLS
+	if !TLS {
+		return nil
+	}
+
+	if tlsCertificates == nil {
+		dir, err := ioutil.TempDir("", "skydive-tls")
+		if err != nil {
+			return err
+		}
+
+		if tlsCertificates, err = GenerateTLSCertificates(dir, listenAddr); err != nil {
+			return err
+		}
+	}
+
+	params["AnalyzerX509Cert"] = tlsCertificates.ServerCert
+	params["AnalyzerX509Key"] = tlsCertificates.ServerKey
+	params["AgentX509Cert"] = tlsCertificates.ClientCert
+	params["AgentX509Key"] = tlsCertificates.ClientKey
+	return nil
+}
+
+// newTLSClientConfig returns the configuration verifying the server with the
+// CA of the certificate file, presenting the client certificate if given
+func newTLSClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if certFile != "" && keyFile != "" {
+		var err error
+		if tlsConfig, err = common.SetupTLSClientConfig(certFile, keyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	roots, err := common.SetupTLSLoadCertificate(caFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = roots
+
+	return tlsConfig, nil
+}
+
+// wsTLSConfig returns the TLS configuration of the websocket connections
+// based on the certificates of the test configuration
+func wsTLSConfig(opts *WSOpts) (*tls.Config, error) {
+	var certFile, keyFile string
+	if opts != nil && opts.TLSClientCert {
+		certFile, keyFile = config.GetString("agent.X509_cert"), config.GetString("agent.X509_key")
+	}
+
+	return newTLSClientConfig(config.GetString("analyzer.X509_cert"), certFile, keyFile)
+}
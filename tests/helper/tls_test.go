@@ -0,0 +1,185 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+)
+
+func tlsHandshake(t *testing.T, certs *TLSCertificates, clientCert bool) error {
+	serverConfig, err := common.SetupTLSServerConfig(certs.ServerCert, certs.ServerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serverConfig.ClientCAs, err = common.SetupTLSLoadCertificate(certs.ClientCert); err != nil {
+		t.Fatal(err)
+	}
+	serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.(*tls.Conn).Handshake()
+		conn.Close()
+	}()
+
+	var certFile, keyFile string
+	if clientCert {
+		certFile, keyFile = certs.ClientCert, certs.ClientKey
+	}
+	clientConfig, err := newTLSClientConfig(certs.ServerCert, certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConfig.ServerName = "127.0.0.1"
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), clientConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// the server rejects the client certificate after the client handshake,
+	// otherwise it closes the connection
+	if _, err = conn.Read(make([]byte, 1)); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func TestGenerateTLSCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-tls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certs, err := GenerateTLSCertificates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := common.SetupTLSLoadCertificate(certs.CA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.LoadX509KeyPair(certs.ServerCert, certs.ServerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: pool, DNSName: "localhost"}); err != nil {
+		t.Fatalf("Server certificate not signed by the CA: %s", err)
+	}
+
+	if err := tlsHandshake(t, certs, true); err != nil {
+		t.Fatalf("Handshake with client certificate failed: %s", err)
+	}
+	if err := tlsHandshake(t, certs, false); err == nil {
+		t.Fatal("Handshake without client certificate should fail")
+	}
+}
+
+func TestSendPCAPFileTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "skydive-tls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certs, err := GenerateTLSCertificates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range map[string]string{
+		"analyzer.X509_cert": certs.ServerCert,
+		"agent.X509_cert":    certs.ClientCert,
+		"agent.X509_key":     certs.ClientKey,
+	} {
+		config.Set(key, value)
+		defer config.Set(key, "")
+	}
+
+	serverConfig, err := common.SetupTLSServerConfig(certs.ServerCert, certs.ServerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serverConfig.ClientCAs, err = common.SetupTLSLoadCertificate(certs.ClientCert); err != nil {
+		t.Fatal(err)
+	}
+	serverConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		data, _ := ioutil.ReadAll(conn)
+		received <- data
+	}()
+
+	content := bytes.Repeat([]byte("pcap"), 100000)
+	filename := filepath.Join(dir, "test.pcap")
+	if err := ioutil.WriteFile(filename, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SendPCAPFileWithTransport(filename, ln.Addr().String(), PCAPTransportTLS); err != nil {
+		t.Fatal(err)
+	}
+	if data := <-received; !bytes.Equal(data, content) {
+		t.Errorf("Expected %d bytes to be received, got %d", len(content), len(data))
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/logging"
+)
+
+// Topology builds network namespaces, veth pairs and bridges and tracks the
+// created resources so that they can be removed by Cleanup. A failing step
+// removes what was already created and aborts the test.
+type Topology struct {
+	t         *testing.T
+	resources [][]string
+	ifIndex   map[string]int
+	recorders []*TrafficRecorder
+	// ipv6Interfaces are the interfaces having IPv6 addresses, as
+	// namespace and interface name pairs
+	ipv6Interfaces [][2]string
+}
+
+// NewTopology returns a topology builder, Cleanup has to be called once the
+// test is done, usually with a defer
+func NewTopology(t *testing.T) *Topology {
+	return &Topology{t: t, ifIndex: make(map[string]int)}
+}
+
+func runCommand(args ...string) (string, error) {
+	logging.GetLogger().Debugf("Executing command %+v", args)
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if len(output) > 0 {
+		logging.GetLogger().Debugf("Command returned %s", string(output))
+	}
+	if err != nil {
+		return string(output), fmt.Errorf("command '%s' failed: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// run runs the commands, cleaning up and failing the test on error
+func (tp *Topology) run(cmds ...[]string) {
+	for _, args := range cmds {
+		if _, err := runCommand(args...); err != nil {
+			tp.Cleanup()
+			tp.t.Fatal(err)
+		}
+	}
+}
+
+// track registers the command removing a created resource
+func (tp *Topology) track(args ...string) {
+	tp.resources = append(tp.resources, args)
+}
+
+// nextInterface returns the next free interface name of a namespace
+func (tp *Topology) nextInterface(ns string) string {
+	name := fmt.Sprintf("eth%d", tp.ifIndex[ns])
+	tp.ifIndex[ns]++
+	return name
+}
+
+func netnsArgs(ns string, args ...string) []string {
+	return append([]string{"ip", "netns", "exec", ns}, args...)
+}
+
+// AddNetNS creates a network namespace with its loopback interface up
+func (tp *Topology) AddNetNS(ns string) *Topology {
+	tp.run([]string{"ip", "netns", "add", ns})
+	tp.track("ip", "netns", "del", ns)
+	tp.run(netnsArgs(ns, "ip", "link", "set", "lo", "up"))
+	return tp
+}
+
+// isIPv6CIDR returns whether the address, with or without prefix length,
+// is an IPv6 one
+func isIPv6CIDR(addr string) bool {
+	return strings.Contains(strings.SplitN(addr, "/", 2)[0], ":")
+}
+
+// setAddress assigns the addresses, separated by commas, to an interface of
+// a namespace and brings it up. The interfaces having IPv6 addresses don't
+// send router solicitations, which would pollute the captures.
+func (tp *Topology) setAddress(ns, intf, addr string) {
+	ipv6 := false
+	for _, a := range strings.Split(addr, ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+		if isIPv6CIDR(a) {
+			ipv6 = true
+		}
+		tp.run(netnsArgs(ns, "ip", "address", "add", a, "dev", intf))
+	}
+
+	if ipv6 {
+		tp.run(netnsArgs(ns, "sysctl", "-q", "-w", fmt.Sprintf("net.ipv6.conf.%s.router_solicitations=0", intf)))
+		tp.ipv6Interfaces = append(tp.ipv6Interfaces, [2]string{ns, intf})
+	}
+	tp.run(netnsArgs(ns, "ip", "link", "set", intf, "up"))
+}
+
+// AddVethPair connects two namespaces with a veth pair, the interfaces are
+// named ethN in each namespace. Addresses are optional, IPv4 or IPv6 CIDRs
+// separated by commas for dual stack interfaces, like "10.0.0.1/24,fd00::1/64".
+func (tp *Topology) AddVethPair(ns1, ns2, addr1, addr2 string) *Topology {
+	intf1, intf2 := tp.nextInterface(ns1), tp.nextInterface(ns2)
+
+	tp.run(netnsArgs(ns1, "ip", "link", "add", intf1, "type", "veth", "peer", "name", intf2, "netns", ns2))
+	tp.setAddress(ns1, intf1, addr1)
+	tp.setAddress(ns2, intf2, addr2)
+	return tp
+}
+
+// AddBridge creates a Linux bridge in the root namespace
+func (tp *Topology) AddBridge(bridge string) *Topology {
+	tp.run([]string{"ip", "link", "add", bridge, "type", "bridge"})
+	tp.track("ip", "link", "del", bridge)
+	tp.run([]string{"ip", "link", "set", bridge, "up"})
+	return tp
+}
+
+// AddBridgePort connects a namespace to a bridge with a veth pair, the
+// bridge side is named after the bridge and the namespace
+func (tp *Topology) AddBridgePort(bridge, ns, addr string) *Topology {
+	intf := tp.nextInterface(ns)
+	port := fmt.Sprintf("%s-%s", bridge, ns)
+	if len(port) > 15 {
+		port = port[:15]
+	}
+
+	tp.run([]string{"ip", "link", "add", port, "type", "veth", "peer", "name", intf, "netns", ns})
+	tp.track("ip", "link", "del", port)
+	tp.run(
+		[]string{"ip", "link", "set", port, "master", bridge},
+		[]string{"ip", "link", "set", port, "up"},
+	)
+	tp.setAddress(ns, intf, addr)
+	return tp
+}
+
+// WaitIPv6Ready waits for the duplicate address detection of the IPv6
+// addresses of the created interfaces to complete, the addresses being
+// unusable while tentative. The test fails if an address is a duplicate or
+// if the detection doesn't complete in time.
+func (tp *Topology) WaitIPv6Ready(timeout time.Duration) *Topology {
+	for _, ni := range tp.ipv6Interfaces {
+		ns, intf := ni[0], ni[1]
+		err := Retry(timeout, 100*time.Millisecond, func() error {
+			output, err := runCommand(netnsArgs(ns, "ip", "-6", "-o", "address", "show", "dev", intf)...)
+			if err != nil {
+				return Fatal(err)
+			}
+			if strings.Contains(output, "dadfailed") {
+				return Fatal(fmt.Errorf("duplicate IPv6 address on %s in namespace %s: %s", intf, ns, output))
+			}
+			if strings.Contains(output, "tentative") {
+				return fmt.Errorf("IPv6 address of %s in namespace %s still tentative: %s", intf, ns, output)
+			}
+			return nil
+		})
+		if err != nil {
+			tp.Cleanup()
+			tp.t.Fatal(err)
+		}
+	}
+	return tp
+}
+
+// RunIn executes a command inside a namespace and returns its output
+func (tp *Topology) RunIn(ns string, cmd string) (string, error) {
+	return runCommand(netnsArgs(ns, strings.Fields(cmd)...)...)
+}
+
+// RecordTraffic records the packets of an interface of a namespace until
+// Cleanup is called, the pcap file being kept only if the test fails
+func (tp *Topology) RecordTraffic(ns, intf string) *Topology {
+	tp.recorders = append(tp.recorders, RecordTraffic(tp.t, ns, intf))
+	return tp
+}
+
+// Cleanup stops the traffic recorders and removes the created resources in
+// the reverse order of their creation, it can be called several times
+func (tp *Topology) Cleanup() {
+	// the recorded traffic is kept when Cleanup is deferred by a panicking test
+	err := recover()
+	for _, r := range tp.recorders {
+		r.stop(err != nil)
+	}
+	tp.recorders = nil
+	if err != nil {
+		defer panic(err)
+	}
+
+	for i := len(tp.resources) - 1; i >= 0; i-- {
+		if _, err := runCommand(tp.resources[i]...); err != nil {
+			tp.t.Log(err)
+		}
+	}
+	tp.resources = nil
+}
@@ -0,0 +1,450 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/common"
+)
+
+// netnsRunPath is where the namespaces created by ip netns are mounted
+const netnsRunPath = "/var/run/netns"
+
+// The generated frames use locally administered MAC addresses so that the
+// peer of the interface drops them instead of answering, the replies being
+// generated by the helper too. Both directions are injected on the same
+// interface and the packet counts are exactly the requested ones.
+var (
+	trafficMACA = net.HardwareAddr{0x02, 0x5e, 0x00, 0x00, 0x00, 0x0a}
+	trafficMACB = net.HardwareAddr{0x02, 0x5e, 0x00, 0x00, 0x00, 0x0b}
+)
+
+var routeDevice = regexp.MustCompile(`\bdev\s+(\S+)`)
+
+var trafficSerializeOptions = gopacket.SerializeOptions{
+	ComputeChecksums: true,
+	FixLengths:       true,
+}
+
+// trafficEndpoints holds the addresses of a generated conversation
+type trafficEndpoints struct {
+	ipA, ipB     net.IP
+	portA, portB int
+}
+
+// checksumLayer is implemented by the transport layers whose checksum
+// depends on the network layer
+type checksumLayer interface {
+	SetNetworkLayerForChecksum(gopacket.NetworkLayer) error
+}
+
+// parseTrafficEndpoints parses the source and destination of a conversation,
+// given as IP addresses or as host:port when ports are used
+func parseTrafficEndpoints(src, dst string, withPorts bool) (*trafficEndpoints, error) {
+	parse := func(addr string) (net.IP, int, error) {
+		host, port := addr, 0
+		if withPorts {
+			h, p, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, 0, err
+			}
+			if port, err = strconv.Atoi(p); err != nil || port <= 0 || port > 65535 {
+				return nil, 0, fmt.Errorf("invalid port in address %s", addr)
+			}
+			host = h
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, 0, fmt.Errorf("invalid IP address %s", host)
+		}
+		return ip, port, nil
+	}
+
+	ipA, portA, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	ipB, portB, err := parse(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if (ipA.To4() == nil) != (ipB.To4() == nil) {
+		return nil, fmt.Errorf("addresses %s and %s are not of the same family", src, dst)
+	}
+
+	return &trafficEndpoints{ipA: ipA, ipB: ipB, portA: portA, portB: portB}, nil
+}
+
+func (e *trafficEndpoints) isIPv6() bool {
+	return e.ipA.To4() == nil
+}
+
+// serializeFrame returns an ethernet frame carrying the transport layer and
+// the payload, reply frames going from B to A
+func (e *trafficEndpoints) serializeFrame(reply bool, transport gopacket.SerializableLayer, payload []byte) ([]byte, error) {
+	srcMAC, dstMAC, srcIP, dstIP := trafficMACA, trafficMACB, e.ipA, e.ipB
+	if reply {
+		srcMAC, dstMAC, srcIP, dstIP = trafficMACB, trafficMACA, e.ipB, e.ipA
+	}
+
+	var protocol layers.IPProtocol
+	switch transport.(type) {
+	case *layers.TCP:
+		protocol = layers.IPProtocolTCP
+	case *layers.UDP:
+		protocol = layers.IPProtocolUDP
+	case *layers.ICMPv4:
+		protocol = layers.IPProtocolICMPv4
+	case *layers.ICMPv6:
+		protocol = layers.IPProtocolICMPv6
+	default:
+		return nil, fmt.Errorf("unsupported transport layer %T", transport)
+	}
+
+	eth := &layers.Ethernet{SrcMAC: srcMAC, DstMAC: dstMAC}
+	var network gopacket.NetworkLayer
+	if e.isIPv6() {
+		eth.EthernetType = layers.EthernetTypeIPv6
+		network = &layers.IPv6{Version: 6, SrcIP: srcIP, DstIP: dstIP, NextHeader: protocol, HopLimit: 64}
+	} else {
+		eth.EthernetType = layers.EthernetTypeIPv4
+		network = &layers.IPv4{Version: 4, SrcIP: srcIP, DstIP: dstIP, Protocol: protocol, TTL: 64}
+	}
+
+	if l, ok := transport.(checksumLayer); ok {
+		if err := l.SetNetworkLayerForChecksum(network); err != nil {
+			return nil, err
+		}
+	}
+
+	buffer := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buffer, trafficSerializeOptions,
+		eth, network.(gopacket.SerializableLayer), transport, gopacket.Payload(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize %s packet: %s", protocol, err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// forgeUDPFlow returns packets UDP datagrams from A to B
+func forgeUDPFlow(e *trafficEndpoints, packets, payloadSize int) ([][]byte, error) {
+	var frames [][]byte
+	for i := 0; i < packets; i++ {
+		udp := &layers.UDP{SrcPort: layers.UDPPort(e.portA), DstPort: layers.UDPPort(e.portB)}
+		frame, err := e.serializeFrame(false, udp, make([]byte, payloadSize))
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// tcpSegment is a segment of a generated TCP connection, replies going
+// from B to A
+type tcpSegment struct {
+	reply   bool
+	tcp     *layers.TCP
+	payload []byte
+}
+
+// Initial sequence numbers of the generated TCP connections
+const (
+	tcpSeqA = 1000
+	tcpSeqB = 5000
+)
+
+// tcpHandshakeSegments returns the SYN, SYN-ACK and ACK segments of a TCP
+// connection from A to B
+func tcpHandshakeSegments() []tcpSegment {
+	return []tcpSegment{
+		{false, &layers.TCP{SYN: true, Seq: tcpSeqA}, nil},
+		{true, &layers.TCP{SYN: true, ACK: true, Seq: tcpSeqB, Ack: tcpSeqA + 1}, nil},
+		{false, &layers.TCP{ACK: true, Seq: tcpSeqA + 1, Ack: tcpSeqB + 1}, nil},
+	}
+}
+
+// tcpSessionSegments returns the segments of a TCP connection from A to B
+// sending count segments of payloadSize bytes, each one acknowledged by B,
+// before being closed by A
+func tcpSessionSegments(count, payloadSize int) []tcpSegment {
+	segments := tcpHandshakeSegments()
+
+	seqA := uint32(tcpSeqA + 1)
+	for i := 0; i < count; i++ {
+		segments = append(segments,
+			tcpSegment{false, &layers.TCP{PSH: true, ACK: true, Seq: seqA, Ack: tcpSeqB + 1}, make([]byte, payloadSize)},
+			tcpSegment{true, &layers.TCP{ACK: true, Seq: tcpSeqB + 1, Ack: seqA + uint32(payloadSize)}, nil})
+		seqA += uint32(payloadSize)
+	}
+
+	return append(segments,
+		tcpSegment{false, &layers.TCP{FIN: true, ACK: true, Seq: seqA, Ack: tcpSeqB + 1}, nil},
+		tcpSegment{true, &layers.TCP{FIN: true, ACK: true, Seq: tcpSeqB + 1, Ack: seqA + 1}, nil},
+		tcpSegment{false, &layers.TCP{ACK: true, Seq: seqA + 1, Ack: tcpSeqB + 2}, nil})
+}
+
+// forgeTCPSegments returns the frames of the segments of a TCP connection
+// from A to B
+func forgeTCPSegments(e *trafficEndpoints, segments []tcpSegment) ([][]byte, error) {
+	var frames [][]byte
+	for _, s := range segments {
+		s.tcp.Window = 29200
+		if s.reply {
+			s.tcp.SrcPort, s.tcp.DstPort = layers.TCPPort(e.portB), layers.TCPPort(e.portA)
+		} else {
+			s.tcp.SrcPort, s.tcp.DstPort = layers.TCPPort(e.portA), layers.TCPPort(e.portB)
+		}
+
+		frame, err := e.serializeFrame(s.reply, s.tcp, s.payload)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// forgeTCPHandshake returns the SYN, SYN-ACK and ACK packets of a TCP
+// connection from A to B
+func forgeTCPHandshake(e *trafficEndpoints) ([][]byte, error) {
+	return forgeTCPSegments(e, tcpHandshakeSegments())
+}
+
+// forgeICMPEcho returns packets echo requests from A to B, each one followed
+// by its reply
+func forgeICMPEcho(e *trafficEndpoints, packets, payloadSize int) ([][]byte, error) {
+	const id = 0x5344
+
+	var frames [][]byte
+	for seq := 1; seq <= packets; seq++ {
+		for _, reply := range []bool{false, true} {
+			var icmp gopacket.SerializableLayer
+			if e.isIPv6() {
+				typ := uint8(layers.ICMPv6TypeEchoRequest)
+				if reply {
+					typ = layers.ICMPv6TypeEchoReply
+				}
+				icmp = &layers.ICMPv6{
+					TypeCode:  layers.CreateICMPv6TypeCode(typ, 0),
+					TypeBytes: []byte{id >> 8, id & 0xff, byte(seq >> 8), byte(seq)},
+				}
+			} else {
+				typ := uint8(layers.ICMPv4TypeEchoRequest)
+				if reply {
+					typ = layers.ICMPv4TypeEchoReply
+				}
+				icmp = &layers.ICMPv4{
+					TypeCode: layers.CreateICMPv4TypeCode(typ, 0),
+					Id:       id,
+					Seq:      uint16(seq),
+				}
+			}
+
+			frame, err := e.serializeFrame(reply, icmp, make([]byte, payloadSize))
+			if err != nil {
+				return nil, err
+			}
+			frames = append(frames, frame)
+		}
+	}
+	return frames, nil
+}
+
+// trafficInterface returns the interface of the namespace routing the
+// destination, the root namespace being used if ns is empty
+func trafficInterface(ns string, dst net.IP) (string, error) {
+	args := []string{"ip", "-o", "route", "get", dst.String()}
+	if ns != "" {
+		args = netnsArgs(ns, args...)
+	}
+
+	output, err := runCommand(args...)
+	if err != nil {
+		return "", err
+	}
+
+	match := routeDevice.FindStringSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("no interface routing %s in namespace '%s': %s", dst, ns, output)
+	}
+	return match[1], nil
+}
+
+// trafficSocket opens a raw socket on the interface of the namespace
+// routing the destination, the root namespace being used if ns is empty
+func trafficSocket(ns string, dst net.IP) (socket *common.RawSocket, intf string, err error) {
+	if intf, err = trafficInterface(ns, dst); err != nil {
+		return nil, "", err
+	}
+
+	err = InNetNS(ns, func() (err error) {
+		socket, err = common.NewRawSocket(intf, common.AllPackets)
+		return err
+	})
+	return socket, intf, err
+}
+
+// injectFrames writes the frames on the interface of the namespace routing
+// the destination of the conversation
+func injectFrames(ns string, e *trafficEndpoints, frames [][]byte) error {
+	socket, intf, err := trafficSocket(ns, e.ipB)
+	if err != nil {
+		return err
+	}
+	defer socket.Close()
+
+	for i, frame := range frames {
+		if _, err := socket.Write(frame); err != nil {
+			return fmt.Errorf("failed to inject packet %d/%d on %s: %s", i+1, len(frames), intf, err)
+		}
+	}
+	return nil
+}
+
+// injectSegments writes the frames of the segments of a TCP connection, the
+// ones of A on the interface of nsA routing B, the replies on the interface
+// of nsB routing A
+func injectSegments(nsA, nsB string, e *trafficEndpoints, segments []tcpSegment, frames [][]byte) error {
+	if nsA == nsB {
+		return injectFrames(nsA, e, frames)
+	}
+
+	socketA, intfA, err := trafficSocket(nsA, e.ipB)
+	if err != nil {
+		return err
+	}
+	defer socketA.Close()
+
+	socketB, intfB, err := trafficSocket(nsB, e.ipA)
+	if err != nil {
+		return err
+	}
+	defer socketB.Close()
+
+	for i, frame := range frames {
+		socket, intf := socketA, intfA
+		if segments[i].reply {
+			socket, intf = socketB, intfB
+		}
+		if _, err := socket.Write(frame); err != nil {
+			return fmt.Errorf("failed to inject packet %d/%d on %s: %s", i+1, len(frames), intf, err)
+		}
+	}
+	return nil
+}
+
+// SendUDPFlow injects packets UDP datagrams carrying payloadSize bytes from
+// src to dst, given as host:port, on the interface of the namespace ns
+// routing dst. The root namespace is used if ns is empty.
+func SendUDPFlow(ns, src, dst string, packets, payloadSize int) error {
+	e, err := parseTrafficEndpoints(src, dst, true)
+	if err != nil {
+		return err
+	}
+
+	frames, err := forgeUDPFlow(e, packets, payloadSize)
+	if err != nil {
+		return err
+	}
+	return injectFrames(ns, e, frames)
+}
+
+// SendTCPHandshake injects the three packets of a TCP handshake between
+// src and dst, given as host:port, on the interface of the namespace ns
+// routing dst. The root namespace is used if ns is empty.
+func SendTCPHandshake(ns, src, dst string) error {
+	e, err := parseTrafficEndpoints(src, dst, true)
+	if err != nil {
+		return err
+	}
+
+	frames, err := forgeTCPHandshake(e)
+	if err != nil {
+		return err
+	}
+	return injectFrames(ns, e, frames)
+}
+
+// SendTCPSession injects a TCP connection from src to dst, given as
+// host:port, sending segments segments of payloadSize bytes, each one
+// acknowledged, before being closed. The packets of src are injected on the
+// interface of the namespace nsA routing dst, the ones of dst on the
+// interface of nsB routing src, the root namespace being used if empty.
+func SendTCPSession(nsA, nsB, src, dst string, segments, payloadSize int) error {
+	e, err := parseTrafficEndpoints(src, dst, true)
+	if err != nil {
+		return err
+	}
+
+	session := tcpSessionSegments(segments, payloadSize)
+	frames, err := forgeTCPSegments(e, session)
+	if err != nil {
+		return err
+	}
+	return injectSegments(nsA, nsB, e, session, frames)
+}
+
+// SendICMPEcho injects packets ICMP or ICMPv6 echo requests carrying
+// payloadSize bytes from src to dst and their replies, on the interface of
+// the namespace ns routing dst. The root namespace is used if ns is empty.
+func SendICMPEcho(ns, src, dst string, packets, payloadSize int) error {
+	e, err := parseTrafficEndpoints(src, dst, false)
+	if err != nil {
+		return err
+	}
+
+	frames, err := forgeICMPEcho(e, packets, payloadSize)
+	if err != nil {
+		return err
+	}
+	return injectFrames(ns, e, frames)
+}
+
+// SendICMPv6Echo injects packets ICMPv6 echo requests carrying payloadSize
+// bytes from src to dst and their replies like SendICMPEcho, failing if the
+// addresses are not IPv6 ones
+func SendICMPv6Echo(ns, src, dst string, packets, payloadSize int) error {
+	e, err := parseTrafficEndpoints(src, dst, false)
+	if err != nil {
+		return err
+	}
+	if !e.isIPv6() {
+		return fmt.Errorf("%s and %s are not IPv6 addresses", src, dst)
+	}
+
+	frames, err := forgeICMPEcho(e, packets, payloadSize)
+	if err != nil {
+		return err
+	}
+	return injectFrames(ns, e, frames)
+}
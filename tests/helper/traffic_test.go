@@ -0,0 +1,253 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/skydive-project/skydive/flow"
+)
+
+func decodeFrames(frames [][]byte) (packets []gopacket.Packet) {
+	for _, frame := range frames {
+		packets = append(packets, gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default))
+	}
+	return packets
+}
+
+func TestForgeUDPFlow(t *testing.T) {
+	for _, addrs := range [][]string{{"10.0.0.1:4000", "10.0.0.2:53"}, {"[fd00::1]:4000", "[fd00::2]:53"}} {
+		e, err := parseTrafficEndpoints(addrs[0], addrs[1], true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		frames, err := forgeUDPFlow(e, 5, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frames) != 5 {
+			t.Fatalf("expected 5 packets, got %d", len(frames))
+		}
+
+		for _, packet := range decodeFrames(frames) {
+			udp, ok := packet.Layer(layers.LayerTypeUDP).(*layers.UDP)
+			if !ok {
+				t.Fatalf("expected an UDP packet, got %s", packet)
+			}
+			if udp.SrcPort != 4000 || udp.DstPort != 53 || len(udp.Payload) != 100 {
+				t.Errorf("unexpected UDP packet %s", packet)
+			}
+			if nl := packet.NetworkLayer(); nl == nil || nl.NetworkFlow().Src().String() != e.ipA.String() {
+				t.Errorf("unexpected network layer %s", packet)
+			}
+		}
+	}
+}
+
+func TestForgeTCPHandshake(t *testing.T) {
+	e, err := parseTrafficEndpoints("10.0.0.1:4000", "10.0.0.2:80", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := forgeTCPHandshake(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		syn, ack bool
+		srcPort  layers.TCPPort
+	}{{true, false, 4000}, {true, true, 80}, {false, true, 4000}}
+
+	packets := decodeFrames(frames)
+	if len(packets) != len(expected) {
+		t.Fatalf("expected %d packets, got %d", len(expected), len(packets))
+	}
+	for i, packet := range packets {
+		tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+		if !ok || tcp.SYN != expected[i].syn || tcp.ACK != expected[i].ack || tcp.SrcPort != expected[i].srcPort {
+			t.Errorf("unexpected packet %d: %s", i, packet)
+		}
+	}
+}
+
+func TestForgeTCPSession(t *testing.T) {
+	e, err := parseTrafficEndpoints("10.0.0.1:4000", "10.0.0.2:80", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segments := tcpSessionSegments(2, 100)
+	frames, err := forgeTCPSegments(e, segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		syn, ack, fin bool
+		srcPort       layers.TCPPort
+		payload       int
+	}{
+		{true, false, false, 4000, 0}, {true, true, false, 80, 0}, {false, true, false, 4000, 0},
+		{false, true, false, 4000, 100}, {false, true, false, 80, 0},
+		{false, true, false, 4000, 100}, {false, true, false, 80, 0},
+		{false, true, true, 4000, 0}, {false, true, true, 80, 0}, {false, true, false, 4000, 0},
+	}
+
+	packets := decodeFrames(frames)
+	if len(packets) != len(expected) {
+		t.Fatalf("expected %d packets, got %d", len(expected), len(packets))
+	}
+
+	// each segment acknowledges the last byte sent by the peer
+	next := map[layers.TCPPort]uint32{}
+	for i, packet := range packets {
+		tcp, ok := packet.Layer(layers.LayerTypeTCP).(*layers.TCP)
+		if !ok || tcp.SYN != expected[i].syn || tcp.ACK != expected[i].ack || tcp.FIN != expected[i].fin ||
+			tcp.SrcPort != expected[i].srcPort || len(tcp.Payload) != expected[i].payload {
+			t.Fatalf("unexpected packet %d: %s", i, packet)
+		}
+		if segments[i].reply != (tcp.SrcPort == 80) {
+			t.Errorf("unexpected direction of packet %d: %s", i, packet)
+		}
+		if tcp.ACK && tcp.Ack != next[tcp.DstPort] {
+			t.Errorf("packet %d acknowledges %d, expected %d", i, tcp.Ack, next[tcp.DstPort])
+		}
+
+		next[tcp.SrcPort] = tcp.Seq + uint32(len(tcp.Payload))
+		if tcp.SYN || tcp.FIN {
+			next[tcp.SrcPort]++
+		}
+	}
+}
+
+func TestForgeICMPEcho(t *testing.T) {
+	for _, addrs := range [][]string{{"10.0.0.1", "10.0.0.2"}, {"fd00::1", "fd00::2"}} {
+		e, err := parseTrafficEndpoints(addrs[0], addrs[1], false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		frames, err := forgeICMPEcho(e, 3, 56)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		packets := decodeFrames(frames)
+		if len(packets) != 6 {
+			t.Fatalf("expected 6 packets, got %d", len(packets))
+		}
+		for i, packet := range packets {
+			src := packet.NetworkLayer().NetworkFlow().Src().String()
+			if (i%2 == 0 && src != addrs[0]) || (i%2 == 1 && src != addrs[1]) {
+				t.Errorf("unexpected source of packet %d: %s", i, packet)
+			}
+			if e.isIPv6() && packet.Layer(layers.LayerTypeICMPv6) == nil {
+				t.Errorf("expected an ICMPv6 packet, got %s", packet)
+			}
+			if !e.isIPv6() && packet.Layer(layers.LayerTypeICMPv4) == nil {
+				t.Errorf("expected an ICMPv4 packet, got %s", packet)
+			}
+		}
+	}
+}
+
+func TestParseTrafficEndpoints(t *testing.T) {
+	for _, addrs := range [][]string{{"10.0.0.1", "fd00::2"}, {"10.0.0.1:0", "10.0.0.2:80"}, {"host", "10.0.0.2"}} {
+		if _, err := parseTrafficEndpoints(addrs[0], addrs[1], addrs[0] == "10.0.0.1:0"); err == nil {
+			t.Errorf("expected an error for %v", addrs)
+		}
+	}
+}
+
+func framesSize(frames [][]byte) (size int64) {
+	for _, frame := range frames {
+		size += int64(len(frame))
+	}
+	return size
+}
+
+func TestTrafficExpectations(t *testing.T) {
+	for _, addrs := range [][]string{{"10.0.0.1", "10.0.0.2"}, {"fd00::1", "fd00::2"}} {
+		for _, payloadSize := range []int{0, 56} {
+			e, _ := parseTrafficEndpoints(addrs[0], addrs[1], false)
+			frames, err := forgeICMPEcho(e, 3, payloadSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectation := ICMPEchoExpectation(addrs[0], addrs[1], 3, payloadSize)
+			if expectation.MinPackets != int64(len(frames)) || expectation.MinBytes != framesSize(frames) {
+				t.Errorf("expected %d packets of %d bytes, got %s", len(frames), framesSize(frames), expectation.String())
+			}
+			if e.isIPv6() != (expectation.Proto == flow.FlowProtocol_IPV6) {
+				t.Errorf("unexpected protocol %s for %v", expectation.Proto, addrs)
+			}
+		}
+	}
+
+	e, _ := parseTrafficEndpoints("[fd00::1]:4000", "[fd00::2]:53", true)
+	frames, err := forgeUDPFlow(e, 5, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation, err := UDPFlowExpectation("[fd00::1]:4000", "[fd00::2]:53", 5, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectation.MinBytes != framesSize(frames) || expectation.A != "fd00::1" || expectation.PortB != 53 {
+		t.Errorf("unexpected UDP expectation %s", expectation.String())
+	}
+	for _, addrs := range [][]string{{"10.0.0.1:4000", "10.0.0.2:80"}, {"[fd00::1]:4000", "[fd00::2]:80"}} {
+		e, _ := parseTrafficEndpoints(addrs[0], addrs[1], true)
+		segments := tcpSessionSegments(3, 1000)
+		frames, err := forgeTCPSegments(e, segments)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ab, ba [][]byte
+		for i, frame := range frames {
+			if segments[i].reply {
+				ba = append(ba, frame)
+			} else {
+				ab = append(ab, frame)
+			}
+		}
+
+		expectation, err := TCPSessionExpectation(addrs[0], addrs[1], 3, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expectation.ABPackets != int64(len(ab)) || expectation.ABBytes != framesSize(ab) ||
+			expectation.BAPackets != int64(len(ba)) || expectation.BABytes != framesSize(ba) ||
+			expectation.MinBytes != framesSize(frames) {
+			t.Errorf("expected %d/%d packets of %d/%d bytes, got %s", len(ab), len(ba), framesSize(ab), framesSize(ba), expectation.String())
+		}
+	}
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// watchdogLogLines is the number of lines of each log file reported when
+// a watchdog expires
+const watchdogLogLines = 50
+
+// TestWatchdog reports a test running longer than its timeout
+type TestWatchdog struct {
+	sync.Mutex
+	t        testing.TB
+	timeout  time.Duration
+	deadline time.Time
+	timer    *time.Timer
+	ctx      context.Context
+	cancel   context.CancelFunc
+	out      io.Writer
+	stopped  bool
+	expired  bool
+}
+
+// watchdogs are the armed watchdogs, the last one being the one of the
+// running test
+var watchdogs struct {
+	sync.Mutex
+	armed []*TestWatchdog
+}
+
+// Watchdog arms a timer failing the test if it is still running after the
+// timeout. On expiry the name of the test, the stacks of all the goroutines
+// and the end of the logs are written to the standard error and the
+// context of the watchdog is cancelled, so that the retry and websocket
+// helpers give up and the rest of the suite can run. Stop has to be
+// called before the teardown, as the helpers fail once it expired.
+func Watchdog(t *testing.T, timeout time.Duration) *TestWatchdog {
+	return newWatchdog(t, timeout, os.Stderr)
+}
+
+func newWatchdog(t testing.TB, timeout time.Duration, out io.Writer) *TestWatchdog {
+	w := &TestWatchdog{
+		t:        t,
+		timeout:  timeout,
+		deadline: time.Now().Add(timeout),
+		out:      out,
+	}
+	w.ctx, w.cancel = context.WithDeadline(context.Background(), w.deadline)
+	w.timer = time.AfterFunc(timeout, w.expire)
+
+	watchdogs.Lock()
+	watchdogs.armed = append(watchdogs.armed, w)
+	watchdogs.Unlock()
+
+	return w
+}
+
+// goroutineDump returns the stacks of all the goroutines
+func goroutineDump() []byte {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func (w *TestWatchdog) expire() {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.expired = true
+
+	fmt.Fprintf(w.out, "=== WATCHDOG %s: still running after %s\n", w.t.Name(), w.timeout)
+	fmt.Fprintf(w.out, "%s\n", goroutineDump())
+	for _, file := range logFiles() {
+		data, err := tailFile(file, watchdogLogLines)
+		if err != nil {
+			fmt.Fprintf(w.out, "Failed to read %s: %s\n", file, err)
+			continue
+		}
+		fmt.Fprintf(w.out, "=== End of %s\n%s\n", file, data)
+	}
+
+	w.cancel()
+	w.t.Errorf("Watchdog expired after %s, see the goroutine dump", w.timeout)
+}
+
+// Context returns a context cancelled when the watchdog expires
+func (w *TestWatchdog) Context() context.Context {
+	return w.ctx
+}
+
+// Remaining returns the time left before the watchdog expires
+func (w *TestWatchdog) Remaining() time.Duration {
+	return time.Until(w.deadline)
+}
+
+// Expired returns whether the watchdog expired
+func (w *TestWatchdog) Expired() bool {
+	w.Lock()
+	defer w.Unlock()
+	return w.expired
+}
+
+// Stop disarms the watchdog. It can be called more than once, so that the
+// watchdog is stopped before undoing the setup of a test.
+func (w *TestWatchdog) Stop() {
+	w.Lock()
+	w.stopped = true
+	w.timer.Stop()
+	w.Unlock()
+
+	w.cancel()
+
+	watchdogs.Lock()
+	defer watchdogs.Unlock()
+
+	for i, armed := range watchdogs.armed {
+		if armed == w {
+			watchdogs.armed = append(watchdogs.armed[:i], watchdogs.armed[i+1:]...)
+			break
+		}
+	}
+}
+
+// watchdogContext returns the context of the watchdog of the running test,
+// the deadlines of the helpers being bounded by its remaining budget
+func watchdogContext() context.Context {
+	watchdogs.Lock()
+	defer watchdogs.Unlock()
+
+	if n := len(watchdogs.armed); n > 0 {
+		return watchdogs.armed[n-1].ctx
+	}
+	return context.Background()
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// watchdogTB records the errors reported by a watchdog
+type watchdogTB struct {
+	testing.TB
+	errors []string
+}
+
+func (t *watchdogTB) Name() string {
+	return "TestStuck"
+}
+
+func (t *watchdogTB) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestWatchdogExpire(t *testing.T) {
+	tb := &watchdogTB{}
+	var out bytes.Buffer
+
+	w := newWatchdog(tb, 200*time.Millisecond, &out)
+	defer w.Stop()
+
+	if w.Remaining() <= 0 || w.Remaining() > 200*time.Millisecond {
+		t.Errorf("Unexpected remaining budget: %s", w.Remaining())
+	}
+
+	// the retry helper gives up when the watchdog expires
+	start := time.Now()
+	err := Retry(time.Minute, 10*time.Millisecond, func() error { return errors.New("stuck") })
+	if err == nil || time.Since(start) > 5*time.Second {
+		t.Fatalf("Expected retry to be bounded by the watchdog, got %v after %s", err, time.Since(start))
+	}
+
+	<-w.Context().Done()
+	if !w.Expired() {
+		t.Fatal("Expected the watchdog to be expired")
+	}
+	if len(tb.errors) != 1 {
+		t.Errorf("Expected the test to fail once, got: %v", tb.errors)
+	}
+	if dump := out.String(); !strings.Contains(dump, "TestStuck") || !strings.Contains(dump, "goroutine ") {
+		t.Errorf("Expected the test name and the goroutine dump, got: %s", dump)
+	}
+
+	// the teardown commands still run once the expired watchdog is stopped
+	w.Stop()
+	if _, err := execCmd(Cmd{Cmd: "true"}); err != nil {
+		t.Errorf("Expected the command to run after stopping the watchdog, got: %s", err)
+	}
+}
+
+func TestWatchdogStop(t *testing.T) {
+	tb := &watchdogTB{}
+	w := newWatchdog(tb, 50*time.Millisecond, &bytes.Buffer{})
+	if watchdogContext() != w.Context() {
+		t.Error("Expected the helpers to use the context of the watchdog")
+	}
+	w.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if w.Expired() || len(tb.errors) != 0 {
+		t.Errorf("Stopped watchdog should not expire: %v", tb.errors)
+	}
+	if watchdogContext() == w.Context() {
+		t.Error("Stopped watchdog should not bound the helpers")
+	}
+}
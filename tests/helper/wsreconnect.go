@@ -0,0 +1,286 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// ErrSubscriberClosed is returned when reading from a closed subscriber
+var ErrSubscriberClosed = errors.New("subscriber closed")
+
+// ReconnectOpts defines the options of a reconnecting subscriber
+type ReconnectOpts struct {
+	WSOpts
+	// Resync sends a graph synchronization request after each reconnection
+	Resync bool
+	// Interval is the initial delay between two connection attempts, it is
+	// doubled after each attempt
+	Interval time.Duration
+	// Timeout bounds the duration of a reconnection, 30 seconds if zero
+	Timeout time.Duration
+}
+
+// bufferedMessage is a received message along with the generation of the
+// connection it was received on
+type bufferedMessage struct {
+	msg        *shttp.WSStructMessage
+	generation int
+}
+
+// ReconnectingSubscriber is a websocket subscriber which re-dials the
+// endpoint when the connection drops. The received messages are buffered
+// so that the ones received before a drop remain available.
+type ReconnectingSubscriber struct {
+	sync.RWMutex
+	endpoint    string
+	opts        ReconnectOpts
+	conn        *websocket.Conn
+	messages    []bufferedMessage
+	next        int
+	generation  int
+	disconnects int
+	replayed    int
+	updated     chan struct{}
+	err         error
+	quit        chan struct{}
+}
+
+// NewReconnectingSubscriber connects to the subscriber endpoint of the
+// analyzer and keeps the subscription alive until closed
+func NewReconnectingSubscriber(endpoint string, opts *ReconnectOpts) (*ReconnectingSubscriber, error) {
+	s := newReconnectingSubscriber(endpoint, opts)
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	go s.run()
+
+	return s, nil
+}
+
+func newReconnectingSubscriber(endpoint string, opts *ReconnectOpts) *ReconnectingSubscriber {
+	o := ReconnectOpts{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Protocol == "" {
+		o.Protocol = shttp.JsonProtocol
+	}
+	if o.Interval == 0 {
+		o.Interval = wsRetryInterval
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 30 * time.Second
+	}
+
+	return &ReconnectingSubscriber{
+		endpoint: endpoint,
+		opts:     o,
+		updated:  make(chan struct{}),
+		quit:     make(chan struct{}),
+	}
+}
+
+// connect dials the endpoint with an exponential backoff, the subscription
+// is carried by the headers of the request so that it is re-issued
+func (s *ReconnectingSubscriber) connect() error {
+	ctx, cancel := context.WithTimeout(watchdogContext(), s.opts.Timeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var conn *websocket.Conn
+	err := retry(ctx, s.opts.Interval, 2, func() (err error) {
+		conn, err = newWSClient(s.endpoint, &s.opts.WSOpts)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to connect to %s: %s", s.endpoint, err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.err != nil {
+		conn.Close()
+		return s.err
+	}
+	s.conn = conn
+	s.generation++
+
+	if s.opts.Resync && s.generation > 1 {
+		msg := shttp.NewWSStructMessage(graph.Namespace, graph.SyncRequestMsgType, graph.SyncRequestMsg{})
+		if err := conn.WriteMessage(websocket.TextMessage, msg.Bytes(s.opts.Protocol)); err != nil {
+			logging.GetLogger().Errorf("Failed to request a resync to %s: %s", s.endpoint, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *ReconnectingSubscriber) run() {
+	for {
+		s.RLock()
+		conn := s.conn
+		s.RUnlock()
+
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			if s.closed() {
+				return
+			}
+
+			logging.GetLogger().Debugf("Connection to %s dropped: %s", s.endpoint, err)
+			s.disconnected()
+
+			if err := s.connect(); err != nil {
+				s.stop(err)
+				return
+			}
+			continue
+		}
+
+		if msg := DecodeWSStructMessage(b, s.opts.Protocol); msg != nil {
+			s.push(msg)
+		}
+	}
+}
+
+func (s *ReconnectingSubscriber) push(msg *shttp.WSStructMessage) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.messages = append(s.messages, bufferedMessage{msg: msg, generation: s.generation})
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+func (s *ReconnectingSubscriber) disconnected() {
+	s.Lock()
+	s.disconnects++
+	s.conn.Close()
+	s.Unlock()
+}
+
+func (s *ReconnectingSubscriber) closed() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.err != nil
+}
+
+func (s *ReconnectingSubscriber) stop(err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.err == nil {
+		s.err = err
+		close(s.quit)
+	}
+}
+
+// Next returns the oldest message not consumed yet, waiting for it until
+// the timeout expires. Buffered messages are still returned once the
+// subscriber is closed.
+func (s *ReconnectingSubscriber) Next(timeout time.Duration) (*shttp.WSStructMessage, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.Lock()
+		if s.next < len(s.messages) {
+			m := s.messages[s.next]
+			s.next++
+			if m.generation < s.generation {
+				s.replayed++
+			}
+			s.Unlock()
+			return m.msg, nil
+		}
+		updated, err := s.updated, s.err
+		s.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-updated:
+		case <-s.quit:
+		case <-timer.C:
+			return nil, fmt.Errorf("No message received after %s", timeout)
+		}
+	}
+}
+
+// Messages returns all the messages received so far
+func (s *ReconnectingSubscriber) Messages() []*shttp.WSStructMessage {
+	s.RLock()
+	defer s.RUnlock()
+
+	messages := make([]*shttp.WSStructMessage, len(s.messages))
+	for i, m := range s.messages {
+		messages[i] = m.msg
+	}
+	return messages
+}
+
+// Disconnects returns the number of times the connection dropped
+func (s *ReconnectingSubscriber) Disconnects() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.disconnects
+}
+
+// Replayed returns the number of messages received before a drop and
+// consumed after it
+func (s *ReconnectingSubscriber) Replayed() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.replayed
+}
+
+// Close stops the subscriber, the buffered messages remain available
+func (s *ReconnectingSubscriber) Close() {
+	s.stop(ErrSubscriberClosed)
+
+	s.RLock()
+	defer s.RUnlock()
+	if s.conn != nil {
+		WSClose(s.conn)
+	}
+}
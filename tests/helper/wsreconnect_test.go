@@ -0,0 +1,76 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectingSubscriber(t *testing.T) {
+	s := newReconnectingSubscriber("127.0.0.1:8082", nil)
+	s.generation = 1
+
+	s.push(DecodeWSStructMessageJSON([]byte(`{"Namespace":"Graph","Type":"NodeAdded","Obj":{"ID":"a"}}`)))
+	s.push(DecodeWSStructMessageJSON([]byte(`{"Namespace":"Graph","Type":"NodeAdded","Obj":{"ID":"b"}}`)))
+
+	msg, err := s.Next(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != "NodeAdded" {
+		t.Fatalf("Unexpected message: %+v", msg)
+	}
+
+	// simulate a drop followed by a reconnection
+	s.disconnects++
+	s.generation++
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s.push(DecodeWSStructMessageJSON([]byte(`{"Namespace":"Graph","Type":"SyncReply","Obj":{}}`)))
+	}()
+
+	if msg, err = s.Next(time.Second); err != nil || msg.Type != "NodeAdded" {
+		t.Fatalf("Expected the message received before the drop, got: %+v (%v)", msg, err)
+	}
+	if msg, err = s.Next(5 * time.Second); err != nil || msg.Type != "SyncReply" {
+		t.Fatalf("Expected the message received after the drop, got: %+v (%v)", msg, err)
+	}
+
+	if s.Disconnects() != 1 || s.Replayed() != 1 {
+		t.Fatalf("Expected 1 disconnect and 1 replayed message, got %d and %d", s.Disconnects(), s.Replayed())
+	}
+
+	if _, err := s.Next(100 * time.Millisecond); err == nil {
+		t.Fatal("Expected a timeout when no message is pending")
+	}
+
+	s.Close()
+	if _, err := s.Next(time.Second); err != ErrSubscriberClosed {
+		t.Fatalf("Expected closed subscriber error, got: %v", err)
+	}
+	if len(s.Messages()) != 3 {
+		t.Fatalf("Expected the 3 received messages to remain available, got %d", len(s.Messages()))
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	shttp "github.com/skydive-project/skydive/http"
+)
+
+// wsMessageConn is the part of a websocket connection used by WSRequest
+type wsMessageConn interface {
+	WriteMessage(messageType int, data []byte) error
+	ReadMessage() (messageType int, p []byte, err error)
+	SetReadDeadline(t time.Time) error
+}
+
+// wsBuffers are the messages read by WSRequest which were not the expected
+// replies, indexed by connection
+var wsBuffers = struct {
+	sync.Mutex
+	conns map[wsMessageConn][]*shttp.WSStructMessage
+}{conns: make(map[wsMessageConn][]*shttp.WSStructMessage)}
+
+// WSRequest sends the message and returns the reply of the same UUID,
+// decoded with the protocol of the message, JSON if not set. The other
+// messages read meanwhile are kept for WSBufferedMessages. The requests on
+// a connection must not overlap and, a websocket read timeout being fatal,
+// the connection can't be used anymore once a request timed out.
+func WSRequest(conn *websocket.Conn, msg *shttp.WSStructMessage, timeout time.Duration) (*shttp.WSStructMessage, error) {
+	return wsRequest(conn, msg, timeout)
+}
+
+func wsRequest(conn wsMessageConn, msg *shttp.WSStructMessage, timeout time.Duration) (*shttp.WSStructMessage, error) {
+	protocol := msg.Protocol
+	if protocol == "" {
+		protocol = shttp.JsonProtocol
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, msg.Bytes(protocol)); err != nil {
+		return nil, fmt.Errorf("Failed to send %s/%s message %s: %s", msg.Namespace, msg.Type, msg.UUID, err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	var seen []string
+	for {
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("No reply to %s/%s message %s within %s, received [%s]: %s", msg.Namespace, msg.Type, msg.UUID, timeout, strings.Join(seen, ", "), err)
+		}
+
+		reply := DecodeWSStructMessage(b, protocol)
+		if reply == nil {
+			seen = append(seen, "invalid message")
+			continue
+		}
+		if reply.UUID == msg.UUID {
+			return reply, nil
+		}
+
+		seen = append(seen, reply.Namespace+"/"+reply.Type)
+		wsBuffers.Lock()
+		wsBuffers.conns[conn] = append(wsBuffers.conns[conn], reply)
+		wsBuffers.Unlock()
+	}
+}
+
+// WSBufferedMessages returns the messages read by WSRequest on the
+// connection which were not replies, and forgets them
+func WSBufferedMessages(conn *websocket.Conn) []*shttp.WSStructMessage {
+	return wsBufferedMessages(conn)
+}
+
+func wsBufferedMessages(conn wsMessageConn) []*shttp.WSStructMessage {
+	wsBuffers.Lock()
+	defer wsBuffers.Unlock()
+
+	messages := wsBuffers.conns[conn]
+	delete(wsBuffers.conns, conn)
+	return messages
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	shttp "github.com/skydive-project/skydive/http"
+)
+
+// fakeWSConn replies to each written message with the events then with the
+// reply of the same UUID, unless reply is false
+type fakeWSConn struct {
+	protocol string
+	events   []*shttp.WSStructMessage
+	reply    bool
+	pending  [][]byte
+	deadline time.Time
+}
+
+func (c *fakeWSConn) WriteMessage(messageType int, data []byte) error {
+	msg := DecodeWSStructMessage(data, c.protocol)
+	if msg == nil {
+		return errors.New("invalid message")
+	}
+
+	for _, event := range c.events {
+		c.pending = append(c.pending, event.Bytes(c.protocol))
+	}
+	if c.reply {
+		reply := shttp.NewWSStructMessage(msg.Namespace, msg.Type+"Reply", map[string]string{"Status": "done"}, msg.UUID)
+		c.pending = append(c.pending, reply.Bytes(c.protocol))
+	}
+	return nil
+}
+
+func (c *fakeWSConn) ReadMessage() (int, []byte, error) {
+	if len(c.pending) == 0 {
+		return 0, nil, errors.New("i/o timeout")
+	}
+	b := c.pending[0]
+	c.pending = c.pending[1:]
+	return 1, b, nil
+}
+
+func (c *fakeWSConn) SetReadDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+func TestWSRequest(t *testing.T) {
+	for _, protocol := range []string{shttp.JsonProtocol, shttp.ProtobufProtocol} {
+		conn := &fakeWSConn{
+			protocol: protocol,
+			events: []*shttp.WSStructMessage{
+				shttp.NewWSStructMessage("Graph", "NodeAdded", map[string]string{"ID": "node1"}),
+				shttp.NewWSStructMessage("Alert", "AlertEvent", map[string]string{"ID": "alert1"}),
+			},
+			reply: true,
+		}
+
+		msg := shttp.NewWSStructMessage("Graph", "SyncRequest", map[string]string{}, "a1b2c3")
+		msg.Protocol = protocol
+
+		reply, err := wsRequest(conn, msg, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reply.UUID != "a1b2c3" || reply.Type != "SyncRequestReply" || reply.Protocol != protocol {
+			t.Fatalf("Wrong %s reply: %s", protocol, reply.Debug())
+		}
+
+		if !conn.deadline.IsZero() {
+			t.Fatalf("Read deadline should be reset after the request, got: %s", conn.deadline)
+		}
+
+		buffered := wsBufferedMessages(conn)
+		if len(buffered) != 2 || buffered[0].Type != "NodeAdded" || buffered[1].Type != "AlertEvent" {
+			t.Fatalf("Unrelated %s messages should be buffered, got: %v", protocol, buffered)
+		}
+
+		if buffered := wsBufferedMessages(conn); len(buffered) != 0 {
+			t.Fatalf("Buffered messages should be forgotten once returned, got: %v", buffered)
+		}
+	}
+}
+
+func TestWSRequestTimeout(t *testing.T) {
+	conn := &fakeWSConn{
+		protocol: shttp.JsonProtocol,
+		events: []*shttp.WSStructMessage{
+			shttp.NewWSStructMessage("Graph", "NodeUpdated", map[string]string{"ID": "node1"}),
+		},
+	}
+
+	msg := shttp.NewWSStructMessage("Graph", "SyncRequest", map[string]string{}, "d4e5f6")
+	_, err := wsRequest(conn, msg, time.Second)
+	if err == nil {
+		t.Fatal("Request without reply should fail")
+	}
+
+	if !strings.Contains(err.Error(), "d4e5f6") || !strings.Contains(err.Error(), "[Graph/NodeUpdated]") {
+		t.Fatalf("Error should mention the request and the messages received, got: %s", err)
+	}
+
+	if buffered := wsBufferedMessages(conn); len(buffered) != 1 {
+		t.Fatalf("Unrelated messages should be buffered on timeout, got: %v", buffered)
+	}
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/skydive-project/skydive/flow"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/statics"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// flowSchema describes the flows as published on the flow namespace, the
+// fields being omitted when empty
+const flowSchema = `{
+  "$schema": "http://json-schema.org/draft-04/schema#",
+  "id": "http://skydive.network/schemas/flow.json",
+  "type": "object",
+  "definitions": {
+    "flowLayer": {
+      "type": "object",
+      "properties": {
+        "Protocol": { "type": "string" },
+        "A": { "type": "string" },
+        "B": { "type": "string" },
+        "ID": { "type": "integer" }
+      }
+    },
+    "transportLayer": {
+      "type": "object",
+      "properties": {
+        "Protocol": { "type": "string" },
+        "A": { "type": "integer" },
+        "B": { "type": "integer" },
+        "ID": { "type": "integer" }
+      }
+    },
+    "icmpLayer": {
+      "type": "object",
+      "properties": {
+        "Type": { "type": "string" },
+        "Code": { "type": "integer" },
+        "ID": { "type": "integer" }
+      }
+    },
+    "metric": {
+      "type": "object",
+      "properties": {
+        "ABPackets": { "type": "integer" },
+        "ABBytes": { "type": "integer" },
+        "BAPackets": { "type": "integer" },
+        "BABytes": { "type": "integer" },
+        "Start": { "type": "integer" },
+        "Last": { "type": "integer" }
+      }
+    }
+  },
+  "properties": {
+    "UUID": { "type": "string", "minLength": 1 },
+    "LayersPath": { "type": "string" },
+    "Application": { "type": "string" },
+    "Link": { "$ref": "#/definitions/flowLayer" },
+    "Network": { "$ref": "#/definitions/flowLayer" },
+    "Transport": { "$ref": "#/definitions/transportLayer" },
+    "ICMP": { "$ref": "#/definitions/icmpLayer" },
+    "LastUpdateMetric": { "$ref": "#/definitions/metric" },
+    "Metric": { "$ref": "#/definitions/metric" },
+    "TCPMetric": { "type": "object" },
+    "IPMetric": { "type": "object" },
+    "Start": { "type": "integer" },
+    "Last": { "type": "integer" },
+    "RTT": { "type": "integer" },
+    "TrackingID": { "type": "string" },
+    "L3TrackingID": { "type": "string" },
+    "ParentUUID": { "type": "string" },
+    "NodeTID": { "type": "string" }
+  },
+  "required": [ "UUID" ]
+}`
+
+// wsSchemas holds the schemas of the elements carried by the messages
+var wsSchemas struct {
+	sync.Once
+	node, edge, flow gojsonschema.JSONLoader
+	err              error
+}
+
+func loadWSSchemas() error {
+	wsSchemas.Do(func() {
+		node, err := statics.Asset("statics/schemas/node.schema")
+		if err != nil {
+			wsSchemas.err = err
+			return
+		}
+
+		edge, err := statics.Asset("statics/schemas/edge.schema")
+		if err != nil {
+			wsSchemas.err = err
+			return
+		}
+
+		wsSchemas.node = gojsonschema.NewBytesLoader(node)
+		wsSchemas.edge = gojsonschema.NewBytesLoader(edge)
+		wsSchemas.flow = gojsonschema.NewStringLoader(flowSchema)
+	})
+	return wsSchemas.err
+}
+
+// wsMessagePayload returns the raw JSON object of the message, the protobuf
+// messages carrying JSON objects too
+func wsMessagePayload(msg *shttp.WSStructMessage) []byte {
+	if msg.Protocol == shttp.ProtobufProtocol {
+		return msg.ProtobufObj
+	}
+	if msg.JsonObj == nil {
+		return nil
+	}
+	return *msg.JsonObj
+}
+
+// validateJSON validates the document against the schema
+func validateJSON(schema gojsonschema.JSONLoader, doc []byte) error {
+	result, err := gojsonschema.Validate(schema, gojsonschema.NewBytesLoader(doc))
+	if err != nil {
+		return err
+	}
+	if !result.Valid() {
+		var errs []string
+		for _, e := range result.Errors() {
+			errs = append(errs, e.String())
+		}
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validateJSONList validates each element of the list against the schema
+func validateJSONList(schema gojsonschema.JSONLoader, list []json.RawMessage) error {
+	for i, doc := range list {
+		if err := validateJSON(schema, doc); err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// validateGraphMessage validates the payload of a graph namespace message
+func validateGraphMessage(msgType string, payload []byte) error {
+	switch msgType {
+	case graph.NodeAddedMsgType, graph.NodeUpdatedMsgType, graph.NodeDeletedMsgType:
+		return validateJSON(wsSchemas.node, payload)
+	case graph.EdgeAddedMsgType, graph.EdgeUpdatedMsgType, graph.EdgeDeletedMsgType:
+		return validateJSON(wsSchemas.edge, payload)
+	case graph.SyncMsgType, graph.SyncReplyMsgType:
+		var sync struct {
+			Nodes []json.RawMessage
+			Edges []json.RawMessage
+		}
+		if err := json.Unmarshal(payload, &sync); err != nil {
+			return err
+		}
+		if err := validateJSONList(wsSchemas.node, sync.Nodes); err != nil {
+			return fmt.Errorf("Nodes: %s", err)
+		}
+		if err := validateJSONList(wsSchemas.edge, sync.Edges); err != nil {
+			return fmt.Errorf("Edges: %s", err)
+		}
+		return nil
+	case graph.HostGraphDeletedMsgType:
+		var host string
+		return json.Unmarshal(payload, &host)
+	case graph.SyncRequestMsgType:
+		var request map[string]interface{}
+		return json.Unmarshal(payload, &request)
+	}
+	return fmt.Errorf("no schema for message type %s", msgType)
+}
+
+// validateFlowMessage validates the payload of a flow namespace message,
+// holding a single flow or a list of flows like the initial sync
+func validateFlowMessage(payload []byte) error {
+	var flows []json.RawMessage
+	if err := json.Unmarshal(payload, &flows); err == nil {
+		return validateJSONList(wsSchemas.flow, flows)
+	}
+	return validateJSON(wsSchemas.flow, payload)
+}
+
+// indentJSON returns the JSON document pretty-printed, or as is if invalid
+func indentJSON(doc []byte) string {
+	var b bytes.Buffer
+	if err := json.Indent(&b, doc, "", "  "); err != nil {
+		return string(doc)
+	}
+	return b.String()
+}
+
+// ValidateWSMessage checks the object of a graph or flow namespace message
+// against the schema of its namespace and type. The error holds the
+// offending payload pretty-printed.
+func ValidateWSMessage(msg *shttp.WSStructMessage) error {
+	if err := loadWSSchemas(); err != nil {
+		return fmt.Errorf("Failed to load the message schemas: %s", err)
+	}
+
+	payload := wsMessagePayload(msg)
+	if payload == nil {
+		return fmt.Errorf("Invalid %s %s message: no object", msg.Namespace, msg.Type)
+	}
+
+	var err error
+	switch msg.Namespace {
+	case graph.Namespace:
+		err = validateGraphMessage(msg.Type, payload)
+	case flow.Namespace:
+		err = validateFlowMessage(payload)
+	default:
+		err = fmt.Errorf("no schema for namespace %s", msg.Namespace)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Invalid %s %s message: %s\n%s", msg.Namespace, msg.Type, err, indentJSON(payload))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package helper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateWSMessage(t *testing.T) {
+	valid := []string{
+		`{"Namespace":"Flow","Type":"SyncReply","Obj":[{"UUID":"a","Last":2},{"UUID":"b","Network":{"Protocol":"IPV4","A":"192.168.0.1","B":"192.168.0.2"}}]}`,
+		`{"Namespace":"Flow","Type":"FlowUpdated","Obj":{"UUID":"a","Metric":{"ABPackets":1,"ABBytes":64}}}`,
+		`{"Namespace":"Graph","Type":"NodeAdded","Obj":{"ID":"n1","Metadata":{"Type":"device","Name":"eth0"}}}`,
+		`{"Namespace":"Graph","Type":"EdgeDeleted","Obj":{"ID":"e1","Parent":"n1","Child":"n2","Metadata":{"RelationType":"ownership"}}}`,
+		`{"Namespace":"Graph","Type":"SyncReply","Obj":{"Nodes":[{"ID":"n1","Metadata":{"Type":"netns","Path":"/var/run/netns/ns1"}}],"Edges":[]}}`,
+		`{"Namespace":"Graph","Type":"HostGraphDeleted","Obj":"host1"}`,
+	}
+	for _, m := range valid {
+		if err := ValidateWSMessage(DecodeWSStructMessageJSON([]byte(m))); err != nil {
+			t.Errorf("Expected message %s to be valid: %s", m, err)
+		}
+	}
+
+	invalid := []string{
+		// the metric packets field renamed
+		`{"Namespace":"Flow","Type":"FlowUpdated","Obj":{"UUID":"a","Metric":{"ABPackets":"1"}}}`,
+		`{"Namespace":"Flow","Type":"SyncReply","Obj":[{"UUID":"a"},{"Last":1}]}`,
+		`{"Namespace":"Graph","Type":"NodeAdded","Obj":{"Id":"n1","Metadata":{"Type":"device"}}}`,
+		`{"Namespace":"Graph","Type":"SyncReply","Obj":{"Nodes":[{"ID":"n1","Metadata":{"Type":"netns"}}]}}`,
+		`{"Namespace":"Graph","Type":"HostGraphDeleted","Obj":{"Host":"host1"}}`,
+		`{"Namespace":"Graph","Type":"Unknown","Obj":{}}`,
+		`{"Namespace":"Unknown","Type":"Unknown","Obj":{}}`,
+	}
+	for _, m := range invalid {
+		if err := ValidateWSMessage(DecodeWSStructMessageJSON([]byte(m))); err == nil {
+			t.Errorf("Expected message %s to be invalid", m)
+		}
+	}
+
+	// the offending payload is pretty-printed
+	err := ValidateWSMessage(DecodeWSStructMessageJSON([]byte(`{"Namespace":"Flow","Type":"FlowUpdated","Obj":{"Start":"now"}}`)))
+	if err == nil || !strings.Contains(err.Error(), "{\n  \"Start\": \"now\"\n}") {
+		t.Errorf("Expected the payload in the error, got: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (C) 2016 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/skydive-project/skydive/tests/helper"
+)
+
+// BenchmarkFlowIngestion replays packets into a pcapsocket capture at the
+// rates given by -bench.rates. Run it with each -analyzer.flow.backend to
+// compare the storage backends.
+func BenchmarkFlowIngestion(b *testing.B) {
+	if !helper.Standalone {
+		b.Skip("Ingestion benchmark requires the standalone mode")
+	}
+
+	opts, err := helper.IngestionOptionsFromFlags()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	helper.ExecCmds(b, helper.Cmd{Cmd: "ovs-vsctl add-br br-bench", Check: true})
+	defer helper.ExecCmds(b, helper.Cmd{Cmd: "ovs-vsctl del-br br-bench", Check: true})
+
+	capture := helper.StartCapture(b, `G.V().Has("Name", "br-bench", "Type", "ovsbridge")`, helper.CaptureOptions{Type: "pcapsocket"})
+	defer capture.Delete()
+
+	for i := 0; i < b.N; i++ {
+		steps, err := helper.RunIngestion(capture, opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Logf("Flow backend '%s':\n%s", helper.FlowBackend, helper.IngestionReport(steps))
+	}
+}
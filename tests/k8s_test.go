@@ -50,14 +50,14 @@ const (
 
 func setupFromConfigFile(file string) []helper.Cmd {
 	return []helper.Cmd{
-		{"kubectl create -f " + k8sConfigFile(file), true},
+		{Cmd: "kubectl create -f " + k8sConfigFile(file), Check: true},
 	}
 }
 
 func tearDownFromConfigFile(file string) []helper.Cmd {
 	return []helper.Cmd{
-		{"kubectl delete --grace-period=0 --force -f " + k8sConfigFile(file), false},
-		{"sleep 2", true},
+		{Cmd: "kubectl delete --grace-period=0 --force -f " + k8sConfigFile(file), Check: false},
+		{Cmd: "sleep 2", Check: true},
 	}
 }
 
@@ -252,6 +252,20 @@ func TestK8sStorageClassNode(t *testing.T) {
 	testNodeCreationFromConfig(t, "storageclass", objName+"-storageclass")
 }
 
+/* -- test the objects deployed in the kind cluster -- */
+func TestK8sKindTopology(t *testing.T) {
+	helper.RequireKindCluster(t)
+
+	const timeout = 2 * time.Minute
+	for _, args := range [][]interface{}{
+		makeHasArgsType("namespace", "Name", helper.KindNamespace),
+		makeHasArgsType("pod", "Namespace", helper.KindNamespace, "Name", helper.KindPod),
+		makeHasArgsType("service", "Namespace", helper.KindNamespace, "Name", helper.KindService),
+	} {
+		helper.WaitForNodes(t, g.G.V().Has(args...).String(), 1, helper.Exactly, timeout)
+	}
+}
+
 /* -- test multi-node scenarios -- */
 func TestK8sIngressScenario1(t *testing.T) {
 	file := "ingress1"
@@ -286,10 +300,10 @@ func TestHelloNodeScenario(t *testing.T) {
 	testRunner(
 		t,
 		[]helper.Cmd{
-			{"kubectl run hello-node --image=hello-node:v1 --port=8080", true},
+			{Cmd: "kubectl run hello-node --image=hello-node:v1 --port=8080", Check: true},
 		},
 		[]helper.Cmd{
-			{"kubectl delete --grace-period=0 --force deploy hello-node", false},
+			{Cmd: "kubectl delete --grace-period=0 --force deploy hello-node", Check: false},
 		},
 		[]CheckFunction{
 			func(c *CheckContext) error {
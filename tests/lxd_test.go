@@ -44,11 +44,11 @@ func TestLxdSimple(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"lxc launch images:fedora/27 test-skydive-lxd-simple", false},
+			{Cmd: "lxc launch images:fedora/27 test-skydive-lxd-simple", Check: false},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"lxc delete --force test-skydive-lxd-simple", false},
+			{Cmd: "lxc delete --force test-skydive-lxd-simple", Check: false},
 		},
 
 		mode: Replay,
@@ -135,13 +135,13 @@ func TestNeutron(t *testing.T) {
 	ovsctl += ` external-ids:iface-status=active external-ids:attached-mac=%s external-ids:vm-uuid=skydive-vm type=internal`
 
 	setupCmds := []helper.Cmd{
-		{fmt.Sprintf(ovsctl, dev, dev, port.ID, port.MACAddress), true},
-		{"sleep 1", true},
-		{fmt.Sprintf("ip link set %s up", dev), true},
+		{Cmd: fmt.Sprintf(ovsctl, dev, dev, port.ID, port.MACAddress), Check: true},
+		{Cmd: "sleep 1", Check: true},
+		{Cmd: fmt.Sprintf("ip link set %s up", dev), Check: true},
 	}
 
 	tearDownCmds := []helper.Cmd{
-		{fmt.Sprintf("ovs-vsctl del-port %s", dev), true},
+		{Cmd: fmt.Sprintf("ovs-vsctl del-port %s", dev), Check: true},
 	}
 	helper.ExecCmds(t, setupCmds...)
 	defer helper.ExecCmds(t, tearDownCmds...)
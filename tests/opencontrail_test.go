@@ -12,13 +12,13 @@ import (
 func TestOpenContrailTopology(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"contrail-create-network.py default-domain:default-project:vn1", true},
-			{"netns-daemon-start -n default-domain:default-project:vn1 vm1", true},
+			{Cmd: "contrail-create-network.py default-domain:default-project:vn1", Check: true},
+			{Cmd: "netns-daemon-start -n default-domain:default-project:vn1 vm1", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
 			// We should delete the net
-			{"netns-daemon-stop vm1", true},
+			{Cmd: "netns-daemon-stop vm1", Check: true},
 		},
 
 		mode: Replay,
@@ -45,12 +45,12 @@ func TestOpenContrailTopology(t *testing.T) {
 func TestOpenContrailRoutingTable(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"contrail-create-network.py default-domain:default-project:vn1", true},
-			{"netns-daemon-start -n default-domain:default-project:vn1 vm1", true},
+			{Cmd: "contrail-create-network.py default-domain:default-project:vn1", Check: true},
+			{Cmd: "netns-daemon-start -n default-domain:default-project:vn1 vm1", Check: true},
 		},
 		tearDownCmds: []helper.Cmd{
 			// We should delete the net
-			{"netns-daemon-stop vm1", true},
+			{Cmd: "netns-daemon-stop vm1", Check: true},
 		},
 
 		mode: Replay,
@@ -37,9 +37,9 @@ func verify(c *CheckContext, expected []int) error {
 func makeTest(t *testing.T, rules []ruleCmd, expected []int) {
 	checkTest(t)
 	setupCmds := []helper.Cmd{
-		{"ovs-vsctl add-br br-testof1", true},
-		{"ovs-vsctl add-port br-testof1 intf1 -- set interface intf1 type=internal", true},
-		{"ovs-vsctl add-port br-testof1 intf2 -- set interface intf2 type=internal", true},
+		{Cmd: "ovs-vsctl add-br br-testof1", Check: true},
+		{Cmd: "ovs-vsctl add-port br-testof1 intf1 -- set interface intf1 type=internal", Check: true},
+		{Cmd: "ovs-vsctl add-port br-testof1 intf2 -- set interface intf2 type=internal", Check: true},
 	}
 
 	for _, ruleCmd := range rules {
@@ -56,7 +56,7 @@ func makeTest(t *testing.T, rules []ruleCmd, expected []int) {
 		setupCmds: setupCmds,
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-testof1", true},
+			{Cmd: "ovs-vsctl del-br br-testof1", Check: true},
 		},
 
 		mode: Replay,
@@ -102,13 +102,13 @@ func TestSuperimposedOFRule(t *testing.T) {
 func TestDelRuleWithBridgeOFRule(t *testing.T) {
 	checkTest(t)
 	setupCmds := []helper.Cmd{
-		{"ovs-vsctl add-br br-testof1", true},
-		{"ovs-vsctl add-port br-testof1 intf1 -- set interface intf1 type=internal", true},
-		{"ovs-vsctl add-port br-testof1 intf2 -- set interface intf2 type=internal", true},
-		{"sleep 1", true},
-		{"ovs-ofctl add-flow br-testof1 table=0,priority=1,actions=resubmit(,1)", true},
-		{"sleep 1", true},
-		{"ovs-vsctl del-br br-testof1", true},
+		{Cmd: "ovs-vsctl add-br br-testof1", Check: true},
+		{Cmd: "ovs-vsctl add-port br-testof1 intf1 -- set interface intf1 type=internal", Check: true},
+		{Cmd: "ovs-vsctl add-port br-testof1 intf2 -- set interface intf2 type=internal", Check: true},
+		{Cmd: "sleep 1", Check: true},
+		{Cmd: "ovs-ofctl add-flow br-testof1 table=0,priority=1,actions=resubmit(,1)", Check: true},
+		{Cmd: "sleep 1", Check: true},
+		{Cmd: "ovs-vsctl del-br br-testof1", Check: true},
 	}
 
 	test := &Test{
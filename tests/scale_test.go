@@ -273,7 +273,7 @@ func genICMPv4(t *testing.T, scale, src string, dst string, count int) error {
 	var seen int
 	pingFnc := func() error {
 		setupCmds := []helper.Cmd{
-			{fmt.Sprintf("%s ping %s %s -c 1", scale, src, dst), false},
+			{Cmd: fmt.Sprintf("%s ping %s %s -c 1", scale, src, dst), Check: false},
 		}
 		if err := helper.ExecCmds(t, setupCmds...); err == nil {
 			seen++
@@ -291,13 +291,13 @@ func TestScaleHA(t *testing.T) {
 	scale := gopath + "/src/github.com/skydive-project/skydive/scripts/scale.sh"
 
 	setupCmds := []helper.Cmd{
-		{fmt.Sprintf("%s start 2 2 2", scale), true},
-		{"sleep 30", false},
+		{Cmd: fmt.Sprintf("%s start 2 2 2", scale), Check: true},
+		{Cmd: "sleep 30", Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
 	tearDownCmds := []helper.Cmd{
-		{fmt.Sprintf("%s stop 2 4 2", scale), false},
+		{Cmd: fmt.Sprintf("%s stop 2 4 2", scale), Check: false},
 	}
 	defer helper.ExecCmds(t, tearDownCmds...)
 
@@ -361,7 +361,7 @@ func TestScaleHA(t *testing.T) {
 
 	// increase the agent number
 	setupCmds = []helper.Cmd{
-		{fmt.Sprintf("%s start 2 4 2", scale), false},
+		{Cmd: fmt.Sprintf("%s start 2 4 2", scale), Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
@@ -379,7 +379,7 @@ func TestScaleHA(t *testing.T) {
 
 	// kill the last agent
 	setupCmds = []helper.Cmd{
-		{fmt.Sprintf("%s stop-agent 4", scale), false},
+		{Cmd: fmt.Sprintf("%s stop-agent 4", scale), Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
@@ -404,8 +404,8 @@ func TestScaleHA(t *testing.T) {
 
 	// destroy the second analyzer
 	setupCmds = []helper.Cmd{
-		{fmt.Sprintf("%s stop-analyzer 2", scale), false},
-		{"sleep 5", false},
+		{Cmd: fmt.Sprintf("%s stop-analyzer 2", scale), Check: false},
+		{Cmd: "sleep 5", Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
@@ -434,7 +434,7 @@ func TestScaleHA(t *testing.T) {
 
 	// iperf test  10 sec, 1Mbits/s
 	setupCmds = []helper.Cmd{
-		{fmt.Sprintf("%s iperf agent-3-vm1 agent-1-vm1", scale), false},
+		{Cmd: fmt.Sprintf("%s iperf agent-3-vm1 agent-1-vm1", scale), Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 	if err = checkIPerfFlows(gh, 2); err != nil {
@@ -451,8 +451,8 @@ func TestScaleHA(t *testing.T) {
 
 	// restore the second analyzer
 	setupCmds = []helper.Cmd{
-		{fmt.Sprintf("%s start 2 3 2", scale), false},
-		{"sleep 5", false},
+		{Cmd: fmt.Sprintf("%s start 2 3 2", scale), Check: false},
+		{Cmd: "sleep 5", Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
@@ -465,7 +465,7 @@ func TestScaleHA(t *testing.T) {
 
 	// delete an agent
 	setupCmds = []helper.Cmd{
-		{fmt.Sprintf("%s stop-agent 1", scale), false},
+		{Cmd: fmt.Sprintf("%s stop-agent 1", scale), Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
@@ -477,8 +477,8 @@ func TestScaleHA(t *testing.T) {
 
 	// restart the agent 1 to check that flows are still forwarded to analyzer
 	setupCmds = []helper.Cmd{
-		{fmt.Sprintf("%s start 2 3 2", scale), false},
-		{"sleep 5", false},
+		{Cmd: fmt.Sprintf("%s start 2 3 2", scale), Check: false},
+		{Cmd: "sleep 5", Check: false},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
@@ -522,7 +522,7 @@ func (s *seleniumHelper) moveOnByID(id string) error {
 
 func (s *seleniumHelper) startVideoRecord(name string) {
 	cmds := []helper.Cmd{
-		{"docker exec grid start-video", true},
+		{Cmd: "docker exec grid start-video", Check: true},
 	}
 	helper.ExecCmds(s.t, cmds...)
 	s.currVideoName = name
@@ -530,9 +530,9 @@ func (s *seleniumHelper) startVideoRecord(name string) {
 
 func (s *seleniumHelper) stopVideoRecord() {
 	cmds := []helper.Cmd{
-		{"docker exec grid stop-video", true},
-		{"docker cp grid:/videos/. .", true},
-		{fmt.Sprintf("mv cdd.mp4 %s.mp4", s.currVideoName), true},
+		{Cmd: "docker exec grid stop-video", Check: true},
+		{Cmd: "docker cp grid:/videos/. .", Check: true},
+		{Cmd: fmt.Sprintf("mv cdd.mp4 %s.mp4", s.currVideoName), Check: true},
 	}
 	helper.ExecCmds(s.t, cmds...)
 
@@ -543,17 +543,17 @@ func (s *seleniumHelper) quit() {
 	s.webdriver.Quit()
 
 	tearDownCmds := []helper.Cmd{
-		{"docker stop grid", true},
-		{"docker rm -f grid", true},
+		{Cmd: "docker stop grid", Check: true},
+		{Cmd: "docker rm -f grid", Check: true},
 	}
 	helper.ExecCmds(s.t, tearDownCmds...)
 }
 
 func newSeleniumHelper(t *testing.T, analyzerAddr string, analyzerPort int) (*seleniumHelper, error) {
 	setupCmds := []helper.Cmd{
-		{"docker pull skydive/cdd-docker-selenium", true},
-		{"docker run -d --name=grid -p 4444:24444 -p 5900:25900 -e --shm-size=1g -p 6080:26080 -e SCREEN_WIDTH=1600 -e SCREEN_HEIGHT=1000 -e NOVNC=true -e VIDEO_FILE_NAME=cdd skydive/cdd-docker-selenium", true},
-		{"docker exec grid wait_all_done 30s", true},
+		{Cmd: "docker pull skydive/cdd-docker-selenium", Check: true},
+		{Cmd: "docker run -d --name=grid -p 4444:24444 -p 5900:25900 -e --shm-size=1g -p 6080:26080 -e SCREEN_WIDTH=1600 -e SCREEN_HEIGHT=1000 -e NOVNC=true -e VIDEO_FILE_NAME=cdd skydive/cdd-docker-selenium", Check: true},
+		{Cmd: "docker exec grid wait_all_done 30s", Check: true},
 	}
 	helper.ExecCmds(t, setupCmds...)
 
@@ -7,7 +7,6 @@ import (
 	"github.com/skydive-project/skydive/api/client"
 	"github.com/skydive-project/skydive/api/types"
 	"github.com/skydive-project/skydive/common"
-	shttp "github.com/skydive-project/skydive/http"
 	"github.com/skydive-project/skydive/tests/helper"
 )
 
@@ -16,23 +15,23 @@ func TestStartupCapture(t *testing.T) {
 		mode: OneShot,
 
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-startup", true},
-			{"ovs-vsctl add-port br-startup startup-intf2 -- set interface startup-intf2 type=internal", true},
-			{"ip netns add startup-vm2", true},
-			{"ip link set startup-intf2 netns startup-vm2", true},
-			{"ip netns exec startup-vm2 ip address add 169.254.33.34/24 dev startup-intf2", true},
-			{"ip netns exec startup-vm2 ip link set startup-intf2 up", true},
+			{Cmd: "ovs-vsctl add-br br-startup", Check: true},
+			{Cmd: "ovs-vsctl add-port br-startup startup-intf2 -- set interface startup-intf2 type=internal", Check: true},
+			{Cmd: "ip netns add startup-vm2", Check: true},
+			{Cmd: "ip link set startup-intf2 netns startup-vm2", Check: true},
+			{Cmd: "ip netns exec startup-vm2 ip address add 169.254.33.34/24 dev startup-intf2", Check: true},
+			{Cmd: "ip netns exec startup-vm2 ip link set startup-intf2 up", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del startup-vm2", true},
-			{"ovs-vsctl del-br br-startup", true},
+			{Cmd: "ip netns del startup-vm2", Check: true},
+			{Cmd: "ovs-vsctl del-br br-startup", Check: true},
 		},
 
 		checks: []CheckFunction{func(c *CheckContext) error {
 			//Get config captures
 			var captures map[string]types.Capture
-			cl, err := client.NewCrudClientFromConfig(&shttp.AuthenticationOpts{})
+			cl, err := client.NewCrudClientFromConfig(helper.AuthenticationOpts())
 			if err != nil {
 				return err
 			}
@@ -52,7 +51,7 @@ func TestStartupCapture(t *testing.T) {
 				return fmt.Errorf("Preconfigured capture wasn't created!")
 			}
 
-			gh := client.NewGremlinQueryHelper(&shttp.AuthenticationOpts{})
+			gh := client.NewGremlinQueryHelper(helper.AuthenticationOpts())
 
 			nodes, err := gh.GetNodes(capture.GremlinQuery)
 			if err != nil {
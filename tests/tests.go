@@ -48,20 +48,41 @@ const (
 	Replay
 )
 
+// standaloneAgents and standaloneAnalyzers are the agents and analyzers
+// started in addition to the in-process ones when running in standalone mode
+var (
+	standaloneAgents    []*helper.AgentProcess
+	standaloneAnalyzers []*helper.AnalyzerProcess
+)
+
 const testConfig = `---
+{{if .HostID}}host_id: {{.HostID}}{{end}}
+
 http:
   ws:
     pong_timeout: 10
+    enable_compression: true
 
 analyzers:
-  - 127.0.0.1:{{.AnalyzerPort}}
+{{- range .Analyzers}}
+  - {{.}}
+{{- end}}
 
 analyzer:
   listen: {{.AnalyzerAddr}}:{{.AnalyzerPort}}
+{{- if .TLS}}
+  X509_cert: {{.AnalyzerX509Cert}}
+  X509_key: {{.AnalyzerX509Key}}
+{{- end}}
   flow:
     backend: {{.FlowBackend}}
   analyzer_username: admin
   analyzer_password: password
+{{- if .Auth}}
+  auth:
+    api:
+      backend: {{.Auth}}
+{{- end}}
   topology:
     backend: {{.TopologyBackend}}
     probes: {{block "list" .}}{{"\n"}}{{range .AnalyzerProbes}}{{println "    -" .}}{{end}}{{end}}
@@ -70,6 +91,10 @@ analyzer:
 
 agent:
   listen: {{.AgentAddr}}:{{.AgentPort}}
+{{- if .TLS}}
+  X509_cert: {{.AgentX509Cert}}
+  X509_key: {{.AgentX509Key}}
+{{- end}}
   topology:
     probes:
       - netlink
@@ -99,9 +124,31 @@ ovs:
   oflow:
     enable: true
 
+{{- if eq .Auth "basic"}}
+
+auth:
+  basic:
+    type: basic
+    file: {{.AuthHtpasswd}}
+{{- else if eq .Auth "keystone"}}
+
+auth:
+  keystone:
+    type: keystone
+    auth_url: {{.AuthKeystoneURL}}
+{{- end}}
+
+{{- if .K8sConfigFile}}
+
+k8s:
+  config_file: {{.K8sConfigFile}}
+{{- end}}
+
 storage:
+  elasticsearch:
+    host: {{.ElasticsearchHost}}
   orientdb:
-    addr: http://127.0.0.1:2480
+    addr: {{.OrientDBAddr}}
     database: Skydive
     username: root
     password: {{.OrientDBRootPassword}}
@@ -112,8 +159,11 @@ logging:
 etcd:
   data_dir: /tmp/skydive-etcd
   embedded: {{.EmbeddedEtcd}}
+  listen: 127.0.0.1:{{.EtcdPort}}
   servers:
-    - {{.EtcdServer}}
+{{- range .EtcdServers}}
+    - {{.}}
+{{- end}}
 `
 
 type TestContext struct {
@@ -225,6 +275,26 @@ func (c *TestContext) getWholeGraph(t *testing.T, at time.Time) string {
 
 		return "\n" + string(output)
 
+	case "graphml", "gexf":
+		data, err := c.gh.QueryRaw(gremlin)
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+
+		convert := helper.GraphToGraphML
+		if helper.GraphOutputFormat == "gexf" {
+			convert = helper.GraphToGEXF
+		}
+
+		output, err := convert(data)
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+
+		return "\n" + output
+
 	default:
 		data, err := c.gh.QueryRaw(gremlin)
 		if err != nil {
@@ -254,16 +324,39 @@ func (c *TestContext) getAllFlows(t *testing.T, at time.Time) string {
 
 func (c *TestContext) getSystemState(t *testing.T) {
 	stateCmds := []helper.Cmd{
-		{"ip addr", false},
-		{"ip netns list", false},
-		{"ovs-vsctl show", false},
-		{"brctl show", false},
+		{Cmd: "ip addr", Check: false},
+		{Cmd: "ip netns list", Check: false},
+		{Cmd: "ovs-vsctl show", Check: false},
+		{Cmd: "brctl show", Check: false},
 	}
 	helper.ExecCmds(t, stateCmds...)
 }
 
 func RunTest(t *testing.T, test *Test) {
-	client, err := gclient.NewCrudClientFromConfig(&shttp.AuthenticationOpts{})
+	// the setup commands are undone once the artifacts are dumped
+	cleanup := helper.NewCleanupStack(t)
+	defer cleanup.Cleanup()
+	defer helper.CaptureLogs(t).Stop()
+	defer helper.DumpOnFailure(t)
+
+	// the watchdog is stopped before the teardown, so that the setup is
+	// still undone and the artifacts dumped once it expired
+	var watchdog *helper.TestWatchdog
+	if helper.WatchdogTimeout > 0 {
+		watchdog = helper.Watchdog(t, helper.WatchdogTimeout)
+		defer watchdog.Stop()
+	}
+
+	// the replay still runs under an armed watchdog, only an expired one
+	// is stopped before the tear down commands
+	tearDown := func() {
+		if watchdog != nil && watchdog.Expired() {
+			watchdog.Stop()
+		}
+		helper.ExecCmds(t, test.tearDownCmds...)
+	}
+
+	client, err := gclient.NewCrudClientFromConfig(helper.AuthenticationOpts())
 	if err != nil {
 		t.Fatalf("Failed to create client: %s", err)
 	}
@@ -292,10 +385,10 @@ func RunTest(t *testing.T, test *Test) {
 	if test.preCleanup {
 		helper.ExecCmds(t, test.tearDownCmds...)
 	}
-	helper.ExecCmds(t, test.setupCmds...)
+	cleanup.ExecCmds(test.setupCmds...)
 
 	context := &TestContext{
-		gh:       gclient.NewGremlinQueryHelper(&shttp.AuthenticationOpts{}),
+		gh:       gclient.NewGremlinQueryHelper(helper.AuthenticationOpts()),
 		client:   client,
 		captures: captures,
 		data:     make(map[string]interface{}),
@@ -342,7 +435,7 @@ func RunTest(t *testing.T, test *Test) {
 
 	if err != nil {
 		g := context.getWholeGraph(t, time.Now())
-		helper.ExecCmds(t, test.tearDownCmds...)
+		tearDown()
 		context.getSystemState(t)
 		t.Fatalf("Failed to setup captures: %s, graph: %s", err, g)
 	}
@@ -363,7 +456,7 @@ func RunTest(t *testing.T, test *Test) {
 		if err != nil {
 			g := context.getWholeGraph(t, settleTime)
 			f := context.getAllFlows(t, settleTime)
-			helper.ExecCmds(t, test.tearDownCmds...)
+			tearDown()
 			context.getSystemState(t)
 			t.Errorf("Test failed to settle: %s, graph: %s, flows: %s", err, g, f)
 			return
@@ -380,7 +473,7 @@ func RunTest(t *testing.T, test *Test) {
 		if err = test.setupFunction(context); err != nil {
 			g := context.getWholeGraph(t, time.Time{})
 			f := context.getAllFlows(t, time.Time{})
-			helper.ExecCmds(t, test.tearDownCmds...)
+			tearDown()
 			context.getSystemState(t)
 			t.Fatalf("Failed to setup test: %s, graph: %s, flows: %s", err, g, f)
 		}
@@ -426,7 +519,7 @@ func RunTest(t *testing.T, test *Test) {
 	if err != nil {
 		g := context.getWholeGraph(t, time.Time{})
 		f := context.getAllFlows(t, time.Time{})
-		helper.ExecCmds(t, test.tearDownCmds...)
+		tearDown()
 		context.getSystemState(t)
 		t.Fatalf("Failed to setup test: %s, graph: %s, flows: %s", err, g, f)
 	}
@@ -475,7 +568,7 @@ func RunTest(t *testing.T, test *Test) {
 		if err := pingRequest(t, context, packet); err != nil {
 			g := context.getWholeGraph(t, time.Time{})
 			f := context.getAllFlows(t, time.Time{})
-			helper.ExecCmds(t, test.tearDownCmds...)
+			tearDown()
 			context.getSystemState(t)
 			t.Errorf("Packet injection failed: %s, graph: %s, flows: %s", err, g, f)
 			return
@@ -509,7 +602,7 @@ func RunTest(t *testing.T, test *Test) {
 		if err != nil {
 			g := checkContext.getWholeGraph(t, time.Time{})
 			f := checkContext.getAllFlows(t, time.Time{})
-			helper.ExecCmds(t, test.tearDownCmds...)
+			tearDown()
 			context.getSystemState(t)
 			t.Errorf("Test failed: %s, graph: %s, flows: %s", err, g, f)
 			return
@@ -519,13 +612,13 @@ func RunTest(t *testing.T, test *Test) {
 	t.Log("Running tear down commands")
 	if test.tearDownFunction != nil {
 		if err = test.tearDownFunction(context); err != nil {
-			helper.ExecCmds(t, test.tearDownCmds...)
+			tearDown()
 			context.getSystemState(t)
 			t.Fatalf("Fail to tear test down: %s", err)
 		}
 	}
 
-	helper.ExecCmds(t, test.tearDownCmds...)
+	tearDown()
 
 	if test.mode == Replay && helper.AgentTestsOnly == false {
 		if helper.TopologyBackend != "memory" {
@@ -616,30 +709,70 @@ func delaySec(sec int, err ...error) error {
 	return delay(time.Duration(sec)*time.Second, err...)
 }
 
+// startInProcess starts the analyzer and the agent of the test process
+func startInProcess() error {
+	if err := helper.InitConfig(testConfig); err != nil {
+		return fmt.Errorf("Failed to initialize config: %s", err)
+	}
+
+	if err := logging.InitLogging(); err != nil {
+		return fmt.Errorf("Failed to initialize logging system: %s", err)
+	}
+
+	helper.ReleasePorts()
+
+	server, err := analyzer.NewServerFromConfig()
+	if err != nil {
+		return err
+	}
+	if err := server.Start(); err != nil {
+		server.Stop()
+		return err
+	}
+
+	newAgent := func() (helper.AgentService, error) { return agent.NewAgent() }
+	a, err := newAgent()
+	if err != nil {
+		server.Stop()
+		return err
+	}
+
+	a.Start()
+	helper.RegisterAgent(a, newAgent)
+	return nil
+}
+
 func init() {
 	http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost = 100
 
-	if helper.Standalone {
-		if err := helper.InitConfig(testConfig); err != nil {
-			panic(fmt.Sprintf("Failed to initialize config: %s", err))
-		}
+	if err := helper.ProvisionBackends(); err != nil {
+		helper.StopProvisionedBackends()
+		panic(fmt.Sprintf("Failed to provision storage backends: %s", err))
+	}
 
-		if err := logging.InitLogging(); err != nil {
-			panic(fmt.Sprintf("Failed to initialize logging system: %s", err))
+	if helper.Standalone {
+		if helper.EtcdMembers > 1 {
+			if err := helper.StartEtcdCluster(helper.EtcdMembers); err != nil {
+				panic(fmt.Sprintf("Failed to start etcd cluster: %s", err))
+			}
 		}
 
-		server, err := analyzer.NewServerFromConfig()
-		if err != nil {
+		if err := helper.RetryOnBindError(startInProcess); err != nil {
 			panic(err)
 		}
-		server.Start()
 
-		agent, err := agent.NewAgent()
-		if err != nil {
-			panic(err)
+		var err error
+		if helper.AnalyzerCount > 1 {
+			if standaloneAnalyzers, err = helper.StartAnalyzers(testConfig, helper.AnalyzerCount-1, nil); err != nil {
+				panic(err)
+			}
 		}
 
-		agent.Start()
+		if helper.AgentCount > 1 {
+			if standaloneAgents, err = helper.StartAgents(testConfig, helper.AgentCount-1, nil); err != nil {
+				panic(err)
+			}
+		}
 
 		// TODO: check for storage status instead of sleeping
 		time.Sleep(3 * time.Second)
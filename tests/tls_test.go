@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package tests
+
+import (
+	"testing"
+
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/tests/helper"
+)
+
+func TestTLSWebsocket(t *testing.T) {
+	if !helper.TLS {
+		t.Skip("TLS not enabled, use -tls")
+	}
+
+	if !config.IsTLSenabled() {
+		t.Fatal("TLS should be enabled in the configuration")
+	}
+
+	analyzer := config.GetStringSlice("analyzers")[0]
+	for _, clientCert := range []bool{false, true} {
+		ws, err := helper.WSConnectWithOpts(analyzer, 5, nil, &helper.WSOpts{TLSClientCert: clientCert})
+		if err != nil {
+			t.Fatalf("Failed to connect with client certificate %v: %s", clientCert, err)
+		}
+		helper.WSClose(ws)
+	}
+
+	// the API helpers use the CA of the configuration as well
+	if _, err := helper.GremlinQuery(t, "G.V().Count()").GetInt(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -45,11 +45,11 @@ import (
 func TestBridgeOVS(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-testbovs1", true},
+			{Cmd: "ovs-vsctl add-br br-testbovs1", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-testbovs1", true},
+			{Cmd: "ovs-vsctl del-br br-testbovs1", Check: true},
 		},
 
 		mode: Replay,
@@ -83,17 +83,17 @@ func TestBridgeOVS(t *testing.T) {
 func TestPatchOVS(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-testpaovs1", true},
-			{"ovs-vsctl add-br br-testpaovs2", true},
-			{"ovs-vsctl add-port br-testpaovs1 patch-br-testpaovs2 -- set interface patch-br-testpaovs2 type=patch", true},
-			{"ovs-vsctl add-port br-testpaovs2 patch-br-testpaovs1 -- set interface patch-br-testpaovs1 type=patch", true},
-			{"ovs-vsctl set interface patch-br-testpaovs2 option:peer=patch-br-testpaovs1", true},
-			{"ovs-vsctl set interface patch-br-testpaovs1 option:peer=patch-br-testpaovs2", true},
+			{Cmd: "ovs-vsctl add-br br-testpaovs1", Check: true},
+			{Cmd: "ovs-vsctl add-br br-testpaovs2", Check: true},
+			{Cmd: "ovs-vsctl add-port br-testpaovs1 patch-br-testpaovs2 -- set interface patch-br-testpaovs2 type=patch", Check: true},
+			{Cmd: "ovs-vsctl add-port br-testpaovs2 patch-br-testpaovs1 -- set interface patch-br-testpaovs1 type=patch", Check: true},
+			{Cmd: "ovs-vsctl set interface patch-br-testpaovs2 option:peer=patch-br-testpaovs1", Check: true},
+			{Cmd: "ovs-vsctl set interface patch-br-testpaovs1 option:peer=patch-br-testpaovs2", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-testpaovs1", true},
-			{"ovs-vsctl del-br br-testpaovs2", true},
+			{Cmd: "ovs-vsctl del-br br-testpaovs1", Check: true},
+			{Cmd: "ovs-vsctl del-br br-testpaovs2", Check: true},
 		},
 
 		mode: Replay,
@@ -132,12 +132,12 @@ func TestPatchOVS(t *testing.T) {
 func TestInterfaceOVS(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-test1", true},
-			{"ovs-vsctl add-port br-test1 intf1 -- set interface intf1 type=internal", true},
+			{Cmd: "ovs-vsctl add-br br-test1", Check: true},
+			{Cmd: "ovs-vsctl add-port br-test1 intf1 -- set interface intf1 type=internal", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-test1", true},
+			{Cmd: "ovs-vsctl del-br br-test1", Check: true},
 		},
 
 		mode: Replay,
@@ -175,11 +175,11 @@ func TestInterfaceOVS(t *testing.T) {
 func TestVeth(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip l add vm1-veth0 type veth peer name vm1-veth1", true},
+			{Cmd: "ip l add vm1-veth0 type veth peer name vm1-veth1", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip link del vm1-veth0", true},
+			{Cmd: "ip link del vm1-veth0", Check: true},
 		},
 
 		mode: Replay,
@@ -205,14 +205,14 @@ func TestVeth(t *testing.T) {
 func TestBridge(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"brctl addbr br-test", true},
-			{"ip tuntap add mode tap dev intf1", true},
-			{"brctl addif br-test intf1", true},
+			{Cmd: "brctl addbr br-test", Check: true},
+			{Cmd: "ip tuntap add mode tap dev intf1", Check: true},
+			{Cmd: "brctl addif br-test intf1", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"brctl delbr br-test", true},
-			{"ip link del intf1", true},
+			{Cmd: "brctl delbr br-test", Check: true},
+			{Cmd: "ip link del intf1", Check: true},
 		},
 
 		mode: Replay,
@@ -240,13 +240,13 @@ func TestBridge(t *testing.T) {
 func TestMacNameUpdate(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip l add vm1-veth0 type veth peer name vm1-veth1", true},
-			{"ip l set vm1-veth1 name vm1-veth2", true},
-			{"ip l set vm1-veth2 address 00:00:00:00:00:aa", true},
+			{Cmd: "ip l add vm1-veth0 type veth peer name vm1-veth1", Check: true},
+			{Cmd: "ip l set vm1-veth1 name vm1-veth2", Check: true},
+			{Cmd: "ip l set vm1-veth2 address 00:00:00:00:00:aa", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip link del vm1-veth0", true},
+			{Cmd: "ip link del vm1-veth0", Check: true},
 		},
 
 		mode: Replay,
@@ -279,11 +279,11 @@ func TestMacNameUpdate(t *testing.T) {
 func TestNameSpace(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add ns1", true},
+			{Cmd: "ip netns add ns1", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del ns1", true},
+			{Cmd: "ip netns del ns1", Check: true},
 		},
 
 		mode: Replay,
@@ -311,13 +311,13 @@ func TestNameSpace(t *testing.T) {
 func TestNameSpaceVeth(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add ns1", true},
-			{"ip l add vm1-veth0 type veth peer name vm1-veth1 netns ns1", true},
+			{Cmd: "ip netns add ns1", Check: true},
+			{Cmd: "ip l add vm1-veth0 type veth peer name vm1-veth1 netns ns1", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip link del vm1-veth0", true},
-			{"ip netns del ns1", true},
+			{Cmd: "ip link del vm1-veth0", Check: true},
+			{Cmd: "ip netns del ns1", Check: true},
 		},
 
 		mode: Replay,
@@ -345,15 +345,15 @@ func TestNameSpaceVeth(t *testing.T) {
 func TestNameSpaceOVSInterface(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add ns1", true},
-			{"ovs-vsctl add-br br-test1", true},
-			{"ovs-vsctl add-port br-test1 intf1 -- set interface intf1 type=internal", true},
-			{"ip l set intf1 netns ns1", true},
+			{Cmd: "ip netns add ns1", Check: true},
+			{Cmd: "ovs-vsctl add-br br-test1", Check: true},
+			{Cmd: "ovs-vsctl add-port br-test1 intf1 -- set interface intf1 type=internal", Check: true},
+			{Cmd: "ip l set intf1 netns ns1", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-test1", true},
-			{"ip netns del ns1", true},
+			{Cmd: "ovs-vsctl del-br br-test1", Check: true},
+			{Cmd: "ip netns del ns1", Check: true},
 		},
 
 		mode: Replay,
@@ -392,13 +392,13 @@ func TestInterfaceUpdate(t *testing.T) {
 
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add iu", true},
-			{"sleep 5", false},
-			{"ip netns exec iu ip link set lo up", true},
+			{Cmd: "ip netns add iu", Check: true},
+			{Cmd: "sleep 5", Check: false},
+			{Cmd: "ip netns exec iu ip link set lo up", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del iu", true},
+			{Cmd: "ip netns del iu", Check: true},
 		},
 
 		mode: OneShot,
@@ -443,9 +443,9 @@ func TestInterfaceUpdate(t *testing.T) {
 func TestInterfaceMetrics(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ip netns add im", true},
-			{"ip netns exec im ip link set lo up", true},
-			{"sleep 2", false},
+			{Cmd: "ip netns add im", Check: true},
+			{Cmd: "ip netns exec im ip link set lo up", Check: true},
+			{Cmd: "sleep 2", Check: false},
 		},
 
 		setupFunction: func(c *TestContext) error {
@@ -457,7 +457,7 @@ func TestInterfaceMetrics(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ip netns del im", true},
+			{Cmd: "ip netns del im", Check: true},
 		},
 
 		mode: OneShot,
@@ -520,16 +520,16 @@ func TestInterfaceMetrics(t *testing.T) {
 func TestOVSOwnershipLink(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-owner", true},
-			{"ovs-vsctl add-port br-owner patch-br-owner -- set interface patch-br-owner type=patch", true},
-			{"ovs-vsctl add-port br-owner gre-br-owner -- set interface gre-br-owner type=gre", true},
-			{"ovs-vsctl add-port br-owner vxlan-br-owner -- set interface vxlan-br-owner type=vxlan", true},
-			{"ovs-vsctl add-port br-owner geneve-br-owner -- set interface geneve-br-owner type=geneve", true},
-			{"ovs-vsctl add-port br-owner intf-owner -- set interface intf-owner type=internal", true},
+			{Cmd: "ovs-vsctl add-br br-owner", Check: true},
+			{Cmd: "ovs-vsctl add-port br-owner patch-br-owner -- set interface patch-br-owner type=patch", Check: true},
+			{Cmd: "ovs-vsctl add-port br-owner gre-br-owner -- set interface gre-br-owner type=gre", Check: true},
+			{Cmd: "ovs-vsctl add-port br-owner vxlan-br-owner -- set interface vxlan-br-owner type=vxlan", Check: true},
+			{Cmd: "ovs-vsctl add-port br-owner geneve-br-owner -- set interface geneve-br-owner type=geneve", Check: true},
+			{Cmd: "ovs-vsctl add-port br-owner intf-owner -- set interface intf-owner type=internal", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-owner", true},
+			{Cmd: "ovs-vsctl del-br br-owner", Check: true},
 		},
 
 		mode: Replay,
@@ -683,7 +683,7 @@ func TestUserMetadata(t *testing.T) {
 	umd := types.NewUserMetadata(g.G.V().Has("Name", "br-umd", "Type", "ovsbridge").String(), "testKey", "testValue")
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-umd", true},
+			{Cmd: "ovs-vsctl add-br br-umd", Check: true},
 		},
 
 		setupFunction: func(c *TestContext) error {
@@ -696,7 +696,7 @@ func TestUserMetadata(t *testing.T) {
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-umd", true},
+			{Cmd: "ovs-vsctl del-br br-umd", Check: true},
 		},
 
 		mode: Replay,
@@ -748,27 +748,27 @@ func TestAgentMetadata(t *testing.T) {
 func TestRouteTable(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-rt", true},
-			{"ip netns add rt-vm1", true},
-			{"ip link add rt-vm1-eth0 type veth peer name rt-eth-src netns rt-vm1", true},
-			{"ip link set rt-vm1-eth0 up", true},
-			{"ip netns exec rt-vm1 ip link set rt-eth-src up", true},
-			{"ip netns exec rt-vm1 ip address add 124.65.91.42/24 dev rt-eth-src", true},
-			{"ovs-vsctl add-port br-rt rt-vm1-eth0", true},
-			{"ip netns add rt-vm2", true},
-			{"ip link add rt-vm2-eth0 type veth peer name rt-eth-dst netns rt-vm2", true},
-			{"ip link set rt-vm2-eth0 up", true},
-			{"ip netns exec rt-vm2 ip link set rt-eth-dst up", true},
-			{"ip netns exec rt-vm2 ip address add 124.65.92.43/24 dev rt-eth-dst", true},
-			{"ovs-vsctl add-port br-rt rt-vm2-eth0", true},
+			{Cmd: "ovs-vsctl add-br br-rt", Check: true},
+			{Cmd: "ip netns add rt-vm1", Check: true},
+			{Cmd: "ip link add rt-vm1-eth0 type veth peer name rt-eth-src netns rt-vm1", Check: true},
+			{Cmd: "ip link set rt-vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec rt-vm1 ip link set rt-eth-src up", Check: true},
+			{Cmd: "ip netns exec rt-vm1 ip address add 124.65.91.42/24 dev rt-eth-src", Check: true},
+			{Cmd: "ovs-vsctl add-port br-rt rt-vm1-eth0", Check: true},
+			{Cmd: "ip netns add rt-vm2", Check: true},
+			{Cmd: "ip link add rt-vm2-eth0 type veth peer name rt-eth-dst netns rt-vm2", Check: true},
+			{Cmd: "ip link set rt-vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec rt-vm2 ip link set rt-eth-dst up", Check: true},
+			{Cmd: "ip netns exec rt-vm2 ip address add 124.65.92.43/24 dev rt-eth-dst", Check: true},
+			{Cmd: "ovs-vsctl add-port br-rt rt-vm2-eth0", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-rt", true},
-			{"ip link del rt-vm1-eth0", true},
-			{"ip netns del rt-vm1", true},
-			{"ip link del rt-vm2-eth0", true},
-			{"ip netns del rt-vm2", true},
+			{Cmd: "ovs-vsctl del-br br-rt", Check: true},
+			{Cmd: "ip link del rt-vm1-eth0", Check: true},
+			{Cmd: "ip netns del rt-vm1", Check: true},
+			{Cmd: "ip link del rt-vm2-eth0", Check: true},
+			{Cmd: "ip netns del rt-vm2", Check: true},
 		},
 
 		mode: OneShot,
@@ -816,29 +816,29 @@ func TestRouteTable(t *testing.T) {
 func TestRouteTableHistory(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"ovs-vsctl add-br br-rth", true},
-			{"ip netns add rth-vm1", true},
-			{"ip link add rth-vm1-eth0 type veth peer name rth-eth-src netns rth-vm1", true},
-			{"ip link set rth-vm1-eth0 up", true},
-			{"ip netns exec rth-vm1 ip link set rth-eth-src up", true},
-			{"ip netns exec rth-vm1 ip address add 124.65.75.42/24 dev rth-eth-src", true},
-			{"ovs-vsctl add-port br-rth rth-vm1-eth0", true},
-			{"ip netns add rth-vm2", true},
-			{"ip link add rth-vm2-eth0 type veth peer name rth-eth-dst netns rth-vm2", true},
-			{"ip link set rth-vm2-eth0 up", true},
-			{"ip netns exec rth-vm2 ip link set rth-eth-dst up", true},
-			{"ip netns exec rth-vm2 ip address add 124.65.76.43/24 dev rth-eth-dst", true},
-			{"ovs-vsctl add-port br-rth rth-vm2-eth0", true},
-			{"sleep 5", false},
-			{"ip netns exec rth-vm1 ip route add 124.65.75.0/24 via 124.65.75.42 table 2", true},
+			{Cmd: "ovs-vsctl add-br br-rth", Check: true},
+			{Cmd: "ip netns add rth-vm1", Check: true},
+			{Cmd: "ip link add rth-vm1-eth0 type veth peer name rth-eth-src netns rth-vm1", Check: true},
+			{Cmd: "ip link set rth-vm1-eth0 up", Check: true},
+			{Cmd: "ip netns exec rth-vm1 ip link set rth-eth-src up", Check: true},
+			{Cmd: "ip netns exec rth-vm1 ip address add 124.65.75.42/24 dev rth-eth-src", Check: true},
+			{Cmd: "ovs-vsctl add-port br-rth rth-vm1-eth0", Check: true},
+			{Cmd: "ip netns add rth-vm2", Check: true},
+			{Cmd: "ip link add rth-vm2-eth0 type veth peer name rth-eth-dst netns rth-vm2", Check: true},
+			{Cmd: "ip link set rth-vm2-eth0 up", Check: true},
+			{Cmd: "ip netns exec rth-vm2 ip link set rth-eth-dst up", Check: true},
+			{Cmd: "ip netns exec rth-vm2 ip address add 124.65.76.43/24 dev rth-eth-dst", Check: true},
+			{Cmd: "ovs-vsctl add-port br-rth rth-vm2-eth0", Check: true},
+			{Cmd: "sleep 5", Check: false},
+			{Cmd: "ip netns exec rth-vm1 ip route add 124.65.75.0/24 via 124.65.75.42 table 2", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"ovs-vsctl del-br br-rth", true},
-			{"ip link del rth-vm1-eth0", true},
-			{"ip netns del rth-vm1", true},
-			{"ip link del rth-vm2-eth0", true},
-			{"ip netns del rth-vm2", true},
+			{Cmd: "ovs-vsctl del-br br-rth", Check: true},
+			{Cmd: "ip link del rth-vm1-eth0", Check: true},
+			{Cmd: "ip netns del rth-vm1", Check: true},
+			{Cmd: "ip link del rth-vm2-eth0", Check: true},
+			{Cmd: "ip netns del rth-vm2", Check: true},
 		},
 
 		mode: OneShot,
@@ -872,11 +872,11 @@ func TestRouteTableHistory(t *testing.T) {
 func TestInterfaceFeatures(t *testing.T) {
 	test := &Test{
 		setupCmds: []helper.Cmd{
-			{"brctl addbr br-features", true},
+			{Cmd: "brctl addbr br-features", Check: true},
 		},
 
 		tearDownCmds: []helper.Cmd{
-			{"brctl delbr br-features", true},
+			{Cmd: "brctl delbr br-features", Check: true},
 		},
 
 		checks: []CheckFunction{
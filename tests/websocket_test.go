@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/skydive-project/skydive/api/client"
+	"github.com/skydive-project/skydive/api/types"
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/config"
+	shttp "github.com/skydive-project/skydive/http"
+	"github.com/skydive-project/skydive/tests/helper"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// TestSubscriberCompression checks that the subscriber connections
+// requesting it get their messages compressed by the analyzer
+func TestSubscriberCompression(t *testing.T) {
+	hostID := "test-subscriber-compression"
+
+	ws, err := helper.WSConnectWithOpts(config.GetStringSlice("analyzers")[0], 5, nil, &helper.WSOpts{Compression: true, HostID: hostID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helper.WSClose(ws)
+
+	msg := shttp.NewWSStructMessage(graph.Namespace, graph.SyncRequestMsgType, graph.SyncRequestMsg{})
+	if _, err := helper.WSRequest(ws, msg, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	crudClient, err := client.NewCrudClientFromConfig(helper.AuthenticationOpts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = common.Retry(func() error {
+		resp, err := crudClient.Request("GET", "status", nil, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var status types.AnalyzerStatus
+		if err := common.JSONDecode(resp.Body, &status); err != nil {
+			return err
+		}
+
+		subscriber, found := status.Subscribers[hostID]
+		if !found {
+			return fmt.Errorf("Subscriber %s not found in the analyzer status", hostID)
+		}
+		if !subscriber.Compression {
+			return fmt.Errorf("Compression was not negotiated with subscriber %s", hostID)
+		}
+		if subscriber.WireBytesSent == 0 || subscriber.WireBytesSent >= subscriber.RawBytesSent {
+			return fmt.Errorf("Messages sent to subscriber %s were not compressed, %d bytes sent for %d bytes of messages", hostID, subscriber.WireBytesSent, subscriber.RawBytesSent)
+		}
+		return nil
+	}, 10, time.Second)
+
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -383,18 +383,30 @@ func (b *ElasticSearchBackend) MetadataUpdated(i interface{}) bool {
 // Query the database for a "node" or "edge"
 func (b *ElasticSearchBackend) Query(typ string, tsq *TimedSearchQuery) (sr *elastic.SearchResult, _ error) {
 	fltrs := []elastic.Query{
-		es.FormatFilter(filters.NewTermStringFilter("_Type", typ), ""),
+		elastic.NewTermQuery("_Type", typ),
 	}
 
-	if tf := es.FormatFilter(tsq.TimeFilter, ""); tf != nil {
+	tf, err := es.FormatFilter(tsq.TimeFilter, "")
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil {
 		fltrs = append(fltrs, tf)
 	}
 
-	if f := es.FormatFilter(tsq.Filter, ""); f != nil {
+	f, err := es.FormatFilter(tsq.Filter, "")
+	if err != nil {
+		return nil, err
+	}
+	if f != nil {
 		fltrs = append(fltrs, f)
 	}
 
-	if mf := es.FormatFilter(tsq.MetadataFilter, "Metadata"); mf != nil {
+	mf, err := es.FormatFilter(tsq.MetadataFilter, "Metadata")
+	if err != nil {
+		return nil, err
+	}
+	if mf != nil {
 		fltrs = append(fltrs, mf)
 	}
 
@@ -390,3 +390,63 @@ func TestElasticsearchEdge(t *testing.T) {
 		t.Fatalf("Expected elasticsearch archived records not found: %s", diff)
 	}
 }
+
+func TestElasticsearchTypedMetadata(t *testing.T) {
+	g, client := newElasticsearchGraph(t)
+
+	g.newNode("aaa", typedMetadata, time.Unix(1, 0), "host1")
+
+	source, err := json.Marshal(client.indices[topologyLiveIndex.Name].entries["aaa"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := json.RawMessage(source)
+	client.searchResult.Hits.Hits = []*elastic.SearchHit{{Source: &raw}}
+
+	nodes := g.backend.GetNode("aaa", liveContext)
+	if len(nodes) != 1 {
+		t.Fatalf("Expected one node, got %+v", nodes)
+	}
+	checkTypedMetadata(t, nodes[0])
+}
+
+func TestElasticsearchIPV6RangeFilter(t *testing.T) {
+	g, client := newElasticsearchGraph(t)
+
+	f, err := filters.NewIPV6RangeFilter("IPV6", "fd00::/64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := g.backend.GetNodes(liveContext, NewGraphElementFilter(&filters.Filter{IPV6RangeFilter: f}))
+	if len(nodes) != 0 {
+		t.Fatalf("Expected no node, got %+v", nodes)
+	}
+
+	if len(client.searches) != 0 {
+		t.Fatalf("Expected no search to be sent, got %+v", client.searches)
+	}
+}
+
+func TestElasticsearchCIDRFilter(t *testing.T) {
+	g, client := newElasticsearchGraph(t)
+
+	f, err := filters.NewCIDRFilter("IPV4", "192.168.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.backend.GetNodes(liveContext, NewGraphElementFilter(&filters.Filter{CIDRFilter: f}))
+
+	if len(client.searches) != 1 {
+		t.Fatalf("Expected one search to be sent, got %+v", client.searches)
+	}
+
+	if f, err = filters.NewCIDRFilter("IPV6", "fd00::/64"); err != nil {
+		t.Fatal(err)
+	}
+	g.backend.GetNodes(liveContext, NewGraphElementFilter(&filters.Filter{CIDRFilter: f}))
+
+	if len(client.searches) != 1 {
+		t.Fatalf("Expected no search to be sent for an IPv6 CIDR, got %+v", client.searches)
+	}
+}
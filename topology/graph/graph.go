@@ -204,6 +204,7 @@ func (c *DefaultGraphListener) OnEdgeDeleted(e *Edge) {
 type GraphEventHandler struct {
 	common.RWMutex
 	eventListeners       []GraphEventListener
+	eventFilters         []GraphElementMatcher // filter of each listener, nil to get all the events
 	eventChan            chan graphEvent
 	eventConsumed        bool
 	currentEventListener GraphEventListener
@@ -214,12 +215,29 @@ func (g *GraphEventHandler) notifyListeners(ge graphEvent) {
 	// and we wont to notify a listener which generated a graph element
 	g.RLock()
 	defer g.RUnlock()
-	for _, g.currentEventListener = range g.eventListeners {
+
+	var element *graphElement
+	switch e := ge.element.(type) {
+	case *Node:
+		element = &e.graphElement
+	case *Edge:
+		element = &e.graphElement
+	}
+
+	for i, l := range g.eventListeners {
 		// do not notify the listener which generated the event
-		if g.currentEventListener == ge.listener {
+		if l == ge.listener {
 			continue
 		}
 
+		// skip the listeners not interested in this element, deletions are
+		// always notified as the element may have matched before an update
+		if m := g.eventFilters[i]; m != nil && ge.kind != nodeDeleted && ge.kind != edgeDeleted && !m.Match(element) {
+			continue
+		}
+
+		g.currentEventListener = l
+
 		switch ge.kind {
 		case nodeAdded:
 			g.currentEventListener.OnNodeAdded(ge.element.(*Node))
@@ -260,10 +278,18 @@ func (g *GraphEventHandler) notifyEvent(ge graphEvent) {
 
 // AddEventListener subscibe a new graph listener
 func (g *GraphEventHandler) AddEventListener(l GraphEventListener) {
+	g.AddEventListenerWithFilter(l, nil)
+}
+
+// AddEventListenerWithFilter subscribe a new graph listener only notified
+// of the events of the elements matching the given filter, except for the
+// deletions that are notified for all the elements
+func (g *GraphEventHandler) AddEventListenerWithFilter(l GraphEventListener, m GraphElementMatcher) {
 	g.Lock()
 	defer g.Unlock()
 
 	g.eventListeners = append(g.eventListeners, l)
+	g.eventFilters = append(g.eventFilters, m)
 }
 
 // RemoveEventListener unsubscribe a graph listener
@@ -274,6 +300,7 @@ func (g *GraphEventHandler) RemoveEventListener(l GraphEventListener) {
 	for i, el := range g.eventListeners {
 		if l == el {
 			g.eventListeners = append(g.eventListeners[:i], g.eventListeners[i+1:]...)
+			g.eventFilters = append(g.eventFilters[:i], g.eventFilters[i+1:]...)
 			break
 		}
 	}
@@ -423,23 +450,26 @@ func (e *graphElement) GetFieldStringList(name string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	return common.ToStringList(v)
+}
 
-	switch l := v.(type) {
-	case []interface{}:
-		var l2 []string
-		for _, i := range l {
-			s, ok := i.(string)
-			if !ok {
-				return nil, common.ErrFieldWrongType
-			}
-			l2 = append(l2, s)
-		}
-		return l2, nil
-	case []string:
-		return l, nil
-	default:
-		return nil, common.ErrFieldWrongType
+// GetFieldFloat64 returns the value of a numeric field as float64
+func (e *graphElement) GetFieldFloat64(name string) (float64, error) {
+	v, err := e.GetField(name)
+	if err != nil {
+		return 0, err
 	}
+	return common.ToFloat64(v)
+}
+
+// GetFieldBool returns the value of a field as bool, converting the
+// strings and the numbers
+func (e *graphElement) GetFieldBool(name string) (bool, error) {
+	v, err := e.GetField(name)
+	if err != nil {
+		return false, err
+	}
+	return common.ToBool(v)
 }
 
 // Metadata returns metadata. Note that the metadata returned shouldn't be modify directly
@@ -796,7 +826,8 @@ func (g *Graph) SetMetadata(i interface{}, m Metadata) bool {
 	return true
 }
 
-// DelMetadata delete a metadata to an associated edge or node
+// DelMetadata delete a metadata to an associated edge or node, the key can be
+// a dotted path whose parents left empty are removed
 func (g *Graph) DelMetadata(i interface{}, k string) bool {
 	var e *graphElement
 	ge := graphEvent{element: i}
@@ -810,7 +841,7 @@ func (g *Graph) DelMetadata(i interface{}, k string) bool {
 		ge.kind = edgeUpdated
 	}
 
-	if updated := common.DelField(e.metadata, k); !updated {
+	if updated := e.metadata.DelField(k); !updated {
 		return updated
 	}
 
@@ -825,16 +856,77 @@ func (g *Graph) DelMetadata(i interface{}, k string) bool {
 	return true
 }
 
-// SetField set metadata value based on dot key ("a.b.c.d" = "ok")
-func (m *Metadata) SetField(k string, v interface{}) bool {
+// SetField set metadata value based on dot key ("a.b.c.d" = "ok"), creating
+// the missing intermediate maps
+func (m *Metadata) SetField(k string, v interface{}) error {
 	return common.SetField(*m, k, v)
 }
 
 // SetFieldAndNormalize set metadata value after normalization (and deepcopy)
-func (m *Metadata) SetFieldAndNormalize(k string, v interface{}) bool {
+func (m *Metadata) SetFieldAndNormalize(k string, v interface{}) error {
 	return common.SetField(*m, k, common.NormalizeValue(v))
 }
 
+// DelField removes a metadata based on dot key and its parents left empty
+func (m *Metadata) DelField(k string) bool {
+	return common.DelField(*m, k)
+}
+
+// GetField returns the value of a metadata based on dot key
+func (m Metadata) GetField(k string) (interface{}, error) {
+	return common.GetField(m, k)
+}
+
+// GetFieldInt64 returns the value of a numeric metadata as int64
+func (m Metadata) GetFieldInt64(k string) (int64, error) {
+	v, err := m.GetField(k)
+	if err != nil {
+		return 0, err
+	}
+	return common.ToInt64(v)
+}
+
+// GetFieldFloat64 returns the value of a numeric metadata as float64
+func (m Metadata) GetFieldFloat64(k string) (float64, error) {
+	v, err := m.GetField(k)
+	if err != nil {
+		return 0, err
+	}
+	return common.ToFloat64(v)
+}
+
+// GetFieldBool returns the value of a metadata as bool, converting the
+// strings and the numbers
+func (m Metadata) GetFieldBool(k string) (bool, error) {
+	v, err := m.GetField(k)
+	if err != nil {
+		return false, err
+	}
+	return common.ToBool(v)
+}
+
+// GetFieldString returns the value of a string metadata
+func (m Metadata) GetFieldString(k string) (string, error) {
+	v, err := m.GetField(k)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", common.ErrFieldWrongType
+	}
+	return s, nil
+}
+
+// GetFieldStringList returns the value of a metadata holding a list of strings
+func (m Metadata) GetFieldStringList(k string) ([]string, error) {
+	v, err := m.GetField(k)
+	if err != nil {
+		return nil, err
+	}
+	return common.ToStringList(v)
+}
+
 func (g *Graph) addMetadata(i interface{}, k string, v interface{}, t time.Time) bool {
 	var e *graphElement
 	ge := graphEvent{element: i}
@@ -848,11 +940,11 @@ func (g *Graph) addMetadata(i interface{}, k string, v interface{}, t time.Time)
 		ge.kind = edgeUpdated
 	}
 
-	if o, ok := e.metadata[k]; ok && reflect.DeepEqual(o, v) {
+	if o, err := e.metadata.GetField(k); err == nil && reflect.DeepEqual(o, v) {
 		return false
 	}
 
-	if !e.metadata.SetField(k, v) {
+	if err := e.metadata.SetField(k, v); err != nil {
 		return false
 	}
 
@@ -872,7 +964,9 @@ func (g *Graph) updateMetadata(i interface{}, metadata Metadata, t time.Time) bo
 	return true
 }
 
-// AddMetadata add a metadata to an associated edge or node
+// AddMetadata add a metadata to an associated edge or node. The key can be a
+// dotted path, the missing intermediate maps are then created. It returns false
+// if an intermediate value of the path is not a map.
 func (g *Graph) AddMetadata(i interface{}, k string, v interface{}) bool {
 	return g.addMetadata(i, k, v, time.Now().UTC())
 }
@@ -903,17 +997,17 @@ func (t *MetadataTransaction) Commit() {
 
 	var updated bool
 	for k, v := range t.adds {
-		if o, ok := e.metadata[k]; ok && reflect.DeepEqual(o, v) {
+		if o, err := e.metadata.GetField(k); err == nil && reflect.DeepEqual(o, v) {
 			continue
 		}
 
-		if e.metadata.SetField(k, v) {
+		if err := e.metadata.SetField(k, v); err == nil {
 			updated = true
 		}
 	}
 
 	for _, k := range t.removes {
-		updated = common.DelField(e.metadata, k) || updated
+		updated = e.metadata.DelField(k) || updated
 	}
 	if !updated {
 		return
@@ -1104,6 +1198,13 @@ func (g *Graph) Link(n1 *Node, n2 *Node, m Metadata, h ...string) *Edge {
 	return g.NewEdge(GenID(), n1, n2, nil, h...)
 }
 
+// LinkOnce links the nodes n1, n2 unless an edge with the same RelationType,
+// or without any if none is given, already exists between them, in which case
+// its metadata are updated. It returns the edge and whether it was created.
+func (g *Graph) LinkOnce(n1 *Node, n2 *Node, m Metadata, h ...string) (*Edge, bool) {
+	return g.NewEdgeOnce(GenID(), n1, n2, m, h...)
+}
+
 // Unlink the nodes n1, n2 ; delete the associated edge
 func (g *Graph) Unlink(n1 *Node, n2 *Node) {
 	for _, e := range g.backend.GetNodeEdges(n1, liveContext, nil) {
@@ -1277,6 +1378,42 @@ func (g *Graph) NewEdge(i Identifier, p *Node, c *Node, m Metadata, h ...string)
 	return g.newEdge(i, p, c, m, time.Now().UTC(), h...)
 }
 
+// NewEdgeOnce creates a new edge with the given ID unless an edge with the same
+// RelationType already links the parent to the child, in which case the given
+// metadata are merged into the ones of the existing edge. Without RelationType
+// only an edge having none is reused. It returns the edge and whether it was
+// created.
+func (g *Graph) NewEdgeOnce(i Identifier, p *Node, c *Node, m Metadata, h ...string) (*Edge, bool) {
+	var filter GraphElementMatcher
+	relationType, hasRelationType := m["RelationType"]
+	if hasRelationType {
+		filter = Metadata{"RelationType": relationType}
+	}
+
+	for _, e := range g.GetNodeEdges(p, filter) {
+		if e.parent != p.ID || e.child != c.ID {
+			continue
+		}
+
+		if _, ok := e.metadata["RelationType"]; ok && !hasRelationType {
+			continue
+		}
+
+		merged := Metadata{}
+		for k, v := range e.metadata {
+			merged[k] = v
+		}
+		for k, v := range m {
+			merged[k] = v
+		}
+		g.SetMetadata(e, merged)
+
+		return e, false
+	}
+
+	return g.NewEdge(i, p, c, m, h...), true
+}
+
 // EdgeDeleted event
 func (g *Graph) EdgeDeleted(e *Edge) {
 	if g.backend.EdgeDeleted(e) {
@@ -1430,6 +1567,13 @@ func (g *Graph) AddEventListener(l GraphEventListener) {
 	g.eventHandler.AddEventListener(l)
 }
 
+// AddEventListenerWithFilter subscribe a new graph listener only notified
+// of the events of the elements matching the given filter, except for the
+// deletions that are notified for all the elements
+func (g *Graph) AddEventListenerWithFilter(l GraphEventListener, m GraphElementMatcher) {
+	g.eventHandler.AddEventListenerWithFilter(l, m)
+}
+
 // RemoveEventListener unsubscribe a graph listener
 func (g *Graph) RemoveEventListener(l GraphEventListener) {
 	g.eventHandler.RemoveEventListener(l)
@@ -24,6 +24,7 @@ package graph
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -40,6 +41,74 @@ func newGraph(t *testing.T) *Graph {
 	return NewGraphFromConfig(b, common.UnknownService)
 }
 
+// typedMetadata holds values of the types the probes are setting
+var typedMetadata = Metadata{
+	"MTU":     1500,
+	"Speed":   int32(1000),
+	"UsedPct": float32(0.5),
+	"Up":      "true",
+	"IPV4":    []string{"10.0.0.1/24", "10.0.0.2/24"},
+	"Name":    "eth0",
+}
+
+type typedGetter interface {
+	GetFieldInt64(string) (int64, error)
+	GetFieldFloat64(string) (float64, error)
+	GetFieldBool(string) (bool, error)
+	GetFieldString(string) (string, error)
+	GetFieldStringList(string) ([]string, error)
+}
+
+// checkTypedMetadata checks the typed getters on the values of typedMetadata
+func checkTypedMetadata(t *testing.T, g typedGetter) {
+	if mtu, err := g.GetFieldInt64("MTU"); err != nil || mtu != 1500 {
+		t.Errorf("Expected MTU 1500, got %d (%v)", mtu, err)
+	}
+
+	if speed, err := g.GetFieldInt64("Speed"); err != nil || speed != 1000 {
+		t.Errorf("Expected Speed 1000, got %d (%v)", speed, err)
+	}
+
+	if usedPct, err := g.GetFieldFloat64("UsedPct"); err != nil || usedPct != 0.5 {
+		t.Errorf("Expected UsedPct 0.5, got %f (%v)", usedPct, err)
+	}
+
+	if up, err := g.GetFieldBool("Up"); err != nil || !up {
+		t.Errorf("Expected Up to be true, got %v (%v)", up, err)
+	}
+
+	if ips, err := g.GetFieldStringList("IPV4"); err != nil || strings.Join(ips, ",") != "10.0.0.1/24,10.0.0.2/24" {
+		t.Errorf("Expected 2 IPV4 addresses, got %v (%v)", ips, err)
+	}
+
+	if name, err := g.GetFieldString("Name"); err != nil || name != "eth0" {
+		t.Errorf("Expected Name eth0, got %s (%v)", name, err)
+	}
+
+	if _, err := g.GetFieldInt64("Name"); err == nil {
+		t.Error("Expected an error when getting Name as int64")
+	}
+
+	if _, err := g.GetFieldBool("Name"); err == nil {
+		t.Error("Expected an error when getting Name as bool")
+	}
+
+	if _, err := g.GetFieldStringList("MTU"); err != common.ErrFieldWrongType {
+		t.Errorf("Expected a wrong type error when getting MTU as a string list, got %v", err)
+	}
+
+	if _, err := g.GetFieldFloat64("Unknown"); err != common.ErrFieldNotFound {
+		t.Errorf("Expected a not found error when getting Unknown, got %v", err)
+	}
+}
+
+func TestTypedMetadata(t *testing.T) {
+	g := newGraph(t)
+
+	checkTypedMetadata(t, typedMetadata)
+	checkTypedMetadata(t, g.NewNode(GenID(), typedMetadata))
+}
+
 func TestLinks(t *testing.T) {
 	g := newGraph(t)
 
@@ -67,6 +136,62 @@ func TestLinks(t *testing.T) {
 	}
 }
 
+func TestLinkOnce(t *testing.T) {
+	g := newGraph(t)
+
+	n1 := g.NewNode(GenID(), Metadata{"Value": 1, "Type": "intf"})
+	n2 := g.NewNode(GenID(), Metadata{"Value": 2, "Type": "intf"})
+
+	e, created := g.LinkOnce(n1, n2, Metadata{"RelationType": "mount", "Device": "sda1"})
+	if !created {
+		t.Error("first LinkOnce should create the edge")
+	}
+
+	// repeated passes should update the same edge
+	for i := 0; i < 3; i++ {
+		e2, created := g.LinkOnce(n1, n2, Metadata{"RelationType": "mount", "Options": "rw"})
+		if created || e2.ID != e.ID {
+			t.Errorf("LinkOnce should have updated edge %s, got %s (created %v)", e.ID, e2.ID, created)
+		}
+	}
+
+	if edges := g.GetNodeEdges(n1, nil); len(edges) != 1 {
+		t.Fatalf("Expected 1 edge, got %d", len(edges))
+	}
+
+	expected := Metadata{"RelationType": "mount", "Device": "sda1", "Options": "rw"}
+	if !reflect.DeepEqual(e.Metadata(), expected) {
+		t.Errorf("Expected metadata %v, got %v", expected, e.Metadata())
+	}
+
+	// another relation type or the reverse direction is another edge
+	if _, created := g.LinkOnce(n1, n2, Metadata{"RelationType": "swap"}); !created {
+		t.Error("LinkOnce should create an edge for another relation type")
+	}
+
+	if _, created := g.LinkOnce(n2, n1, Metadata{"RelationType": "mount"}); !created {
+		t.Error("LinkOnce should create an edge for the reverse direction")
+	}
+
+	if edges := g.GetNodeEdges(n1, nil); len(edges) != 3 {
+		t.Errorf("Expected 3 edges, got %d", len(edges))
+	}
+
+	// without relation type only an edge having none is reused
+	e, created = g.LinkOnce(n1, n2, Metadata{"Weight": 1})
+	if !created {
+		t.Error("LinkOnce without relation type should not reuse a typed edge")
+	}
+
+	if e2, created := g.LinkOnce(n1, n2, Metadata{"Weight": 2}); created || e2.ID != e.ID {
+		t.Errorf("LinkOnce should have updated edge %s, got %s (created %v)", e.ID, e2.ID, created)
+	}
+
+	if edges := g.GetNodeEdges(n1, nil); len(edges) != 4 {
+		t.Errorf("Expected 4 edges, got %d", len(edges))
+	}
+}
+
 func TestAreLinkedWithMetadata(t *testing.T) {
 	g := newGraph(t)
 
@@ -405,6 +530,50 @@ func TestMetadata(t *testing.T) {
 	}
 }
 
+func TestMetadataDottedPath(t *testing.T) {
+	g := newGraph(t)
+
+	n := g.NewNode(GenID(), Metadata{"Type": "host"})
+
+	if !g.AddMetadata(n, "Software.Ceph.OSD.ID", 1) {
+		t.Error("Metadata with a dotted path not added")
+	}
+
+	if g.AddMetadata(n, "Software.Ceph.OSD.ID", 1) {
+		t.Error("Same metadata value should not update the node")
+	}
+
+	if id, err := n.GetFieldInt64("Software.Ceph.OSD.ID"); err != nil || id != 1 {
+		t.Errorf("Nested metadata not found: %v", n.Metadata())
+	}
+
+	revision := n.revision
+	if g.AddMetadata(n, "Software.Ceph.OSD.ID.Sub", 2) {
+		t.Error("Metadata should not overwrite a non map value")
+	}
+
+	if n.revision != revision {
+		t.Error("Node should not be updated on conflict")
+	}
+
+	g.AddMetadata(n, "Software.Ceph.Version", "12.2")
+	if !g.DelMetadata(n, "Software.Ceph.OSD.ID") {
+		t.Error("Nested metadata not deleted")
+	}
+
+	expected := Metadata{
+		"Type": "host",
+		"Software": map[string]interface{}{
+			"Ceph": map[string]interface{}{
+				"Version": "12.2",
+			},
+		},
+	}
+	if !reflect.DeepEqual(n.Metadata(), expected) {
+		t.Errorf("Expected metadata %v, got %v", expected, n.Metadata())
+	}
+}
+
 func TestMetadataTransaction(t *testing.T) {
 	g := newGraph(t)
 
@@ -548,3 +717,100 @@ func TestRecursiveEvents(t *testing.T) {
 		t.Error("Events are not in the right order")
 	}
 }
+
+type countingListener struct {
+	DefaultGraphListener
+	nodeUpdated int
+	nodeDeleted int
+	edgeAdded   int
+}
+
+func (c *countingListener) OnNodeUpdated(n *Node) {
+	c.nodeUpdated++
+}
+
+func (c *countingListener) OnNodeDeleted(n *Node) {
+	c.nodeDeleted++
+}
+
+func (c *countingListener) OnEdgeAdded(e *Edge) {
+	c.edgeAdded++
+}
+
+func TestEventListenerWithFilter(t *testing.T) {
+	g := newGraph(t)
+
+	all := &countingListener{}
+	g.AddEventListener(all)
+
+	hosts := &countingListener{}
+	g.AddEventListenerWithFilter(hosts, Metadata{"Type": "host"})
+
+	host := g.NewNode(GenID(), Metadata{"Type": "host"})
+	intf := g.NewNode(GenID(), Metadata{"Type": "intf"})
+	g.Link(host, intf, Metadata{"RelationType": "ownership"})
+
+	for i := 0; i < 10; i++ {
+		g.AddMetadata(intf, "Counter", i)
+	}
+	g.AddMetadata(host, "Name", "host1")
+
+	if all.nodeUpdated != 11 || all.edgeAdded != 1 {
+		t.Errorf("Listener without filter should get all the events, got %d updates and %d edges", all.nodeUpdated, all.edgeAdded)
+	}
+
+	if hosts.nodeUpdated != 1 || hosts.edgeAdded != 0 {
+		t.Errorf("Filtered listener should only get the host events, got %d updates and %d edges", hosts.nodeUpdated, hosts.edgeAdded)
+	}
+
+	g.RemoveEventListener(all)
+	g.AddMetadata(host, "Name", "host2")
+
+	if hosts.nodeUpdated != 2 {
+		t.Errorf("Filter should follow its listener after a removal, got %d updates", hosts.nodeUpdated)
+	}
+
+	// a node no longer matching the filter is still notified when deleted
+	g.DelMetadata(host, "Type")
+	g.DelNode(host)
+
+	if hosts.nodeDeleted != 1 {
+		t.Errorf("Filtered listener should get the deletion of a node updated out of its filter, got %d", hosts.nodeDeleted)
+	}
+}
+
+func benchmarkEventListener(b *testing.B, filter GraphElementMatcher) {
+	backend, err := NewMemoryBackend()
+	if err != nil {
+		b.Fatal(err)
+	}
+	g := NewGraphFromConfig(backend, common.UnknownService)
+
+	l := &countingListener{}
+	g.AddEventListenerWithFilter(l, filter)
+
+	g.NewNode(GenID(), Metadata{"Type": "host"})
+
+	var intfs []*Node
+	for i := 0; i < 100; i++ {
+		intfs = append(intfs, g.NewNode(GenID(), Metadata{"Type": "intf"}))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, intf := range intfs {
+			g.AddMetadata(intf, "Counter", i)
+		}
+	}
+	b.StopTimer()
+
+	b.Logf("%d callbacks for %d updates", l.nodeUpdated, b.N*len(intfs))
+}
+
+func BenchmarkEventListener(b *testing.B) {
+	benchmarkEventListener(b, nil)
+}
+
+func BenchmarkEventListenerWithFilter(b *testing.B) {
+	benchmarkEventListener(b, Metadata{"Type": "host"})
+}
@@ -71,15 +71,22 @@ func metadataToOrientDBSetString(m Metadata) string {
 	return ""
 }
 
-func metadataToOrientDBSelectString(m GraphElementMatcher) string {
+// timeSliceToOrientDBSelectString returns the condition on the time slice,
+// it only holds range filters which are always supported
+func timeSliceToOrientDBSelectString(t *common.TimeSlice) string {
+	query, _ := orientdb.FilterToExpression(getTimeFilter(t), nil)
+	return query
+}
+
+func metadataToOrientDBSelectString(m GraphElementMatcher) (string, error) {
 	if m == nil {
-		return ""
+		return "", nil
 	}
 
 	var filter *filters.Filter
 	filter, err := m.Filter()
 	if err != nil {
-		return ""
+		return "", err
 	}
 
 	return orientdb.FilterToExpression(filter, func(k string) string {
@@ -218,7 +225,7 @@ func (o *OrientDBBackend) NodeDeleted(n *Node) bool {
 
 // GetNode get a node within a time slice
 func (o *OrientDBBackend) GetNode(i Identifier, t GraphContext) (nodes []*Node) {
-	query := orientdb.FilterToExpression(getTimeFilter(t.TimeSlice), nil)
+	query := timeSliceToOrientDBSelectString(t.TimeSlice)
 	query += fmt.Sprintf(" AND ID = '%s' ORDER BY Revision", i)
 	if t.TimePoint {
 		query += " DESC LIMIT 1"
@@ -228,9 +235,15 @@ func (o *OrientDBBackend) GetNode(i Identifier, t GraphContext) (nodes []*Node)
 
 // GetNodeEdges returns a list of a node edges within time slice
 func (o *OrientDBBackend) GetNodeEdges(n *Node, t GraphContext, m GraphElementMatcher) (edges []*Edge) {
-	query := orientdb.FilterToExpression(getTimeFilter(t.TimeSlice), nil)
+	query := timeSliceToOrientDBSelectString(t.TimeSlice)
 	query += fmt.Sprintf(" AND (Parent = '%s' OR Child = '%s')", n.ID, n.ID)
-	if metadataQuery := metadataToOrientDBSelectString(m); metadataQuery != "" {
+
+	metadataQuery, err := metadataToOrientDBSelectString(m)
+	if err != nil {
+		logging.GetLogger().Errorf("Error while retrieving edges: %s", err)
+		return nil
+	}
+	if metadataQuery != "" {
 		query += " AND " + metadataQuery
 	}
 	return o.searchEdges(t, query)
@@ -261,7 +274,7 @@ func (o *OrientDBBackend) EdgeDeleted(e *Edge) bool {
 
 // GetEdge get an edge within a time slice
 func (o *OrientDBBackend) GetEdge(i Identifier, t GraphContext) []*Edge {
-	query := orientdb.FilterToExpression(getTimeFilter(t.TimeSlice), nil)
+	query := timeSliceToOrientDBSelectString(t.TimeSlice)
 	query += fmt.Sprintf(" AND ID = '%s' ORDER BY Revision", i)
 	if t.TimePoint {
 		query += " DESC LIMIT 1"
@@ -271,7 +284,7 @@ func (o *OrientDBBackend) GetEdge(i Identifier, t GraphContext) []*Edge {
 
 // GetEdgeNodes returns the parents and child nodes of an edge within time slice, matching metadata
 func (o *OrientDBBackend) GetEdgeNodes(e *Edge, t GraphContext, parentMetadata, childMetadata GraphElementMatcher) (parents []*Node, children []*Node) {
-	query := orientdb.FilterToExpression(getTimeFilter(t.TimeSlice), nil)
+	query := timeSliceToOrientDBSelectString(t.TimeSlice)
 	query += fmt.Sprintf(" AND ID in [\"%s\", \"%s\"]", e.parent, e.child)
 
 	for _, node := range o.searchNodes(t, query) {
@@ -309,8 +322,14 @@ func (o *OrientDBBackend) MetadataUpdated(i interface{}) bool {
 
 // GetNodes returns a list of nodes within time slice, matching metadata
 func (o *OrientDBBackend) GetNodes(t GraphContext, m GraphElementMatcher) (nodes []*Node) {
-	query := orientdb.FilterToExpression(getTimeFilter(t.TimeSlice), nil)
-	if metadataQuery := metadataToOrientDBSelectString(m); metadataQuery != "" {
+	query := timeSliceToOrientDBSelectString(t.TimeSlice)
+
+	metadataQuery, err := metadataToOrientDBSelectString(m)
+	if err != nil {
+		logging.GetLogger().Errorf("Error while retrieving nodes: %s", err)
+		return nil
+	}
+	if metadataQuery != "" {
 		query += " AND " + metadataQuery
 	}
 
@@ -320,8 +339,14 @@ func (o *OrientDBBackend) GetNodes(t GraphContext, m GraphElementMatcher) (nodes
 
 // GetEdges returns a list of edges within time slice, matching metadata
 func (o *OrientDBBackend) GetEdges(t GraphContext, m GraphElementMatcher) (edges []*Edge) {
-	query := orientdb.FilterToExpression(getTimeFilter(t.TimeSlice), nil)
-	if metadataQuery := metadataToOrientDBSelectString(m); metadataQuery != "" {
+	query := timeSliceToOrientDBSelectString(t.TimeSlice)
+
+	metadataQuery, err := metadataToOrientDBSelectString(m)
+	if err != nil {
+		logging.GetLogger().Errorf("Error while retrieving edges: %s", err)
+		return nil
+	}
+	if metadataQuery != "" {
 		query += " AND " + metadataQuery
 	}
 
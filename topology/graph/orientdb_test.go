@@ -23,10 +23,12 @@
 package graph
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -322,3 +324,68 @@ func TestLocalHistory(t *testing.T) {
 		t.Fatalf("Expected orientdb records not found: \nexpected: %s\ngot: %s", spew.Sdump(expected), spew.Sdump(client.getOps()))
 	}
 }
+
+func TestOrientDBTypedMetadata(t *testing.T) {
+	g, client := newOrientDBGraph(t)
+
+	g.newNode("aaa", typedMetadata, time.Unix(1, 0), "host1")
+
+	data, err := json.Marshal(client.getOps()[0].data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc orientdb.Document
+	if err := common.JSONDecode(bytes.NewReader(data), &doc); err != nil {
+		t.Fatal(err)
+	}
+	client.searchResult = []orientdb.Document{doc}
+
+	nodes := g.backend.GetNode("aaa", liveContext)
+	if len(nodes) != 1 {
+		t.Fatalf("Expected one node, got %+v", nodes)
+	}
+	checkTypedMetadata(t, nodes[0])
+}
+
+func TestOrientDBIPV6RangeFilter(t *testing.T) {
+	g, client := newOrientDBGraph(t)
+
+	f, err := filters.NewIPV6RangeFilter("IPV6", "fd00::/64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := g.backend.GetNodes(liveContext, NewGraphElementFilter(&filters.Filter{IPV6RangeFilter: f}))
+	if len(nodes) != 0 {
+		t.Fatalf("Expected no node, got %+v", nodes)
+	}
+
+	if ops := client.getOps(); len(ops) != 0 {
+		t.Fatalf("Expected no search to be sent, got %+v", ops)
+	}
+}
+
+func TestOrientDBCIDRFilter(t *testing.T) {
+	g, client := newOrientDBGraph(t)
+
+	f, err := filters.NewCIDRFilter("IPV4", "192.168.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.backend.GetNodes(liveContext, NewGraphElementFilter(&filters.Filter{CIDRFilter: f}))
+
+	ops := client.getOps()
+	if len(ops) != 1 || !strings.Contains(ops[0].data.(string), "Metadata['IPV4'] MATCHES") {
+		t.Fatalf("Expected a MATCHES search, got %+v", ops)
+	}
+
+	if f, err = filters.NewCIDRFilter("IPV6", "fd00::/64"); err != nil {
+		t.Fatal(err)
+	}
+	g.backend.GetNodes(liveContext, NewGraphElementFilter(&filters.Filter{CIDRFilter: f}))
+
+	if ops := client.getOps(); len(ops) != 1 {
+		t.Fatalf("Expected no search to be sent for an IPv6 CIDR, got %+v", ops)
+	}
+}
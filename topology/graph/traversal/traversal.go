@@ -224,6 +224,30 @@ func KeyValueToFilter(k string, v interface{}) (*filters.Filter, error) {
 		}
 
 		return &filters.Filter{IPV4RangeFilter: rf}, nil
+	case *IPV6RangeGraphElementMatcher:
+		cidr, ok := v.value.(string)
+		if !ok {
+			return nil, errors.New("Ipv6Range value has to be a string")
+		}
+
+		rf, err := filters.NewIPV6RangeFilter(k, cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &filters.Filter{IPV6RangeFilter: rf}, nil
+	case *CIDRGraphElementMatcher:
+		cidr, ok := v.value.(string)
+		if !ok {
+			return nil, errors.New("InCidr value has to be a string")
+		}
+
+		cf, err := filters.NewCIDRFilter(k, cidr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &filters.Filter{CIDRFilter: cf}, nil
 	default:
 		i, err := common.ToInt64(v)
 		if err != nil {
@@ -434,6 +458,26 @@ func IPV4Range(s interface{}) *IPV4RangeGraphElementMatcher {
 	return &IPV4RangeGraphElementMatcher{value: s}
 }
 
+// IPV6RangeGraphElementMatcher matches ipv6 contained in an ipv6 range
+type IPV6RangeGraphElementMatcher struct {
+	value interface{}
+}
+
+// IPV6Range step
+func IPV6Range(s interface{}) *IPV6RangeGraphElementMatcher {
+	return &IPV6RangeGraphElementMatcher{value: s}
+}
+
+// CIDRGraphElementMatcher matches ip of either family contained in a cidr
+type CIDRGraphElementMatcher struct {
+	value interface{}
+}
+
+// InCIDR step
+func InCIDR(s interface{}) *CIDRGraphElementMatcher {
+	return &CIDRGraphElementMatcher{value: s}
+}
+
 // Since describes a list of metadata that match since seconds
 type Since struct {
 	Seconds int64
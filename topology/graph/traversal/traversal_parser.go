@@ -1034,6 +1034,24 @@ func (p *GremlinTraversalParser) parseStepParams() ([]interface{}, error) {
 				return nil, fmt.Errorf("One parameter expected with IPV4RANGE: %v", ipParams)
 			}
 			params = append(params, IPV4Range(ipParams[0]))
+		case IPV6RANGE:
+			ipParams, err := p.parseStepParams()
+			if err != nil {
+				return nil, err
+			}
+			if len(ipParams) != 1 {
+				return nil, fmt.Errorf("One parameter expected with IPV6RANGE: %v", ipParams)
+			}
+			params = append(params, IPV6Range(ipParams[0]))
+		case INCIDR:
+			cidrParams, err := p.parseStepParams()
+			if err != nil {
+				return nil, err
+			}
+			if len(cidrParams) != 1 {
+				return nil, fmt.Errorf("One parameter expected with INCIDR: %v", cidrParams)
+			}
+			params = append(params, InCIDR(cidrParams[0]))
 		case FOREVER:
 			params = append(params, &ForeverPredicate{})
 		case NOW:
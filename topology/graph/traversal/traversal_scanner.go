@@ -91,6 +91,8 @@ const (
 	ASC
 	DESC
 	IPV4RANGE
+	IPV6RANGE
+	INCIDR
 	SUBGRAPH
 	FOREVER
 	NOW
@@ -294,6 +296,10 @@ func (s *GremlinTraversalScanner) scanIdent() (tok Token, lit string) {
 		return DESC, buf.String()
 	case "IPV4RANGE":
 		return IPV4RANGE, buf.String()
+	case "IPV6RANGE":
+		return IPV6RANGE, buf.String()
+	case "INCIDR":
+		return INCIDR, buf.String()
 	case "SUBGRAPH":
 		return SUBGRAPH, buf.String()
 	case "FOREVER":
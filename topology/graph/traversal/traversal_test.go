@@ -27,6 +27,7 @@ import (
 	"testing"
 
 	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/filters"
 	"github.com/skydive-project/skydive/topology/graph"
 )
 
@@ -381,6 +382,157 @@ func TestTraversalIpv4Range(t *testing.T) {
 	}
 }
 
+func TestTraversalIpv6Range(t *testing.T) {
+	g := newGraph(t)
+
+	g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node1", "IPV6": "2001:db8:0:1::10/64"})
+	g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node2", "IPV6": []string{"10.0.0.1", "fe80::1%eth0"}})
+	g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node3", "IPV6": []interface{}{"192.168.0.1/24", "2001:0db8:0000:0002:0000:0000:0000:0001"}})
+	g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node4", "IPV6": "10.0.0.2"})
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().Has("IPV6", IPV6Range("2001:db8:0:1::/64"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("2001:db8::/32"))
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("2001:db8:0:2::1/128"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("2001:db8:0:2::2/128"))
+	if len(tv.Values()) != 0 {
+		t.Fatalf("Shouldn't return node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("fe80::/64"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test, ipv4 addresses are never matched
+	tv = tr.V().Has("IPV6", IPV6Range("::/0"))
+	if len(tv.Values()) != 3 {
+		t.Fatalf("Should return 3 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IPV6", IPV6Range("10.0.0.0/24"))
+	if tv.Error() == nil {
+		t.Fatal("Should return an error with an ipv4 range")
+	}
+
+	// next test
+	res := execTraversalQuery(t, g, `G.V().Has("IPV6", Ipv6Range("2001:db8:0:1::/64"))`)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+}
+
+func TestTraversalInCIDR(t *testing.T) {
+	g := newGraph(t)
+
+	n1 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node1", "IP": "192.168.0.34/24"})
+	n2 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node2", "IP": []string{"10.0.0.1", "2001:db8:0:1::10/64"}})
+	n3 := g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node3", "IP": []interface{}{"fe80::1%eth0"}})
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().Has("IP", InCIDR("192.168.0.0/16"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IP", InCIDR("2001:db8::/32"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IP", InCIDR("10.0.0.1/32"))
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("IP", InCIDR("10.0.0.0/33"))
+	if tv.Error() == nil {
+		t.Fatal("Should return an error with an invalid cidr")
+	}
+
+	// next test
+	res := execTraversalQuery(t, g, `G.V().Has("IP", InCidr("fe80::/10"))`)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+
+	// next test, composed with the boolean filters
+	v4, err := filters.NewCIDRFilter("IP", "10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v6, err := filters.NewCIDRFilter("IP", "2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter := filters.NewAndFilter(&filters.Filter{CIDRFilter: v4}, &filters.Filter{CIDRFilter: v6})
+	if filter.Eval(n1) || !filter.Eval(n2) || filter.Eval(n3) {
+		t.Fatal("And filter of cidrs should only match Node2")
+	}
+
+	filter = filters.NewNotFilter(&filters.Filter{CIDRFilter: v4})
+	if !filter.Eval(n1) || filter.Eval(n2) || !filter.Eval(n3) {
+		t.Fatal("Not filter of cidr should match all the nodes but Node2")
+	}
+}
+
+func TestTraversalArrayField(t *testing.T) {
+	g := newGraph(t)
+
+	g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node1", "CPU": []interface{}{
+		map[string]interface{}{"ModelName": "Xeon"},
+		map[string]interface{}{"ModelName": "Opteron"},
+	}})
+	g.NewNode(graph.GenID(), graph.Metadata{"Name": "Node2", "CPU": []interface{}{
+		map[string]interface{}{"ModelName": "Opteron"},
+	}})
+
+	tr := NewGraphTraversal(g, false)
+
+	// next test
+	tv := tr.V().Has("CPU.0.ModelName", "Opteron")
+	if len(tv.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", tv.Values())
+	}
+
+	// next test
+	tv = tr.V().Has("CPU.*.ModelName", "Opteron")
+	if len(tv.Values()) != 2 {
+		t.Fatalf("Should return 2 nodes, returned: %v", tv.Values())
+	}
+
+	// next test
+	res := execTraversalQuery(t, g, `G.V().Has("CPU.1.ModelName", "Opteron")`)
+	if len(res.Values()) != 1 {
+		t.Fatalf("Should return 1 node, returned: %v", res.Values())
+	}
+}
+
 func TestTraversalBoth(t *testing.T) {
 	g := newTransversalGraph(t)
 
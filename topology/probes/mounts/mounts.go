@@ -0,0 +1,265 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package mounts
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/skydive-project/skydive/config"
+	"github.com/skydive-project/skydive/logging"
+	"github.com/skydive-project/skydive/topology"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// usageDelta is the minimal change of the used percentage of a filesystem
+// that triggers an update of its node
+const usageDelta = 1.0
+
+// MountInfo describes a mounted filesystem as reported by /proc/self/mountinfo
+type MountInfo struct {
+	Root       string
+	Mountpoint string
+	Options    string
+	FSType     string
+	Source     string
+}
+
+// IsBindMount returns whether the mount is a bind mount of a sub directory
+func (m *MountInfo) IsBindMount() bool {
+	return m.Root != "/"
+}
+
+// MountsProbe describes a probe that reports the mounted filesystems
+type MountsProbe struct {
+	graph           *graph.Graph
+	host            *graph.Node
+	quit            chan bool
+	nodes           map[string]*graph.Node
+	excludedFSTypes map[string]bool
+	bindMounts      bool
+}
+
+// unescapeMountInfo decodes the octal escaped characters of mountinfo fields
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// ParseMountInfo parses the content of a mountinfo file
+func ParseMountInfo(content []byte) []*MountInfo {
+	var mounts []*MountInfo
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep == -1 || sep+2 >= len(fields) {
+			continue
+		}
+
+		mounts = append(mounts, &MountInfo{
+			Root:       unescapeMountInfo(fields[3]),
+			Mountpoint: unescapeMountInfo(fields[4]),
+			Options:    fields[5],
+			FSType:     fields[sep+1],
+			Source:     unescapeMountInfo(fields[sep+2]),
+		})
+	}
+
+	return mounts
+}
+
+func (p *MountsProbe) isExcluded(mount *MountInfo) bool {
+	return p.excludedFSTypes[mount.FSType] || (!p.bindMounts && mount.IsBindMount())
+}
+
+func mountMetadata(mount *MountInfo) graph.Metadata {
+	m := graph.Metadata{
+		"Type":       "filesystem",
+		"Name":       mount.Mountpoint,
+		"Mountpoint": mount.Mountpoint,
+		"FSType":     mount.FSType,
+		"Device":     mount.Source,
+		"Options":    mount.Options,
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(mount.Mountpoint, &stat); err == nil {
+		size := int64(stat.Blocks) * int64(stat.Bsize)
+		used := size - int64(stat.Bfree)*int64(stat.Bsize)
+		avail := int64(stat.Bavail) * int64(stat.Bsize)
+
+		m["Size"] = size
+		m["Used"] = used
+		if used+avail > 0 {
+			// same computation as df, reserved blocks are not accounted
+			m["UsedPct"] = math.Ceil(float64(used)*10000/float64(used+avail)) / 100
+		}
+	}
+
+	return m
+}
+
+// linkBlockDevice links the filesystem node to the node of its backing block device if any
+func (p *MountsProbe) linkBlockDevice(node *graph.Node, device string) {
+	if !strings.HasPrefix(device, "/dev/") {
+		return
+	}
+
+	if path, err := filepath.EvalSymlinks(device); err == nil {
+		device = path
+	}
+
+	filter := graph.Metadata{"Type": "blockdev", "Name": filepath.Base(device)}
+	if blockdev := p.graph.LookupFirstChild(p.host, filter); blockdev != nil {
+		p.graph.LinkOnce(node, blockdev, graph.Metadata{"RelationType": "mount"})
+	}
+}
+
+// updateNode updates the usage of a filesystem node only if it changed more
+// than usageDelta to avoid generating an event at each update
+func (p *MountsProbe) updateNode(node *graph.Node, m graph.Metadata) {
+	if last, err := node.GetFieldFloat64("UsedPct"); err == nil {
+		if usedPct, err := m.GetFieldFloat64("UsedPct"); err == nil && math.Abs(last-usedPct) < usageDelta {
+			m["UsedPct"], _ = node.GetField("UsedPct")
+			m["Used"], _ = node.GetField("Used")
+		}
+	}
+
+	tr := p.graph.StartMetadataTransaction(node)
+	for k, v := range m {
+		tr.AddMetadata(k, v)
+	}
+	tr.Commit()
+}
+
+func (p *MountsProbe) update() {
+	content, err := ioutil.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		logging.GetLogger().Errorf("Unable to read mountinfo: %s", err)
+		return
+	}
+
+	mounts := make(map[string]graph.Metadata)
+	for _, mount := range ParseMountInfo(content) {
+		if !p.isExcluded(mount) {
+			mounts[mount.Mountpoint] = mountMetadata(mount)
+		}
+	}
+
+	p.graph.Lock()
+	defer p.graph.Unlock()
+
+	for mountpoint, node := range p.nodes {
+		if _, ok := mounts[mountpoint]; !ok {
+			p.graph.DelNode(node)
+			delete(p.nodes, mountpoint)
+		}
+	}
+
+	for mountpoint, m := range mounts {
+		node, ok := p.nodes[mountpoint]
+		if ok {
+			p.updateNode(node, m)
+		} else {
+			node = p.graph.NewNode(graph.GenID(), m)
+			topology.AddOwnershipLink(p.graph, p.host, node, nil)
+			p.nodes[mountpoint] = node
+		}
+
+		device, _ := m["Device"].(string)
+		p.linkBlockDevice(node, device)
+	}
+}
+
+// Start the mounts probe
+func (p *MountsProbe) Start() {
+	go func() {
+		ticker := time.NewTicker(config.GetDuration("agent.topology.mounts.update"))
+		defer ticker.Stop()
+
+		p.update()
+		for {
+			select {
+			case <-p.quit:
+				return
+			case <-ticker.C:
+				p.update()
+			}
+		}
+	}()
+}
+
+// Stop the mounts probe
+func (p *MountsProbe) Stop() {
+	p.quit <- true
+}
+
+// NewMountsProbe creates a new mounts probe
+func NewMountsProbe(g *graph.Graph, host *graph.Node) *MountsProbe {
+	excludedFSTypes := make(map[string]bool)
+	for _, fsType := range config.GetStringSlice("agent.topology.mounts.exclude_fstypes") {
+		excludedFSTypes[fsType] = true
+	}
+
+	return &MountsProbe{
+		graph:           g,
+		host:            host,
+		quit:            make(chan bool),
+		nodes:           make(map[string]*graph.Node),
+		excludedFSTypes: excludedFSTypes,
+		bindMounts:      config.GetBool("agent.topology.mounts.bind_mounts"),
+	}
+}
@@ -0,0 +1,52 @@
+// +build linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package mounts
+
+import (
+	"reflect"
+	"testing"
+)
+
+const mountInfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+36 22 8:1 /srv/data /mnt/my\040data rw,noatime master:1 - ext4 /dev/sda1 rw
+`
+
+func TestParseMountInfo(t *testing.T) {
+	expected := []*MountInfo{
+		{Root: "/", Mountpoint: "/", Options: "rw,relatime", FSType: "ext4", Source: "/dev/sda1"},
+		{Root: "/", Mountpoint: "/proc", Options: "rw,nosuid,nodev,noexec,relatime", FSType: "proc", Source: "proc"},
+		{Root: "/srv/data", Mountpoint: "/mnt/my data", Options: "rw,noatime", FSType: "ext4", Source: "/dev/sda1"},
+	}
+
+	mounts := ParseMountInfo([]byte(mountInfo))
+	if !reflect.DeepEqual(mounts, expected) {
+		t.Fatalf("Parsing of mountinfo failed, expected: %+v, got: %+v", expected, mounts)
+	}
+
+	if mounts[0].IsBindMount() || !mounts[2].IsBindMount() {
+		t.Fatal("Bind mounts detection failed")
+	}
+}
@@ -0,0 +1,46 @@
+// +build !linux
+
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package mounts
+
+import (
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+// MountsProbe describes a probe that reports the mounted filesystems
+type MountsProbe struct {
+}
+
+// Start the mounts probe
+func (p *MountsProbe) Start() {
+}
+
+// Stop the mounts probe
+func (p *MountsProbe) Stop() {
+}
+
+// NewMountsProbe creates a new mounts probe
+func NewMountsProbe(g *graph.Graph, host *graph.Node) *MountsProbe {
+	return &MountsProbe{}
+}
@@ -636,19 +636,16 @@ func (u *NetNsNetLinkProbe) onAddressAdded(addr netlink.Addr, family int, index
 		return
 	}
 
-	var ips []string
 	key := getFamilyKey(family)
-	if v, err := intf.GetField(key); err == nil {
-		ips, ok := v.([]string)
-		if !ok {
-			logging.GetLogger().Errorf("Failed to get IP addresses for node %s", intf.ID)
+	ips, err := intf.GetFieldStringList(key)
+	if err == common.ErrFieldWrongType {
+		logging.GetLogger().Errorf("Failed to get IP addresses for node %s: %s", intf.ID, err)
+		return
+	}
+	for _, ip := range ips {
+		if ip == addr.IPNet.String() {
 			return
 		}
-		for _, ip := range ips {
-			if ip == addr.IPNet.String() {
-				return
-			}
-		}
 	}
 
 	u.Graph.AddMetadata(intf, key, append(ips, addr.IPNet.String()))
@@ -665,25 +662,26 @@ func (u *NetNsNetLinkProbe) onAddressDeleted(addr netlink.Addr, family int, inde
 	}
 
 	key := getFamilyKey(family)
-	if v, err := intf.GetField(key); err == nil {
-		ips, ok := v.([]string)
-		if !ok {
-			logging.GetLogger().Errorf("Failed to get IP addresses for node %s", intf.ID)
-			return
-		}
-		for i, ip := range ips {
-			if ip == addr.IPNet.String() {
-				ips = append(ips[:i], ips[i+1:]...)
-				break
-			}
+	ips, err := intf.GetFieldStringList(key)
+	if err != nil {
+		if err == common.ErrFieldWrongType {
+			logging.GetLogger().Errorf("Failed to get IP addresses for node %s: %s", intf.ID, err)
 		}
+		return
+	}
 
-		if len(ips) == 0 {
-			u.Graph.DelMetadata(intf, key)
-		} else {
-			u.Graph.AddMetadata(intf, key, ips)
+	for i, ip := range ips {
+		if ip == addr.IPNet.String() {
+			ips = append(ips[:i], ips[i+1:]...)
+			break
 		}
 	}
+
+	if len(ips) == 0 {
+		u.Graph.DelMetadata(intf, key)
+	} else {
+		u.Graph.AddMetadata(intf, key, ips)
+	}
 }
 
 func (u *NetNsNetLinkProbe) initialize() {
@@ -879,8 +877,7 @@ Ready:
 	}
 	u.initialize()
 
-	seconds := config.GetInt("agent.topology.netlink.metrics_update")
-	metricTicker := time.NewTicker(time.Duration(seconds) * time.Second)
+	metricTicker := time.NewTicker(config.GetDuration("agent.topology.netlink.metrics_update"))
 	defer metricTicker.Stop()
 
 	featureTicker := time.NewTicker(5 * time.Second)
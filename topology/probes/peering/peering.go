@@ -23,6 +23,7 @@
 package peering
 
 import (
+	"github.com/skydive-project/skydive/filters"
 	"github.com/skydive-project/skydive/logging"
 	"github.com/skydive-project/skydive/topology"
 	"github.com/skydive-project/skydive/topology/graph"
@@ -95,7 +96,10 @@ func NewPeeringProbe(g *graph.Graph) *PeeringProbe {
 		graph: g,
 		peers: make(map[string]*graph.Node),
 	}
-	g.AddEventListener(probe)
+
+	// only the interfaces having a MAC address or a peer MAC address are of interest
+	filter := filters.NewOrFilter(filters.NewNotNullFilter("MAC"), filters.NewNotNullFilter("PeerIntfMAC"))
+	g.AddEventListenerWithFilter(probe, graph.NewGraphElementFilter(filter))
 
 	return probe
 }
@@ -275,8 +275,7 @@ func (s *ProcSocketInfoProbe) Start() {
 	s.updateMetadata()
 
 	go func() {
-		seconds := config.GetInt("agent.topology.socketinfo.host_update")
-		ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+		ticker := time.NewTicker(config.GetDuration("agent.topology.socketinfo.host_update"))
 		defer ticker.Stop()
 
 		for {
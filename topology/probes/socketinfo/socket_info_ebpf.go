@@ -109,8 +109,7 @@ func (s *EBPFSocketInfoProbe) Start() {
 	s.updateMetadata()
 
 	go func() {
-		seconds := config.GetInt("agent.topology.socketinfo.host_update")
-		ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+		ticker := time.NewTicker(config.GetDuration("agent.topology.socketinfo.host_update"))
 		defer ticker.Stop()
 
 		for {
@@ -126,7 +126,7 @@ func GetOwnershipLink(g *graph.Graph, parent *graph.Node, child *graph.Node) *gr
 func AddOwnershipLink(g *graph.Graph, parent *graph.Node, child *graph.Node, metadata graph.Metadata, h ...string) *graph.Edge {
 	// a child node can only have one parent of type ownership, so delete the previous link
 	for _, e := range g.GetNodeEdges(child, OwnershipMetadata) {
-		if e.GetChild() == child.ID {
+		if e.GetChild() == child.ID && e.GetParent() != parent.ID {
 			logging.GetLogger().Debugf("Delete previous ownership link: %v", e)
 			g.DelEdge(e)
 		}
@@ -138,7 +138,8 @@ func AddOwnershipLink(g *graph.Graph, parent *graph.Node, child *graph.Node, met
 	}
 
 	id, _ := uuid.NewV5(uuid.NamespaceOID, []byte(parent.ID+child.ID+OwnershipLink))
-	return g.NewEdge(graph.Identifier(id.String()), parent, child, m, h...)
+	e, _ := g.NewEdgeOnce(graph.Identifier(id.String()), parent, child, m, h...)
+	return e
 }
 
 // HaveLayer2Link returns true if parent and child have the same layer 2
@@ -154,5 +155,6 @@ func AddLayer2Link(g *graph.Graph, node1 *graph.Node, node2 *graph.Node, metadat
 	}
 
 	id, _ := uuid.NewV5(uuid.NamespaceOID, []byte(node1.ID+node2.ID+Layer2Link))
-	return g.NewEdge(graph.Identifier(id.String()), node1, node2, m)
+	e, _ := g.NewEdgeOnce(graph.Identifier(id.String()), node1, node2, m)
+	return e
 }
@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2018 Red Hat, Inc.
+ *
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ */
+
+package topology
+
+import (
+	"testing"
+
+	"github.com/skydive-project/skydive/common"
+	"github.com/skydive-project/skydive/topology/graph"
+)
+
+func TestAddOwnershipLinkOnce(t *testing.T) {
+	b, err := graph.NewMemoryBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := graph.NewGraphFromConfig(b, common.UnknownService)
+
+	parent := g.NewNode(graph.GenID(), graph.Metadata{"Type": "host"})
+	child := g.NewNode(graph.GenID(), graph.Metadata{"Type": "device"})
+
+	e := AddOwnershipLink(g, parent, child, nil)
+	for i := 0; i < 3; i++ {
+		if e2 := AddOwnershipLink(g, parent, child, graph.Metadata{"Pass": i}); e2.ID != e.ID {
+			t.Errorf("Ownership link should have been updated, got a new edge %s", e2.ID)
+		}
+	}
+
+	if edges := g.GetNodeEdges(child, nil); len(edges) != 1 {
+		t.Fatalf("Expected 1 edge, got %d", len(edges))
+	}
+
+	if pass, _ := e.GetFieldInt64("Pass"); pass != 2 {
+		t.Errorf("Ownership link metadata not updated: %v", e.Metadata())
+	}
+
+	// a new parent replaces the previous ownership link
+	newParent := g.NewNode(graph.GenID(), graph.Metadata{"Type": "netns"})
+	AddOwnershipLink(g, newParent, child, nil)
+
+	if edges := g.GetNodeEdges(child, nil); len(edges) != 1 || edges[0].GetParent() != newParent.ID {
+		t.Errorf("Expected only the ownership link of the new parent, got %v", edges)
+	}
+}